@@ -5,14 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"time"
 
 	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/events"
+	"github.com/Melikhov-p/go-loyalty-system/internal/grpcapi"
 	"github.com/Melikhov-p/go-loyalty-system/internal/logger"
 	"github.com/Melikhov-p/go-loyalty-system/internal/router"
+	"github.com/Melikhov-p/go-loyalty-system/internal/services"
 	"github.com/Melikhov-p/go-loyalty-system/internal/workers"
 	"github.com/Melikhov-p/go-loyalty-system/pkg"
 	"go.uber.org/zap"
@@ -49,6 +53,9 @@ func Run() (err error) {
 	})
 
 	cfg := config.BuildConfig()
+	if err = cfg.Validate(); err != nil {
+		return fmt.Errorf("error validating config: %w", err)
+	}
 
 	lgr, err := logger.BuildLogger(cfg.LogLevel)
 	if err != nil {
@@ -61,17 +68,31 @@ func Run() (err error) {
 		return fmt.Errorf("error connecting database: %w", err)
 	}
 	lgr.Debug("database connected")
+
+	jobs := services.NewJobs(ctx)
 	// отслеживаем успешное закрытие соединения с БД
 	eg.Go(func() error {
 		defer log.Print("closed DB")
 
 		<-ctx.Done()
 
+		// фоновые задания записывают свой итог и после отмены контекста
+		jobs.Wait()
 		_ = db.Close()
 		return nil
 	})
 
-	r := router.CreateRouter(cfg, lgr, db)
+	broker := events.NewBroker(lgr, cfg, db)
+	// слушаем события пользователей из БД, при остановке брокер закрывает все подписки
+	eg.Go(func() error {
+		broker.Listen(ctx)
+		return nil
+	})
+
+	r, err := router.CreateRouter(cfg, lgr, db, broker, jobs)
+	if err != nil {
+		return fmt.Errorf("error creating router: %w", err)
+	}
 	lgr.Debug("router ready")
 
 	lgr.Debug("starting server",
@@ -104,6 +125,40 @@ func Run() (err error) {
 		return nil
 	})
 
+	grpcServer := grpcapi.NewGRPCServer(lgr, cfg, db, broker)
+	lgr.Debug("starting grpc server", zap.String("GRPCAddr", cfg.GRPCAddr))
+
+	eg.Go(func() error {
+		listener, err := net.Listen("tcp", cfg.GRPCAddr)
+		if err != nil {
+			return fmt.Errorf("error listening grpc address: %w", err)
+		}
+		// после Stop или GracefulStop Serve возвращает nil
+		if err = grpcServer.Serve(listener); err != nil {
+			return fmt.Errorf("grpc server can not serve anymore: %w", err)
+		}
+		return nil
+	})
+
+	eg.Go(func() error {
+		defer lgr.Debug("grpc server has been shutdown")
+		<-ctx.Done()
+
+		// GracefulStop ждёт открытые потоки WatchOrders, они завершаются вместе с брокером
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(timeoutServerShutdown):
+			grpcServer.Stop()
+		}
+		return nil
+	})
+
 	workDispatcher := workers.NewDispatcher(lgr, maxWorkersCount, cfg, db, cfg.Dispatcher.PingInterval)
 
 	eg.Go(func() error {
@@ -118,6 +173,36 @@ func Run() (err error) {
 		return nil
 	})
 
+	notificationSender, err := services.NewNotificationSender(lgr, cfg, db)
+	if err != nil {
+		return fmt.Errorf("error creating notification sender: %w", err)
+	}
+
+	scheduler := workers.NewScheduler(lgr)
+	scheduler.Add("tier recalculation", cfg.Scheduler.TierRecalcInterval,
+		services.NewTierService(lgr, cfg, db).RecalculateTiers)
+	scheduler.Add("points expiry", cfg.Scheduler.PointsExpiryInterval,
+		services.NewExpiryService(lgr, cfg, db).ExpirePoints)
+	scheduler.Add("referral rewards", cfg.Scheduler.ReferralInterval,
+		services.NewReferralService(lgr, cfg, db).ReleaseRewards)
+	scheduler.Add("hold expiry", cfg.Scheduler.HoldExpiryInterval,
+		services.NewHoldService(lgr, cfg, db).ExpireHolds)
+	scheduler.Add("accrual verification", cfg.Scheduler.VerificationInterval,
+		services.NewVerificationService(lgr, cfg, db).VerifyOrders)
+	scheduler.Add("notifications", cfg.Scheduler.NotifyInterval, notificationSender.ProcessNotifications)
+	scheduler.Add("data export expiry", cfg.Scheduler.ExportExpiryInterval,
+		services.NewExportService(lgr, cfg, db).ExpireExports)
+
+	scheduler.Add("stale order batch jobs", cfg.Scheduler.StaleJobsInterval,
+		services.NewOrderService(lgr, cfg, db).FailStaleBatchJobs)
+	scheduler.Add("stale data exports", cfg.Scheduler.StaleJobsInterval,
+		services.NewExportService(lgr, cfg, db).FailStaleExports)
+
+	eg.Go(func() error {
+		scheduler.Run(ctx)
+		return nil
+	})
+
 	if err = eg.Wait(); err != nil {
 		return fmt.Errorf("errgroup error: %w", err)
 	}
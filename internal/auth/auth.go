@@ -11,9 +11,12 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// Claims ProgramID пуст в токенах, выпущенных до появления программ.
 type Claims struct {
 	jwt.RegisteredClaims
-	UserID int
+	UserID       int
+	ProgramID    int `json:",omitempty"`
+	TokenVersion int `json:",omitempty"`
 }
 
 func GenerateSecretKey() (string, error) {
@@ -26,12 +29,19 @@ func GenerateSecretKey() (string, error) {
 	return hex.EncodeToString(b), nil
 }
 
-func BuildJWTToken(userID int, secretKey string, tokenLifeTime time.Duration) (string, error) {
+func BuildJWTToken(
+	userID, programID, tokenVersion int,
+	issuer, secretKey string,
+	tokenLifeTime time.Duration,
+) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenLifeTime)),
 		},
-		UserID: userID,
+		UserID:       userID,
+		ProgramID:    programID,
+		TokenVersion: tokenVersion,
 	})
 
 	tokenString, err := token.SignedString([]byte(secretKey))
@@ -42,7 +52,7 @@ func BuildJWTToken(userID int, secretKey string, tokenLifeTime time.Duration) (s
 	return tokenString, nil
 }
 
-func GetUserIDbyToken(tokenString string, secretKey string) (int, error) {
+func ParseToken(tokenString string, secretKey string) (*Claims, error) {
 	claims := Claims{}
 	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
 		if token.Method != jwt.SigningMethodHS256 {
@@ -51,14 +61,14 @@ func GetUserIDbyToken(tokenString string, secretKey string) (int, error) {
 		return []byte(secretKey), nil
 	})
 	if err != nil {
-		return -1, fmt.Errorf("error parsing token with claims %w", err)
+		return nil, fmt.Errorf("error parsing token with claims %w", err)
 	}
 
 	if !token.Valid {
-		return -1, errors.New("invalid token")
+		return nil, errors.New("invalid token")
 	}
 
-	return claims.UserID, nil
+	return &claims, nil
 }
 
 func HashFor(target string) string {
@@ -0,0 +1,63 @@
+package campaign
+
+import (
+	"math"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+)
+
+func Evaluate(campaigns []*models.Campaign, order *models.CampaignOrder) []*models.CampaignGrant {
+	var grants []*models.CampaignGrant
+	for _, c := range campaigns {
+		if !isRunning(c, order) {
+			continue
+		}
+
+		points := rulePoints(&c.Rule, order)
+		if c.UserCap != nil {
+			points = math.Min(points, *c.UserCap-order.Granted[c.ID])
+		}
+		points = math.Round(points*100) / 100
+		if points <= 0 {
+			continue
+		}
+
+		grants = append(grants, &models.CampaignGrant{
+			CampaignID:  c.ID,
+			UserID:      order.UserID,
+			OrderNumber: order.Number,
+			RuleType:    c.Rule.Type,
+			Points:      points,
+		})
+	}
+
+	return grants
+}
+
+func isRunning(c *models.Campaign, order *models.CampaignOrder) bool {
+	if !c.Active || order.ProcessedAt.Before(c.StartsAt) {
+		return false
+	}
+	if c.EndsAt != nil && !order.ProcessedAt.Before(*c.EndsAt) {
+		return false
+	}
+	return c.Rule.MerchantID == "" || c.Rule.MerchantID == order.MerchantID
+}
+
+func rulePoints(rule *models.CampaignRule, order *models.CampaignOrder) float64 {
+	switch rule.Type {
+	case models.CampaignRuleMultiplier:
+		return order.Accrual * (rule.Multiplier - 1)
+	case models.CampaignRuleFixed:
+		return rule.Points
+	case models.CampaignRuleFirstOrder:
+		if order.OrdersTotal == 1 {
+			return rule.Points
+		}
+	case models.CampaignRuleNthOrder:
+		if order.OrdersInMonth == rule.N {
+			return rule.Points
+		}
+	}
+	return 0
+}
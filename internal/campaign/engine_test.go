@@ -0,0 +1,94 @@
+package campaign
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2025, 2, 8, 12, 0, 0, 0, time.UTC)
+	weekendEnd := now.Add(36 * time.Hour)
+	cap150 := 150.0
+
+	double := &models.Campaign{
+		ID: 1, Active: true, StartsAt: now.Add(-time.Hour), EndsAt: &weekendEnd,
+		Rule: models.CampaignRule{Type: models.CampaignRuleMultiplier, Multiplier: 2},
+	}
+	firstOrder := &models.Campaign{
+		ID: 2, Active: true, StartsAt: now.AddDate(0, -1, 0),
+		Rule: models.CampaignRule{Type: models.CampaignRuleFirstOrder, Points: 100},
+	}
+	fifthOrder := &models.Campaign{
+		ID: 3, Active: true, StartsAt: now.AddDate(0, -1, 0),
+		Rule: models.CampaignRule{Type: models.CampaignRuleNthOrder, N: 5, Points: 50},
+	}
+	merchant := &models.Campaign{
+		ID: 4, Active: true, StartsAt: now.AddDate(0, -1, 0), UserCap: &cap150,
+		Rule: models.CampaignRule{Type: models.CampaignRuleFixed, Points: 100, MerchantID: "shop-x"},
+	}
+	inactive := &models.Campaign{
+		ID: 5, Active: false, StartsAt: now.AddDate(0, -1, 0),
+		Rule: models.CampaignRule{Type: models.CampaignRuleFixed, Points: 10},
+	}
+	all := []*models.Campaign{double, firstOrder, fifthOrder, merchant, inactive}
+
+	testCases := []struct {
+		name     string
+		order    models.CampaignOrder
+		expected map[int]float64
+	}{
+		{
+			name:     "Double Points And First Order",
+			order:    models.CampaignOrder{Accrual: 42.5, ProcessedAt: now, OrdersTotal: 1, OrdersInMonth: 1},
+			expected: map[int]float64{1: 42.5, 2: 100},
+		},
+		{
+			name:     "Outside Weekend Window",
+			order:    models.CampaignOrder{Accrual: 42.5, ProcessedAt: weekendEnd, OrdersTotal: 2, OrdersInMonth: 2},
+			expected: map[int]float64{},
+		},
+		{
+			name:     "Fifth Order In Month",
+			order:    models.CampaignOrder{Accrual: 10, ProcessedAt: weekendEnd, OrdersTotal: 9, OrdersInMonth: 5},
+			expected: map[int]float64{3: 50},
+		},
+		{
+			name: "Merchant Bonus",
+			order: models.CampaignOrder{
+				MerchantID: "shop-x", ProcessedAt: weekendEnd, OrdersTotal: 3, OrdersInMonth: 3,
+			},
+			expected: map[int]float64{4: 100},
+		},
+		{
+			name: "Merchant Bonus Capped",
+			order: models.CampaignOrder{
+				MerchantID: "shop-x", ProcessedAt: weekendEnd, OrdersTotal: 4, OrdersInMonth: 4,
+				Granted: map[int]float64{4: 100},
+			},
+			expected: map[int]float64{4: 50},
+		},
+		{
+			name: "Merchant Cap Reached",
+			order: models.CampaignOrder{
+				MerchantID: "shop-x", ProcessedAt: weekendEnd, OrdersTotal: 6, OrdersInMonth: 6,
+				Granted: map[int]float64{4: 150},
+			},
+			expected: map[int]float64{},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			grants := Evaluate(all, &test.order)
+
+			got := make(map[int]float64, len(grants))
+			for _, g := range grants {
+				got[g.CampaignID] = g.Points
+			}
+			assert.Equal(t, test.expected, got)
+		})
+	}
+}
@@ -1,15 +1,93 @@
 package config
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"os"
+	"strconv"
 	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
 )
 
+var ErrBadConfig error = errors.New("bad config")
+
 type WorkDispatcherConfig struct {
 	PingInterval time.Duration
 }
 
+type SchedulerConfig struct {
+	TierRecalcInterval   time.Duration
+	PointsExpiryInterval time.Duration
+	ReferralInterval     time.Duration
+	HoldExpiryInterval   time.Duration
+	VerificationInterval time.Duration
+	NotifyInterval       time.Duration
+	ExportExpiryInterval time.Duration
+	StaleJobsInterval    time.Duration
+	// TierWindow скользящее окно, за которое считаются накопления для уровня
+	TierWindow time.Duration
+}
+
+type PointsConfig struct {
+	ExpiryMonths int
+	// ExpiringSoonWindow за сколько до сгорания баллы попадают в expiring_soon и уведомление
+	ExpiringSoonWindow time.Duration
+}
+
+type ReferralConfig struct {
+	ReferrerPoints float64
+	RefereePoints  float64
+	// HoldPeriod сколько заказ приглашённого должен оставаться обработанным до выплаты бонусов
+	HoldPeriod     time.Duration
+	MaxPerReferrer int
+}
+
+type TransferConfig struct {
+	DailyLimit float64
+	// ConfirmAbove переводы больше этой суммы ждут подтверждения отправителем, 0 отключает подтверждение
+	ConfirmAbove float64
+	ConfirmTTL   time.Duration
+}
+
+type HoldConfig struct {
+	// TTL через сколько незахваченный резерв возвращается на баланс
+	TTL time.Duration
+}
+
+type VerificationConfig struct {
+	// Window сколько после обработки заказ перепроверяется в системе начислений, 0 отключает сверку
+	Window time.Duration
+	// NegativePolicy allow_debt, block_withdrawals или review
+	NegativePolicy string
+}
+
+type NotifyConfig struct {
+	// SMTPAddr host:port почтового сервера, пустой адрес отключает письма
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string `json:"-"`
+	From         string
+	// FileSink путь к файлу, куда вместо отправки пишутся все уведомления; для тестов и локального запуска
+	FileSink     string
+	MaxAttempts  int
+	RetryBackoff time.Duration
+	// RateLimit сколько уведомлений пользователь получает за RateWindow, остальные откладываются
+	RateLimit  int
+	RateWindow time.Duration
+}
+
+type ExportConfig struct {
+	// TTL сколько готовый архив доступен для скачивания
+	TTL time.Duration
+}
+
+type JobsConfig struct {
+	// Timeout сколько может выполняться задание; не завершённое за это время считается прерванным
+	Timeout time.Duration
+}
+
 type configDB struct {
 	DatabaseURI    string
 	MigrationPath  string
@@ -19,15 +97,29 @@ type configDB struct {
 
 type Config struct {
 	RunAddr       string
+	GRPCAddr      string
 	AccrualAddr   string
 	LogLevel      string
+	AdminAPIKey   string `json:"-"`
 	DB            *configDB
 	Dispatcher    *WorkDispatcherConfig
+	Scheduler     *SchedulerConfig
+	Points        *PointsConfig
+	Referral      *ReferralConfig
+	Transfer      *TransferConfig
+	Hold          *HoldConfig
+	Verification  *VerificationConfig
+	Notify        *NotifyConfig
+	Export        *ExportConfig
+	Jobs          *JobsConfig
 	TokenLifeTime time.Duration
+	// DefaultProgram код программы для запросов без X-Program, известного хоста и программы в токене
+	DefaultProgram string
 }
 
 const (
 	defaultRunAddr          = "localhost:8081"
+	defaultGRPCAddr         = "localhost:3200"
 	defaultDatabaseURI      = "_"
 	defaultAccrualAddr      = "http://localhost:8080"
 	defaultLogLevel         = "DEBUG"
@@ -35,14 +127,44 @@ const (
 	defaultDBContextTimeout = 15 * time.Second
 	defaultTokenLifeTime    = 24 * time.Hour
 	defaultWorkerPingTasks  = 500 * time.Millisecond
+	defaultTierRecalc       = time.Hour
+	defaultTierWindow       = 365 * 24 * time.Hour
+	defaultPointsExpiry     = time.Hour
+	defaultExpiryMonths     = 12
+	defaultExpiringSoon     = 30 * 24 * time.Hour
+	defaultReferralCheck    = 10 * time.Minute
+	defaultReferrerPoints   = 100
+	defaultRefereePoints    = 100
+	defaultReferralHold     = 14 * 24 * time.Hour
+	defaultReferralLimit    = 50
+	defaultTransferDaily    = 10000
+	defaultTransferConfirm  = 1000
+	defaultTransferTTL      = 15 * time.Minute
+	defaultHoldTTL          = 30 * time.Minute
+	defaultHoldExpiry       = time.Minute
+	defaultVerifyInterval   = time.Hour
+	defaultNegativePolicy   = "block_withdrawals"
+	defaultNotifyInterval   = 15 * time.Second
+	defaultNotifyFrom       = "no-reply@gophermart.local"
+	defaultNotifyAttempts   = 5
+	defaultNotifyBackoff    = time.Minute
+	defaultNotifyRateLimit  = 10
+	defaultNotifyRateWindow = time.Hour
+	defaultExportExpiry     = time.Hour
+	defaultExportTTL        = 7 * 24 * time.Hour
+	defaultJobTimeout       = 10 * time.Minute
+	defaultStaleJobs        = 5 * time.Minute
+	defaultProgram          = "default"
 )
 
 func BuildConfig() *Config {
 	cfg := Config{
-		RunAddr:       defaultRunAddr,
-		AccrualAddr:   defaultAccrualAddr,
-		LogLevel:      defaultLogLevel,
-		TokenLifeTime: defaultTokenLifeTime,
+		RunAddr:        defaultRunAddr,
+		GRPCAddr:       defaultGRPCAddr,
+		AccrualAddr:    defaultAccrualAddr,
+		DefaultProgram: defaultProgram,
+		LogLevel:       defaultLogLevel,
+		TokenLifeTime:  defaultTokenLifeTime,
 		DB: &configDB{
 			DatabaseURI:    defaultDatabaseURI,
 			MigrationPath:  defaultMigrationPath,
@@ -52,6 +174,51 @@ func BuildConfig() *Config {
 		Dispatcher: &WorkDispatcherConfig{
 			PingInterval: defaultWorkerPingTasks,
 		},
+		Scheduler: &SchedulerConfig{
+			TierRecalcInterval:   defaultTierRecalc,
+			TierWindow:           defaultTierWindow,
+			PointsExpiryInterval: defaultPointsExpiry,
+			ReferralInterval:     defaultReferralCheck,
+			HoldExpiryInterval:   defaultHoldExpiry,
+			VerificationInterval: defaultVerifyInterval,
+			NotifyInterval:       defaultNotifyInterval,
+			ExportExpiryInterval: defaultExportExpiry,
+			StaleJobsInterval:    defaultStaleJobs,
+		},
+		Points: &PointsConfig{
+			ExpiryMonths:       defaultExpiryMonths,
+			ExpiringSoonWindow: defaultExpiringSoon,
+		},
+		Referral: &ReferralConfig{
+			ReferrerPoints: defaultReferrerPoints,
+			RefereePoints:  defaultRefereePoints,
+			HoldPeriod:     defaultReferralHold,
+			MaxPerReferrer: defaultReferralLimit,
+		},
+		Transfer: &TransferConfig{
+			DailyLimit:   defaultTransferDaily,
+			ConfirmAbove: defaultTransferConfirm,
+			ConfirmTTL:   defaultTransferTTL,
+		},
+		Hold: &HoldConfig{
+			TTL: defaultHoldTTL,
+		},
+		Verification: &VerificationConfig{
+			NegativePolicy: defaultNegativePolicy,
+		},
+		Notify: &NotifyConfig{
+			From:         defaultNotifyFrom,
+			MaxAttempts:  defaultNotifyAttempts,
+			RetryBackoff: defaultNotifyBackoff,
+			RateLimit:    defaultNotifyRateLimit,
+			RateWindow:   defaultNotifyRateWindow,
+		},
+		Export: &ExportConfig{
+			TTL: defaultExportTTL,
+		},
+		Jobs: &JobsConfig{
+			Timeout: defaultJobTimeout,
+		},
 	}
 
 	cfg.parseFlags()
@@ -64,6 +231,13 @@ func BuildConfig() *Config {
 			cfg.RunAddr = defaultRunAddr
 		}
 	}
+	if cfg.GRPCAddr == "" {
+		if osv, ok := os.LookupEnv("GRPC_ADDRESS"); ok {
+			cfg.GRPCAddr = osv
+		} else {
+			cfg.GRPCAddr = defaultGRPCAddr
+		}
+	}
 	if cfg.DB.DatabaseURI == "" {
 		if osv, ok := os.LookupEnv("DATABASE_URI"); ok {
 			cfg.DB.DatabaseURI = osv
@@ -78,14 +252,121 @@ func BuildConfig() *Config {
 			cfg.AccrualAddr = defaultAccrualAddr
 		}
 	}
+	if cfg.AdminAPIKey == "" {
+		if osv, ok := os.LookupEnv("ADMIN_API_KEY"); ok {
+			cfg.AdminAPIKey = osv
+		}
+	}
+	if osv, ok := os.LookupEnv("DEFAULT_PROGRAM"); ok && osv != "" {
+		cfg.DefaultProgram = osv
+	}
+	if osv, ok := os.LookupEnv("TIER_RECALC_INTERVAL"); ok {
+		if interval, err := time.ParseDuration(osv); err == nil && interval > 0 {
+			cfg.Scheduler.TierRecalcInterval = interval
+		}
+	}
+	if osv, ok := os.LookupEnv("POINTS_EXPIRY_MONTHS"); ok {
+		if months, err := strconv.Atoi(osv); err == nil && months > 0 {
+			cfg.Points.ExpiryMonths = months
+		}
+	}
+	if osv, ok := os.LookupEnv("REFERRER_BONUS"); ok {
+		if points, err := strconv.ParseFloat(osv, 64); err == nil && points >= 0 {
+			cfg.Referral.ReferrerPoints = points
+		}
+	}
+	if osv, ok := os.LookupEnv("REFEREE_BONUS"); ok {
+		if points, err := strconv.ParseFloat(osv, 64); err == nil && points >= 0 {
+			cfg.Referral.RefereePoints = points
+		}
+	}
+	if osv, ok := os.LookupEnv("REFERRAL_HOLD_PERIOD"); ok {
+		if hold, err := time.ParseDuration(osv); err == nil && hold >= 0 {
+			cfg.Referral.HoldPeriod = hold
+		}
+	}
+	if osv, ok := os.LookupEnv("REFERRAL_LIMIT"); ok {
+		if limit, err := strconv.Atoi(osv); err == nil && limit > 0 {
+			cfg.Referral.MaxPerReferrer = limit
+		}
+	}
+	if osv, ok := os.LookupEnv("TRANSFER_DAILY_LIMIT"); ok {
+		if limit, err := strconv.ParseFloat(osv, 64); err == nil && limit > 0 {
+			cfg.Transfer.DailyLimit = limit
+		}
+	}
+	if osv, ok := os.LookupEnv("TRANSFER_CONFIRM_ABOVE"); ok {
+		if threshold, err := strconv.ParseFloat(osv, 64); err == nil && threshold >= 0 {
+			cfg.Transfer.ConfirmAbove = threshold
+		}
+	}
+	if osv, ok := os.LookupEnv("SMTP_ADDRESS"); ok {
+		cfg.Notify.SMTPAddr = osv
+	}
+	if osv, ok := os.LookupEnv("SMTP_USERNAME"); ok {
+		cfg.Notify.SMTPUsername = osv
+	}
+	if osv, ok := os.LookupEnv("SMTP_PASSWORD"); ok {
+		cfg.Notify.SMTPPassword = osv
+	}
+	if osv, ok := os.LookupEnv("NOTIFY_FROM"); ok && osv != "" {
+		cfg.Notify.From = osv
+	}
+	if osv, ok := os.LookupEnv("NOTIFY_FILE_SINK"); ok {
+		cfg.Notify.FileSink = osv
+	}
+	if osv, ok := os.LookupEnv("NOTIFY_RATE_LIMIT"); ok {
+		if limit, err := strconv.Atoi(osv); err == nil && limit > 0 {
+			cfg.Notify.RateLimit = limit
+		}
+	}
+	if osv, ok := os.LookupEnv("DATA_EXPORT_TTL"); ok {
+		if ttl, err := time.ParseDuration(osv); err == nil && ttl > 0 {
+			cfg.Export.TTL = ttl
+		}
+	}
+	if cfg.Verification.Window == 0 {
+		if osv, ok := os.LookupEnv("VERIFICATION_WINDOW"); ok {
+			if window, err := time.ParseDuration(osv); err == nil && window > 0 {
+				cfg.Verification.Window = window
+			}
+		}
+	}
+	if cfg.Verification.NegativePolicy == "" {
+		if osv, ok := os.LookupEnv("NEGATIVE_BALANCE_POLICY"); ok {
+			cfg.Verification.NegativePolicy = osv
+		} else {
+			cfg.Verification.NegativePolicy = defaultNegativePolicy
+		}
+	}
+	if osv, ok := os.LookupEnv("JOB_TIMEOUT"); ok {
+		if timeout, err := time.ParseDuration(osv); err == nil && timeout > 0 {
+			cfg.Jobs.Timeout = timeout
+		}
+	}
 
 	return &cfg
 }
 
 func (c *Config) parseFlags() {
 	flag.StringVar(&c.RunAddr, "a", "", "Server host and port")
+	flag.StringVar(&c.GRPCAddr, "g", "", "gRPC server host and port")
 	flag.StringVar(&c.DB.DatabaseURI, "d", "", "Database URI")
 	flag.StringVar(&c.AccrualAddr, "r", "", "Accrual system host and port")
 	flag.StringVar(&c.LogLevel, "l", defaultLogLevel, "Logging level")
+	flag.StringVar(&c.AdminAPIKey, "k", "", "Admin API key")
+	flag.DurationVar(&c.Verification.Window, "verify-window", 0, "Accrual verification window, 0 disables it")
+	flag.StringVar(&c.Verification.NegativePolicy, "negative-policy", "",
+		"Negative balance policy: allow_debt, block_withdrawals or review")
 	flag.Parse()
 }
+
+func (c *Config) Validate() error {
+	switch c.Verification.NegativePolicy {
+	case models.NegativeAllowDebt, models.NegativeBlockWithdrawals, models.NegativeReview:
+	default:
+		return fmt.Errorf("%w: unknown negative balance policy %q", ErrBadConfig, c.Verification.NegativePolicy)
+	}
+
+	return nil
+}
@@ -4,4 +4,8 @@ type contextKey int
 
 const (
 	ContextUserKey contextKey = iota
+	ContextRequestIDKey
+	ContextAPIVersionKey
+	ContextProgramKey
+	ContextMerchantKey
 )
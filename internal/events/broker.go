@@ -0,0 +1,180 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.uber.org/zap"
+)
+
+const (
+	subscriberBuffer = 16
+	reconnectDelay   = time.Second
+)
+
+var ErrUnexpectedDriverConn = errors.New("unexpected driver connection type for LISTEN")
+
+type Broker struct {
+	logger      *zap.Logger
+	cfg         *config.Config
+	db          *sql.DB
+	subscribers map[int]map[chan *models.Event]struct{}
+	mu          sync.Mutex
+	closed      bool
+}
+
+func NewBroker(logger *zap.Logger, cfg *config.Config, db *sql.DB) *Broker {
+	return &Broker{
+		logger:      logger,
+		cfg:         cfg,
+		db:          db,
+		subscribers: make(map[int]map[chan *models.Event]struct{}),
+		mu:          sync.Mutex{},
+	}
+}
+
+func (b *Broker) Subscribe(userID int) (<-chan *models.Event, func()) {
+	ch := make(chan *models.Event, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[chan *models.Event]struct{})
+	}
+	b.subscribers[userID][ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		b.dropLocked(userID, ch)
+	}
+}
+
+func (b *Broker) dropLocked(userID int, ch chan *models.Event) {
+	if _, ok := b.subscribers[userID][ch]; !ok {
+		return
+	}
+	delete(b.subscribers[userID], ch)
+	if len(b.subscribers[userID]) == 0 {
+		delete(b.subscribers, userID)
+	}
+	close(ch)
+}
+
+func (b *Broker) Listen(ctx context.Context) {
+	defer b.close()
+
+	for {
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			b.logger.Debug("events broker has been shutdown")
+			return
+		}
+		b.logger.Error("error listening user events, reconnecting", zap.Error(err))
+		// пока соединения нет, уведомления теряются: клиенты переподключатся и дочитают их по Last-Event-ID
+		b.dropAll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+func (b *Broker) listen(ctx context.Context) error {
+	conn, err := b.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting connection for listen %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	err = conn.Raw(func(driverConn any) error {
+		stdConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return ErrUnexpectedDriverConn
+		}
+		pgxConn := stdConn.Conn()
+
+		if _, err := pgxConn.Exec(ctx, "LISTEN "+repository.EventsChannel); err != nil {
+			return fmt.Errorf("error executing listen %w", err)
+		}
+		b.logger.Debug("events broker is listening", zap.String("CHANNEL", repository.EventsChannel))
+
+		for {
+			notification, err := pgxConn.WaitForNotification(ctx)
+			if err != nil {
+				// соединение в режиме LISTEN не должно вернуться в пул
+				return errors.Join(driver.ErrBadConn, err)
+			}
+
+			var event models.Event
+			if err = json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+				b.logger.Error("error unmarshal user event notification", zap.Error(err))
+				continue
+			}
+			b.dispatch(&event)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("error waiting for notifications %w", err)
+	}
+
+	return nil
+}
+
+func (b *Broker) dispatch(event *models.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[event.UserID] {
+		select {
+		case ch <- event:
+		default:
+			// поток закрывается, а не теряет событие: клиент дочитает его при переподключении
+			b.logger.Warn("subscriber is too slow, stream closed",
+				zap.Int("USER_ID", event.UserID),
+				zap.Int64("EVENT_ID", event.ID))
+			b.dropLocked(event.UserID, ch)
+		}
+	}
+}
+
+func (b *Broker) close() {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+
+	b.dropAll()
+}
+
+func (b *Broker) dropAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for userID, subs := range b.subscribers {
+		for ch := range subs {
+			close(ch)
+		}
+		delete(b.subscribers, userID)
+	}
+}
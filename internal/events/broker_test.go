@@ -0,0 +1,57 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestBrokerDispatch(t *testing.T) {
+	t.Run("Slow Subscriber Closed Instead Of Losing Events", func(t *testing.T) {
+		b := NewBroker(zap.NewNop(), nil, nil)
+		events, unsubscribe := b.Subscribe(1)
+		defer unsubscribe()
+
+		for id := int64(1); id <= subscriberBuffer+1; id++ {
+			b.dispatch(&models.Event{ID: id, UserID: 1})
+		}
+
+		var received []int64
+		for event := range events {
+			received = append(received, event.ID)
+		}
+		assert.Len(t, received, subscriberBuffer)
+		assert.Equal(t, int64(subscriberBuffer), received[len(received)-1])
+	})
+
+	t.Run("Other Users Not Affected", func(t *testing.T) {
+		b := NewBroker(zap.NewNop(), nil, nil)
+		events, unsubscribe := b.Subscribe(2)
+		defer unsubscribe()
+
+		b.dispatch(&models.Event{ID: 1, UserID: 1})
+		b.dispatch(&models.Event{ID: 2, UserID: 2})
+
+		event := <-events
+		assert.Equal(t, int64(2), event.ID)
+	})
+
+	t.Run("Reconnect Closes Streams", func(t *testing.T) {
+		b := NewBroker(zap.NewNop(), nil, nil)
+		events, unsubscribe := b.Subscribe(1)
+		defer unsubscribe()
+
+		b.dropAll()
+
+		_, ok := <-events
+		assert.False(t, ok)
+
+		// после переподключения можно подписаться снова
+		again, unsubscribeAgain := b.Subscribe(1)
+		defer unsubscribeAgain()
+		b.dispatch(&models.Event{ID: 3, UserID: 1})
+		assert.Equal(t, int64(3), (<-again).ID)
+	})
+}
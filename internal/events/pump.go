@@ -0,0 +1,57 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+)
+
+const Heartbeat = 15 * time.Second
+
+type Sink interface {
+	Send(event *models.Event) error
+	Ping() error
+}
+
+// Pump сначала отправляет пропущенные события, затем новые; повторы по id пропускаются.
+func Pump(
+	ctx context.Context,
+	sink Sink,
+	missed []*models.Event,
+	liveEvents <-chan *models.Event,
+	lastEventID int64,
+) error {
+	for _, event := range missed {
+		if err := sink.Send(event); err != nil {
+			return err
+		}
+		lastEventID = event.ID
+	}
+
+	ticker := time.NewTicker(Heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if err := sink.Ping(); err != nil {
+				return err
+			}
+		case event, ok := <-liveEvents:
+			if !ok {
+				return nil
+			}
+			// событие уже могло быть отправлено из истории
+			if event.ID <= lastEventID {
+				continue
+			}
+			if err := sink.Send(event); err != nil {
+				return err
+			}
+			lastEventID = event.ID
+		}
+	}
+}
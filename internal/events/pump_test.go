@@ -0,0 +1,96 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSink struct {
+	sent []int64
+	fail error
+}
+
+func (s *recordingSink) Send(event *models.Event) error {
+	if s.fail != nil {
+		return s.fail
+	}
+	s.sent = append(s.sent, event.ID)
+	return nil
+}
+
+func (s *recordingSink) Ping() error {
+	return nil
+}
+
+func TestPump(t *testing.T) {
+	testCases := []struct {
+		name        string
+		lastEventID int64
+		missed      []int64
+		live        []int64
+		expected    []int64
+	}{
+		{
+			name:     "Live Only",
+			live:     []int64{1, 2},
+			expected: []int64{1, 2},
+		},
+		{
+			name:        "Replay Then Live",
+			lastEventID: 3,
+			missed:      []int64{4, 5},
+			live:        []int64{6},
+			expected:    []int64{4, 5, 6},
+		},
+		{
+			name:        "Live Duplicates Of Replay Skipped",
+			lastEventID: 3,
+			missed:      []int64{4, 5},
+			live:        []int64{5, 6, 4, 7},
+			expected:    []int64{4, 5, 6, 7},
+		},
+		{
+			name:        "Live Older Than Last-Event-ID Skipped",
+			lastEventID: 10,
+			live:        []int64{9, 10, 11},
+			expected:    []int64{11},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			missed := make([]*models.Event, 0, len(test.missed))
+			for _, id := range test.missed {
+				missed = append(missed, &models.Event{ID: id})
+			}
+			live := make(chan *models.Event, len(test.live))
+			for _, id := range test.live {
+				live <- &models.Event{ID: id}
+			}
+			close(live)
+
+			sink := &recordingSink{}
+			require.NoError(t, Pump(context.Background(), sink, missed, live, test.lastEventID))
+			assert.Equal(t, test.expected, sink.sent)
+		})
+	}
+
+	t.Run("Stops On Context", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		assert.NoError(t, Pump(ctx, &recordingSink{}, nil, make(chan *models.Event), 0))
+	})
+
+	t.Run("Send Error", func(t *testing.T) {
+		sendErr := errors.New("connection reset")
+		err := Pump(context.Background(), &recordingSink{fail: sendErr}, []*models.Event{{ID: 1}}, nil, 0)
+		assert.ErrorIs(t, err, sendErr)
+	})
+}
@@ -0,0 +1,177 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+)
+
+var ErrNoOpenList error = errors.New("no list is open in export archive")
+
+var files = []string{"profile.json", "balance.json", "orders.json", "withdrawals.json"}
+
+type manifest struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	UserID      int       `json:"user_id"`
+	Files       []string  `json:"files"`
+}
+
+// Writer дописывает списки по элементу, не держа их в памяти целиком.
+type Writer struct {
+	zw       *zip.Writer
+	modified time.Time
+	list     io.Writer
+	listLen  int
+}
+
+func NewWriter(w io.Writer, userID int, generatedAt time.Time) (*Writer, error) {
+	aw := &Writer{
+		zw:       zip.NewWriter(w),
+		modified: generatedAt,
+	}
+	if err := aw.WriteFile("manifest.json", &manifest{
+		GeneratedAt: generatedAt.UTC(),
+		UserID:      userID,
+		Files:       files,
+	}); err != nil {
+		return nil, err
+	}
+
+	return aw, nil
+}
+
+func (aw *Writer) WriteFile(name string, content any) error {
+	if err := aw.endList(); err != nil {
+		return err
+	}
+	fw, err := aw.create(name)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(fw)
+	enc.SetIndent("", "  ")
+	if err = enc.Encode(content); err != nil {
+		return fmt.Errorf("error encoding %s for export archive %w", name, err)
+	}
+
+	return nil
+}
+
+func (aw *Writer) BeginList(name string) error {
+	if err := aw.endList(); err != nil {
+		return err
+	}
+	fw, err := aw.create(name)
+	if err != nil {
+		return err
+	}
+	if _, err = io.WriteString(fw, "["); err != nil {
+		return fmt.Errorf("error writing %s to export archive %w", name, err)
+	}
+	aw.list, aw.listLen = fw, 0
+
+	return nil
+}
+
+func (aw *Writer) Append(item any) error {
+	if aw.list == nil {
+		return ErrNoOpenList
+	}
+	raw, err := json.MarshalIndent(item, "  ", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding list item for export archive %w", err)
+	}
+
+	sep := ",\n  "
+	if aw.listLen == 0 {
+		sep = "\n  "
+	}
+	if _, err = io.WriteString(aw.list, sep); err != nil {
+		return fmt.Errorf("error writing list item to export archive %w", err)
+	}
+	if _, err = aw.list.Write(raw); err != nil {
+		return fmt.Errorf("error writing list item to export archive %w", err)
+	}
+	aw.listLen++
+
+	return nil
+}
+
+func (aw *Writer) Close() error {
+	if err := aw.endList(); err != nil {
+		return err
+	}
+	if err := aw.zw.Close(); err != nil {
+		return fmt.Errorf("error closing export archive %w", err)
+	}
+
+	return nil
+}
+
+func (aw *Writer) endList() error {
+	if aw.list == nil {
+		return nil
+	}
+
+	end := "\n]\n"
+	if aw.listLen == 0 {
+		end = "]\n"
+	}
+	if _, err := io.WriteString(aw.list, end); err != nil {
+		return fmt.Errorf("error closing list in export archive %w", err)
+	}
+	aw.list = nil
+
+	return nil
+}
+
+func (aw *Writer) create(name string) (io.Writer, error) {
+	fw, err := aw.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: aw.modified,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating %s in export archive %w", name, err)
+	}
+
+	return fw, nil
+}
+
+func WriteArchive(w io.Writer, userID int, data *models.PersonalData) error {
+	aw, err := NewWriter(w, userID, data.GeneratedAt)
+	if err != nil {
+		return err
+	}
+	if err = aw.WriteFile("profile.json", data.Profile); err != nil {
+		return err
+	}
+	if err = aw.WriteFile("balance.json", data.Balance); err != nil {
+		return err
+	}
+
+	if err = aw.BeginList("orders.json"); err != nil {
+		return err
+	}
+	for _, order := range data.Orders {
+		if err = aw.Append(order); err != nil {
+			return err
+		}
+	}
+	if err = aw.BeginList("withdrawals.json"); err != nil {
+		return err
+	}
+	for _, item := range data.Withdrawals {
+		if err = aw.Append(item); err != nil {
+			return err
+		}
+	}
+
+	return aw.Close()
+}
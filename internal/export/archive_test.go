@@ -0,0 +1,90 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readArchive(t *testing.T, archive []byte) map[string][]byte {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+
+	files := make(map[string][]byte, len(zr.File))
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		files[f.Name] = content
+	}
+	return files
+}
+
+func TestWriteArchive(t *testing.T) {
+	generatedAt := time.Date(2025, 3, 7, 10, 0, 0, 0, time.UTC)
+	email := "user@example.com"
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteArchive(&buf, 7, &models.PersonalData{
+		GeneratedAt: generatedAt,
+		Profile:     &models.Profile{Login: "user", Email: &email},
+		Balance:     &models.Balance{Current: 120.5, Withdrawn: 30},
+		Orders: []*models.Order{{
+			ID:         1,
+			UserID:     7,
+			Number:     "12345678903",
+			Status:     "PROCESSED",
+			Accrual:    &sql.NullFloat64{Float64: 150.5, Valid: true},
+			UploadedAt: generatedAt.Add(-time.Hour),
+		}},
+	}))
+
+	files := readArchive(t, buf.Bytes())
+	assert.Len(t, files, 5)
+
+	var m manifest
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &m))
+	assert.Equal(t, 7, m.UserID)
+	assert.True(t, generatedAt.Equal(m.GeneratedAt))
+	assert.Equal(t, []string{"profile.json", "balance.json", "orders.json", "withdrawals.json"}, m.Files)
+
+	assert.JSONEq(t, `{"login": "user", "email": "user@example.com"}`, string(files["profile.json"]))
+	assert.JSONEq(t, `{"current": 120.5, "withdrawn": 30, "held": 0}`, string(files["balance.json"]))
+	assert.JSONEq(t, `[{"ID": 1, "UserID": 7, "number": "12345678903", "status": "PROCESSED", "accrual": 150.5,
+		"uploaded_at": "2025-03-07T09:00:00Z"}]`, string(files["orders.json"]))
+	assert.JSONEq(t, `[]`, string(files["withdrawals.json"]))
+}
+
+func TestWriterList(t *testing.T) {
+	var buf bytes.Buffer
+	aw, err := NewWriter(&buf, 7, time.Date(2025, 3, 7, 10, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	require.NoError(t, aw.BeginList("orders.json"))
+	for _, number := range []string{"12345678903", "79927398713"} {
+		require.NoError(t, aw.Append(&models.Order{Number: number, Status: "NEW"}))
+	}
+	require.NoError(t, aw.BeginList("withdrawals.json"))
+	require.NoError(t, aw.Close())
+	assert.ErrorIs(t, aw.Append(&models.Order{}), ErrNoOpenList)
+
+	files := readArchive(t, buf.Bytes())
+
+	var orders []*models.Order
+	require.NoError(t, json.Unmarshal(files["orders.json"], &orders))
+	require.Len(t, orders, 2)
+	assert.Equal(t, "79927398713", orders[1].Number)
+	assert.JSONEq(t, `[]`, string(files["withdrawals.json"]))
+}
@@ -0,0 +1,134 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/services"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	authorizationKey = "authorization"
+	bearerPrefix     = "Bearer "
+	programKey       = "x-program"
+	authorityKey     = ":authority"
+)
+
+// authenticator повторяет middlewares.WithProgram и WithAuth для gRPC.
+type authenticator struct {
+	logger         *zap.Logger
+	userService    *services.UserService
+	programService *services.ProgramService
+}
+
+func (a *authenticator) unary(
+	ctx context.Context,
+	req any,
+	_ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	ctx, err := a.withUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authenticator) stream(
+	srv any,
+	ss grpc.ServerStream,
+	_ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx, err := a.withUser(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+func (a *authenticator) withUser(ctx context.Context) (context.Context, error) {
+	user := a.userService.UserRepo.NewEmptyUser()
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	if values := md.Get(authorizationKey); len(values) > 0 {
+		var ok bool
+		if token, ok = strings.CutPrefix(values[0], bearerPrefix); !ok {
+			return nil, status.Error(codes.InvalidArgument, "authorization metadata must be a bearer token")
+		}
+	}
+
+	// без токена программа нужна только Register и Login, они выбирают её сами через requestProgram
+	if token != "" {
+		program, err := resolveProgram(ctx, a.programService, token)
+		if err != nil {
+			a.logger.Debug("error resolving loyalty program", zap.Error(err))
+			return nil, err
+		}
+		ctx = context.WithValue(ctx, contextkeys.ContextProgramKey, program)
+
+		authUser, err := a.userService.GetUserByToken(ctx, token, program)
+		if err != nil {
+			a.logger.Error("error getting user by token", zap.Error(err))
+		} else {
+			user = authUser
+		}
+	}
+
+	return context.WithValue(ctx, contextkeys.ContextUserKey, user), nil
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func resolveProgram(
+	ctx context.Context,
+	programService *services.ProgramService,
+	token string,
+) (*models.Program, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	program, err := programService.ResolveProgram(ctx, firstValue(md, programKey), firstValue(md, authorityKey), token)
+	if err != nil {
+		return nil, statusFromError(err)
+	}
+	return program, nil
+}
+
+func (s *Server) requestProgram(ctx context.Context) (*models.Program, error) {
+	if program, ok := ctx.Value(contextkeys.ContextProgramKey).(*models.Program); ok {
+		return program, nil
+	}
+	return resolveProgram(ctx, s.programService, "")
+}
+
+func authenticatedUser(ctx context.Context) (*models.User, error) {
+	user, ok := ctx.Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		return nil, status.Error(codes.Internal, "error getting user model from context")
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+	return user, nil
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,134 @@
+package grpcapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	gophermartv1 "github.com/Melikhov-p/go-loyalty-system/pkg/api/gophermart/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func orderToProto(order *models.Order) *gophermartv1.Order {
+	return &gophermartv1.Order{
+		Number:     order.Number,
+		Status:     order.Status,
+		Accrual:    order.GetAccrual(),
+		UploadedAt: timestamppb.New(order.UploadedAt),
+	}
+}
+
+func balanceToProto(balance *models.Balance) *gophermartv1.Balance {
+	return &gophermartv1.Balance{
+		Current:   balance.Current,
+		Withdrawn: balance.Withdrawn,
+		Held:      balance.Held,
+	}
+}
+
+func withdrawalToProto(item *models.WithdrawHistoryItem) *gophermartv1.Withdrawal {
+	return &gophermartv1.Withdrawal{
+		Order:       item.Order,
+		Sum:         item.Sum,
+		ProcessedAt: timestamppb.New(item.ProcessedAt),
+		Status:      item.Status,
+	}
+}
+
+func eventToProto(event *models.Event) (*gophermartv1.Event, error) {
+	msg := &gophermartv1.Event{
+		Id:        event.ID,
+		CreatedAt: timestamppb.New(event.CreatedAt),
+	}
+
+	switch event.Type {
+	case models.EventOrderStatus:
+		var payload models.OrderStatusEvent
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return nil, fmt.Errorf("error unmarshal order status event %w", err)
+		}
+		msg.Payload = &gophermartv1.Event_OrderStatus{OrderStatus: &gophermartv1.OrderStatusEvent{
+			Number:  payload.Number,
+			Status:  payload.Status,
+			Accrual: payload.Accrual,
+		}}
+	case models.EventBalance:
+		var payload models.BalanceEvent
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return nil, fmt.Errorf("error unmarshal balance event %w", err)
+		}
+		msg.Payload = &gophermartv1.Event_Balance{Balance: &gophermartv1.Balance{
+			Current:   payload.Current,
+			Withdrawn: payload.Withdrawn,
+		}}
+	case models.EventPointsExpiring:
+		var payload models.PointsExpiringEvent
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return nil, fmt.Errorf("error unmarshal points expiring event %w", err)
+		}
+		msg.Payload = &gophermartv1.Event_PointsExpiring{PointsExpiring: &gophermartv1.PointsExpiringEvent{
+			Amount:    payload.Amount,
+			ExpiresAt: timestamppb.New(payload.ExpiresAt),
+		}}
+	}
+
+	return msg, nil
+}
+
+func orderListFilter(req *gophermartv1.ListOrdersRequest) (*models.OrderListFilter, error) {
+	page, err := listPage(req.GetPage(), "uploaded_at", "uploaded_at", "accrual")
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &models.OrderListFilter{
+		ListPage:   *page,
+		From:       timeFromProto(req.GetFrom()),
+		To:         timeFromProto(req.GetTo()),
+		MinAccrual: req.MinAccrual,
+		MaxAccrual: req.MaxAccrual,
+	}
+	for _, status := range req.GetStatuses() {
+		status = strings.ToUpper(strings.TrimSpace(status))
+		if _, ok := models.OrderStatuses[status]; !ok {
+			return nil, fmt.Errorf("%w: unknown status %s", models.ErrBadPage, status)
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+
+	return filter, nil
+}
+
+func withdrawListFilter(req *gophermartv1.ListWithdrawalsRequest) (*models.WithdrawListFilter, error) {
+	page, err := listPage(req.GetPage(), "processed_at", "processed_at", "sum")
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WithdrawListFilter{
+		ListPage: *page,
+		From:     timeFromProto(req.GetFrom()),
+		To:       timeFromProto(req.GetTo()),
+		MinSum:   req.MinSum,
+		MaxSum:   req.MaxSum,
+	}, nil
+}
+
+func listPage(page *gophermartv1.Page, defaultSort string, sorts ...string) (*models.ListPage, error) {
+	listPage, err := models.NewListPage(
+		int(page.GetLimit()), page.GetSort(), page.GetCursor(), defaultSort, sorts...)
+	if err != nil {
+		return nil, fmt.Errorf("error building list page %w", err)
+	}
+	return listPage, nil
+}
+
+func timeFromProto(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime().In(time.Local)
+	return &t
+}
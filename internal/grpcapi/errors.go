@@ -0,0 +1,40 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"github.com/Melikhov-p/go-loyalty-system/internal/services"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var codesMapping = []struct {
+	err  error
+	code codes.Code
+}{
+	{repository.ErrUserWithLoginExist, codes.AlreadyExists},
+	{repository.ErrOrderNumberExist, codes.AlreadyExists},
+	{repository.ErrOrderNumberNotFound, codes.NotFound},
+	{repository.ErrProgramNotFound, codes.NotFound},
+	{repository.ErrWithdrawalExists, codes.AlreadyExists},
+	{repository.ErrUnknownSort, codes.InvalidArgument},
+	{repository.ErrReferralCodeNotFound, codes.InvalidArgument},
+	{services.ErrNotEnough, codes.FailedPrecondition},
+	{services.ErrWithdrawalLimit, codes.FailedPrecondition},
+	{services.ErrWithdrawalsBlocked, codes.FailedPrecondition},
+	{repository.ErrTransferDailyLimit, codes.FailedPrecondition},
+	{services.ErrIncorrectPass, codes.Unauthenticated},
+	{context.Canceled, codes.Canceled},
+	{context.DeadlineExceeded, codes.DeadlineExceeded},
+}
+
+func statusFromError(err error) error {
+	for _, m := range codesMapping {
+		if errors.Is(err, m.err) {
+			return status.Error(m.code, m.err.Error())
+		}
+	}
+	return status.Error(codes.Internal, "internal error")
+}
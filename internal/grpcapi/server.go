@@ -0,0 +1,275 @@
+package grpcapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/events"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"github.com/Melikhov-p/go-loyalty-system/internal/services"
+	gophermartv1 "github.com/Melikhov-p/go-loyalty-system/pkg/api/gophermart/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type Server struct {
+	gophermartv1.UnimplementedGophermartServer
+
+	logger         *zap.Logger
+	cfg            *config.Config
+	userService    *services.UserService
+	orderService   *services.OrderService
+	balanceService *services.BalanceService
+	eventService   *services.EventService
+	programService *services.ProgramService
+	broker         *events.Broker
+}
+
+func NewServer(logger *zap.Logger, cfg *config.Config, db *sql.DB, broker *events.Broker) *Server {
+	return &Server{
+		logger:         logger,
+		cfg:            cfg,
+		userService:    services.NewUserService(logger, cfg, db),
+		orderService:   services.NewOrderService(logger, cfg, db),
+		balanceService: services.NewBalanceService(logger, cfg, db),
+		eventService:   services.NewEventService(logger, cfg, db),
+		programService: services.NewProgramService(logger, cfg, db),
+		broker:         broker,
+	}
+}
+
+func NewGRPCServer(logger *zap.Logger, cfg *config.Config, db *sql.DB, broker *events.Broker) *grpc.Server {
+	s := NewServer(logger, cfg, db, broker)
+	a := &authenticator{
+		logger:         logger,
+		userService:    s.userService,
+		programService: s.programService,
+	}
+
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(a.unary),
+		grpc.StreamInterceptor(a.stream),
+	)
+	gophermartv1.RegisterGophermartServer(grpcServer, s)
+
+	return grpcServer
+}
+
+func (s *Server) Register(ctx context.Context, req *gophermartv1.Credentials) (*gophermartv1.AuthResponse, error) {
+	if err := validateCredentials(req); err != nil {
+		return nil, err
+	}
+
+	program, err := s.requestProgram(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userService.AddNewUser(ctx, program, req.GetLogin(), req.GetPassword(), req.GetReferralCode())
+	if err != nil {
+		s.logger.Error("error register new user", zap.Error(err))
+		return nil, statusFromError(err)
+	}
+
+	return &gophermartv1.AuthResponse{Token: user.AuthInfo.Token}, nil
+}
+
+func (s *Server) Login(ctx context.Context, req *gophermartv1.Credentials) (*gophermartv1.AuthResponse, error) {
+	if err := validateCredentials(req); err != nil {
+		return nil, err
+	}
+
+	program, err := s.requestProgram(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userService.AuthUser(ctx, program, req.GetLogin(), req.GetPassword())
+	if err != nil {
+		if !errors.Is(err, services.ErrIncorrectPass) {
+			s.logger.Error("error authenticate user", zap.Error(err))
+		}
+		return nil, statusFromError(err)
+	}
+
+	return &gophermartv1.AuthResponse{Token: user.AuthInfo.Token}, nil
+}
+
+func (s *Server) UploadOrder(
+	ctx context.Context,
+	req *gophermartv1.UploadOrderRequest,
+) (*gophermartv1.UploadOrderResponse, error) {
+	user, err := authenticatedUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	number := strings.TrimSpace(req.GetNumber())
+	if number == "" || !s.orderService.ValidateOrderNumber(number) {
+		return nil, status.Error(codes.InvalidArgument, "order number is invalid")
+	}
+
+	if err = s.orderService.CreateOrder(ctx, number, user); err != nil {
+		if errors.Is(err, repository.ErrOrderByUserExist) {
+			return &gophermartv1.UploadOrderResponse{Created: false}, nil
+		}
+		s.logger.Error("error creating new order", zap.String("NUMBER", number), zap.Error(err))
+		return nil, statusFromError(err)
+	}
+
+	return &gophermartv1.UploadOrderResponse{Created: true}, nil
+}
+
+func (s *Server) ListOrders(
+	ctx context.Context,
+	req *gophermartv1.ListOrdersRequest,
+) (*gophermartv1.ListOrdersResponse, error) {
+	user, err := authenticatedUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := orderListFilter(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	orders, cursor, err := s.orderService.GetOrdersByUser(ctx, user, filter)
+	if err != nil {
+		if errors.Is(err, repository.ErrOrdersNotFound) {
+			return &gophermartv1.ListOrdersResponse{}, nil
+		}
+		s.logger.Error("error get orders by user", zap.Error(err))
+		return nil, statusFromError(err)
+	}
+
+	resp := &gophermartv1.ListOrdersResponse{
+		Orders:     make([]*gophermartv1.Order, 0, len(orders)),
+		NextCursor: encodeCursor(cursor),
+	}
+	for _, order := range orders {
+		resp.Orders = append(resp.Orders, orderToProto(order))
+	}
+
+	return resp, nil
+}
+
+func (s *Server) WatchOrders(req *gophermartv1.WatchOrdersRequest, stream gophermartv1.Gophermart_WatchOrdersServer) error {
+	user, err := authenticatedUser(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	// подписываемся до чтения пропущенных событий, чтобы не потерять пришедшие между запросами
+	liveEvents, unsubscribe := s.broker.Subscribe(user.ID)
+	defer unsubscribe()
+
+	missed, err := s.eventService.GetUserEventsAfter(stream.Context(), user, req.GetLastEventId())
+	if err != nil {
+		s.logger.Error("error getting missed user events", zap.Error(err), zap.Int("USER_ID", user.ID))
+		return statusFromError(err)
+	}
+
+	sink := &streamSink{stream: stream}
+	if err = events.Pump(stream.Context(), sink, missed, liveEvents, req.GetLastEventId()); err != nil {
+		s.logger.Debug("order watch closed", zap.Error(err), zap.Int("USER_ID", user.ID))
+		return statusFromError(err)
+	}
+
+	return nil
+}
+
+func (s *Server) GetBalance(ctx context.Context, _ *gophermartv1.GetBalanceRequest) (*gophermartv1.Balance, error) {
+	user, err := authenticatedUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.balanceService.GetUserBalance(ctx, user); err != nil {
+		s.logger.Error("error getting user balance", zap.Error(err))
+		return nil, statusFromError(err)
+	}
+
+	return balanceToProto(user.BalanceInfo), nil
+}
+
+func (s *Server) Withdraw(ctx context.Context, req *gophermartv1.WithdrawRequest) (*gophermartv1.Balance, error) {
+	user, err := authenticatedUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.GetSum() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "sum must be greater than zero")
+	}
+	if !s.orderService.ValidateWithdrawNumber(req.GetOrder()) {
+		return nil, status.Error(codes.InvalidArgument, "order number is invalid")
+	}
+
+	order := repository.NewEmptyOrder()
+	order.Number = req.GetOrder()
+
+	balance, err := s.balanceService.Withdraw(ctx, order, user, req.GetSum())
+	if err != nil {
+		if !errors.Is(err, services.ErrNotEnough) && !errors.Is(err, repository.ErrWithdrawalExists) {
+			s.logger.Error("error withdraw balance", zap.Error(err))
+		}
+		return nil, statusFromError(err)
+	}
+
+	return balanceToProto(balance), nil
+}
+
+func (s *Server) ListWithdrawals(
+	ctx context.Context,
+	req *gophermartv1.ListWithdrawalsRequest,
+) (*gophermartv1.ListWithdrawalsResponse, error) {
+	user, err := authenticatedUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := withdrawListFilter(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	history, cursor, err := s.balanceService.GetUserWithdrawHistory(ctx, user, filter)
+	if err != nil {
+		if errors.Is(err, repository.ErrEmptyBalanceHistory) {
+			return &gophermartv1.ListWithdrawalsResponse{}, nil
+		}
+		s.logger.Error("error getting balance history", zap.Error(err))
+		return nil, statusFromError(err)
+	}
+
+	resp := &gophermartv1.ListWithdrawalsResponse{
+		Withdrawals: make([]*gophermartv1.Withdrawal, 0, len(history)),
+		NextCursor:  encodeCursor(cursor),
+	}
+	for _, item := range history {
+		resp.Withdrawals = append(resp.Withdrawals, withdrawalToProto(item))
+	}
+
+	return resp, nil
+}
+
+func validateCredentials(req *gophermartv1.Credentials) error {
+	if req.GetLogin() == "" || req.GetPassword() == "" {
+		return status.Error(codes.InvalidArgument, "login and password must not be empty")
+	}
+	return nil
+}
+
+func encodeCursor(cursor *models.Cursor) string {
+	if cursor == nil {
+		return ""
+	}
+	return cursor.Encode()
+}
@@ -0,0 +1,158 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/services"
+	gophermartv1 "github.com/Melikhov-p/go-loyalty-system/pkg/api/gophermart/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestClient(t *testing.T) gophermartv1.GophermartClient {
+	cfg := &config.Config{}
+	listener := bufconn.Listen(1 << 20)
+
+	grpcServer := NewGRPCServer(zap.NewNop(), cfg, nil, nil)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return gophermartv1.NewGophermartClient(conn)
+}
+
+func TestServerRejectsRequests(t *testing.T) {
+	client := newTestClient(t)
+
+	testCases := []struct {
+		name         string
+		call         func(ctx context.Context) error
+		md           metadata.MD
+		expectedCode codes.Code
+	}{
+		{
+			name: "Unauthenticated Balance",
+			call: func(ctx context.Context) error {
+				_, err := client.GetBalance(ctx, &gophermartv1.GetBalanceRequest{})
+				return err
+			},
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name: "Unauthenticated Upload",
+			call: func(ctx context.Context) error {
+				_, err := client.UploadOrder(ctx, &gophermartv1.UploadOrderRequest{Number: "12345678903"})
+				return err
+			},
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name: "Unauthenticated Watch",
+			call: func(ctx context.Context) error {
+				stream, err := client.WatchOrders(ctx, &gophermartv1.WatchOrdersRequest{})
+				if err != nil {
+					return err
+				}
+				_, err = stream.Recv()
+				return err
+			},
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name: "Not Bearer Authorization",
+			call: func(ctx context.Context) error {
+				_, err := client.GetBalance(ctx, &gophermartv1.GetBalanceRequest{})
+				return err
+			},
+			md:           metadata.Pairs(authorizationKey, "Token abc"),
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name: "Empty Credentials",
+			call: func(ctx context.Context) error {
+				_, err := client.Register(ctx, &gophermartv1.Credentials{Login: "login"})
+				return err
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if test.md != nil {
+				ctx = metadata.NewOutgoingContext(ctx, test.md)
+			}
+
+			err := test.call(ctx)
+			assert.Equal(t, test.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestUploadOrderRejectsMerchantNumber(t *testing.T) {
+	s := NewServer(zap.NewNop(), &config.Config{}, nil, nil)
+	user := &models.User{ID: 1, ProgramID: 1, AuthInfo: &models.AuthInfo{IsAuthenticated: true}}
+	ctx := context.WithValue(context.Background(), contextkeys.ContextUserKey, user)
+
+	_, err := s.UploadOrder(ctx, &gophermartv1.UploadOrderRequest{Number: "x:4561261212345467"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestStatusFromError(t *testing.T) {
+	assert.Equal(t, codes.FailedPrecondition, status.Code(statusFromError(fmt.Errorf("wrap %w", services.ErrNotEnough))))
+	assert.Equal(t, codes.Internal, status.Code(statusFromError(fmt.Errorf("connection refused"))))
+}
+
+func TestEventToProto(t *testing.T) {
+	accrual := 10.5
+	payload, err := json.Marshal(&models.OrderStatusEvent{Number: "12345678903", Status: "PROCESSED", Accrual: &accrual})
+	require.NoError(t, err)
+
+	msg, err := eventToProto(&models.Event{ID: 7, Type: models.EventOrderStatus, Payload: payload})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(7), msg.GetId())
+	assert.Equal(t, "PROCESSED", msg.GetOrderStatus().GetStatus())
+	assert.Equal(t, accrual, msg.GetOrderStatus().GetAccrual())
+}
+
+func TestPointsExpiringEventToProto(t *testing.T) {
+	expiresAt := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	payload, err := json.Marshal(&models.PointsExpiringEvent{Amount: 120, ExpiresAt: expiresAt})
+	require.NoError(t, err)
+
+	msg, err := eventToProto(&models.Event{ID: 8, Type: models.EventPointsExpiring, Payload: payload})
+	require.NoError(t, err)
+
+	assert.Equal(t, 120.0, msg.GetPointsExpiring().GetAmount())
+	assert.True(t, expiresAt.Equal(msg.GetPointsExpiring().GetExpiresAt().AsTime()))
+}
@@ -0,0 +1,32 @@
+package grpcapi
+
+import (
+	"fmt"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	gophermartv1 "github.com/Melikhov-p/go-loyalty-system/pkg/api/gophermart/v1"
+)
+
+type streamSink struct {
+	stream gophermartv1.Gophermart_WatchOrdersServer
+}
+
+func (s *streamSink) Send(event *models.Event) error {
+	msg, err := eventToProto(event)
+	if err != nil {
+		return err
+	}
+	if err = s.stream.Send(msg); err != nil {
+		return fmt.Errorf("error sending watch event %w", err)
+	}
+
+	return nil
+}
+
+// Ping живость соединения проверяют keepalive-пинги HTTP/2.
+func (s *streamSink) Ping() error {
+	if err := s.stream.Context().Err(); err != nil {
+		return fmt.Errorf("watch stream is done %w", err)
+	}
+	return nil
+}
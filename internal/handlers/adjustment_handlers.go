@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+func (ah *AdjustmentHandlers) ListAdjustments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.AdjustmentApplied, models.AdjustmentBlocked, models.AdjustmentReview, models.AdjustmentDismissed:
+	default:
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeBadQueryParameter, "unknown adjustment status "+status)
+		return
+	}
+
+	adjustments, err := ah.verificationService.GetAdjustments(r.Context(), status)
+	if err != nil {
+		ah.logger.Error("error getting adjustments", zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	ah.writeJSON(w, http.StatusOK, adjustments)
+}
+
+func (ah *AdjustmentHandlers) ApproveAdjustment(w http.ResponseWriter, r *http.Request) {
+	ah.resolveAdjustment(w, r, true)
+}
+
+func (ah *AdjustmentHandlers) DismissAdjustment(w http.ResponseWriter, r *http.Request) {
+	ah.resolveAdjustment(w, r, false)
+}
+
+func (ah *AdjustmentHandlers) resolveAdjustment(w http.ResponseWriter, r *http.Request, approve bool) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeProblem(w, r, http.StatusNotFound, problem.CodeAdjustmentNotFound, "")
+		return
+	}
+
+	adj, err := ah.verificationService.ResolveAdjustment(r.Context(), id, approve)
+	if err != nil {
+		ah.logger.Debug("error resolving adjustment", zap.Int("ADJUSTMENT_ID", id), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	ah.logger.Info("adjustment resolved", zap.Int("ADJUSTMENT_ID", id), zap.String("STATUS", adj.Status))
+	ah.writeJSON(w, http.StatusOK, adj)
+}
+
+func (ah *AdjustmentHandlers) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		ah.logger.Error("error encoding adjustment response to json", zap.Error(err))
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/services"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func AdjustmentTestHandlers(t *testing.T) {
+	testCases := []struct {
+		testCase
+		method   string
+		endPoint string
+	}{
+		{
+			testCase: testCase{name: "List Waiting For Review", query: "status=REVIEW", expectedCode: http.StatusOK},
+			method:   http.MethodGet,
+			endPoint: `/api/admin/adjustments`,
+		},
+		{
+			testCase: testCase{name: "Unknown Status", query: "status=LOST", expectedCode: http.StatusBadRequest},
+			method:   http.MethodGet,
+			endPoint: `/api/admin/adjustments`,
+		},
+		{
+			testCase: testCase{name: "Approve Unknown Adjustment", expectedCode: http.StatusNotFound},
+			method:   http.MethodPost,
+			endPoint: `/api/admin/adjustments/0/approve`,
+		},
+		{
+			testCase: testCase{name: "Dismiss Unknown Adjustment", expectedCode: http.StatusNotFound},
+			method:   http.MethodPost,
+			endPoint: `/api/admin/adjustments/999999999/dismiss`,
+		},
+		{
+			testCase: testCase{name: "Without Admin Key", expectedCode: http.StatusUnauthorized},
+			method:   http.MethodGet,
+			endPoint: `/api/admin/adjustments`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + test.endPoint
+			r.Method = test.method
+			r.SetQueryString(test.query)
+
+			if test.expectedCode != http.StatusUnauthorized {
+				r.SetHeader("X-Admin-Key", testAdminKey)
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+		})
+	}
+
+	t.Run("Verification Downgrade", func(t *testing.T) {
+		AdjustmentVerification(t)
+	})
+}
+
+// AdjustmentVerification сверка с включённым окном: понижение начисления отзывает разницу,
+// а пустой ответ и промежуточный статус системы начислений заказ не меняют.
+func AdjustmentVerification(t *testing.T) {
+	const (
+		downgraded = "7000000001"
+		noContent  = "7000000002"
+		processing = "7000000003"
+	)
+
+	accrual := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch strings.TrimPrefix(r.URL.Path, "/api/orders/") {
+		case downgraded:
+			_, _ = w.Write([]byte(`{"order":"` + downgraded + `","status":"PROCESSED","accrual":40}`))
+		case processing:
+			_, _ = w.Write([]byte(`{"order":"` + processing + `","status":"PROCESSING"}`))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer accrual.Close()
+
+	resp, err := resty.New().R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(`{"login":"verification", "password":"password"}`).
+		Post(server.URL + "/api/user/register")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	var userID int
+	require.NoError(t, db.QueryRow(`SELECT id FROM "user" WHERE login = 'verification'`).Scan(&userID))
+	defer func() {
+		assert.NoError(t, delTestUser(db, "verification"))
+	}()
+
+	_, err = db.Exec(`UPDATE balance SET current = 300 WHERE user_id = $1`, userID)
+	require.NoError(t, err)
+	for _, number := range []string{downgraded, noContent, processing} {
+		_, err = db.Exec(`INSERT INTO "order" (number, uploaded_at, user_id, program_id, status, accrual)
+		VALUES ($1, now(), $2, $3, 'PROCESSED', 100)`, number, userID, testProgramID)
+		require.NoError(t, err)
+	}
+
+	verifyCfg := *cfg
+	verifyCfg.AccrualAddr = accrual.URL
+	verifyCfg.Verification = &config.VerificationConfig{
+		Window:         time.Hour,
+		NegativePolicy: models.NegativeAllowDebt,
+	}
+	verification := services.NewVerificationService(zap.NewNop(), &verifyCfg, db)
+
+	ctx := context.Background()
+	for _, number := range []string{downgraded, noContent, processing} {
+		require.NoError(t, verification.ScheduleVerification(ctx, &models.WatchedOrder{
+			OrderNumber: number,
+			ProgramID:   testProgramID,
+		}))
+	}
+	require.NoError(t, verification.VerifyOrders(ctx))
+
+	var amount float64
+	require.NoError(t, db.QueryRow(`SELECT amount FROM accrual_adjustment WHERE order_number = $1`, downgraded).
+		Scan(&amount))
+	assert.Equal(t, -60.0, amount)
+
+	var current float64
+	require.NoError(t, db.QueryRow(`SELECT current FROM balance WHERE user_id = $1`, userID).Scan(&current))
+	assert.Equal(t, 240.0, current)
+
+	var untouched int
+	require.NoError(t, db.QueryRow(`SELECT count(*) FROM "order"
+	WHERE number IN ($1, $2) AND status = 'PROCESSED' AND accrual = 100
+	AND NOT EXISTS (SELECT 1 FROM accrual_adjustment a WHERE a.order_number = "order".number)`,
+		noContent, processing).Scan(&untouched))
+	assert.Equal(t, 2, untouched)
+}
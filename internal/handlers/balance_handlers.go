@@ -3,36 +3,56 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"time"
 
 	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
 	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
 	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
 	"github.com/Melikhov-p/go-loyalty-system/internal/services"
+	"github.com/Melikhov-p/go-loyalty-system/internal/statement"
 	"go.uber.org/zap"
 )
 
 func (bh *BalanceHandlers) GetBalance(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
 		return
 	}
 
 	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
 	if !ok {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
 		bh.logger.Error(ErrGettingContextUser.Error())
 		return
 	}
 	if !user.AuthInfo.IsAuthenticated {
-		w.WriteHeader(http.StatusUnauthorized)
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
 		return
 	}
 
 	err := bh.balanceService.GetUserBalance(r.Context(), user)
 	if err != nil {
 		bh.logger.Error("error getting user balance", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+
+	user.BalanceInfo.Tier, err = bh.tierService.GetUserTier(r.Context(), user)
+	if err != nil {
+		bh.logger.Error("error getting user tier", zap.Error(err))
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+
+	user.BalanceInfo.ExpiringSoon, err = bh.balanceService.GetExpiringPoints(r.Context(), user)
+	if err != nil {
+		bh.logger.Error("error getting expiring points", zap.Error(err))
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
 		return
 	}
 
@@ -40,37 +60,78 @@ func (bh *BalanceHandlers) GetBalance(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	if err = enc.Encode(user.BalanceInfo); err != nil {
 		bh.logger.Error("error encoding user balance to json", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func (bh *BalanceHandlers) GetTierHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		bh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	history, err := bh.tierService.GetUserTierHistory(r.Context(), user)
+	if err != nil {
+		bh.logger.Error("error getting user tier history", zap.Error(err))
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+	if len(history) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(history); err != nil {
+		bh.logger.Error("error encoding tier history to json", zap.Error(err))
 		return
 	}
 }
 
 func (bh *BalanceHandlers) RequestWithdraw(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
 		return
 	}
 
 	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
 	if !ok {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
 		bh.logger.Error(ErrGettingContextUser.Error())
 		return
 	}
 	if !user.AuthInfo.IsAuthenticated {
-		w.WriteHeader(http.StatusUnauthorized)
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
 		return
 	}
 
-	var req *models.WithdrawRequest
+	var req models.WithdrawRequest
 	dec := json.NewDecoder(r.Body)
 	defer func() {
 		_ = r.Body.Close()
 	}()
 
 	if err := dec.Decode(&req); err != nil {
-		bh.logger.Error("error decoding withdraw request", zap.Error(err))
-		w.WriteHeader(http.StatusBadRequest)
+		if errors.Is(err, io.EOF) {
+			writeProblem(w, r, http.StatusBadRequest, problem.CodeEmptyBody, "")
+			return
+		}
+		bh.logger.Debug("error decoding withdraw request", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
+		return
+	}
+	if !bh.validateWithdrawRequest(w, r, &req) {
 		return
 	}
 
@@ -80,13 +141,14 @@ func (bh *BalanceHandlers) RequestWithdraw(w http.ResponseWriter, r *http.Reques
 
 	user.BalanceInfo, err = bh.balanceService.Withdraw(r.Context(), order, user, req.Sum)
 	if err != nil {
-		if errors.Is(err, services.ErrNotEnough) {
-			w.WriteHeader(http.StatusPaymentRequired)
+		if errors.Is(err, services.ErrNotEnough) || errors.Is(err, services.ErrWithdrawalLimit) ||
+			errors.Is(err, services.ErrWithdrawalsBlocked) || errors.Is(err, repository.ErrWithdrawalExists) {
+			writeError(w, r, err)
 			return
 		}
 
 		bh.logger.Error("error withdraw balance", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
 		return
 	}
 
@@ -94,24 +156,60 @@ func (bh *BalanceHandlers) RequestWithdraw(w http.ResponseWriter, r *http.Reques
 	w.WriteHeader(http.StatusOK)
 }
 
+// validateWithdrawRequest сам отвечает клиенту, если запрос некорректен.
+func (bh *BalanceHandlers) validateWithdrawRequest(
+	w http.ResponseWriter,
+	r *http.Request,
+	req *models.WithdrawRequest,
+) bool {
+	if req.Sum <= 0 {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "",
+			problem.FieldError{Field: "sum", Message: "must be greater than zero"})
+		return false
+	}
+	if cents := req.Sum * 100; math.Abs(cents-math.Round(cents)) > 1e-6 {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "",
+			problem.FieldError{Field: "sum", Message: "must have at most 2 decimal places"})
+		return false
+	}
+	if req.Order == "" {
+		writeProblem(w, r, http.StatusUnprocessableEntity, problem.CodeInvalidOrderNumber, "order number is empty")
+		return false
+	}
+	if !bh.orderService.ValidateWithdrawNumber(req.Order) {
+		writeProblem(w, r, http.StatusUnprocessableEntity, problem.CodeInvalidOrderNumber,
+			"order number must contain only digits and pass Luhn check")
+		return false
+	}
+
+	return true
+}
+
 func (bh *BalanceHandlers) GetWithdrawals(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
 		return
 	}
 
 	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
 	if !ok {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
 		bh.logger.Error(ErrGettingContextUser.Error())
 		return
 	}
 	if !user.AuthInfo.IsAuthenticated {
-		w.WriteHeader(http.StatusUnauthorized)
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	filter, err := parseWithdrawListFilter(r.URL.Query())
+	if err != nil {
+		bh.logger.Debug("error parsing withdrawals list params", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeBadQueryParameter, err.Error())
 		return
 	}
 
-	balanceHistory, err := bh.balanceService.GetUserWithdrawHistory(r.Context(), user)
+	balanceHistory, cursor, err := bh.balanceService.GetUserWithdrawHistory(r.Context(), user, filter)
 	if err != nil {
 		if errors.Is(err, repository.ErrEmptyBalanceHistory) {
 			w.WriteHeader(http.StatusNoContent)
@@ -119,16 +217,75 @@ func (bh *BalanceHandlers) GetWithdrawals(w http.ResponseWriter, r *http.Request
 		}
 
 		bh.logger.Error("error getting balance history", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
 		return
 	}
 
 	enc := json.NewEncoder(w)
+	setNextPageLink(w, r, cursor)
 	w.Header().Set("Content-Type", "application/json")
 
 	if err = enc.Encode(balanceHistory); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
 		bh.logger.Error("error encoding response for balance history", zap.Error(err))
 		return
 	}
 }
+
+func (bh *BalanceHandlers) GetStatement(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		bh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	q := r.URL.Query()
+	var from, to *time.Time
+	if err := parseTimeParam(q, "from", &from); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeBadQueryParameter, err.Error())
+		return
+	}
+	if err := parseEndTimeParam(q, "to", &to); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeBadQueryParameter, err.Error())
+		return
+	}
+	if to == nil {
+		now := time.Now()
+		to = &now
+	}
+	if from != nil && !from.Before(*to) {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeBadQueryParameter, "from must be before to")
+		return
+	}
+
+	format := q.Get("format")
+	if format == "" {
+		format = models.StatementFormatJSON
+	}
+
+	writer, contentType, err := statement.NewWriter(format, w)
+	if err != nil {
+		bh.logger.Debug("error choosing statement format", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeBadQueryParameter, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="statement-%s.%s"`, to.Format(time.DateOnly), format))
+
+	// после начала записи статус уже не поменять, поэтому ошибку только логируем
+	if err = bh.balanceService.WriteStatement(r.Context(), user, from, *to, writer); err != nil {
+		bh.logger.Error("error writing statement", zap.Error(err), zap.Int("USER_ID", user.ID))
+		return
+	}
+}
@@ -14,9 +14,12 @@ func BalanceTestHandlers(t *testing.T) {
 	defer func() {
 		err := delTestOrder(db)
 		assert.NoError(t, err)
+		err = delTestWithdrawals(db)
+		assert.NoError(t, err)
 	}()
 
-	userToken, err := auth.BuildJWTToken(testUserID, cfg.DB.SecretKey, cfg.TokenLifeTime)
+	userToken, err := auth.BuildJWTToken(testUserID, testProgramID, 0, testTokenIssuer, cfg.DB.SecretKey,
+		cfg.TokenLifeTime)
 	assert.NoError(t, err)
 
 	t.Run("GET BALANCE", func(t *testing.T) {
@@ -28,6 +31,21 @@ func BalanceTestHandlers(t *testing.T) {
 	t.Run("BALANCE WITHDRAW HISTORY", func(t *testing.T) {
 		BalanceWithdrawHistory(t, userToken)
 	})
+	t.Run("BALANCE STATEMENT", func(t *testing.T) {
+		BalanceStatement(t, userToken)
+	})
+	t.Run("TIER HISTORY", func(t *testing.T) {
+		TierHistoryGet(t, userToken)
+	})
+	t.Run("REFERRAL STATS", func(t *testing.T) {
+		ReferralStatsGet(t, userToken)
+	})
+	t.Run("TRANSFER", func(t *testing.T) {
+		BalanceTransfer(t, userToken)
+	})
+	t.Run("HOLDS", func(t *testing.T) {
+		BalanceHolds(t, userToken)
+	})
 
 	err = delTestUser(db, "login")
 	assert.NoError(t, err)
@@ -106,10 +124,52 @@ func BalanceWithdraw(t *testing.T, userToken string) {
 			expectedCode: http.StatusPaymentRequired,
 			expectedBody: "",
 		},
+		{
+			name:         "Duplicate Order",
+			body:         fmt.Sprintf(`{"order":"%s", "sum": 1}`, testOrderNumber),
+			expectedCode: http.StatusConflict,
+			expectedBody: "",
+		},
 		{
 			name:         "Wrong Order Number",
 			body:         fmt.Sprintf(`{"order":"%s", "sum": 1}`, "123"),
-			expectedCode: http.StatusOK,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: "",
+		},
+		{
+			name:         "Order Number With Separators",
+			body:         `{"order":"4561-2612-1234-5467", "sum": 1}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: "",
+		},
+		{
+			name:         "Merchant Order Number",
+			body:         `{"order":"M1:4561261212345467", "sum": 1}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: "",
+		},
+		{
+			name:         "Zero Sum",
+			body:         `{"order":"2377225624", "sum": 0}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "",
+		},
+		{
+			name:         "Negative Sum",
+			body:         `{"order":"2377225624", "sum": -10}`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "",
+		},
+		{
+			name:         "Null Body",
+			body:         `null`,
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "",
+		},
+		{
+			name:         "Malformed JSON",
+			body:         `{"order":`,
+			expectedCode: http.StatusBadRequest,
 			expectedBody: "",
 		},
 	}
@@ -165,6 +225,18 @@ func BalanceWithdrawHistory(t *testing.T, userToken string) {
 			expectedCode: http.StatusMethodNotAllowed,
 			expectedBody: "",
 		},
+		{
+			name:         "Sorted by sum",
+			query:        "sort=-sum&min_sum=0.5&limit=10",
+			expectedCode: http.StatusOK,
+			expectedBody: "",
+		},
+		{
+			name:         "Unknown sort",
+			query:        "sort=order",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "",
+		},
 	}
 
 	endPoint := `/api/user/withdrawals`
@@ -173,6 +245,7 @@ func BalanceWithdrawHistory(t *testing.T, userToken string) {
 		t.Run(test.name, func(t *testing.T) {
 			r := resty.New().R()
 			r.URL = server.URL + endPoint
+			r.SetQueryString(test.query)
 
 			r.SetHeader("Content-Type", "text/plain")
 			r.SetBody(test.body)
@@ -197,3 +270,281 @@ func BalanceWithdrawHistory(t *testing.T, userToken string) {
 		})
 	}
 }
+
+func BalanceStatement(t *testing.T, userToken string) {
+	testCases := []testCase{
+		{
+			name:         "Happy JSON statement",
+			query:        "from=2024-01-01",
+			expectedCode: http.StatusOK,
+			expectedBody: "",
+		},
+		{
+			name:         "Happy PDF statement",
+			query:        "format=pdf",
+			expectedCode: http.StatusOK,
+			expectedBody: "",
+		},
+		{
+			name:         "Unknown format",
+			query:        "format=xls",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "",
+		},
+		{
+			name:         "Period is reversed",
+			query:        "from=2025-02-01&to=2025-01-01",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "",
+		},
+		{
+			name:         "Unauthorized",
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: "",
+		},
+	}
+
+	endPoint := `/api/user/statement`
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + endPoint
+			r.Method = http.MethodGet
+			r.SetQueryString(test.query)
+
+			if test.expectedCode != http.StatusUnauthorized {
+				r.SetCookie(&http.Cookie{
+					Name:  "Token",
+					Value: userToken,
+				})
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+		})
+	}
+}
+
+func TierHistoryGet(t *testing.T, userToken string) {
+	testCases := []testCase{
+		{
+			name:         "No Tier Changes Yet",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "Unauthorized",
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	endPoint := `/api/user/tier/history`
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + endPoint
+			r.Method = http.MethodGet
+
+			if test.expectedCode != http.StatusUnauthorized {
+				r.SetCookie(&http.Cookie{
+					Name:  "Token",
+					Value: userToken,
+				})
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+		})
+	}
+}
+
+func ReferralStatsGet(t *testing.T, userToken string) {
+	testCases := []testCase{
+		{
+			name:         "Stats Without Referrals",
+			expectedCode: http.StatusOK,
+			expectedBody: `"invited":0`,
+		},
+		{
+			name:         "Unauthorized",
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	endPoint := `/api/user/referrals`
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + endPoint
+			r.Method = http.MethodGet
+
+			if test.expectedCode != http.StatusUnauthorized {
+				r.SetCookie(&http.Cookie{
+					Name:  "Token",
+					Value: userToken,
+				})
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+			if test.expectedBody != "" {
+				assert.Contains(t, string(resp.Body()), test.expectedBody)
+			}
+		})
+	}
+}
+
+func BalanceTransfer(t *testing.T, userToken string) {
+	testCases := []testCase{
+		{
+			name:         "Transfer To Yourself",
+			body:         `{"to":"login","amount":1}`,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "Unknown Recipient",
+			body:         `{"to":"no-such-login","amount":1}`,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "Negative Amount",
+			body:         `{"to":"login","amount":-5}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Fractional Cents",
+			body:         `{"to":"login","amount":0.001}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Unauthorized",
+			body:         `{"to":"login","amount":1}`,
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	endPoint := `/api/user/balance/transfer`
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + endPoint
+			r.Method = http.MethodPost
+			r.SetHeader("Content-Type", "application/json")
+			r.SetBody(test.body)
+
+			if test.expectedCode != http.StatusUnauthorized {
+				r.SetCookie(&http.Cookie{
+					Name:  "Token",
+					Value: userToken,
+				})
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+		})
+	}
+
+	t.Run("No Transfers Yet", func(t *testing.T) {
+		r := resty.New().R()
+		r.URL = server.URL + `/api/user/transfers`
+		r.Method = http.MethodGet
+		r.SetCookie(&http.Cookie{
+			Name:  "Token",
+			Value: userToken,
+		})
+
+		resp, err := r.Send()
+		assert.NoError(t, err)
+
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode())
+	})
+}
+
+func BalanceHolds(t *testing.T, userToken string) {
+	testCases := []struct {
+		testCase
+		endPoint string
+	}{
+		{
+			testCase: testCase{
+				name:         "Wrong Order Number",
+				body:         `{"order":"12345","sum":1}`,
+				expectedCode: http.StatusUnprocessableEntity,
+			},
+			endPoint: `/api/user/balance/holds`,
+		},
+		{
+			testCase: testCase{
+				name:         "Not Enough Points",
+				body:         `{"order":"2377225624","sum":1000000}`,
+				expectedCode: http.StatusPaymentRequired,
+			},
+			endPoint: `/api/user/balance/holds`,
+		},
+		{
+			testCase: testCase{
+				name:         "Zero Sum",
+				body:         `{"order":"2377225624","sum":0}`,
+				expectedCode: http.StatusBadRequest,
+			},
+			endPoint: `/api/user/balance/holds`,
+		},
+		{
+			testCase: testCase{
+				name:         "Capture Unknown Hold",
+				expectedCode: http.StatusNotFound,
+			},
+			endPoint: `/api/user/balance/holds/0/capture`,
+		},
+		{
+			testCase: testCase{
+				name:         "Release Unknown Hold",
+				expectedCode: http.StatusNotFound,
+			},
+			endPoint: `/api/user/balance/holds/0/release`,
+		},
+		{
+			testCase: testCase{
+				name:         "Unauthorized",
+				body:         `{"order":"2377225624","sum":1}`,
+				expectedCode: http.StatusUnauthorized,
+			},
+			endPoint: `/api/user/balance/holds`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + test.endPoint
+			r.Method = http.MethodPost
+			r.SetHeader("Content-Type", "application/json")
+			if test.body != "" {
+				r.SetBody(test.body)
+			}
+
+			if test.expectedCode != http.StatusUnauthorized {
+				r.SetCookie(&http.Cookie{
+					Name:  "Token",
+					Value: userToken,
+				})
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+		})
+	}
+}
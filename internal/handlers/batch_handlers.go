@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	maxBatchBodySize = 32 << 20
+	// загрузки больше этого размера обрабатываются фоновым заданием
+	maxSyncBatchLines = 1000
+)
+
+var ErrUnsupportedBatchFormat error = errors.New("unsupported batch content type")
+
+func (oh *OrderHandlers) CreateOrdersBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		oh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxBatchBodySize)
+	defer func() {
+		_ = body.Close()
+	}()
+
+	lines, err := parseBatchBody(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		oh.logger.Debug("error parsing orders batch", zap.Error(err), zap.Int("USER_ID", user.ID))
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, ErrUnsupportedBatchFormat):
+			writeProblem(w, r, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, err.Error())
+		case errors.As(err, &maxBytesErr):
+			writeProblem(w, r, http.StatusRequestEntityTooLarge, problem.CodePayloadTooLarge, "")
+		default:
+			writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, err.Error())
+		}
+		return
+	}
+	if len(lines) == 0 {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeEmptyBody, "")
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	w.Header().Set("Content-Type", "application/json")
+
+	if len(lines) > maxSyncBatchLines {
+		job, err := oh.orderService.StartOrdersBatchJob(r.Context(), oh.jobs, user, lines)
+		if err != nil {
+			oh.logger.Error("error starting orders batch job", zap.Error(err), zap.Int("USER_ID", user.ID))
+			writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+			return
+		}
+
+		w.Header().Set("Location", "/api/user/orders/batch/"+strconv.Itoa(job.ID))
+		w.WriteHeader(http.StatusAccepted)
+		if err = enc.Encode(job); err != nil {
+			oh.logger.Error("error encoding response to json", zap.Error(err))
+		}
+		return
+	}
+
+	result, err := oh.orderService.CreateOrdersBatch(r.Context(), user, lines)
+	if err != nil {
+		oh.logger.Error("error creating orders batch", zap.Error(err), zap.Int("USER_ID", user.ID))
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+
+	oh.logger.Debug("orders batch processed", zap.Int("USER_ID", user.ID), zap.Any("SUMMARY", result.Summary))
+	w.WriteHeader(http.StatusOK)
+	if err = enc.Encode(result); err != nil {
+		oh.logger.Error("error encoding response to json", zap.Error(err))
+	}
+}
+
+func (oh *OrderHandlers) GetOrdersBatchJob(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		oh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, http.StatusNotFound, problem.CodeBatchJobNotFound, "")
+		return
+	}
+
+	job, err := oh.orderService.GetOrdersBatchJob(r.Context(), user, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrBatchJobNotFound) {
+			writeError(w, r, err)
+			return
+		}
+		oh.logger.Error("error getting orders batch job", zap.Error(err), zap.Int("JOB_ID", id))
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	w.Header().Set("Content-Type", "application/json")
+
+	if err = enc.Encode(job); err != nil {
+		oh.logger.Error("error encoding response to json", zap.Error(err))
+		return
+	}
+}
+
+func parseBatchBody(contentType string, body io.Reader) ([]*models.BatchLine, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupportedBatchFormat, err)
+	}
+
+	switch mediaType {
+	case "text/csv":
+		return parseBatchCSV(body)
+	case "application/x-ndjson", "application/ndjson":
+		return parseBatchNDJSON(body)
+	case "application/json":
+		return parseBatchJSON(body)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedBatchFormat, mediaType)
+	}
+}
+
+func parseBatchCSV(body io.Reader) ([]*models.BatchLine, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var lines []*models.BatchLine
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading csv record %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		number := strings.TrimSpace(record[0])
+		// заголовок допускается только первой строкой
+		if len(lines) == 0 && strings.EqualFold(number, "number") {
+			continue
+		}
+		lines = append(lines, &models.BatchLine{Line: line, Number: number})
+	}
+
+	return lines, nil
+}
+
+func parseBatchNDJSON(body io.Reader) ([]*models.BatchLine, error) {
+	scanner := bufio.NewScanner(body)
+
+	var lines []*models.BatchLine
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		raw := strings.TrimSpace(scanner.Text())
+		if raw == "" {
+			continue
+		}
+		lines = append(lines, &models.BatchLine{Line: lineNumber, Number: batchNumberFromJSON([]byte(raw))})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error scanning ndjson %w", err)
+	}
+
+	return lines, nil
+}
+
+func parseBatchJSON(body io.Reader) ([]*models.BatchLine, error) {
+	var items []json.RawMessage
+	if err := json.NewDecoder(body).Decode(&items); err != nil {
+		return nil, fmt.Errorf("error decoding json array %w", err)
+	}
+
+	lines := make([]*models.BatchLine, 0, len(items))
+	for i, item := range items {
+		lines = append(lines, &models.BatchLine{Line: i + 1, Number: batchNumberFromJSON(item)})
+	}
+
+	return lines, nil
+}
+
+// batchNumberFromJSON принимает строку, число или объект {"number": ...}.
+func batchNumberFromJSON(raw []byte) string {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return strings.TrimSpace(text)
+	}
+
+	var number json.Number
+	if err := json.Unmarshal(raw, &number); err == nil {
+		return number.String()
+	}
+
+	var item struct {
+		Number json.RawMessage `json:"number"`
+	}
+	if err := json.Unmarshal(raw, &item); err == nil && len(item.Number) > 0 && item.Number[0] != '{' {
+		return batchNumberFromJSON(item.Number)
+	}
+
+	return string(raw)
+}
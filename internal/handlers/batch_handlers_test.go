@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBatchBody(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		body        string
+		expected    []*models.BatchLine
+		wantErr     error
+	}{
+		{
+			name:        "CSV with header",
+			contentType: "text/csv",
+			body:        "number,comment\n12345678903,first\n\n513,second\n",
+			expected: []*models.BatchLine{
+				{Line: 2, Number: "12345678903"},
+				{Line: 4, Number: "513"},
+			},
+		},
+		{
+			name:        "NDJSON mixed lines",
+			contentType: "application/x-ndjson; charset=utf-8",
+			body:        "{\"number\":\"12345678903\"}\n\n\"513\"\n{\"number\":513}\nbroken\n",
+			expected: []*models.BatchLine{
+				{Line: 1, Number: "12345678903"},
+				{Line: 3, Number: "513"},
+				{Line: 4, Number: "513"},
+				{Line: 5, Number: "broken"},
+			},
+		},
+		{
+			name:        "JSON array",
+			contentType: "application/json",
+			body:        `["12345678903", {"number": "0513"}, 42]`,
+			expected: []*models.BatchLine{
+				{Line: 1, Number: "12345678903"},
+				{Line: 2, Number: "0513"},
+				{Line: 3, Number: "42"},
+			},
+		},
+		{
+			name:        "Unsupported content type",
+			contentType: "text/plain",
+			body:        "12345678903",
+			wantErr:     ErrUnsupportedBatchFormat,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			lines, err := parseBatchBody(test.contentType, strings.NewReader(test.body))
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, lines)
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+const maxCampaignNameLength = 100
+
+func (ch *CampaignHandlers) ListCampaigns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+	program, ok := ch.program(w, r)
+	if !ok {
+		return
+	}
+
+	campaigns, err := ch.campaignService.GetCampaigns(r.Context(), program.ID)
+	if err != nil {
+		ch.logger.Error("error getting campaigns", zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	ch.writeJSON(w, http.StatusOK, campaigns)
+}
+
+func (ch *CampaignHandlers) CreateCampaign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	program, ok := ch.program(w, r)
+	if !ok {
+		return
+	}
+	req, ok := ch.decodeCampaignRequest(w, r)
+	if !ok {
+		return
+	}
+
+	c, err := ch.campaignService.CreateCampaign(r.Context(), program.ID, req)
+	if err != nil {
+		ch.logger.Error("error creating campaign", zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	ch.logger.Info("campaign created", zap.Int("CAMPAIGN_ID", c.ID), zap.String("RULE", c.Rule.Type))
+	w.Header().Set("Location", fmt.Sprintf("/api/admin/campaigns/%d", c.ID))
+	ch.writeJSON(w, http.StatusCreated, c)
+}
+
+func (ch *CampaignHandlers) GetCampaign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	program, ok := ch.program(w, r)
+	if !ok {
+		return
+	}
+	id, ok := campaignID(w, r)
+	if !ok {
+		return
+	}
+
+	c, err := ch.campaignService.GetCampaign(r.Context(), program.ID, id)
+	if err != nil {
+		ch.logger.Debug("error getting campaign", zap.Int("CAMPAIGN_ID", id), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	ch.writeJSON(w, http.StatusOK, c)
+}
+
+func (ch *CampaignHandlers) UpdateCampaign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	program, ok := ch.program(w, r)
+	if !ok {
+		return
+	}
+	id, ok := campaignID(w, r)
+	if !ok {
+		return
+	}
+	req, ok := ch.decodeCampaignRequest(w, r)
+	if !ok {
+		return
+	}
+
+	c, err := ch.campaignService.UpdateCampaign(r.Context(), program.ID, id, req)
+	if err != nil {
+		ch.logger.Error("error updating campaign", zap.Int("CAMPAIGN_ID", id), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	ch.logger.Info("campaign updated", zap.Int("CAMPAIGN_ID", c.ID))
+	ch.writeJSON(w, http.StatusOK, c)
+}
+
+func (ch *CampaignHandlers) DeleteCampaign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	program, ok := ch.program(w, r)
+	if !ok {
+		return
+	}
+	id, ok := campaignID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := ch.campaignService.DeleteCampaign(r.Context(), program.ID, id); err != nil {
+		ch.logger.Error("error deleting campaign", zap.Int("CAMPAIGN_ID", id), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	ch.logger.Info("campaign deleted", zap.Int("CAMPAIGN_ID", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ch *CampaignHandlers) GetCampaignGrants(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	program, ok := ch.program(w, r)
+	if !ok {
+		return
+	}
+	id, ok := campaignID(w, r)
+	if !ok {
+		return
+	}
+
+	grants, err := ch.campaignService.GetCampaignGrants(r.Context(), program.ID, id)
+	if err != nil {
+		ch.logger.Debug("error getting campaign grants", zap.Int("CAMPAIGN_ID", id), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	ch.writeJSON(w, http.StatusOK, grants)
+}
+
+func (ch *CampaignHandlers) DryRunCampaigns(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	program, ok := ch.program(w, r)
+	if !ok {
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	var req models.CampaignDryRunRequest
+	if err := dec.Decode(&req); err != nil {
+		ch.logger.Debug("error decoding campaign dry-run request", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
+		return
+	}
+
+	var fields []problem.FieldError
+	if req.UserID <= 0 {
+		fields = append(fields, problem.FieldError{Field: "user_id", Message: "must be greater than zero"})
+	}
+	if req.Accrual < 0 {
+		fields = append(fields, problem.FieldError{Field: "accrual", Message: "must not be negative"})
+	}
+	if len(fields) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "", fields...)
+		return
+	}
+
+	grants, err := ch.campaignService.DryRun(r.Context(), program.ID, &req)
+	if err != nil {
+		ch.logger.Error("error evaluating campaigns", zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	result := &models.CampaignDryRunResult{Grants: []*models.CampaignGrant{}}
+	for _, g := range grants {
+		result.Grants = append(result.Grants, g)
+		result.Total += g.Points
+	}
+
+	ch.writeJSON(w, http.StatusOK, result)
+}
+
+func (ch *CampaignHandlers) decodeCampaignRequest(
+	w http.ResponseWriter,
+	r *http.Request,
+) (*models.CampaignRequest, bool) {
+	dec := json.NewDecoder(r.Body)
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	var req models.CampaignRequest
+	if err := dec.Decode(&req); err != nil {
+		ch.logger.Debug("error decoding campaign request", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
+		return nil, false
+	}
+	if fields := validateCampaign(&req); len(fields) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "", fields...)
+		return nil, false
+	}
+
+	return &req, true
+}
+
+func (ch *CampaignHandlers) program(w http.ResponseWriter, r *http.Request) (*models.Program, bool) {
+	program, ok := requestProgram(r)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		ch.logger.Error(ErrGettingContextProgram.Error())
+	}
+	return program, ok
+}
+
+func (ch *CampaignHandlers) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		ch.logger.Error("error encoding campaign response to json", zap.Error(err))
+	}
+}
+
+func campaignID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeProblem(w, r, http.StatusNotFound, problem.CodeCampaignNotFound, "")
+		return 0, false
+	}
+	return id, true
+}
+
+func validateCampaign(req *models.CampaignRequest) []problem.FieldError {
+	var fields []problem.FieldError
+	if req.Name == "" || len([]rune(req.Name)) > maxCampaignNameLength {
+		fields = append(fields, problem.FieldError{
+			Field:   "name",
+			Message: fmt.Sprintf("must be 1 to %d characters long", maxCampaignNameLength),
+		})
+	}
+
+	switch req.Rule.Type {
+	case models.CampaignRuleMultiplier:
+		if req.Rule.Multiplier <= 1 {
+			fields = append(fields, problem.FieldError{Field: "rule.multiplier", Message: "must be greater than 1"})
+		}
+	case models.CampaignRuleFixed, models.CampaignRuleFirstOrder, models.CampaignRuleNthOrder:
+		if req.Rule.Points <= 0 {
+			fields = append(fields, problem.FieldError{Field: "rule.points", Message: "must be greater than zero"})
+		}
+		if req.Rule.Type == models.CampaignRuleNthOrder && req.Rule.N < 1 {
+			fields = append(fields, problem.FieldError{Field: "rule.n", Message: "must be at least 1"})
+		}
+	default:
+		fields = append(fields, problem.FieldError{
+			Field:   "rule.type",
+			Message: "must be one of multiplier, fixed, first_order, nth_order",
+		})
+	}
+	if len(req.Rule.MerchantID) > maxMerchantIDLength {
+		fields = append(fields, problem.FieldError{
+			Field:   "rule.merchant_id",
+			Message: fmt.Sprintf("must be at most %d characters long", maxMerchantIDLength),
+		})
+	}
+
+	if req.StartsAt.IsZero() {
+		fields = append(fields, problem.FieldError{Field: "starts_at", Message: "is required"})
+	}
+	if req.EndsAt != nil && !req.EndsAt.After(req.StartsAt) {
+		fields = append(fields, problem.FieldError{Field: "ends_at", Message: "must be after starts_at"})
+	}
+	if req.UserCap != nil && *req.UserCap <= 0 {
+		fields = append(fields, problem.FieldError{Field: "user_cap", Message: "must be greater than zero"})
+	}
+	return fields
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func CampaignTestHandlers(t *testing.T) {
+	var campaignID int
+
+	t.Run("CREATE CAMPAIGN", func(t *testing.T) {
+		campaignID = CampaignCreate(t)
+	})
+	t.Run("DRY RUN", func(t *testing.T) {
+		CampaignDryRun(t)
+	})
+	t.Run("DELETE CAMPAIGN", func(t *testing.T) {
+		CampaignDelete(t, campaignID)
+	})
+}
+
+func CampaignCreate(t *testing.T) int {
+	startsAt := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	campaignBody := func(name, rule string) string {
+		return fmt.Sprintf(`{"name":%q,"rule":%s,"starts_at":"%s"}`, name, rule, startsAt)
+	}
+
+	testCases := []testCase{
+		{
+			name:         "Valid Fixed Campaign",
+			body:         campaignBody("Бонус", `{"type":"fixed","points":100}`),
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "Multiplier Not Greater Than One",
+			body:         campaignBody("x1", `{"type":"multiplier","multiplier":1}`),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Unknown Rule",
+			body:         campaignBody("?", `{"type":"random","points":1}`),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Without Admin Key",
+			body:         campaignBody("Бонус", `{"type":"fixed","points":100}`),
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	endPoint := `/api/admin/campaigns`
+
+	var created models.Campaign
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + endPoint
+			r.Method = http.MethodPost
+			r.SetHeader("Content-Type", "application/json")
+			r.SetBody(test.body)
+
+			if test.expectedCode != http.StatusUnauthorized {
+				r.SetHeader("X-Admin-Key", testAdminKey)
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+			if test.expectedCode == http.StatusCreated {
+				require.NoError(t, json.Unmarshal(resp.Body(), &created))
+				assert.True(t, created.Active)
+			}
+		})
+	}
+
+	return created.ID
+}
+
+func CampaignDryRun(t *testing.T) {
+	testCases := []testCase{
+		{
+			name:         "Fixed Bonus Applies",
+			body:         fmt.Sprintf(`{"user_id":%d,"order_number":"12345678903","accrual":10}`, testUserID),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Without User",
+			body:         `{"order_number":"12345678903","accrual":10}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	endPoint := `/api/admin/campaigns/dry-run`
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + endPoint
+			r.Method = http.MethodPost
+			r.SetHeader("Content-Type", "application/json")
+			r.SetHeader("X-Admin-Key", testAdminKey)
+			r.SetBody(test.body)
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+			if test.expectedCode == http.StatusOK {
+				var result models.CampaignDryRunResult
+				require.NoError(t, json.Unmarshal(resp.Body(), &result))
+				assert.GreaterOrEqual(t, result.Total, 100.0)
+			}
+		})
+	}
+}
+
+func CampaignDelete(t *testing.T, campaignID int) {
+	testCases := []testCase{
+		{
+			name:         "Delete Campaign Without Grants",
+			query:        fmt.Sprint(campaignID),
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "Already Deleted",
+			query:        fmt.Sprint(campaignID),
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	endPoint := `/api/admin/campaigns/`
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + endPoint + test.query
+			r.Method = http.MethodDelete
+			r.SetHeader("X-Admin-Key", testAdminKey)
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+		})
+	}
+}
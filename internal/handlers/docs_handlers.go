@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"go.uber.org/zap"
+)
+
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Gophermart API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "/api/openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+type DocsHandlers struct {
+	logger *zap.Logger
+	spec   []byte
+}
+
+func NewDocsHandlers(logger *zap.Logger, doc *openapi3.T) (*DocsHandlers, error) {
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("error marshal openapi spec %w", err)
+	}
+
+	return &DocsHandlers{
+		logger: logger,
+		spec:   spec,
+	}, nil
+}
+
+func (dh *DocsHandlers) GetSpec(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(dh.spec); err != nil {
+		dh.logger.Error("error writing openapi spec", zap.Error(err))
+	}
+}
+
+func (dh *DocsHandlers) GetDocs(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write([]byte(docsPage)); err != nil {
+		dh.logger.Error("error writing docs page", zap.Error(err))
+	}
+}
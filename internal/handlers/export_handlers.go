@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+func (eh *ExportHandlers) CreateExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		eh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	export, err := eh.exportService.StartExport(r.Context(), eh.jobs, user)
+	if err != nil {
+		eh.logger.Error("error starting data export", zap.Int("USER_ID", user.ID), zap.Error(err))
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+
+	w.Header().Set("Location", "/api/user/export/"+strconv.Itoa(export.ID))
+	eh.writeJSON(w, http.StatusAccepted, export)
+}
+
+func (eh *ExportHandlers) GetExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		eh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	id, ok := exportID(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := eh.exportService.GetExport(r.Context(), user, id)
+	if err != nil {
+		eh.logger.Debug("error getting data export", zap.Int("EXPORT_ID", id), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	eh.writeJSON(w, http.StatusOK, export)
+}
+
+func (eh *ExportHandlers) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		eh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	id, ok := exportID(w, r)
+	if !ok {
+		return
+	}
+
+	export, err := eh.exportService.GetArchive(r.Context(), user, id)
+	if err != nil {
+		eh.logger.Debug("error getting data export archive", zap.Int("EXPORT_ID", id), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="personal-data-%d.zip"`, export.ID))
+	w.Header().Set("Content-Length", strconv.Itoa(export.Size))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err = eh.exportService.WriteArchive(r.Context(), export, w); err != nil {
+		eh.logger.Error("error writing data export archive", zap.Int("EXPORT_ID", id), zap.Error(err))
+	}
+}
+
+func (eh *ExportHandlers) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		eh.logger.Error("error encoding data export response to json", zap.Error(err))
+	}
+}
+
+func exportID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeProblem(w, r, http.StatusNotFound, problem.CodeExportNotFound, "")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/auth"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ExportTestHandlers(t *testing.T) {
+	userToken, err := auth.BuildJWTToken(testUserID, testProgramID, 0, testTokenIssuer, cfg.DB.SecretKey,
+		cfg.TokenLifeTime)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		testCase
+		method   string
+		endPoint string
+	}{
+		{
+			testCase: testCase{name: "Create Export Unauthorized", expectedCode: http.StatusUnauthorized},
+			method:   http.MethodPost,
+			endPoint: `/api/user/export`,
+		},
+		{
+			testCase: testCase{name: "Unknown Export", expectedCode: http.StatusNotFound},
+			method:   http.MethodGet,
+			endPoint: `/api/user/export/999999999`,
+		},
+		{
+			testCase: testCase{name: "Download Unknown Export", expectedCode: http.StatusNotFound},
+			method:   http.MethodGet,
+			endPoint: `/api/user/export/999999999/download`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + test.endPoint
+			r.Method = test.method
+			if test.expectedCode != http.StatusUnauthorized {
+				r.SetCookie(&http.Cookie{
+					Name:  "Token",
+					Value: userToken,
+				})
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+		})
+	}
+
+	t.Run("Export And Download", func(t *testing.T) {
+		client := resty.New().SetCookie(&http.Cookie{
+			Name:  "Token",
+			Value: userToken,
+		})
+
+		resp, err := client.R().Post(server.URL + `/api/user/export`)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusAccepted, resp.StatusCode())
+
+		var export models.DataExport
+		require.NoError(t, json.Unmarshal(resp.Body(), &export))
+		location := `/api/user/export/` + strconv.Itoa(export.ID)
+		assert.Equal(t, location, resp.Header().Get("Location"))
+
+		require.Eventually(t, func() bool {
+			resp, err = client.R().Get(server.URL + location)
+			if err != nil || resp.StatusCode() != http.StatusOK {
+				return false
+			}
+			return json.Unmarshal(resp.Body(), &export) == nil && export.Status != models.DataExportProcessing
+		}, 5*time.Second, 50*time.Millisecond)
+		require.Equal(t, models.DataExportReady, export.Status)
+		require.NotNil(t, export.ExpiresAt)
+
+		resp, err = client.R().Get(server.URL + location + `/download`)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode())
+		assert.Equal(t, "application/zip", resp.Header().Get("Content-Type"))
+
+		zr, err := zip.NewReader(bytes.NewReader(resp.Body()), int64(len(resp.Body())))
+		require.NoError(t, err)
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		assert.ElementsMatch(t,
+			[]string{"manifest.json", "profile.json", "balance.json", "orders.json", "withdrawals.json"}, names)
+
+		var count int
+		require.NoError(t, db.QueryRow(`SELECT count(*) FROM audit_log WHERE user_id = $1 AND object_id = $2`,
+			testUserID, export.ID).Scan(&count))
+		assert.Equal(t, 2, count)
+	})
+}
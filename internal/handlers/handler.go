@@ -5,56 +5,179 @@ import (
 	"errors"
 
 	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/events"
 	"github.com/Melikhov-p/go-loyalty-system/internal/services"
 	"go.uber.org/zap"
 )
 
 type Handlers struct {
-	ForUser    *UserHandlers
-	ForBalance *BalanceHandlers
-	ForOrder   *OrderHandlers
+	ForUser         *UserHandlers
+	ForBalance      *BalanceHandlers
+	ForOrder        *OrderHandlers
+	ForCampaign     *CampaignHandlers
+	ForAdjustment   *AdjustmentHandlers
+	ForProgram      *ProgramHandlers
+	ForMerchant     *MerchantHandlers
+	ForVoucher      *VoucherHandlers
+	ForReward       *RewardHandlers
+	ForNotification *NotificationHandlers
+	ForExport       *ExportHandlers
 }
 
 type UserHandlers struct {
-	logger      *zap.Logger
-	cfg         *config.Config
-	userService *services.UserService
+	logger          *zap.Logger
+	cfg             *config.Config
+	userService     *services.UserService
+	referralService *services.ReferralService
 }
 
 type BalanceHandlers struct {
-	logger         *zap.Logger
-	cfg            *config.Config
-	balanceService *services.BalanceService
-	orderService   *services.OrderService
+	logger          *zap.Logger
+	cfg             *config.Config
+	balanceService  *services.BalanceService
+	orderService    *services.OrderService
+	tierService     *services.TierService
+	transferService *services.TransferService
+	holdService     *services.HoldService
 }
 
 type OrderHandlers struct {
 	logger       *zap.Logger
 	cfg          *config.Config
 	orderService *services.OrderService
+	eventService *services.EventService
+	broker       *events.Broker
+	jobs         *services.Jobs
+}
+
+type CampaignHandlers struct {
+	logger          *zap.Logger
+	cfg             *config.Config
+	campaignService *services.CampaignService
+}
+
+type AdjustmentHandlers struct {
+	logger              *zap.Logger
+	cfg                 *config.Config
+	verificationService *services.VerificationService
+}
+
+type ProgramHandlers struct {
+	logger         *zap.Logger
+	cfg            *config.Config
+	programService *services.ProgramService
+}
+
+type MerchantHandlers struct {
+	logger          *zap.Logger
+	cfg             *config.Config
+	merchantService *services.MerchantService
+	orderService    *services.OrderService
+}
+
+type VoucherHandlers struct {
+	logger         *zap.Logger
+	cfg            *config.Config
+	voucherService *services.VoucherService
+}
+
+type RewardHandlers struct {
+	logger        *zap.Logger
+	cfg           *config.Config
+	rewardService *services.RewardService
+}
+
+type NotificationHandlers struct {
+	logger              *zap.Logger
+	cfg                 *config.Config
+	notificationService *services.NotificationService
+}
+
+type ExportHandlers struct {
+	logger        *zap.Logger
+	cfg           *config.Config
+	exportService *services.ExportService
+	jobs          *services.Jobs
 }
 
 var (
-	ErrGettingContextUser error = errors.New("error getting user model from context")
+	ErrGettingContextUser     error = errors.New("error getting user model from context")
+	ErrGettingContextProgram  error = errors.New("error getting program model from context")
+	ErrGettingContextMerchant error = errors.New("error getting merchant model from context")
 )
 
-func SetupHandlers(logger *zap.Logger, cfg *config.Config, db *sql.DB) *Handlers {
+func SetupHandlers(
+	logger *zap.Logger,
+	cfg *config.Config,
+	db *sql.DB,
+	broker *events.Broker,
+	jobs *services.Jobs,
+) *Handlers {
 	return &Handlers{
 		ForUser: &UserHandlers{
-			logger:      logger,
-			cfg:         cfg,
-			userService: services.NewUserService(logger, cfg, db),
+			logger:          logger,
+			cfg:             cfg,
+			userService:     services.NewUserService(logger, cfg, db),
+			referralService: services.NewReferralService(logger, cfg, db),
 		},
 		ForBalance: &BalanceHandlers{
-			logger:         logger,
-			cfg:            cfg,
-			balanceService: services.NewBalanceService(logger, cfg, db),
-			orderService:   services.NewOrderService(logger, cfg, db),
+			logger:          logger,
+			cfg:             cfg,
+			balanceService:  services.NewBalanceService(logger, cfg, db),
+			orderService:    services.NewOrderService(logger, cfg, db),
+			tierService:     services.NewTierService(logger, cfg, db),
+			transferService: services.NewTransferService(logger, cfg, db),
+			holdService:     services.NewHoldService(logger, cfg, db),
 		},
 		ForOrder: &OrderHandlers{
 			logger:       logger,
 			cfg:          cfg,
 			orderService: services.NewOrderService(logger, cfg, db),
+			eventService: services.NewEventService(logger, cfg, db),
+			broker:       broker,
+			jobs:         jobs,
+		},
+		ForCampaign: &CampaignHandlers{
+			logger:          logger,
+			cfg:             cfg,
+			campaignService: services.NewCampaignService(logger, cfg, db),
+		},
+		ForAdjustment: &AdjustmentHandlers{
+			logger:              logger,
+			cfg:                 cfg,
+			verificationService: services.NewVerificationService(logger, cfg, db),
+		},
+		ForProgram: &ProgramHandlers{
+			logger:         logger,
+			cfg:            cfg,
+			programService: services.NewProgramService(logger, cfg, db),
+		},
+		ForMerchant: &MerchantHandlers{
+			logger:          logger,
+			cfg:             cfg,
+			merchantService: services.NewMerchantService(logger, cfg, db),
+			orderService:    services.NewOrderService(logger, cfg, db),
+		},
+		ForVoucher: &VoucherHandlers{
+			logger:         logger,
+			cfg:            cfg,
+			voucherService: services.NewVoucherService(logger, cfg, db),
+		},
+		ForReward: &RewardHandlers{
+			logger:        logger,
+			cfg:           cfg,
+			rewardService: services.NewRewardService(logger, cfg, db),
+		},
+		ForNotification: &NotificationHandlers{
+			logger:              logger,
+			cfg:                 cfg,
+			notificationService: services.NewNotificationService(logger, cfg, db),
+		},
+		ForExport: &ExportHandlers{
+			logger:        logger,
+			cfg:           cfg,
+			exportService: services.NewExportService(logger, cfg, db),
+			jobs:          jobs,
 		},
 	}
 }
@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/events"
 	"github.com/Melikhov-p/go-loyalty-system/internal/logger"
 	"github.com/Melikhov-p/go-loyalty-system/internal/middlewares"
+	"github.com/Melikhov-p/go-loyalty-system/internal/openapi"
+	"github.com/Melikhov-p/go-loyalty-system/internal/services"
 	"github.com/Melikhov-p/go-loyalty-system/pkg"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
@@ -16,10 +20,18 @@ import (
 type testCase struct {
 	name         string
 	body         string
+	query        string
 	expectedCode int
 	expectedBody string
 }
 
+const (
+	testAdminKey = "test-admin-key"
+	// пользователи тестов живут в программе по умолчанию из миграции
+	testProgramID   = 1
+	testTokenIssuer = "gophermart"
+)
+
 var (
 	server          *httptest.Server
 	db              *sql.DB
@@ -42,12 +54,37 @@ func TestHandlers(t *testing.T) {
 	t.Run("BALANCE", func(t *testing.T) {
 		BalanceTestHandlers(t)
 	})
+	t.Run("CAMPAIGNS", func(t *testing.T) {
+		CampaignTestHandlers(t)
+	})
+	t.Run("ADJUSTMENTS", func(t *testing.T) {
+		AdjustmentTestHandlers(t)
+	})
+	t.Run("PROGRAMS", func(t *testing.T) {
+		ProgramTestHandlers(t)
+	})
+	t.Run("MERCHANTS", func(t *testing.T) {
+		MerchantTestHandlers(t)
+	})
+	t.Run("VOUCHERS", func(t *testing.T) {
+		VoucherTestHandlers(t)
+	})
+	t.Run("REWARDS", func(t *testing.T) {
+		RewardTestHandlers(t)
+	})
+	t.Run("NOTIFICATIONS", func(t *testing.T) {
+		NotificationTestHandlers(t)
+	})
+	t.Run("EXPORT", func(t *testing.T) {
+		ExportTestHandlers(t)
+	})
 }
 
 func getServer(t *testing.T) {
 	cfg = config.BuildConfig()
 	cfg.DB.MigrationPath = "../migrations"
 	cfg.DB.DatabaseURI = "host=localhost user=postgres password=recnbr dbname=loyalty-system sslmode=disable"
+	cfg.AdminAPIKey = testAdminKey
 
 	lgr, err := logger.BuildLogger(cfg.LogLevel)
 	assert.NoError(t, err)
@@ -55,30 +92,127 @@ func getServer(t *testing.T) {
 	db, err = pkg.ConnectDB(cfg)
 	assert.NoError(t, err)
 
+	doc, err := openapi.Load()
+	assert.NoError(t, err)
+	validator, err := middlewares.NewValidator(lgr, doc)
+	assert.NoError(t, err)
+	docs, err := NewDocsHandlers(lgr, doc)
+	assert.NoError(t, err)
+
 	r := chi.NewRouter()
 
 	mdlwr := middlewares.NewMiddleware(lgr, cfg, db)
+	versions := middlewares.NewVersionNegotiator(r)
 	r.Use(
+		mdlwr.WithRequestID,
+		versions.WithAPIVersion,
 		mdlwr.WithLogging,
+		mdlwr.WithProgram,
 		mdlwr.WithAuth,
 		mdlwr.GzipMiddleware,
+		validator.WithValidation,
 	)
 
-	handlers := SetupHandlers(lgr, cfg, db)
+	handlers := SetupHandlers(lgr, cfg, db, events.NewBroker(lgr, cfg, db), services.NewJobs(context.Background()))
+
+	r.NotFound(NotFound)
+	r.MethodNotAllowed(MethodNotAllowed)
 
 	r.Route("/api", func(r chi.Router) {
+		r.Get("/openapi.json", docs.GetSpec)
+		r.Get("/docs", docs.GetDocs)
+		r.Get("/rewards", handlers.ForReward.ListRewards)
 		r.Route("/user", func(r chi.Router) {
 			r.Post("/register", handlers.ForUser.UserRegister)
 			r.Post("/login", handlers.ForUser.UserLogin)
+			r.Delete("/", handlers.ForUser.DeleteUser)
+			r.Get("/profile", handlers.ForUser.GetProfile)
+			r.Patch("/profile", handlers.ForUser.UpdateProfile)
+			r.Post("/password", handlers.ForUser.ChangePassword)
 			r.Route("/orders", func(r chi.Router) {
 				r.Post("/", handlers.ForOrder.CreateOrder)
 				r.Get("/", handlers.ForOrder.GetOrders)
+				r.Get("/stream", handlers.ForOrder.StreamOrders)
+				r.Post("/batch", handlers.ForOrder.CreateOrdersBatch)
+				r.Get("/batch/{id}", handlers.ForOrder.GetOrdersBatchJob)
+				r.Get("/{number}", handlers.ForOrder.GetOrder)
+				r.Delete("/{number}", handlers.ForOrder.CancelOrder)
 			})
 			r.Route("/balance", func(r chi.Router) {
 				r.Get("/", handlers.ForBalance.GetBalance)
 				r.Post("/withdraw", handlers.ForBalance.RequestWithdraw)
+				r.Post("/transfer", handlers.ForBalance.Transfer)
+				r.Post("/transfer/{id}/confirm", handlers.ForBalance.ConfirmTransfer)
+				r.Post("/holds", handlers.ForBalance.HoldPoints)
+				r.Post("/holds/{id}/capture", handlers.ForBalance.CaptureHold)
+				r.Post("/holds/{id}/release", handlers.ForBalance.ReleaseHold)
 			})
 			r.Get("/withdrawals", handlers.ForBalance.GetWithdrawals)
+			r.Get("/transfers", handlers.ForBalance.GetTransfers)
+			r.Get("/statement", handlers.ForBalance.GetStatement)
+			r.Get("/tier/history", handlers.ForBalance.GetTierHistory)
+			r.Get("/referrals", handlers.ForUser.GetReferralStats)
+			r.Post("/vouchers/redeem", handlers.ForVoucher.Redeem)
+			r.Get("/rewards", handlers.ForReward.GetRedemptions)
+			r.Post("/rewards/{id}/redeem", handlers.ForReward.Redeem)
+			r.Get("/notifications", handlers.ForNotification.GetPreferences)
+			r.Put("/notifications", handlers.ForNotification.UpdatePreferences)
+			r.Post("/export", handlers.ForExport.CreateExport)
+			r.Get("/export/{id}", handlers.ForExport.GetExport)
+			r.Get("/export/{id}/download", handlers.ForExport.DownloadExport)
+		})
+		r.Route("/admin/campaigns", func(r chi.Router) {
+			r.Use(mdlwr.WithAdminKey)
+			r.Get("/", handlers.ForCampaign.ListCampaigns)
+			r.Post("/", handlers.ForCampaign.CreateCampaign)
+			r.Post("/dry-run", handlers.ForCampaign.DryRunCampaigns)
+			r.Get("/{id}", handlers.ForCampaign.GetCampaign)
+			r.Put("/{id}", handlers.ForCampaign.UpdateCampaign)
+			r.Delete("/{id}", handlers.ForCampaign.DeleteCampaign)
+			r.Get("/{id}/grants", handlers.ForCampaign.GetCampaignGrants)
+		})
+		r.Route("/admin/adjustments", func(r chi.Router) {
+			r.Use(mdlwr.WithAdminKey)
+			r.Get("/", handlers.ForAdjustment.ListAdjustments)
+			r.Post("/{id}/approve", handlers.ForAdjustment.ApproveAdjustment)
+			r.Post("/{id}/dismiss", handlers.ForAdjustment.DismissAdjustment)
+		})
+		r.Route("/admin/programs", func(r chi.Router) {
+			r.Use(mdlwr.WithAdminKey)
+			r.Get("/", handlers.ForProgram.ListPrograms)
+			r.Post("/", handlers.ForProgram.CreateProgram)
+		})
+		r.Route("/admin/merchants", func(r chi.Router) {
+			r.Use(mdlwr.WithAdminKey)
+			r.Get("/", handlers.ForMerchant.ListMerchants)
+			r.Post("/", handlers.ForMerchant.CreateMerchant)
+		})
+		r.Route("/admin/vouchers", func(r chi.Router) {
+			r.Use(mdlwr.WithAdminKey)
+			r.Get("/", handlers.ForVoucher.ListBatches)
+			r.Post("/", handlers.ForVoucher.CreateBatch)
+			r.Post("/{id}/void", handlers.ForVoucher.VoidBatch)
+			r.Get("/{id}/codes.csv", handlers.ForVoucher.ExportCodes)
+		})
+		r.Route("/admin/rewards", func(r chi.Router) {
+			r.Use(mdlwr.WithAdminKey)
+			r.Get("/", handlers.ForReward.ListAllRewards)
+			r.Post("/", handlers.ForReward.CreateReward)
+			r.Put("/{id}", handlers.ForReward.UpdateReward)
+		})
+		r.Route("/admin/orders", func(r chi.Router) {
+			r.Use(mdlwr.WithAdminKey)
+			r.Post("/{number}/return", handlers.ForOrder.ReturnOrder)
+		})
+		r.Route("/merchant", func(r chi.Router) {
+			r.Use(mdlwr.WithMerchantKey)
+			r.Post("/orders", handlers.ForMerchant.RegisterOrder)
+			r.Get("/orders", handlers.ForMerchant.ListOrders)
+			r.Get("/summary", handlers.ForMerchant.GetSummary)
+		})
+		// в v2 только изменившиеся эндпоинты, остальное обслуживает v1 через WithAPIVersion
+		r.Route("/v2/user", func(r chi.Router) {
+			r.Post("/orders", handlers.ForOrder.CreateOrderV2)
 		})
 	})
 
@@ -94,3 +228,8 @@ func delTestOrder(db *sql.DB) error {
 	_, err := db.Exec(`DELETE FROM "order" WHERE number=$1`, testOrderNumber)
 	return err
 }
+
+func delTestWithdrawals(db *sql.DB) error {
+	_, err := db.Exec(`DELETE FROM withdraw_history WHERE order_number=$1`, testOrderNumber)
+	return err
+}
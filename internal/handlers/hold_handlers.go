@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+func (bh *BalanceHandlers) HoldPoints(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		bh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	var req models.WithdrawRequest
+	if err := dec.Decode(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			writeProblem(w, r, http.StatusBadRequest, problem.CodeEmptyBody, "")
+			return
+		}
+		bh.logger.Debug("error decoding hold request", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
+		return
+	}
+	if !bh.validateWithdrawRequest(w, r, &req) {
+		return
+	}
+
+	hold, err := bh.holdService.HoldPoints(r.Context(), user, &req)
+	if err != nil {
+		bh.logger.Debug("error holding points", zap.Int("USER_ID", user.ID), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	bh.writeHold(w, http.StatusCreated, hold)
+}
+
+func (bh *BalanceHandlers) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	bh.settleHold(w, r, bh.holdService.CaptureHold)
+}
+
+func (bh *BalanceHandlers) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	bh.settleHold(w, r, bh.holdService.ReleaseHold)
+}
+
+type holdSettler func(ctx context.Context, user *models.User, holdID int) (*models.Hold, error)
+
+func (bh *BalanceHandlers) settleHold(w http.ResponseWriter, r *http.Request, settle holdSettler) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		bh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, http.StatusNotFound, problem.CodeHoldNotFound, "")
+		return
+	}
+
+	hold, err := settle(r.Context(), user, id)
+	if err != nil {
+		bh.logger.Debug("error settling hold", zap.Int("HOLD_ID", id), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	bh.writeHold(w, http.StatusOK, hold)
+}
+
+func (bh *BalanceHandlers) writeHold(w http.ResponseWriter, status int, hold *models.Hold) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(hold); err != nil {
+		bh.logger.Error("error encoding hold to json", zap.Error(err))
+	}
+}
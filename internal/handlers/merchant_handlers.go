@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"go.uber.org/zap"
+)
+
+const maxMerchantNameLength = 100
+
+// код магазина входит в номера заказов, поэтому без двоеточия.
+var merchantCodePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,99}$`)
+
+func (mh *MerchantHandlers) ListMerchants(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	program, ok := mh.program(w, r)
+	if !ok {
+		return
+	}
+
+	merchants, err := mh.merchantService.GetMerchants(r.Context(), program.ID)
+	if err != nil {
+		mh.logger.Error("error getting merchants", zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	mh.writeJSON(w, http.StatusOK, merchants)
+}
+
+func (mh *MerchantHandlers) CreateMerchant(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	program, ok := mh.program(w, r)
+	if !ok {
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	var req models.MerchantRequest
+	if err := dec.Decode(&req); err != nil {
+		mh.logger.Debug("error decoding merchant request", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
+		return
+	}
+
+	var fields []problem.FieldError
+	if !merchantCodePattern.MatchString(req.Code) {
+		fields = append(fields, problem.FieldError{
+			Field:   "code",
+			Message: "must be 1 to 100 latin letters, digits, dots, dashes or underscores",
+		})
+	}
+	if req.Name == "" || len([]rune(req.Name)) > maxMerchantNameLength {
+		fields = append(fields, problem.FieldError{
+			Field:   "name",
+			Message: fmt.Sprintf("must be 1 to %d characters long", maxMerchantNameLength),
+		})
+	}
+	if len(fields) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "", fields...)
+		return
+	}
+
+	credentials, err := mh.merchantService.CreateMerchant(r.Context(), program.ID, &req)
+	if err != nil {
+		mh.logger.Debug("error creating merchant", zap.String("CODE", req.Code), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	mh.logger.Info("merchant created",
+		zap.Int("MERCHANT_ID", credentials.ID), zap.Int("PROGRAM_ID", program.ID), zap.String("CODE", req.Code))
+	mh.writeJSON(w, http.StatusCreated, credentials)
+}
+
+func (mh *MerchantHandlers) RegisterOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	merchant, ok := mh.merchant(w, r)
+	if !ok {
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	var req models.MerchantOrderRequest
+	if err := dec.Decode(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			writeProblem(w, r, http.StatusBadRequest, problem.CodeEmptyBody, "")
+			return
+		}
+		mh.logger.Debug("error decoding merchant order request", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
+		return
+	}
+
+	purchase := &models.OrderPurchase{
+		MerchantID:    merchant.Code,
+		PurchaseTotal: req.PurchaseTotal,
+		Currency:      req.Currency,
+		Items:         req.Items,
+	}
+	if fields := validateOrderPurchase(purchase); len(fields) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "", fields...)
+		return
+	}
+	if req.Number == "" || !mh.orderService.ValidateOrderNumber(req.Number) {
+		writeProblem(w, r, http.StatusUnprocessableEntity, problem.CodeInvalidOrderNumber,
+			"order number failed Luhn check")
+		return
+	}
+
+	receipt, err := mh.merchantService.RegisterOrder(r.Context(), merchant, &req)
+	if err != nil {
+		mh.logger.Debug("error registering merchant order",
+			zap.String("MERCHANT", merchant.Code), zap.String("NUMBER", req.Number), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	mh.logger.Info("merchant order registered", zap.String("MERCHANT", merchant.Code),
+		zap.String("NUMBER", req.Number), zap.Bool("CLAIMED", receipt.Claimed))
+	mh.writeJSON(w, http.StatusCreated, receipt)
+}
+
+func (mh *MerchantHandlers) ListOrders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	merchant, ok := mh.merchant(w, r)
+	if !ok {
+		return
+	}
+
+	filter, err := parseMerchantReceiptFilter(r.URL.Query())
+	if err != nil {
+		mh.logger.Debug("error parsing merchant orders list params", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeBadQueryParameter, err.Error())
+		return
+	}
+
+	receipts, cursor, err := mh.merchantService.GetReceipts(r.Context(), merchant, filter)
+	if err != nil {
+		mh.logger.Error("error getting merchant receipts", zap.String("MERCHANT", merchant.Code), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	setNextPageLink(w, r, cursor)
+	mh.writeJSON(w, http.StatusOK, receipts)
+}
+
+func (mh *MerchantHandlers) GetSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	merchant, ok := mh.merchant(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	var from, to *time.Time
+	if err := parseTimeParam(q, "from", &from); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeBadQueryParameter, err.Error())
+		return
+	}
+	if err := parseEndTimeParam(q, "to", &to); err != nil {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeBadQueryParameter, err.Error())
+		return
+	}
+	if from != nil && to != nil && !from.Before(*to) {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeBadQueryParameter, "from must be before to")
+		return
+	}
+
+	summary, err := mh.merchantService.GetSummary(r.Context(), merchant, from, to)
+	if err != nil {
+		mh.logger.Error("error getting merchant summary", zap.String("MERCHANT", merchant.Code), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	mh.writeJSON(w, http.StatusOK, summary)
+}
+
+func (mh *MerchantHandlers) program(w http.ResponseWriter, r *http.Request) (*models.Program, bool) {
+	program, ok := requestProgram(r)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		mh.logger.Error(ErrGettingContextProgram.Error())
+	}
+	return program, ok
+}
+
+func (mh *MerchantHandlers) merchant(w http.ResponseWriter, r *http.Request) (*models.Merchant, bool) {
+	merchant, ok := r.Context().Value(contextkeys.ContextMerchantKey).(*models.Merchant)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		mh.logger.Error(ErrGettingContextMerchant.Error())
+	}
+	return merchant, ok
+}
+
+func (mh *MerchantHandlers) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		mh.logger.Error("error encoding merchant response to json", zap.Error(err))
+	}
+}
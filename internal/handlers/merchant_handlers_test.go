@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func MerchantTestHandlers(t *testing.T) {
+	testCases := []struct {
+		testCase
+		method      string
+		endPoint    string
+		adminKey    bool
+		merchantKey string
+	}{
+		{
+			testCase: testCase{name: "List Merchants", expectedCode: http.StatusOK},
+			method:   http.MethodGet,
+			endPoint: `/api/admin/merchants`,
+			adminKey: true,
+		},
+		{
+			testCase: testCase{
+				name:         "Merchant Code With Colon",
+				body:         `{"code": "shop:1", "name": "Shop"}`,
+				expectedCode: http.StatusBadRequest,
+			},
+			method:   http.MethodPost,
+			endPoint: `/api/admin/merchants`,
+			adminKey: true,
+		},
+		{
+			testCase: testCase{name: "Merchant Orders Without Key", expectedCode: http.StatusUnauthorized},
+			method:   http.MethodGet,
+			endPoint: `/api/merchant/orders`,
+		},
+		{
+			testCase:    testCase{name: "Merchant Summary With Unknown Key", expectedCode: http.StatusUnauthorized},
+			method:      http.MethodGet,
+			endPoint:    `/api/merchant/summary`,
+			merchantKey: "unknown-key",
+		},
+		{
+			testCase: testCase{
+				name:         "Register Order Without Key",
+				body:         `{"number": "12345678903", "purchase_total": 100, "currency": "RUB"}`,
+				expectedCode: http.StatusUnauthorized,
+			},
+			method:   http.MethodPost,
+			endPoint: `/api/merchant/orders`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + test.endPoint
+			r.Method = test.method
+			if test.body != "" {
+				r.SetHeader("Content-Type", "application/json")
+				r.SetBody(test.body)
+			}
+			if test.adminKey {
+				r.SetHeader("X-Admin-Key", testAdminKey)
+			}
+			if test.merchantKey != "" {
+				r.SetHeader("X-Merchant-Key", test.merchantKey)
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/mail"
+	"regexp"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"go.uber.org/zap"
+)
+
+var (
+	// phoneRe номер в формате E.164
+	phoneRe = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	// channelAddressField поле с адресом, без которого канал нельзя включить
+	channelAddressField = map[string]string{
+		models.NotificationChannelEmail: "email",
+		models.NotificationChannelSMS:   "phone",
+	}
+)
+
+func (nh *NotificationHandlers) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		nh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	prefs, err := nh.notificationService.GetPreferences(r.Context(), user)
+	if err != nil {
+		nh.logger.Error("error getting notification preferences", zap.Int("USER_ID", user.ID), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	nh.writeJSON(w, http.StatusOK, prefs)
+}
+
+func (nh *NotificationHandlers) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		nh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	var prefs models.NotificationPreferences
+	if err := dec.Decode(&prefs); err != nil {
+		if errors.Is(err, io.EOF) {
+			writeProblem(w, r, http.StatusBadRequest, problem.CodeEmptyBody, "")
+			return
+		}
+		nh.logger.Debug("error decoding notification preferences", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
+		return
+	}
+	if fields := validateNotificationPreferences(&prefs); len(fields) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "", fields...)
+		return
+	}
+
+	if err := nh.notificationService.SavePreferences(r.Context(), user, &prefs); err != nil {
+		nh.logger.Error("error saving notification preferences", zap.Int("USER_ID", user.ID), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	nh.writeJSON(w, http.StatusOK, &prefs)
+}
+
+func (nh *NotificationHandlers) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		nh.logger.Error("error encoding notification response to json", zap.Error(err))
+	}
+}
+
+func validateNotificationPreferences(p *models.NotificationPreferences) []problem.FieldError {
+	var fields []problem.FieldError
+	if p.Email != nil && !validEmail(*p.Email) {
+		fields = append(fields, problem.FieldError{Field: "email", Message: "must be a valid email address"})
+	}
+	if p.Phone != nil && !phoneRe.MatchString(*p.Phone) {
+		fields = append(fields, problem.FieldError{Field: "phone", Message: "must be in E.164 format"})
+	}
+	if _, ok := models.NotificationLocales[p.Locale]; p.Locale != "" && !ok {
+		fields = append(fields, problem.FieldError{Field: "locale", Message: "must be one of: ru, en"})
+	}
+	for _, channel := range p.Channels {
+		if _, ok := models.NotificationChannels[channel]; !ok {
+			fields = append(fields, problem.FieldError{Field: "channels", Message: "unknown channel " + channel})
+			continue
+		}
+		if p.Recipient(channel) == "" {
+			fields = append(fields, problem.FieldError{
+				Field:   "channels",
+				Message: channel + " channel requires " + channelAddressField[channel],
+			})
+		}
+	}
+	for _, event := range p.Events {
+		if _, ok := models.NotificationEvents[event]; !ok {
+			fields = append(fields, problem.FieldError{Field: "events", Message: "unknown event " + event})
+		}
+	}
+	return fields
+}
+
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/auth"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func NotificationTestHandlers(t *testing.T) {
+	userToken, err := auth.BuildJWTToken(testUserID, testProgramID, 0, testTokenIssuer, cfg.DB.SecretKey,
+		cfg.TokenLifeTime)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		testCase
+		method string
+	}{
+		{
+			testCase: testCase{name: "Get Preferences", expectedCode: http.StatusOK},
+			method:   http.MethodGet,
+		},
+		{
+			testCase: testCase{name: "Get Preferences Unauthorized", expectedCode: http.StatusUnauthorized},
+			method:   http.MethodGet,
+		},
+		{
+			testCase: testCase{
+				name: "Update Preferences",
+				body: `{"email": "user@example.com", "locale": "en",
+					"channels": ["email"], "events": ["balance"]}`,
+				expectedCode: http.StatusOK,
+			},
+			method: http.MethodPut,
+		},
+		{
+			testCase: testCase{
+				name:         "SMS Without Phone",
+				body:         `{"locale": "ru", "channels": ["sms"], "events": ["order_status"]}`,
+				expectedCode: http.StatusBadRequest,
+			},
+			method: http.MethodPut,
+		},
+		{
+			testCase: testCase{
+				name:         "Unknown Locale",
+				body:         `{"locale": "de", "channels": [], "events": []}`,
+				expectedCode: http.StatusBadRequest,
+			},
+			method: http.MethodPut,
+		},
+		{
+			testCase: testCase{
+				name:         "Disable All",
+				body:         `{"locale": "ru", "channels": [], "events": []}`,
+				expectedCode: http.StatusOK,
+			},
+			method: http.MethodPut,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + `/api/user/notifications`
+			r.Method = test.method
+			if test.body != "" {
+				r.SetHeader("Content-Type", "application/json")
+				r.SetBody(test.body)
+			}
+			if test.expectedCode != http.StatusUnauthorized {
+				r.SetCookie(&http.Cookie{
+					Name:  "Token",
+					Value: userToken,
+				})
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+		})
+	}
+}
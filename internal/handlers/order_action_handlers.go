@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+const maxOrderActionReason = 200
+
+func (oh *OrderHandlers) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		oh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	number := chi.URLParam(r, "number")
+	if !oh.orderService.ValidateOrderNumber(number) {
+		writeProblem(w, r, http.StatusUnprocessableEntity, problem.CodeInvalidOrderNumber,
+			"order number failed Luhn check")
+		return
+	}
+
+	reason := strings.TrimSpace(r.URL.Query().Get("reason"))
+	if utf8.RuneCountInString(reason) > maxOrderActionReason {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeBadQueryParameter, "reason is too long")
+		return
+	}
+
+	if _, err := oh.orderService.CancelOrder(r.Context(), user, number, reason); err != nil {
+		oh.logger.Debug("error cancelling order", zap.String("NUMBER", number), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	oh.logger.Info("order cancelled", zap.Int("USERID", user.ID), zap.String("NUMBER", number))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (oh *OrderHandlers) ReturnOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	number := chi.URLParam(r, "number")
+	if !oh.orderService.ValidateOrderNumber(number) {
+		writeProblem(w, r, http.StatusUnprocessableEntity, problem.CodeInvalidOrderNumber,
+			"order number failed Luhn check")
+		return
+	}
+
+	var req models.OrderReturnRequest
+	dec := json.NewDecoder(r.Body)
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	if err := dec.Decode(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			writeProblem(w, r, http.StatusBadRequest, problem.CodeEmptyBody, "")
+			return
+		}
+		oh.logger.Debug("error decoding order return request", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	switch {
+	case req.Reason == "":
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "",
+			problem.FieldError{Field: "reason", Message: "is required"})
+		return
+	case utf8.RuneCountInString(req.Reason) > maxOrderActionReason:
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "",
+			problem.FieldError{Field: "reason", Message: "must be at most 200 characters"})
+		return
+	}
+
+	program, ok := requestProgram(r)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		oh.logger.Error(ErrGettingContextProgram.Error())
+		return
+	}
+
+	action, err := oh.orderService.ReturnOrder(r.Context(), program.ID, number, models.ActorAdmin, nil, req.Reason)
+	if err != nil {
+		oh.logger.Debug("error returning order", zap.String("NUMBER", number), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	oh.logger.Info("order returned", zap.String("NUMBER", number), zap.Float64("AMOUNT", action.Amount))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(action); err != nil {
+		oh.logger.Error("error encoding response to json", zap.Error(err))
+	}
+}
@@ -8,24 +8,26 @@ import (
 
 	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
 	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
 	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
 func (oh *OrderHandlers) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
 		return
 	}
 
 	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
 	if !ok {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
 		oh.logger.Error(ErrGettingContextUser.Error())
 		return
 	}
 	if !user.AuthInfo.IsAuthenticated {
-		w.WriteHeader(http.StatusUnauthorized)
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
 		return
 	}
 
@@ -34,14 +36,19 @@ func (oh *OrderHandlers) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		_ = r.Body.Close()
 	}()
 
-	if len(orderNumber) == 0 || !oh.orderService.ValidateOrderNumber(string(orderNumber)) {
-		w.WriteHeader(http.StatusUnprocessableEntity)
+	if err != nil {
+		oh.logger.Error("error reading body for create order", zap.Error(err), zap.Int("USER_ID", user.ID))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeEmptyBody, "")
 		return
 	}
 
-	if err != nil {
-		oh.logger.Error("error reading body for create order", zap.Error(err), zap.Int("USER_ID", user.ID))
-		w.WriteHeader(http.StatusBadRequest)
+	if len(orderNumber) == 0 {
+		writeProblem(w, r, http.StatusUnprocessableEntity, problem.CodeInvalidOrderNumber, "order number is empty")
+		return
+	}
+	if !oh.orderService.ValidateOrderNumber(string(orderNumber)) {
+		writeProblem(w, r, http.StatusUnprocessableEntity, problem.CodeInvalidOrderNumber,
+			"order number failed Luhn check")
 		return
 	}
 
@@ -50,10 +57,8 @@ func (oh *OrderHandlers) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		switch {
 		case errors.Is(err, repository.ErrOrderByUserExist):
 			w.WriteHeader(http.StatusOK)
-		case errors.Is(err, repository.ErrOrderNumberExist):
-			w.WriteHeader(http.StatusConflict)
 		default:
-			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, r, err)
 		}
 		oh.logger.Error("error creating new order", zap.String("OrderNumber", string(orderNumber)), zap.Error(err))
 		return
@@ -66,35 +71,80 @@ func (oh *OrderHandlers) CreateOrder(w http.ResponseWriter, r *http.Request) {
 func (oh *OrderHandlers) GetOrders(w http.ResponseWriter, r *http.Request) {
 	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
 	if !ok {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
 		oh.logger.Error(ErrGettingContextUser.Error())
 		return
 	}
 	if !user.AuthInfo.IsAuthenticated {
-		w.WriteHeader(http.StatusUnauthorized)
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	filter, err := parseOrderListFilter(r.URL.Query())
+	if err != nil {
+		oh.logger.Debug("error parsing orders list params", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeBadQueryParameter, err.Error())
 		return
 	}
 
-	orders, err := oh.orderService.GetOrdersByUser(r.Context(), user)
+	orders, cursor, err := oh.orderService.GetOrdersByUser(r.Context(), user, filter)
 	if err != nil {
 		if errors.Is(err, repository.ErrOrdersNotFound) {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		oh.logger.Error("error get orders by user", zap.Error(err))
 		return
 	}
 
 	enc := json.NewEncoder(w)
 
+	setNextPageLink(w, r, cursor)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 
 	var ordersResp models.OrdersResponse
 	ordersResp.Orders = orders
 	if err = enc.Encode(&ordersResp.Orders); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		oh.logger.Error("error encoding response to json", zap.Error(err))
+		return
+	}
+}
+
+func (oh *OrderHandlers) GetOrder(w http.ResponseWriter, r *http.Request) {
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		oh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	number := chi.URLParam(r, "number")
+	if !oh.orderService.ValidateOrderNumber(number) {
+		writeProblem(w, r, http.StatusUnprocessableEntity, problem.CodeInvalidOrderNumber,
+			"order number failed Luhn check")
+		return
+	}
+
+	details, err := oh.orderService.GetOrderDetails(r.Context(), user, number)
+	if err != nil {
+		writeError(w, r, err)
+		if errors.Is(err, repository.ErrOrderNumberNotFound) {
+			return
+		}
+		oh.logger.Error("error get order details", zap.String("NUMBER", number), zap.Error(err))
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	w.Header().Set("Content-Type", "application/json")
+
+	if err = enc.Encode(details); err != nil {
 		oh.logger.Error("error encoding response to json", zap.Error(err))
 		return
 	}
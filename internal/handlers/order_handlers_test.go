@@ -1,24 +1,54 @@
 package handlers
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Melikhov-p/go-loyalty-system/internal/auth"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
 	"github.com/go-resty/resty/v2"
+	"github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 func OrderTestHandlers(t *testing.T) {
-	userToken, err := auth.BuildJWTToken(testUserID, cfg.DB.SecretKey, cfg.TokenLifeTime)
+	userToken, err := auth.BuildJWTToken(testUserID, testProgramID, 0, testTokenIssuer, cfg.DB.SecretKey,
+		cfg.TokenLifeTime)
 	assert.NoError(t, err)
 
 	t.Run("CREATE ORDER", func(t *testing.T) {
 		OrderCreate(t, userToken)
 	})
+	t.Run("CREATE ORDER V2", func(t *testing.T) {
+		OrderCreateV2(t, userToken)
+	})
 	t.Run("GET USER ORDERS", func(t *testing.T) {
 		OrdersGet(t, userToken)
 	})
+	t.Run("CREATE ORDERS BATCH", func(t *testing.T) {
+		OrdersBatchCreate(t, userToken)
+	})
+	t.Run("GET USER ORDER", func(t *testing.T) {
+		OrderGet(t, userToken)
+	})
+	t.Run("STREAM USER ORDERS", func(t *testing.T) {
+		OrdersStream(t, userToken)
+	})
+	t.Run("CANCEL USER ORDER", func(t *testing.T) {
+		OrderCancel(t, userToken)
+	})
+	t.Run("RETURN ORDER", func(t *testing.T) {
+		OrderReturn(t)
+	})
 
 	err = delTestUser(db, "login")
 	assert.NoError(t, err)
@@ -78,6 +108,66 @@ func OrderCreate(t *testing.T, userToken string) {
 	}
 }
 
+func OrderCreateV2(t *testing.T, userToken string) {
+	v2OrderNumber := "12345678903"
+	testCases := []testCase{
+		{
+			name: "Happy create",
+			body: `{"number":"12345678903","merchant_id":"shop-1","purchase_total":1500,"currency":"RUB",` +
+				`"items":[{"description":"Чайник","price":750,"quantity":2}]}`,
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:         "Order Already Exist",
+			body:         `{"number":"12345678903","merchant_id":"shop-1","purchase_total":1500,"currency":"RUB"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "Wrong order format",
+			body:         `{"number":"123","merchant_id":"shop-1","purchase_total":1500,"currency":"RUB"}`,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "Wrong currency",
+			body:         `{"number":"12345678903","merchant_id":"shop-1","purchase_total":1500,"currency":"rub"}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Unauthorized",
+			body:         `{"number":"12345678903","merchant_id":"shop-1","purchase_total":1500,"currency":"RUB"}`,
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	endPoint := `/api/v2/user/orders`
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + endPoint
+			r.Method = http.MethodPost
+
+			r.SetHeader("Content-Type", "application/json")
+			r.SetBody(test.body)
+
+			if test.expectedCode != http.StatusUnauthorized {
+				r.SetCookie(&http.Cookie{
+					Name:  "Token",
+					Value: userToken,
+				})
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+		})
+	}
+
+	_, err := db.Exec(`DELETE FROM "order" WHERE number=$1`, v2OrderNumber)
+	assert.NoError(t, err)
+}
+
 func OrdersGet(t *testing.T, userToken string) {
 	testCases := []testCase{
 		{
@@ -98,6 +188,24 @@ func OrdersGet(t *testing.T, userToken string) {
 			expectedCode: http.StatusUnauthorized,
 			expectedBody: "",
 		},
+		{
+			name:         "Filter by status",
+			query:        "status=NEW,PROCESSING&limit=1&sort=-uploaded_at",
+			expectedCode: http.StatusOK,
+			expectedBody: "",
+		},
+		{
+			name:         "Bad limit",
+			query:        "limit=0",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "",
+		},
+		{
+			name:         "Bad cursor",
+			query:        "cursor=not-a-cursor",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "",
+		},
 	}
 
 	endPoint := `/api/user/orders`
@@ -106,6 +214,7 @@ func OrdersGet(t *testing.T, userToken string) {
 		t.Run(test.name, func(t *testing.T) {
 			r := resty.New().R()
 			r.URL = server.URL + endPoint
+			r.SetQueryString(test.query)
 
 			if test.expectedCode != http.StatusMethodNotAllowed {
 				r.Method = http.MethodGet
@@ -130,3 +239,351 @@ func OrdersGet(t *testing.T, userToken string) {
 		})
 	}
 }
+
+func OrdersStream(t *testing.T, userToken string) {
+	testCases := []testCase{
+		{
+			name:         "Happy streaming",
+			body:         "",
+			expectedCode: http.StatusOK,
+			expectedBody: "",
+		},
+		{
+			name:         "Unauthorized",
+			body:         "",
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: "",
+		},
+		{
+			name:         "Bad Last-Event-ID",
+			body:         "",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "",
+		},
+	}
+
+	endPoint := `/api/user/orders/stream`
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			r := resty.New().R().SetContext(ctx).SetDoNotParseResponse(true)
+			r.URL = server.URL + endPoint
+			r.Method = http.MethodGet
+
+			if test.expectedCode == http.StatusBadRequest {
+				r.SetHeader("Last-Event-ID", "abc")
+			}
+
+			if test.expectedCode != http.StatusUnauthorized {
+				r.SetCookie(&http.Cookie{
+					Name:  "Token",
+					Value: userToken,
+				})
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+			defer func() {
+				_ = resp.RawBody().Close()
+			}()
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+			if test.expectedCode == http.StatusOK {
+				assert.Equal(t, "text/event-stream", resp.Header().Get("Content-Type"))
+			}
+		})
+	}
+
+	eventRepo := repository.NewEventRepo(zap.NewNop(), cfg, db)
+	first, err := eventRepo.AddEvent(context.Background(), testUserID, models.EventBalance,
+		json.RawMessage(`{"current":1,"withdrawn":0}`))
+	require.NoError(t, err)
+	second, err := eventRepo.AddEvent(context.Background(), testUserID, models.EventBalance,
+		json.RawMessage(`{"current":2,"withdrawn":0}`))
+	require.NoError(t, err)
+
+	t.Run("SSE Replay After Last-Event-ID", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		resp, err := resty.New().R().SetContext(ctx).SetDoNotParseResponse(true).
+			SetHeader("Last-Event-ID", strconv.FormatInt(first.ID, 10)).
+			SetCookie(&http.Cookie{Name: "Token", Value: userToken}).
+			Get(server.URL + endPoint)
+		require.NoError(t, err)
+		defer func() {
+			_ = resp.RawBody().Close()
+		}()
+		require.Equal(t, http.StatusOK, resp.StatusCode())
+
+		// первый кадр после Last-Event-ID должен быть следующим событием, а не first
+		frame := readSSEFrame(t, bufio.NewReader(resp.RawBody()))
+		assert.Equal(t, []string{
+			"id: " + strconv.FormatInt(second.ID, 10),
+			"event: " + models.EventBalance,
+			`data: {"current":2,"withdrawn":0}`,
+		}, frame)
+	})
+
+	t.Run("WebSocket Replay After last_event_id", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("Cookie", (&http.Cookie{Name: "Token", Value: userToken}).String())
+		url := "ws" + strings.TrimPrefix(server.URL, "http") + endPoint +
+			"?last_event_id=" + strconv.FormatInt(first.ID, 10)
+
+		conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+		require.NoError(t, err)
+		defer func() {
+			_ = resp.Body.Close()
+			_ = conn.Close()
+		}()
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+
+		var event models.Event
+		require.NoError(t, conn.ReadJSON(&event))
+		assert.Equal(t, second.ID, event.ID)
+		assert.Equal(t, models.EventBalance, event.Type)
+		assert.JSONEq(t, `{"current":2,"withdrawn":0}`, string(event.Payload))
+	})
+}
+
+// readSSEFrame читает строки одного события до пустой строки, пропуская комментарии-пинги.
+func readSSEFrame(t *testing.T, r *bufio.Reader) []string {
+	t.Helper()
+
+	var frame []string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && len(frame) > 0:
+			return frame
+		case line == "" || strings.HasPrefix(line, ":"):
+			continue
+		default:
+			frame = append(frame, line)
+		}
+	}
+}
+
+func OrderGet(t *testing.T, userToken string) {
+	testCases := []testCase{
+		{
+			name:         "Happy getting",
+			body:         testOrderNumber,
+			expectedCode: http.StatusOK,
+			expectedBody: "",
+		},
+		{
+			name:         "Unauthorized",
+			body:         testOrderNumber,
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: "",
+		},
+		{
+			name:         "Not Found",
+			body:         "79927398713",
+			expectedCode: http.StatusNotFound,
+			expectedBody: "",
+		},
+		{
+			name:         "Wrong order format",
+			body:         "123",
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: "",
+		},
+	}
+
+	endPoint := `/api/user/orders/`
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + endPoint + test.body
+			r.Method = http.MethodGet
+
+			if test.expectedCode != http.StatusUnauthorized {
+				r.SetCookie(&http.Cookie{
+					Name:  "Token",
+					Value: userToken,
+				})
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+		})
+	}
+}
+
+func OrderCancel(t *testing.T, userToken string) {
+	testCases := []testCase{
+		{
+			name:         "Unauthorized",
+			body:         testOrderNumber,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "Wrong order format",
+			body:         "123",
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "Not Found",
+			body:         "79927398713",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Happy cancel",
+			body:         testOrderNumber,
+			query:        "reason=ordered by mistake",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "Already Cancelled",
+			body:         testOrderNumber,
+			expectedCode: http.StatusConflict,
+		},
+	}
+
+	endPoint := `/api/user/orders/`
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + endPoint + test.body
+			r.Method = http.MethodDelete
+			r.SetQueryString(test.query)
+
+			if test.expectedCode != http.StatusUnauthorized {
+				r.SetCookie(&http.Cookie{
+					Name:  "Token",
+					Value: userToken,
+				})
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+		})
+	}
+}
+
+// OrderReturn тестовый заказ к этому моменту отменён, поэтому вернуть его нельзя.
+func OrderReturn(t *testing.T) {
+	testCases := []struct {
+		testCase
+		number string
+	}{
+		{
+			testCase: testCase{
+				name:         "Unauthorized",
+				body:         `{"reason":"return"}`,
+				expectedCode: http.StatusUnauthorized,
+			},
+			number: testOrderNumber,
+		},
+		{
+			testCase: testCase{name: "Empty Reason", body: `{"reason":" "}`, expectedCode: http.StatusBadRequest},
+			number:   testOrderNumber,
+		},
+		{
+			testCase: testCase{name: "Not Found", body: `{"reason":"return"}`, expectedCode: http.StatusNotFound},
+			number:   "79927398713",
+		},
+		{
+			testCase: testCase{name: "Not Processed", body: `{"reason":"return"}`, expectedCode: http.StatusConflict},
+			number:   testOrderNumber,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + "/api/admin/orders/" + test.number + "/return"
+			r.Method = http.MethodPost
+			r.SetHeader("Content-Type", "application/json")
+			r.SetBody(test.body)
+
+			if test.expectedCode != http.StatusUnauthorized {
+				r.SetHeader("X-Admin-Key", testAdminKey)
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+		})
+	}
+}
+
+func OrdersBatchCreate(t *testing.T, userToken string) {
+	testCases := []struct {
+		testCase
+		contentType string
+	}{
+		{
+			testCase: testCase{
+				name:         "Happy CSV batch",
+				body:         "number\n" + testOrderNumber + "\n123\n",
+				expectedCode: http.StatusOK,
+			},
+			contentType: "text/csv",
+		},
+		{
+			testCase: testCase{
+				name:         "Happy JSON batch",
+				body:         `["` + testOrderNumber + `"]`,
+				expectedCode: http.StatusOK,
+			},
+			contentType: "application/json",
+		},
+		{
+			testCase: testCase{
+				name:         "Unsupported format",
+				body:         testOrderNumber,
+				expectedCode: http.StatusUnsupportedMediaType,
+			},
+			contentType: "text/plain",
+		},
+		{
+			testCase: testCase{
+				name:         "Unauthorized",
+				body:         testOrderNumber,
+				expectedCode: http.StatusUnauthorized,
+			},
+			contentType: "text/csv",
+		},
+	}
+
+	endPoint := `/api/user/orders/batch`
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + endPoint
+			r.Method = http.MethodPost
+
+			r.SetHeader("Content-Type", test.contentType)
+			r.SetBody(test.body)
+
+			if test.expectedCode != http.StatusUnauthorized {
+				r.SetCookie(&http.Cookie{
+					Name:  "Token",
+					Value: userToken,
+				})
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+		})
+	}
+}
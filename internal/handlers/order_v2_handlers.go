@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"go.uber.org/zap"
+)
+
+const maxMerchantIDLength = 100
+
+func (oh *OrderHandlers) CreateOrderV2(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		oh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	var req models.OrderUploadRequest
+	if err := dec.Decode(&req); err != nil {
+		oh.logger.Debug("error decoding order upload request", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
+		return
+	}
+	if fields := validateOrderPurchase(&req.OrderPurchase); len(fields) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "", fields...)
+		return
+	}
+
+	if req.Number == "" {
+		writeProblem(w, r, http.StatusUnprocessableEntity, problem.CodeInvalidOrderNumber, "order number is empty")
+		return
+	}
+	if !oh.orderService.ValidateOrderNumber(req.Number) {
+		writeProblem(w, r, http.StatusUnprocessableEntity, problem.CodeInvalidOrderNumber,
+			"order number failed Luhn check")
+		return
+	}
+
+	number, err := oh.orderService.CreateOrderWithPurchase(r.Context(), req.Number, &req.OrderPurchase, user)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrOrderByUserExist):
+			w.WriteHeader(http.StatusOK)
+		default:
+			writeError(w, r, err)
+		}
+		oh.logger.Error("error creating new order", zap.String("OrderNumber", req.Number), zap.Error(err))
+		return
+	}
+
+	oh.logger.Debug("create order v2", zap.String("NUMBER", req.Number), zap.String("MERCHANT", req.MerchantID))
+	w.Header().Set("Location", "/api/user/orders/"+url.PathEscape(number))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func validateOrderPurchase(p *models.OrderPurchase) []problem.FieldError {
+	var fields []problem.FieldError
+	if p.MerchantID == "" || len(p.MerchantID) > maxMerchantIDLength {
+		fields = append(fields, problem.FieldError{
+			Field:   "merchant_id",
+			Message: fmt.Sprintf("must be 1 to %d characters long", maxMerchantIDLength),
+		})
+	}
+	if p.PurchaseTotal <= 0 {
+		fields = append(fields, problem.FieldError{Field: "purchase_total", Message: "must be greater than zero"})
+	}
+	if !isCurrencyCode(p.Currency) {
+		fields = append(fields, problem.FieldError{Field: "currency", Message: "must be an ISO 4217 code"})
+	}
+	for i, item := range p.Items {
+		field := fmt.Sprintf("items.%d", i)
+		switch {
+		case item == nil:
+			fields = append(fields, problem.FieldError{Field: field, Message: "must not be null"})
+		case item.Description == "":
+			fields = append(fields, problem.FieldError{Field: field + ".description", Message: "must not be empty"})
+		case item.Price < 0:
+			fields = append(fields, problem.FieldError{Field: field + ".price", Message: "must not be negative"})
+		case item.Quantity < 1:
+			fields = append(fields, problem.FieldError{Field: field + ".quantity", Message: "must be at least 1"})
+		}
+	}
+	return fields
+}
+
+func isCurrencyCode(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, c := range s {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+)
+
+var ErrBadListParam error = errors.New("bad list query parameter")
+
+var numericSorts = map[string]struct{}{
+	"accrual": {},
+	"sum":     {},
+	"amount":  {},
+}
+
+// cursorTimeLayouts форматы, в которых Postgres отдаёт timestamp и timestamptz как text.
+var cursorTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999-07:00",
+}
+
+func parseListPage(q url.Values, defaultSort string, sorts ...string) (*models.ListPage, error) {
+	var limit int
+	if raw := q.Get("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 {
+			return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrBadListParam, models.MaxPageLimit)
+		}
+	}
+
+	page, err := models.NewListPage(limit, q.Get("sort"), q.Get("cursor"), defaultSort, sorts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadListParam, err)
+	}
+	if page.Cursor != nil && !validCursor(page.Cursor) {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrBadListParam)
+	}
+
+	return page, nil
+}
+
+func validCursor(cursor *models.Cursor) bool {
+	if cursor.ID < 1 {
+		return false
+	}
+
+	if _, ok := numericSorts[cursor.Sort]; ok {
+		_, err := strconv.ParseFloat(cursor.Value, 64)
+		return err == nil
+	}
+
+	for _, layout := range cursorTimeLayouts {
+		if _, err := time.Parse(layout, cursor.Value); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func parseOrderListFilter(q url.Values) (*models.OrderListFilter, error) {
+	page, err := parseListPage(q, "uploaded_at", "uploaded_at", "accrual")
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &models.OrderListFilter{ListPage: *page}
+
+	if raw := q.Get("status"); raw != "" {
+		for _, status := range strings.Split(raw, ",") {
+			status = strings.ToUpper(strings.TrimSpace(status))
+			if _, ok := models.OrderStatuses[status]; !ok {
+				return nil, fmt.Errorf("%w: unknown status %s", ErrBadListParam, status)
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	if err = parseTimeParam(q, "from", &filter.From); err != nil {
+		return nil, err
+	}
+	if err = parseEndTimeParam(q, "to", &filter.To); err != nil {
+		return nil, err
+	}
+	if err = parseFloatParam(q, "min_accrual", &filter.MinAccrual); err != nil {
+		return nil, err
+	}
+	if err = parseFloatParam(q, "max_accrual", &filter.MaxAccrual); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+func parseWithdrawListFilter(q url.Values) (*models.WithdrawListFilter, error) {
+	page, err := parseListPage(q, "processed_at", "processed_at", "sum")
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &models.WithdrawListFilter{ListPage: *page}
+
+	if err = parseTimeParam(q, "from", &filter.From); err != nil {
+		return nil, err
+	}
+	if err = parseEndTimeParam(q, "to", &filter.To); err != nil {
+		return nil, err
+	}
+	if err = parseFloatParam(q, "min_sum", &filter.MinSum); err != nil {
+		return nil, err
+	}
+	if err = parseFloatParam(q, "max_sum", &filter.MaxSum); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+func parseTransferListFilter(q url.Values) (*models.TransferListFilter, error) {
+	page, err := parseListPage(q, "created_at", "created_at", "amount")
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &models.TransferListFilter{ListPage: *page}
+
+	switch direction := q.Get("direction"); direction {
+	case "", models.TransferIn, models.TransferOut:
+		filter.Direction = direction
+	default:
+		return nil, fmt.Errorf("%w: direction must be in or out", ErrBadListParam)
+	}
+
+	return filter, nil
+}
+
+func parseMerchantReceiptFilter(q url.Values) (*models.MerchantReceiptFilter, error) {
+	page, err := parseListPage(q, "created_at", "created_at")
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &models.MerchantReceiptFilter{ListPage: *page}
+
+	if raw := q.Get("claimed"); raw != "" {
+		claimed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: claimed must be true or false", ErrBadListParam)
+		}
+		filter.Claimed = &claimed
+	}
+
+	return filter, nil
+}
+
+func parseTimeParam(q url.Values, name string, dst **time.Time) error {
+	raw := q.Get(name)
+	if raw == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		t, err = time.ParseInLocation(time.DateOnly, raw, time.Local)
+		if err != nil {
+			return fmt.Errorf("%w: %s must be RFC3339 or YYYY-MM-DD", ErrBadListParam, name)
+		}
+	}
+	t = t.In(time.Local)
+	*dst = &t
+
+	return nil
+}
+
+// parseEndTimeParam дата включается в период целиком.
+func parseEndTimeParam(q url.Values, name string, dst **time.Time) error {
+	if err := parseTimeParam(q, name, dst); err != nil {
+		return err
+	}
+
+	if *dst != nil && len(q.Get(name)) == len(time.DateOnly) {
+		end := (*dst).AddDate(0, 0, 1)
+		*dst = &end
+	}
+
+	return nil
+}
+
+func parseFloatParam(q url.Values, name string, dst **float64) error {
+	raw := q.Get(name)
+	if raw == "" {
+		return nil
+	}
+
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %s must be a number", ErrBadListParam, name)
+	}
+	*dst = &v
+
+	return nil
+}
+
+func setNextPageLink(w http.ResponseWriter, r *http.Request, cursor *models.Cursor) {
+	if cursor == nil {
+		return
+	}
+
+	next := *r.URL
+	q := next.Query()
+	q.Set("cursor", cursor.Encode())
+	next.RawQuery = q.Encode()
+
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseListPageCursor(t *testing.T) {
+	testCases := []struct {
+		name    string
+		sort    string
+		cursor  models.Cursor
+		wantErr bool
+	}{
+		{
+			name:   "Timestamp",
+			sort:   "uploaded_at",
+			cursor: models.Cursor{Sort: "uploaded_at", Desc: true, Value: "2025-02-14 11:30:45.123456", ID: 3},
+		},
+		{
+			name:   "Timestamptz",
+			sort:   "created_at",
+			cursor: models.Cursor{Sort: "created_at", Desc: true, Value: "2025-02-14 11:30:45+03", ID: 3},
+		},
+		{
+			name:   "Numeric",
+			sort:   "accrual",
+			cursor: models.Cursor{Sort: "accrual", Desc: true, Value: "729.98", ID: 3},
+		},
+		{
+			name:    "Garbage Timestamp",
+			sort:    "uploaded_at",
+			cursor:  models.Cursor{Sort: "uploaded_at", Desc: true, Value: "yesterday", ID: 3},
+			wantErr: true,
+		},
+		{
+			name:    "Garbage Numeric",
+			sort:    "accrual",
+			cursor:  models.Cursor{Sort: "accrual", Desc: true, Value: "1; DROP", ID: 3},
+			wantErr: true,
+		},
+		{
+			name:    "Bad ID",
+			sort:    "uploaded_at",
+			cursor:  models.Cursor{Sort: "uploaded_at", Desc: true, Value: "2025-02-14 11:30:45", ID: 0},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			q := url.Values{"cursor": {test.cursor.Encode()}}
+
+			page, err := parseListPage(q, test.sort, "uploaded_at", "accrual", "created_at")
+			if test.wantErr {
+				assert.ErrorIs(t, err, ErrBadListParam)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.cursor, *page.Cursor)
+		})
+	}
+}
+
+func TestParseEndTimeParam(t *testing.T) {
+	testCases := []struct {
+		name     string
+		raw      string
+		expected time.Time
+	}{
+		{
+			name:     "Date Includes Whole Day",
+			raw:      "2025-02-14",
+			expected: time.Date(2025, 2, 15, 0, 0, 0, 0, time.Local),
+		},
+		{
+			name:     "RFC3339 Kept As Is",
+			raw:      "2025-02-14T12:00:00Z",
+			expected: time.Date(2025, 2, 14, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			var to *time.Time
+			require.NoError(t, parseEndTimeParam(url.Values{"to": {test.raw}}, "to", &to))
+			require.NotNil(t, to)
+			assert.True(t, test.expected.Equal(*to))
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+)
+
+func writeProblem(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	code problem.Code,
+	detail string,
+	fields ...problem.FieldError,
+) {
+	problem.Write(w, r, problem.New(status, code, detail, fields...))
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	problem.Write(w, r, problem.FromError(err))
+}
+
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, http.StatusNotFound, problem.CodeNotFound, "")
+}
+
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"go.uber.org/zap"
+)
+
+const maxProgramNameLength = 100
+
+var programCodePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+func requestProgram(r *http.Request) (*models.Program, bool) {
+	program, ok := r.Context().Value(contextkeys.ContextProgramKey).(*models.Program)
+	return program, ok
+}
+
+func (ph *ProgramHandlers) ListPrograms(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	programs, err := ph.programService.GetPrograms(r.Context())
+	if err != nil {
+		ph.logger.Error("error getting programs", zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	ph.writeJSON(w, http.StatusOK, programs)
+}
+
+func (ph *ProgramHandlers) CreateProgram(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	var req models.ProgramRequest
+	if err := dec.Decode(&req); err != nil {
+		ph.logger.Debug("error decoding program request", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
+		return
+	}
+
+	var fields []problem.FieldError
+	if !programCodePattern.MatchString(req.Code) {
+		fields = append(fields, problem.FieldError{
+			Field:   "code",
+			Message: "must be 1 to 50 lowercase latin letters, digits or dashes",
+		})
+	}
+	if req.Name == "" || len([]rune(req.Name)) > maxProgramNameLength {
+		fields = append(fields, problem.FieldError{Field: "name", Message: "must be 1 to 100 characters long"})
+	}
+	if req.AccrualAddr != "" {
+		if u, err := url.Parse(req.AccrualAddr); err != nil || u.Scheme == "" || u.Host == "" {
+			fields = append(fields, problem.FieldError{Field: "accrual_addr", Message: "must be an absolute URL"})
+		}
+	}
+	if len(fields) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "", fields...)
+		return
+	}
+
+	program, err := ph.programService.CreateProgram(r.Context(), &req)
+	if err != nil {
+		ph.logger.Debug("error creating program", zap.String("CODE", req.Code), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	ph.logger.Info("program created", zap.Int("PROGRAM_ID", program.ID), zap.String("CODE", program.Code))
+	ph.writeJSON(w, http.StatusCreated, program)
+}
+
+func (ph *ProgramHandlers) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		ph.logger.Error("error encoding program response to json", zap.Error(err))
+	}
+}
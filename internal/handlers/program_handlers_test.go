@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func ProgramTestHandlers(t *testing.T) {
+	testCases := []struct {
+		testCase
+		method   string
+		endPoint string
+		program  string
+	}{
+		{
+			testCase: testCase{name: "List Programs", expectedCode: http.StatusOK},
+			method:   http.MethodGet,
+			endPoint: `/api/admin/programs`,
+		},
+		{
+			testCase: testCase{
+				name:         "Invalid Program Code",
+				body:         `{"code": "Bad Code", "name": "Brand"}`,
+				expectedCode: http.StatusBadRequest,
+			},
+			method:   http.MethodPost,
+			endPoint: `/api/admin/programs`,
+		},
+		{
+			testCase: testCase{
+				name:         "Duplicate Program",
+				body:         `{"code": "default", "name": "Gophermart"}`,
+				expectedCode: http.StatusConflict,
+			},
+			method:   http.MethodPost,
+			endPoint: `/api/admin/programs`,
+		},
+		{
+			testCase: testCase{name: "Unknown Program Header", expectedCode: http.StatusNotFound},
+			method:   http.MethodGet,
+			endPoint: `/api/admin/programs`,
+			program:  "no-such-program",
+		},
+		{
+			testCase: testCase{name: "Without Admin Key", expectedCode: http.StatusUnauthorized},
+			method:   http.MethodGet,
+			endPoint: `/api/admin/programs`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + test.endPoint
+			r.Method = test.method
+			if test.body != "" {
+				r.SetHeader("Content-Type", "application/json")
+				r.SetBody(test.body)
+			}
+			if test.program != "" {
+				r.SetHeader("X-Program", test.program)
+			}
+			if test.expectedCode != http.StatusUnauthorized {
+				r.SetHeader("X-Admin-Key", testAdminKey)
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+		})
+	}
+}
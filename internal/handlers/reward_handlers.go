@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	maxRewardNameLength        = 100
+	maxRewardDescriptionLength = 500
+)
+
+func (rh *RewardHandlers) ListRewards(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	program, ok := rh.program(w, r)
+	if !ok {
+		return
+	}
+
+	rewards, err := rh.rewardService.GetRewards(r.Context(), program.ID, true)
+	if err != nil {
+		rh.logger.Error("error getting rewards", zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	rh.writeJSON(w, http.StatusOK, rewards)
+}
+
+func (rh *RewardHandlers) Redeem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		rh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	id, ok := rewardID(w, r)
+	if !ok {
+		return
+	}
+
+	redemption, err := rh.rewardService.Redeem(r.Context(), user, id)
+	if err != nil {
+		rh.logger.Debug("error redeeming reward", zap.Int("USER_ID", user.ID), zap.Int("REWARD_ID", id),
+			zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	rh.logger.Info("reward redeemed", zap.Int("USER_ID", user.ID), zap.Int("REWARD_ID", id),
+		zap.Float64("PRICE", redemption.Price))
+	rh.writeJSON(w, http.StatusCreated, redemption)
+}
+
+func (rh *RewardHandlers) GetRedemptions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		rh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	page, err := parseListPage(r.URL.Query(), "redeemed_at", "redeemed_at")
+	if err != nil {
+		rh.logger.Debug("error parsing reward redemptions list params", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeBadQueryParameter, err.Error())
+		return
+	}
+
+	redemptions, cursor, err := rh.rewardService.GetRedemptions(r.Context(), user, page)
+	if err != nil {
+		rh.logger.Error("error getting reward redemptions", zap.Int("USER_ID", user.ID), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+	if len(redemptions) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	setNextPageLink(w, r, cursor)
+	rh.writeJSON(w, http.StatusOK, redemptions)
+}
+
+func (rh *RewardHandlers) ListAllRewards(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	program, ok := rh.program(w, r)
+	if !ok {
+		return
+	}
+
+	rewards, err := rh.rewardService.GetRewards(r.Context(), program.ID, false)
+	if err != nil {
+		rh.logger.Error("error getting rewards", zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	rh.writeJSON(w, http.StatusOK, rewards)
+}
+
+func (rh *RewardHandlers) CreateReward(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	program, ok := rh.program(w, r)
+	if !ok {
+		return
+	}
+	req, ok := rh.decodeRewardRequest(w, r)
+	if !ok {
+		return
+	}
+
+	reward, err := rh.rewardService.CreateReward(r.Context(), program.ID, req)
+	if err != nil {
+		rh.logger.Error("error creating reward", zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	rh.logger.Info("reward created", zap.Int("REWARD_ID", reward.ID), zap.Int("PROGRAM_ID", program.ID))
+	rh.writeJSON(w, http.StatusCreated, reward)
+}
+
+func (rh *RewardHandlers) UpdateReward(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	program, ok := rh.program(w, r)
+	if !ok {
+		return
+	}
+	id, ok := rewardID(w, r)
+	if !ok {
+		return
+	}
+	req, ok := rh.decodeRewardRequest(w, r)
+	if !ok {
+		return
+	}
+
+	reward, err := rh.rewardService.UpdateReward(r.Context(), program.ID, id, req)
+	if err != nil {
+		rh.logger.Debug("error updating reward", zap.Int("REWARD_ID", id), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	rh.logger.Info("reward updated", zap.Int("REWARD_ID", reward.ID))
+	rh.writeJSON(w, http.StatusOK, reward)
+}
+
+func (rh *RewardHandlers) decodeRewardRequest(w http.ResponseWriter, r *http.Request) (*models.RewardRequest, bool) {
+	dec := json.NewDecoder(r.Body)
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	var req models.RewardRequest
+	if err := dec.Decode(&req); err != nil {
+		rh.logger.Debug("error decoding reward request", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
+		return nil, false
+	}
+	if fields := validateReward(&req); len(fields) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "", fields...)
+		return nil, false
+	}
+
+	return &req, true
+}
+
+func (rh *RewardHandlers) program(w http.ResponseWriter, r *http.Request) (*models.Program, bool) {
+	program, ok := requestProgram(r)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		rh.logger.Error(ErrGettingContextProgram.Error())
+	}
+	return program, ok
+}
+
+func (rh *RewardHandlers) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		rh.logger.Error("error encoding reward response to json", zap.Error(err))
+	}
+}
+
+func rewardID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeProblem(w, r, http.StatusNotFound, problem.CodeRewardNotFound, "")
+		return 0, false
+	}
+	return id, true
+}
+
+func validateReward(req *models.RewardRequest) []problem.FieldError {
+	var fields []problem.FieldError
+	if req.Name == "" || len([]rune(req.Name)) > maxRewardNameLength {
+		fields = append(fields, problem.FieldError{
+			Field:   "name",
+			Message: fmt.Sprintf("must be 1 to %d characters long", maxRewardNameLength),
+		})
+	}
+	if len([]rune(req.Description)) > maxRewardDescriptionLength {
+		fields = append(fields, problem.FieldError{
+			Field:   "description",
+			Message: fmt.Sprintf("must be at most %d characters long", maxRewardDescriptionLength),
+		})
+	}
+	if req.Price <= 0 {
+		fields = append(fields, problem.FieldError{Field: "price", Message: "must be greater than zero"})
+	} else if cents := req.Price * 100; math.Abs(cents-math.Round(cents)) > 1e-6 {
+		fields = append(fields, problem.FieldError{Field: "price", Message: "must have at most 2 decimal places"})
+	}
+	if req.Stock != nil && *req.Stock < 0 {
+		fields = append(fields, problem.FieldError{Field: "stock", Message: "must not be negative"})
+	}
+	if req.UserLimit != nil && *req.UserLimit < 1 {
+		fields = append(fields, problem.FieldError{Field: "user_limit", Message: "must be at least 1"})
+	}
+	return fields
+}
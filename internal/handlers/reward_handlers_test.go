@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/auth"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func RewardTestHandlers(t *testing.T) {
+	userToken, err := auth.BuildJWTToken(testUserID, testProgramID, 0, testTokenIssuer, cfg.DB.SecretKey,
+		cfg.TokenLifeTime)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		testCase
+		method    string
+		endPoint  string
+		adminKey  bool
+		anonymous bool
+	}{
+		{
+			testCase:  testCase{name: "Catalog Without Auth", expectedCode: http.StatusOK},
+			method:    http.MethodGet,
+			endPoint:  `/api/rewards`,
+			anonymous: true,
+		},
+		{
+			testCase: testCase{name: "Admin Catalog", expectedCode: http.StatusOK},
+			method:   http.MethodGet,
+			endPoint: `/api/admin/rewards`,
+			adminKey: true,
+		},
+		{
+			testCase: testCase{
+				name:         "Reward Without Price",
+				body:         `{"name": "Coffee", "price": 0}`,
+				expectedCode: http.StatusBadRequest,
+			},
+			method:   http.MethodPost,
+			endPoint: `/api/admin/rewards`,
+			adminKey: true,
+		},
+		{
+			testCase: testCase{
+				name:         "Update Unknown Reward",
+				body:         `{"name": "Coffee", "price": 150}`,
+				expectedCode: http.StatusNotFound,
+			},
+			method:   http.MethodPut,
+			endPoint: `/api/admin/rewards/999999`,
+			adminKey: true,
+		},
+		{
+			testCase:  testCase{name: "Redeem Unauthorized", expectedCode: http.StatusUnauthorized},
+			method:    http.MethodPost,
+			endPoint:  `/api/user/rewards/1/redeem`,
+			anonymous: true,
+		},
+		{
+			testCase: testCase{name: "Redeem Unknown Reward", expectedCode: http.StatusNotFound},
+			method:   http.MethodPost,
+			endPoint: `/api/user/rewards/999999/redeem`,
+		},
+		{
+			testCase:  testCase{name: "Redemptions Unauthorized", expectedCode: http.StatusUnauthorized},
+			method:    http.MethodGet,
+			endPoint:  `/api/user/rewards`,
+			anonymous: true,
+		},
+		{
+			testCase: testCase{name: "Redemptions Bad Limit", expectedCode: http.StatusBadRequest},
+			method:   http.MethodGet,
+			endPoint: `/api/user/rewards?limit=0`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + test.endPoint
+			r.Method = test.method
+			if test.body != "" {
+				r.SetHeader("Content-Type", "application/json")
+				r.SetBody(test.body)
+			}
+			if test.adminKey {
+				r.SetHeader("X-Admin-Key", testAdminKey)
+			}
+			if !test.anonymous {
+				r.SetCookie(&http.Cookie{
+					Name:  "Token",
+					Value: userToken,
+				})
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+		})
+	}
+}
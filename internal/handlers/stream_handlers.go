@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/events"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const websocketWriteTTL = 10 * time.Second
+
+var upgrader = websocket.Upgrader{}
+
+func (oh *OrderHandlers) StreamOrders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		oh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	lastEventID, err := parseLastEventID(r)
+	if err != nil {
+		oh.logger.Debug("error parsing Last-Event-ID", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeBadQueryParameter, err.Error())
+		return
+	}
+
+	// подписываемся до чтения пропущенных событий, чтобы не потерять пришедшие между запросами
+	liveEvents, unsubscribe := oh.broker.Subscribe(user.ID)
+	defer unsubscribe()
+
+	missed, err := oh.eventService.GetUserEventsAfter(r.Context(), user, lastEventID)
+	if err != nil {
+		oh.logger.Error("error getting missed user events", zap.Error(err), zap.Int("USER_ID", user.ID))
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	var sink events.Sink
+	if websocket.IsWebSocketUpgrade(r) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			oh.logger.Debug("error upgrading connection to websocket", zap.Error(err))
+			return
+		}
+		defer func() {
+			_ = conn.Close()
+		}()
+
+		// читаем входящие кадры только чтобы заметить закрытие соединения клиентом
+		go func() {
+			defer cancel()
+			for {
+				if _, _, err := conn.NextReader(); err != nil {
+					return
+				}
+			}
+		}()
+		sink = &websocketSink{conn: conn}
+	} else {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			oh.logger.Error("response writer does not support flushing")
+			writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		sink = &sseSink{w: w, flusher: flusher}
+	}
+
+	if err = events.Pump(ctx, sink, missed, liveEvents, lastEventID); err != nil {
+		oh.logger.Debug("order stream closed", zap.Error(err), zap.Int("USER_ID", user.ID))
+	}
+}
+
+func parseLastEventID(r *http.Request) (int64, error) {
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		// браузерный WebSocket не умеет передавать заголовки
+		raw = r.URL.Query().Get("last_event_id")
+	}
+	if raw == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing last event id %w", err)
+	}
+
+	return id, nil
+}
+
+type sseSink struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func (s *sseSink) Send(event *models.Event) error {
+	if _, err := fmt.Fprintf(s.w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Payload); err != nil {
+		return fmt.Errorf("error writing server sent event %w", err)
+	}
+	s.flusher.Flush()
+
+	return nil
+}
+
+func (s *sseSink) Ping() error {
+	if _, err := fmt.Fprint(s.w, ": ping\n\n"); err != nil {
+		return fmt.Errorf("error writing server sent ping %w", err)
+	}
+	s.flusher.Flush()
+
+	return nil
+}
+
+type websocketSink struct {
+	conn *websocket.Conn
+}
+
+func (s *websocketSink) Send(event *models.Event) error {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(websocketWriteTTL)); err != nil {
+		return fmt.Errorf("error setting websocket write deadline %w", err)
+	}
+	if err := s.conn.WriteJSON(event); err != nil {
+		return fmt.Errorf("error writing websocket event %w", err)
+	}
+
+	return nil
+}
+
+func (s *websocketSink) Ping() error {
+	if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(websocketWriteTTL)); err != nil {
+		return fmt.Errorf("error writing websocket ping %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSESink(t *testing.T) {
+	rec := httptest.NewRecorder()
+	sink := &sseSink{w: rec, flusher: rec}
+
+	require.NoError(t, sink.Send(&models.Event{
+		ID:      42,
+		Type:    models.EventOrderStatus,
+		Payload: json.RawMessage(`{"number":"12345678903","status":"PROCESSED"}`),
+	}))
+	require.NoError(t, sink.Ping())
+
+	assert.Equal(t,
+		"id: 42\nevent: order_status\ndata: {\"number\":\"12345678903\",\"status\":\"PROCESSED\"}\n\n: ping\n\n",
+		rec.Body.String())
+	assert.True(t, rec.Flushed)
+}
+
+func TestWebsocketSink(t *testing.T) {
+	event := &models.Event{ID: 7, UserID: 1, Type: models.EventBalance, Payload: json.RawMessage(`{"current":10}`)}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = conn.Close()
+		}()
+		_ = (&websocketSink{conn: conn}).Send(event)
+	}))
+	defer srv.Close()
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	require.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+		_ = conn.Close()
+	}()
+
+	msgType, raw, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.TextMessage, msgType)
+
+	var got models.Event
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, event.ID, got.ID)
+	assert.Equal(t, event.Type, got.Type)
+	assert.JSONEq(t, string(event.Payload), string(got.Payload))
+}
+
+func TestParseLastEventID(t *testing.T) {
+	testCases := []struct {
+		name     string
+		header   string
+		query    string
+		expected int64
+		wantErr  bool
+	}{
+		{name: "Empty", expected: 0},
+		{name: "Header", header: "15", expected: 15},
+		{name: "Query For Browser WebSocket", query: "?last_event_id=9", expected: 9},
+		{name: "Header Wins Over Query", header: "3", query: "?last_event_id=9", expected: 3},
+		{name: "Not A Number", header: "abc", wantErr: true},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/user/orders/stream"+test.query, nil)
+			if test.header != "" {
+				r.Header.Set("Last-Event-ID", test.header)
+			}
+
+			id, err := parseLastEventID(r)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, id)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+const maxTransferCommentLength = 140
+
+func (bh *BalanceHandlers) Transfer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		bh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	var req models.TransferRequest
+	if err := dec.Decode(&req); err != nil {
+		bh.logger.Debug("error decoding transfer request", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
+		return
+	}
+	if fields := validateTransfer(&req); len(fields) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "", fields...)
+		return
+	}
+
+	transfer, err := bh.transferService.Transfer(r.Context(), user, &req)
+	if err != nil {
+		bh.logger.Debug("error transferring points",
+			zap.Int("USER_ID", user.ID),
+			zap.String("TO", req.To),
+			zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	status := http.StatusOK
+	if transfer.Status == models.TransferPending {
+		status = http.StatusAccepted
+	}
+	bh.writeTransfer(w, status, transfer)
+}
+
+func (bh *BalanceHandlers) ConfirmTransfer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		bh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		writeProblem(w, r, http.StatusNotFound, problem.CodeTransferNotFound, "")
+		return
+	}
+
+	transfer, err := bh.transferService.ConfirmTransfer(r.Context(), user, id)
+	if err != nil {
+		bh.logger.Debug("error confirming transfer", zap.Int("TRANSFER_ID", id), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	bh.writeTransfer(w, http.StatusOK, transfer)
+}
+
+func (bh *BalanceHandlers) GetTransfers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		bh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	filter, err := parseTransferListFilter(r.URL.Query())
+	if err != nil {
+		bh.logger.Debug("error parsing transfers list params", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeBadQueryParameter, err.Error())
+		return
+	}
+
+	transfers, cursor, err := bh.transferService.GetUserTransfers(r.Context(), user, filter)
+	if err != nil {
+		if errors.Is(err, repository.ErrUnknownSort) {
+			writeError(w, r, err)
+			return
+		}
+		bh.logger.Error("error getting user transfers", zap.Error(err))
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		return
+	}
+	if len(transfers) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	setNextPageLink(w, r, cursor)
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(transfers); err != nil {
+		bh.logger.Error("error encoding response for transfers", zap.Error(err))
+		return
+	}
+}
+
+func (bh *BalanceHandlers) writeTransfer(w http.ResponseWriter, status int, transfer *models.Transfer) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(transfer); err != nil {
+		bh.logger.Error("error encoding transfer to json", zap.Error(err))
+	}
+}
+
+func validateTransfer(req *models.TransferRequest) []problem.FieldError {
+	var fields []problem.FieldError
+	if req.To == "" {
+		fields = append(fields, problem.FieldError{Field: "to", Message: "must not be empty"})
+	}
+	if req.Amount <= 0 {
+		fields = append(fields, problem.FieldError{Field: "amount", Message: "must be greater than zero"})
+	} else if cents := req.Amount * 100; math.Abs(cents-math.Round(cents)) > 1e-6 {
+		fields = append(fields, problem.FieldError{Field: "amount", Message: "must have at most 2 decimal places"})
+	}
+	if len([]rune(req.Comment)) > maxTransferCommentLength {
+		fields = append(fields, problem.FieldError{
+			Field:   "comment",
+			Message: fmt.Sprintf("must be at most %d characters long", maxTransferCommentLength),
+		})
+	}
+	return fields
+}
@@ -3,30 +3,37 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 
 	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
 	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
 	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
 	"github.com/Melikhov-p/go-loyalty-system/internal/services"
 	"go.uber.org/zap"
 )
 
+const (
+	maxReferralCodeLength = 16
+	maxDisplayNameLength  = 100
+)
+
 func (uh *UserHandlers) UserRegister(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
 		return
 	}
 
 	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
 	if !ok {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
 		uh.logger.Error(ErrGettingContextUser.Error())
 		return
 	}
 	if user.AuthInfo.IsAuthenticated {
-		w.WriteHeader(http.StatusConflict)
+		writeProblem(w, r, http.StatusConflict, problem.CodeAlreadyAuthenticated, "")
 		return
 	}
 
@@ -39,23 +46,30 @@ func (uh *UserHandlers) UserRegister(w http.ResponseWriter, r *http.Request) {
 	if err := dec.Decode(&req); err != nil {
 		body, _ := io.ReadAll(r.Body)
 		uh.logger.Debug("error decoding request", zap.Error(err), zap.String("RAW", string(body)))
-		w.WriteHeader(http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
 		return
 	}
-	if req.Login == "" || req.Password == "" {
-		w.WriteHeader(http.StatusBadRequest)
+	if fields := validateLogPass(&req); len(fields) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "", fields...)
+		return
+	}
+
+	program, ok := requestProgram(r)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		uh.logger.Error(ErrGettingContextProgram.Error())
 		return
 	}
 
-	user, err := uh.userService.AddNewUser(r.Context(), req.Login, req.Password)
+	user, err := uh.userService.AddNewUser(r.Context(), program, req.Login, req.Password, req.ReferralCode)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserWithLoginExist) {
 			uh.logger.Error(repository.ErrUserWithLoginExist.Error(), zap.String("LOGIN", req.Login))
-			w.WriteHeader(http.StatusConflict)
+			writeError(w, r, err)
 			return
 		}
 		uh.logger.Error("error register new user", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -68,13 +82,13 @@ func (uh *UserHandlers) UserRegister(w http.ResponseWriter, r *http.Request) {
 
 func (uh *UserHandlers) UserLogin(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
 		return
 	}
 
 	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
 	if !ok {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
 		uh.logger.Error(ErrGettingContextUser.Error())
 		return
 	}
@@ -95,23 +109,30 @@ func (uh *UserHandlers) UserLogin(w http.ResponseWriter, r *http.Request) {
 	var req models.UserLogPassRequest
 	if err := dec.Decode(&req); err != nil {
 		uh.logger.Error("error decoding request", zap.Error(err))
-		w.WriteHeader(http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
+		return
+	}
+	if fields := validateLogPass(&req); len(fields) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "", fields...)
 		return
 	}
-	if req.Login == "" || req.Password == "" {
-		w.WriteHeader(http.StatusBadRequest)
+
+	program, ok := requestProgram(r)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		uh.logger.Error(ErrGettingContextProgram.Error())
 		return
 	}
 
-	user, err := uh.userService.AuthUser(r.Context(), req.Login, req.Password)
+	user, err := uh.userService.AuthUser(r.Context(), program, req.Login, req.Password)
 	if err != nil {
 		if errors.Is(err, services.ErrIncorrectPass) {
-			w.WriteHeader(http.StatusUnauthorized)
+			writeError(w, r, err)
 			return
 		}
 
 		uh.logger.Error("error authenticate user", zap.Error(err))
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -121,3 +142,254 @@ func (uh *UserHandlers) UserLogin(w http.ResponseWriter, r *http.Request) {
 	})
 	w.WriteHeader(http.StatusOK)
 }
+
+func (uh *UserHandlers) GetReferralStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		uh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	stats, err := uh.referralService.GetReferralStats(r.Context(), user)
+	if err != nil {
+		uh.logger.Error("error getting referral stats", zap.Int("USER_ID", user.ID), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(stats); err != nil {
+		uh.logger.Error("error encoding referral stats to json", zap.Error(err))
+		return
+	}
+}
+
+func (uh *UserHandlers) GetProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		uh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	profile, err := uh.userService.GetProfile(r.Context(), user)
+	if err != nil {
+		uh.logger.Error("error getting profile", zap.Int("USER_ID", user.ID), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	uh.writeJSON(w, http.StatusOK, profile)
+}
+
+func (uh *UserHandlers) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		uh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	var update models.ProfileUpdate
+	if err := dec.Decode(&update); err != nil {
+		if errors.Is(err, io.EOF) {
+			writeProblem(w, r, http.StatusBadRequest, problem.CodeEmptyBody, "")
+			return
+		}
+		uh.logger.Debug("error decoding profile update", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
+		return
+	}
+	if fields := validateProfileUpdate(&update); len(fields) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "", fields...)
+		return
+	}
+
+	profile, err := uh.userService.UpdateProfile(r.Context(), user, &update)
+	if err != nil {
+		uh.logger.Error("error updating profile", zap.Int("USER_ID", user.ID), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	uh.writeJSON(w, http.StatusOK, profile)
+}
+
+func (uh *UserHandlers) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		uh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	var req models.PasswordChangeRequest
+	if err := dec.Decode(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			writeProblem(w, r, http.StatusBadRequest, problem.CodeEmptyBody, "")
+			return
+		}
+		uh.logger.Debug("error decoding password change request", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
+		return
+	}
+	if fields := validatePasswordChange(&req); len(fields) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "", fields...)
+		return
+	}
+
+	program, ok := requestProgram(r)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		uh.logger.Error(ErrGettingContextProgram.Error())
+		return
+	}
+
+	token, err := uh.userService.ChangePassword(r.Context(), user, program, &req)
+	if err != nil {
+		uh.logger.Debug("error changing password", zap.Int("USER_ID", user.ID), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	uh.logger.Info("password changed", zap.Int("USER_ID", user.ID))
+	http.SetCookie(w, &http.Cookie{
+		Name:  "Token",
+		Value: token,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (uh *UserHandlers) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		uh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	if err := uh.userService.DeleteUser(r.Context(), user); err != nil {
+		uh.logger.Error("error deleting user", zap.Int("USER_ID", user.ID), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	uh.logger.Info("user deleted", zap.Int("USER_ID", user.ID))
+	http.SetCookie(w, &http.Cookie{
+		Name:   "Token",
+		Value:  "",
+		MaxAge: -1,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (uh *UserHandlers) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		uh.logger.Error("error encoding user response to json", zap.Error(err))
+	}
+}
+
+func validateLogPass(req *models.UserLogPassRequest) []problem.FieldError {
+	var fields []problem.FieldError
+	if req.Login == "" {
+		fields = append(fields, problem.FieldError{Field: "login", Message: "must not be empty"})
+	}
+	if req.Password == "" {
+		fields = append(fields, problem.FieldError{Field: "password", Message: "must not be empty"})
+	}
+	if len(req.ReferralCode) > maxReferralCodeLength {
+		fields = append(fields, problem.FieldError{
+			Field:   "referral_code",
+			Message: fmt.Sprintf("must be at most %d characters long", maxReferralCodeLength),
+		})
+	}
+	return fields
+}
+
+func validateProfileUpdate(update *models.ProfileUpdate) []problem.FieldError {
+	var fields []problem.FieldError
+	if email := update.Email.Value; email != nil && !validEmail(*email) {
+		fields = append(fields, problem.FieldError{Field: "email", Message: "must be a valid email address"})
+	}
+	if phone := update.Phone.Value; phone != nil && !phoneRe.MatchString(*phone) {
+		fields = append(fields, problem.FieldError{Field: "phone", Message: "must be in E.164 format"})
+	}
+	if name := update.DisplayName.Value; name != nil && (*name == "" || len([]rune(*name)) > maxDisplayNameLength) {
+		fields = append(fields, problem.FieldError{
+			Field:   "display_name",
+			Message: fmt.Sprintf("must be 1 to %d characters long", maxDisplayNameLength),
+		})
+	}
+	return fields
+}
+
+func validatePasswordChange(req *models.PasswordChangeRequest) []problem.FieldError {
+	var fields []problem.FieldError
+	if req.CurrentPassword == "" {
+		fields = append(fields, problem.FieldError{Field: "current_password", Message: "must not be empty"})
+	}
+	if req.NewPassword == "" {
+		fields = append(fields, problem.FieldError{Field: "new_password", Message: "must not be empty"})
+	} else if req.NewPassword == req.CurrentPassword {
+		fields = append(fields, problem.FieldError{Field: "new_password", Message: "must differ from current password"})
+	}
+	return fields
+}
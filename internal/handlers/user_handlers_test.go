@@ -21,6 +21,9 @@ func UserTestHandlers(t *testing.T) {
 	t.Run("LOGIN", func(t *testing.T) {
 		UserLogin(t)
 	})
+	t.Run("PROFILE", func(t *testing.T) {
+		UserProfile(t)
+	})
 }
 
 func UserRegister(t *testing.T) {
@@ -46,6 +49,12 @@ func UserRegister(t *testing.T) {
 			expectedCode: http.StatusBadRequest,
 			expectedBody: "",
 		},
+		{
+			name:         "Unknown Referral Code",
+			body:         `{"login":"referee", "password":"password", "referral_code":"NOSUCHCODE"}`,
+			expectedCode: http.StatusUnprocessableEntity,
+			expectedBody: "",
+		},
 	}
 
 	endPoint := "/api/user/register"
@@ -107,3 +116,139 @@ func UserLogin(t *testing.T) {
 		})
 	}
 }
+
+// UserProfile шаги выполняются по порядку: после смены пароля старый токен отозван,
+// после удаления учётной записи не действует и новый.
+func UserProfile(t *testing.T) {
+	credentials := `{"login":"profile", "password":"password"}`
+
+	resp, err := resty.New().R().
+		SetHeader("Content-Type", "application/json").
+		SetBody(credentials).
+		Post(server.URL + "/api/user/register")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+	var userID int
+	assert.NoError(t, db.QueryRow(`SELECT id FROM "user" WHERE login = 'profile'`).Scan(&userID))
+	defer func() {
+		_, err := db.Exec(`DELETE FROM "user" WHERE id = $1`, userID)
+		assert.NoError(t, err)
+	}()
+
+	tokens := map[string]string{}
+	for _, c := range resp.Cookies() {
+		if c.Name == "Token" {
+			tokens["old"] = c.Value
+		}
+	}
+
+	testCases := []struct {
+		testCase
+		method   string
+		endPoint string
+		token    string
+	}{
+		{
+			testCase: testCase{name: "Get Profile", expectedCode: http.StatusOK},
+			method:   http.MethodGet,
+			endPoint: "/api/user/profile",
+			token:    "old",
+		},
+		{
+			testCase: testCase{
+				name:         "Update Profile",
+				body:         `{"email": "user@example.com", "display_name": "Gopher"}`,
+				expectedCode: http.StatusOK,
+			},
+			method:   http.MethodPatch,
+			endPoint: "/api/user/profile",
+			token:    "old",
+		},
+		{
+			testCase: testCase{
+				name:         "Update Profile Bad Phone",
+				body:         `{"phone": "12345"}`,
+				expectedCode: http.StatusBadRequest,
+			},
+			method:   http.MethodPatch,
+			endPoint: "/api/user/profile",
+			token:    "old",
+		},
+		{
+			testCase: testCase{
+				name:         "Wrong Current Password",
+				body:         `{"current_password": "wrong", "new_password": "new-password"}`,
+				expectedCode: http.StatusForbidden,
+			},
+			method:   http.MethodPost,
+			endPoint: "/api/user/password",
+			token:    "old",
+		},
+		{
+			testCase: testCase{
+				name:         "Change Password",
+				body:         `{"current_password": "password", "new_password": "new-password"}`,
+				expectedCode: http.StatusNoContent,
+			},
+			method:   http.MethodPost,
+			endPoint: "/api/user/password",
+			token:    "old",
+		},
+		{
+			testCase: testCase{name: "Old Token Revoked", expectedCode: http.StatusUnauthorized},
+			method:   http.MethodGet,
+			endPoint: "/api/user/profile",
+			token:    "old",
+		},
+		{
+			testCase: testCase{name: "Delete User", expectedCode: http.StatusNoContent},
+			method:   http.MethodDelete,
+			endPoint: "/api/user",
+			token:    "new",
+		},
+		{
+			testCase: testCase{name: "Deleted User Token", expectedCode: http.StatusUnauthorized},
+			method:   http.MethodGet,
+			endPoint: "/api/user/profile",
+			token:    "new",
+		},
+		{
+			testCase: testCase{
+				name:         "Deleted User Login",
+				body:         `{"login":"profile", "password":"new-password"}`,
+				expectedCode: http.StatusUnauthorized,
+			},
+			method:   http.MethodPost,
+			endPoint: "/api/user/login",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + test.endPoint
+			r.Method = test.method
+			if test.body != "" {
+				r.SetHeader("Content-Type", "application/json")
+				r.SetBody(test.body)
+			}
+			if token, ok := tokens[test.token]; ok {
+				r.SetCookie(&http.Cookie{
+					Name:  "Token",
+					Value: token,
+				})
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+			for _, c := range resp.Cookies() {
+				if c.Name == "Token" && c.Value != "" {
+					tokens["new"] = c.Value
+				}
+			}
+		})
+	}
+}
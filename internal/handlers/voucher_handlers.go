@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	maxVoucherBatchNameLength = 100
+	maxVoucherBatchCodes      = 10000
+	maxVoucherUses            = 1000000
+)
+
+func (vh *VoucherHandlers) Redeem(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	user, ok := r.Context().Value(contextkeys.ContextUserKey).(*models.User)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		vh.logger.Error(ErrGettingContextUser.Error())
+		return
+	}
+	if !user.AuthInfo.IsAuthenticated {
+		writeProblem(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	var req models.VoucherRedeemRequest
+	if err := dec.Decode(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			writeProblem(w, r, http.StatusBadRequest, problem.CodeEmptyBody, "")
+			return
+		}
+		vh.logger.Debug("error decoding voucher redeem request", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
+		return
+	}
+	// код неверного формата не может существовать, в БД за ним не ходим
+	code, ok := models.NormalizeVoucherCode(req.Code)
+	if !ok {
+		writeProblem(w, r, http.StatusNotFound, problem.CodeVoucherNotFound, "")
+		return
+	}
+
+	redemption, err := vh.voucherService.Redeem(r.Context(), user, code)
+	if err != nil {
+		vh.logger.Debug("error redeeming voucher", zap.Int("USER_ID", user.ID), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	vh.logger.Info("voucher redeemed", zap.Int("USER_ID", user.ID), zap.Float64("AMOUNT", redemption.Amount))
+	vh.writeJSON(w, http.StatusOK, redemption)
+}
+
+func (vh *VoucherHandlers) ListBatches(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	program, ok := vh.program(w, r)
+	if !ok {
+		return
+	}
+
+	batches, err := vh.voucherService.GetBatches(r.Context(), program.ID)
+	if err != nil {
+		vh.logger.Error("error getting voucher batches", zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	vh.writeJSON(w, http.StatusOK, batches)
+}
+
+func (vh *VoucherHandlers) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	program, ok := vh.program(w, r)
+	if !ok {
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	defer func() {
+		_ = r.Body.Close()
+	}()
+
+	var req models.VoucherBatchRequest
+	if err := dec.Decode(&req); err != nil {
+		vh.logger.Debug("error decoding voucher batch request", zap.Error(err))
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeMalformedJSON, err.Error())
+		return
+	}
+	if fields := validateVoucherBatch(&req, time.Now()); len(fields) > 0 {
+		writeProblem(w, r, http.StatusBadRequest, problem.CodeValidationFailed, "", fields...)
+		return
+	}
+
+	batch, err := vh.voucherService.CreateBatch(r.Context(), program.ID, &req)
+	if err != nil {
+		vh.logger.Error("error creating voucher batch", zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	vh.logger.Info("voucher batch created",
+		zap.Int("BATCH_ID", batch.ID), zap.Int("PROGRAM_ID", program.ID), zap.Int("COUNT", batch.Count))
+	vh.writeJSON(w, http.StatusCreated, batch)
+}
+
+func (vh *VoucherHandlers) VoidBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	program, ok := vh.program(w, r)
+	if !ok {
+		return
+	}
+	id, ok := voucherBatchID(w, r)
+	if !ok {
+		return
+	}
+
+	batch, err := vh.voucherService.VoidBatch(r.Context(), program.ID, id)
+	if err != nil {
+		vh.logger.Debug("error voiding voucher batch", zap.Int("BATCH_ID", id), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	vh.logger.Info("voucher batch voided", zap.Int("BATCH_ID", id))
+	vh.writeJSON(w, http.StatusOK, batch)
+}
+
+func (vh *VoucherHandlers) ExportCodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, r, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, "")
+		return
+	}
+
+	program, ok := vh.program(w, r)
+	if !ok {
+		return
+	}
+	id, ok := voucherBatchID(w, r)
+	if !ok {
+		return
+	}
+
+	batch, vouchers, err := vh.voucherService.GetBatchCodes(r.Context(), program.ID, id)
+	if err != nil {
+		vh.logger.Debug("error getting voucher codes", zap.Int("BATCH_ID", id), zap.Error(err))
+		writeError(w, r, err)
+		return
+	}
+
+	expiresAt := ""
+	if batch.ExpiresAt != nil {
+		expiresAt = batch.ExpiresAt.Format(time.RFC3339)
+	}
+	amount := strconv.FormatFloat(batch.Amount, 'f', 2, 64)
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="vouchers-%d.csv"`, batch.ID))
+
+	// после начала записи статус уже не поменять, поэтому ошибку только логируем
+	cw := csv.NewWriter(w)
+	records := make([][]string, 0, len(vouchers)+1)
+	records = append(records, []string{"code", "amount", "max_uses", "uses", "expires_at"})
+	for _, v := range vouchers {
+		records = append(records,
+			[]string{v.Code, amount, strconv.Itoa(batch.MaxUses), strconv.Itoa(v.Uses), expiresAt})
+	}
+	if err = cw.WriteAll(records); err != nil {
+		vh.logger.Error("error writing voucher codes csv", zap.Int("BATCH_ID", id), zap.Error(err))
+	}
+}
+
+func (vh *VoucherHandlers) program(w http.ResponseWriter, r *http.Request) (*models.Program, bool) {
+	program, ok := requestProgram(r)
+	if !ok {
+		writeProblem(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		vh.logger.Error(ErrGettingContextProgram.Error())
+	}
+	return program, ok
+}
+
+func (vh *VoucherHandlers) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		vh.logger.Error("error encoding voucher response to json", zap.Error(err))
+	}
+}
+
+func voucherBatchID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || id <= 0 {
+		writeProblem(w, r, http.StatusNotFound, problem.CodeVoucherBatchNotFound, "")
+		return 0, false
+	}
+	return id, true
+}
+
+func validateVoucherBatch(req *models.VoucherBatchRequest, now time.Time) []problem.FieldError {
+	var fields []problem.FieldError
+	if req.Name == "" || len([]rune(req.Name)) > maxVoucherBatchNameLength {
+		fields = append(fields, problem.FieldError{
+			Field:   "name",
+			Message: fmt.Sprintf("must be 1 to %d characters long", maxVoucherBatchNameLength),
+		})
+	}
+	if req.Amount <= 0 {
+		fields = append(fields, problem.FieldError{Field: "amount", Message: "must be greater than zero"})
+	} else if cents := req.Amount * 100; math.Abs(cents-math.Round(cents)) > 1e-6 {
+		fields = append(fields, problem.FieldError{Field: "amount", Message: "must have at most 2 decimal places"})
+	}
+	if req.Count < 1 || req.Count > maxVoucherBatchCodes {
+		fields = append(fields, problem.FieldError{
+			Field:   "count",
+			Message: fmt.Sprintf("must be between 1 and %d", maxVoucherBatchCodes),
+		})
+	}
+	if req.MaxUses < 0 || req.MaxUses > maxVoucherUses {
+		fields = append(fields, problem.FieldError{
+			Field:   "max_uses",
+			Message: fmt.Sprintf("must be between 1 and %d", maxVoucherUses),
+		})
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		fields = append(fields, problem.FieldError{Field: "expires_at", Message: "must be in the future"})
+	}
+	return fields
+}
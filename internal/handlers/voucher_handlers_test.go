@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/auth"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func VoucherTestHandlers(t *testing.T) {
+	userToken, err := auth.BuildJWTToken(testUserID, testProgramID, 0, testTokenIssuer, cfg.DB.SecretKey,
+		cfg.TokenLifeTime)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		testCase
+		method   string
+		endPoint string
+		adminKey bool
+	}{
+		{
+			testCase: testCase{name: "List Batches", expectedCode: http.StatusOK},
+			method:   http.MethodGet,
+			endPoint: `/api/admin/vouchers`,
+			adminKey: true,
+		},
+		{
+			testCase: testCase{name: "List Batches Without Key", expectedCode: http.StatusUnauthorized},
+			method:   http.MethodGet,
+			endPoint: `/api/admin/vouchers`,
+		},
+		{
+			testCase: testCase{
+				name:         "Batch Without Codes",
+				body:         `{"name": "Gift card", "amount": 500, "count": 0}`,
+				expectedCode: http.StatusBadRequest,
+			},
+			method:   http.MethodPost,
+			endPoint: `/api/admin/vouchers`,
+			adminKey: true,
+		},
+		{
+			testCase: testCase{name: "Void Unknown Batch", expectedCode: http.StatusNotFound},
+			method:   http.MethodPost,
+			endPoint: `/api/admin/vouchers/999999/void`,
+			adminKey: true,
+		},
+		{
+			testCase: testCase{name: "Export Unknown Batch", expectedCode: http.StatusNotFound},
+			method:   http.MethodGet,
+			endPoint: `/api/admin/vouchers/999999/codes.csv`,
+			adminKey: true,
+		},
+		{
+			testCase: testCase{
+				name:         "Redeem Unauthorized",
+				body:         `{"code": "ABCD-EFGH-JKLM"}`,
+				expectedCode: http.StatusUnauthorized,
+			},
+			method:   http.MethodPost,
+			endPoint: `/api/user/vouchers/redeem`,
+		},
+		{
+			testCase: testCase{
+				name:         "Redeem Malformed Code",
+				body:         `{"code": "not-a-code"}`,
+				expectedCode: http.StatusNotFound,
+			},
+			method:   http.MethodPost,
+			endPoint: `/api/user/vouchers/redeem`,
+		},
+		{
+			testCase: testCase{
+				name:         "Redeem Unknown Code",
+				body:         `{"code": "abcd efgh jklm"}`,
+				expectedCode: http.StatusNotFound,
+			},
+			method:   http.MethodPost,
+			endPoint: `/api/user/vouchers/redeem`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := resty.New().R()
+			r.URL = server.URL + test.endPoint
+			r.Method = test.method
+			if test.body != "" {
+				r.SetHeader("Content-Type", "application/json")
+				r.SetBody(test.body)
+			}
+			if test.adminKey {
+				r.SetHeader("X-Admin-Key", testAdminKey)
+			}
+			if test.expectedCode != http.StatusUnauthorized {
+				r.SetCookie(&http.Cookie{
+					Name:  "Token",
+					Value: userToken,
+				})
+			}
+
+			resp, err := r.Send()
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.expectedCode, resp.StatusCode())
+		})
+	}
+}
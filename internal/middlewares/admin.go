@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+)
+
+const adminKeyHeader = "X-Admin-Key"
+
+// WithAdminKey пока ключ не задан, административный API недоступен.
+func (m *Middleware) WithAdminKey(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := []byte(r.Header.Get(adminKeyHeader))
+		if m.cfg.AdminAPIKey == "" || subtle.ConstantTimeCompare(key, []byte(m.cfg.AdminAPIKey)) != 1 {
+			problem.Write(w, r, problem.New(http.StatusUnauthorized, problem.CodeUnauthorized,
+				"valid "+adminKeyHeader+" header required"))
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
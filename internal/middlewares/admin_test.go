@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestWithAdminKey(t *testing.T) {
+	testCases := []struct {
+		name         string
+		configured   string
+		header       string
+		expectedCode int
+	}{
+		{name: "Valid Key", configured: "secret", header: "secret", expectedCode: http.StatusOK},
+		{name: "Wrong Key", configured: "secret", header: "secrets", expectedCode: http.StatusUnauthorized},
+		{name: "Missing Key", configured: "secret", expectedCode: http.StatusUnauthorized},
+		{name: "Admin API Disabled", configured: "", header: "", expectedCode: http.StatusUnauthorized},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			m := NewMiddleware(zap.NewNop(), &config.Config{AdminAPIKey: test.configured}, nil)
+			h := m.WithAdminKey(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/admin/campaigns", http.NoBody)
+			if test.header != "" {
+				req.Header.Set("X-Admin-Key", test.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expectedCode, rec.Code)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
 	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
 	"github.com/Melikhov-p/go-loyalty-system/internal/services"
 	"go.uber.org/zap"
 )
@@ -16,18 +17,25 @@ func (m *Middleware) WithAuth(handler http.Handler) http.Handler {
 		var user *models.User
 		userService := services.NewUserService(m.logger, m.cfg, m.db)
 
+		program, ok := r.Context().Value(contextkeys.ContextProgramKey).(*models.Program)
+		if !ok {
+			m.logger.Error("error getting program from context")
+			problem.Write(w, r, problem.New(http.StatusInternalServerError, problem.CodeInternal, ""))
+			return
+		}
+
 		tokenCookie, err := r.Cookie("Token")
 		if err != nil {
 			if !errors.Is(err, http.ErrNoCookie) {
 				m.logger.Error("error getting token from cookies", zap.Error(err))
-				w.WriteHeader(http.StatusBadRequest)
+				problem.Write(w, r, problem.New(http.StatusBadRequest, problem.CodeBadCookie, ""))
 				return
 			}
 
 			user = userService.UserRepo.NewEmptyUser()
 		} else {
 			tokenString := tokenCookie.Value
-			user, err = userService.GetUserByToken(r.Context(), tokenString)
+			user, err = userService.GetUserByToken(r.Context(), tokenString, program)
 			if err != nil {
 				m.logger.Error("error getting user by token", zap.Error(err))
 
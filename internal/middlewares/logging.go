@@ -1,10 +1,14 @@
 package middlewares
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
 	"go.uber.org/zap"
 )
 
@@ -24,8 +28,10 @@ func (m *Middleware) WithLogging(h http.Handler) http.Handler {
 
 		duration := time.Since(startTime)
 
+		requestID, _ := r.Context().Value(contextkeys.ContextRequestIDKey).(string)
 		m.logger.Info(
 			"",
+			zap.String("REQUEST_ID", requestID),
 			zap.String("URI", r.RequestURI),
 			zap.String("METHOD", r.Method),
 			zap.Duration("DURATION", duration),
@@ -48,3 +54,26 @@ func (r *loggerResponseWriter) WriteHeader(statusCode int) {
 	r.ResponseWriter.WriteHeader(statusCode)
 	r.responseData.status = statusCode
 }
+
+// Flush нужен потоковым хендлерам (SSE).
+func (r *loggerResponseWriter) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack нужен для перехода соединения на WebSocket.
+func (r *loggerResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("underlying response writer does not support hijacking")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error hijack from loggerResponseWriter %w", err)
+	}
+	r.responseData.status = http.StatusSwitchingProtocols
+
+	return conn, rw, nil
+}
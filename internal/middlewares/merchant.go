@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"github.com/Melikhov-p/go-loyalty-system/internal/services"
+	"go.uber.org/zap"
+)
+
+const merchantKeyHeader = "X-Merchant-Key"
+
+func (m *Middleware) WithMerchantKey(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		unauthorized := problem.New(http.StatusUnauthorized, problem.CodeUnauthorized,
+			"valid "+merchantKeyHeader+" header required")
+
+		key := r.Header.Get(merchantKeyHeader)
+		if key == "" {
+			problem.Write(w, r, unauthorized)
+			return
+		}
+
+		merchantService := services.NewMerchantService(m.logger, m.cfg, m.db)
+		merchant, err := merchantService.GetMerchantByKey(r.Context(), key)
+		if err != nil {
+			if errors.Is(err, repository.ErrMerchantNotFound) {
+				problem.Write(w, r, unauthorized)
+				return
+			}
+			m.logger.Error("error authenticating merchant", zap.Error(err))
+			problem.Write(w, r, problem.FromError(err))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), contextkeys.ContextMerchantKey, merchant)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
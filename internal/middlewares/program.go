@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"github.com/Melikhov-p/go-loyalty-system/internal/services"
+	"go.uber.org/zap"
+)
+
+const programHeader = "X-Program"
+
+// WithProgram должен стоять перед WithAuth.
+func (m *Middleware) WithProgram(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		programService := services.NewProgramService(m.logger, m.cfg, m.db)
+
+		var token string
+		if tokenCookie, err := r.Cookie("Token"); err == nil {
+			token = tokenCookie.Value
+		}
+
+		program, err := programService.ResolveProgram(r.Context(), r.Header.Get(programHeader), r.Host, token)
+		if err != nil {
+			m.logger.Debug("error resolving loyalty program", zap.Error(err))
+			problem.Write(w, r, problem.FromError(err))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), contextkeys.ContextProgramKey, program)
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,103 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"go.uber.org/zap"
+)
+
+type Validator struct {
+	logger *zap.Logger
+	router routers.Router
+}
+
+func NewValidator(logger *zap.Logger, doc *openapi3.T) (*Validator, error) {
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("error building openapi router %w", err)
+	}
+
+	return &Validator{
+		logger: logger,
+		router: router,
+	}, nil
+}
+
+func (v *Validator) WithValidation(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := v.router.FindRoute(r)
+		if err != nil {
+			// неизвестные маршруты и методы отдаём роутеру, он ответит 404 или 405
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				// аутентификацию по cookie выполняет WithAuth
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+				// тело проверяем только для JSON: пакетные загрузки не читаем в память дважды
+				ExcludeRequestBody: !isJSONRequest(r),
+				MultiError:         true,
+			},
+		}
+
+		if err = openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			v.logger.Debug("request does not match openapi spec", zap.Error(err))
+			problem.Write(w, r, validationProblem(err))
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+func validationProblem(err error) *problem.Problem {
+	var fields []problem.FieldError
+	code := problem.CodeValidationFailed
+
+	var errs openapi3.MultiError
+	if !errors.As(err, &errs) {
+		errs = openapi3.MultiError{err}
+	}
+
+	for _, e := range errs {
+		var paramErr *openapi3filter.RequestError
+		var schemaErr *openapi3.SchemaError
+		switch {
+		case errors.As(e, &paramErr) && paramErr.Parameter != nil:
+			code = problem.CodeBadQueryParameter
+			message := paramErr.Reason
+			if paramErr.Err != nil {
+				message = paramErr.Err.Error()
+			}
+			fields = append(fields, problem.FieldError{Field: paramErr.Parameter.Name, Message: message})
+		case errors.As(e, &schemaErr):
+			fields = append(fields, problem.FieldError{
+				Field:   strings.Join(schemaErr.JSONPointer(), "."),
+				Message: schemaErr.Reason,
+			})
+		default:
+			fields = append(fields, problem.FieldError{Message: e.Error()})
+		}
+	}
+
+	return problem.New(http.StatusBadRequest, code, "request does not match API specification", fields...)
+}
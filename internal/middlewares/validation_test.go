@@ -0,0 +1,95 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/openapi"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWithValidation(t *testing.T) {
+	doc, err := openapi.Load()
+	require.NoError(t, err)
+	validator, err := NewValidator(zap.NewNop(), doc)
+	require.NoError(t, err)
+
+	h := validator.WithValidation(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	testCases := []struct {
+		name         string
+		method       string
+		target       string
+		contentType  string
+		body         string
+		expectedCode int
+		expectedErr  problem.Code
+	}{
+		{
+			name:         "Valid Register",
+			method:       http.MethodPost,
+			target:       "/api/user/register",
+			contentType:  "application/json",
+			body:         `{"login":"login","password":"password"}`,
+			expectedCode: http.StatusTeapot,
+		},
+		{
+			name:         "Missing Password",
+			method:       http.MethodPost,
+			target:       "/api/user/register",
+			contentType:  "application/json",
+			body:         `{"login":"login"}`,
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  problem.CodeValidationFailed,
+		},
+		{
+			name:         "Limit Out Of Range",
+			method:       http.MethodGet,
+			target:       "/api/user/orders?limit=0",
+			expectedCode: http.StatusBadRequest,
+			expectedErr:  problem.CodeBadQueryParameter,
+		},
+		{
+			name:         "Plain Text Order Passes Through",
+			method:       http.MethodPost,
+			target:       "/api/user/orders",
+			contentType:  "text/plain",
+			body:         "123",
+			expectedCode: http.StatusTeapot,
+		},
+		{
+			name:         "Unknown Route Passes Through",
+			method:       http.MethodGet,
+			target:       "/api/unknown",
+			expectedCode: http.StatusTeapot,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+			if test.contentType != "" {
+				r.Header.Set("Content-Type", test.contentType)
+			}
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, test.expectedCode, w.Code)
+			if test.expectedErr != "" {
+				var got problem.Problem
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+				assert.Equal(t, test.expectedErr, got.Code)
+				assert.NotEmpty(t, got.Errors)
+			}
+		})
+	}
+}
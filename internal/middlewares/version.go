@@ -0,0 +1,104 @@
+package middlewares
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	apiVersionHeader = "X-API-Version"
+	apiPrefix        = "/api"
+	// vendorMediaPrefix тип вида application/vnd.gophermart.v2+json в Accept выбирает версию API
+	vendorMediaPrefix = "application/vnd.gophermart.v"
+
+	APIVersion1      = 1
+	APIVersion2      = 2
+	LatestAPIVersion = APIVersion2
+)
+
+// VersionNegotiator в /api/v2 только изменившиеся эндпоинты, остальное обслуживает v1.
+type VersionNegotiator struct {
+	routes chi.Routes
+}
+
+func NewVersionNegotiator(routes chi.Routes) *VersionNegotiator {
+	return &VersionNegotiator{routes: routes}
+}
+
+func (vn *VersionNegotiator) WithAPIVersion(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if path != apiPrefix && !strings.HasPrefix(path, apiPrefix+"/") {
+			h.ServeHTTP(w, r)
+			return
+		}
+		rest := strings.TrimPrefix(path, apiPrefix)
+
+		version, rest, versioned := splitVersion(rest)
+		if !versioned {
+			var ok bool
+			if version, ok = requestedVersion(r); !ok {
+				problem.Write(w, r, problem.New(http.StatusNotAcceptable, problem.CodeUnsupportedVersion,
+					"supported API versions: 1, 2"))
+				return
+			}
+		}
+
+		path = apiPrefix + rest
+		if version == APIVersion2 {
+			v2Path := apiPrefix + "/v2" + rest
+			if vn.routes.Match(chi.NewRouteContext(), r.Method, v2Path) {
+				path = v2Path
+			}
+		}
+
+		w.Header().Set(apiVersionHeader, strconv.Itoa(version))
+		ctx := context.WithValue(r.Context(), contextkeys.ContextAPIVersionKey, version)
+		r = r.WithContext(ctx)
+		r.URL.Path = path
+		r.URL.RawPath = ""
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+func splitVersion(rest string) (int, string, bool) {
+	for _, version := range []int{APIVersion1, APIVersion2} {
+		prefix := "/v" + strconv.Itoa(version)
+		if rest == prefix || strings.HasPrefix(rest, prefix+"/") {
+			return version, strings.TrimPrefix(rest, prefix), true
+		}
+	}
+	return APIVersion1, rest, false
+}
+
+func requestedVersion(r *http.Request) (int, bool) {
+	if header := r.Header.Get(apiVersionHeader); header != "" {
+		return parseVersion(header)
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil || !strings.HasPrefix(mediaType, vendorMediaPrefix) {
+			continue
+		}
+		return parseVersion(strings.TrimSuffix(strings.TrimPrefix(mediaType, vendorMediaPrefix), "+json"))
+	}
+
+	return APIVersion1, true
+}
+
+func parseVersion(s string) (int, bool) {
+	version, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(s), "v"))
+	if err != nil || version < APIVersion1 || version > LatestAPIVersion {
+		return 0, false
+	}
+	return version, true
+}
@@ -0,0 +1,121 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithAPIVersion(t *testing.T) {
+	r := chi.NewRouter()
+	versions := NewVersionNegotiator(r)
+	r.Use(versions.WithAPIVersion)
+
+	handler := func(tree string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			version, _ := r.Context().Value(contextkeys.ContextAPIVersionKey).(int)
+			w.Header().Set("X-Tree", tree)
+			w.Header().Set("X-Context-Version", strconv.Itoa(version))
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+	r.Route("/api", func(r chi.Router) {
+		r.Post("/user/orders", handler("v1"))
+		r.Get("/user/balance", handler("v1"))
+		r.Post("/v2/user/orders", handler("v2"))
+	})
+
+	testCases := []struct {
+		name            string
+		method          string
+		target          string
+		headers         map[string]string
+		expectedCode    int
+		expectedTree    string
+		expectedVersion string
+	}{
+		{
+			name:            "Unversioned Is V1",
+			method:          http.MethodPost,
+			target:          "/api/user/orders",
+			expectedCode:    http.StatusOK,
+			expectedTree:    "v1",
+			expectedVersion: "1",
+		},
+		{
+			name:            "V1 Prefix",
+			method:          http.MethodPost,
+			target:          "/api/v1/user/orders",
+			expectedCode:    http.StatusOK,
+			expectedTree:    "v1",
+			expectedVersion: "1",
+		},
+		{
+			name:            "V2 Prefix",
+			method:          http.MethodPost,
+			target:          "/api/v2/user/orders",
+			expectedCode:    http.StatusOK,
+			expectedTree:    "v2",
+			expectedVersion: "2",
+		},
+		{
+			name:            "V2 Falls Back To V1",
+			method:          http.MethodGet,
+			target:          "/api/v2/user/balance",
+			expectedCode:    http.StatusOK,
+			expectedTree:    "v1",
+			expectedVersion: "2",
+		},
+		{
+			name:            "V2 By Header",
+			method:          http.MethodPost,
+			target:          "/api/user/orders",
+			headers:         map[string]string{"X-API-Version": "2"},
+			expectedCode:    http.StatusOK,
+			expectedTree:    "v2",
+			expectedVersion: "2",
+		},
+		{
+			name:            "V2 By Accept",
+			method:          http.MethodPost,
+			target:          "/api/user/orders",
+			headers:         map[string]string{"Accept": "application/vnd.gophermart.v2+json"},
+			expectedCode:    http.StatusOK,
+			expectedTree:    "v2",
+			expectedVersion: "2",
+		},
+		{
+			name:         "Unknown Version",
+			method:       http.MethodPost,
+			target:       "/api/user/orders",
+			headers:      map[string]string{"X-API-Version": "3"},
+			expectedCode: http.StatusNotAcceptable,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.target, http.NoBody)
+			for k, v := range test.headers {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, test.expectedCode, w.Code)
+			if test.expectedTree == "" {
+				return
+			}
+			assert.Equal(t, test.expectedTree, w.Header().Get("X-Tree"))
+			assert.Equal(t, test.expectedVersion, w.Header().Get("X-API-Version"))
+			assert.Equal(t, test.expectedVersion, w.Header().Get("X-Context-Version"))
+		})
+	}
+}
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS order_user_id_uploaded_at_idx ON "order" (user_id, uploaded_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS order_user_id_accrual_idx ON "order" (user_id, (COALESCE(accrual, 0)) DESC, id DESC);
+CREATE INDEX IF NOT EXISTS withdraw_history_user_id_processed_at_idx ON withdraw_history (user_id, processed_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS withdraw_history_user_id_sum_idx ON withdraw_history (user_id, sum DESC, id DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS order_user_id_uploaded_at_idx;
+DROP INDEX IF EXISTS order_user_id_accrual_idx;
+DROP INDEX IF EXISTS withdraw_history_user_id_processed_at_idx;
+DROP INDEX IF EXISTS withdraw_history_user_id_sum_idx;
+-- +goose StatementEnd
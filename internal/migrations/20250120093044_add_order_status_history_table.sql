@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    order_number VARCHAR(100) NOT NULL,
+    FOREIGN KEY (order_number) REFERENCES "order"(number) ON DELETE CASCADE,
+    status VARCHAR(100) NOT NULL,
+    accrual NUMERIC(10, 2) NULL,
+    attempt INTEGER NOT NULL DEFAULT 0,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS order_status_history_order_number_idx ON order_status_history (order_number, id);
+
+ALTER TABLE watched_order ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
+
+INSERT INTO order_status_history (order_number, status, accrual, changed_at)
+SELECT number, status::text, accrual, uploaded_at FROM "order";
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE watched_order DROP COLUMN IF EXISTS attempts;
+
+DROP TABLE IF EXISTS order_status_history;
+-- +goose StatementEnd
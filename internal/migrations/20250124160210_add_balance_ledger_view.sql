@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+-- balance_ledger единая лента движений баллов пользователя, новые источники добавляются в представление
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    o.accrual AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id DESC
+    LIMIT 1
+) h ON true
+WHERE o.status = 'PROCESSED' AND o.accrual > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP VIEW IF EXISTS balance_ledger;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "order"
+    ADD COLUMN merchant_id VARCHAR(100) NULL,
+    ADD COLUMN purchase_total NUMERIC(12, 2) NULL,
+    ADD COLUMN currency CHAR(3) NULL,
+    ADD COLUMN items JSONB NULL;
+
+-- заказы v1 не передают состав покупки, регистрировать их в системе начислений не нужно
+ALTER TABLE watched_order ADD COLUMN registered BOOLEAN NOT NULL DEFAULT true;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE watched_order DROP COLUMN IF EXISTS registered;
+
+ALTER TABLE "order"
+    DROP COLUMN IF EXISTS items,
+    DROP COLUMN IF EXISTS currency,
+    DROP COLUMN IF EXISTS purchase_total,
+    DROP COLUMN IF EXISTS merchant_id;
+-- +goose StatementEnd
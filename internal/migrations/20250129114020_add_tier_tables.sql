@@ -0,0 +1,97 @@
+-- +goose Up
+-- +goose StatementBegin
+-- tier уровни программы лояльности; пороги, множитель и лимиты списаний настраиваются в таблице
+CREATE TABLE IF NOT EXISTS tier (
+    id INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    code VARCHAR(20) NOT NULL UNIQUE,
+    name VARCHAR(50) NOT NULL,
+    rank INTEGER NOT NULL UNIQUE,
+    min_points NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    min_spend NUMERIC(12, 2) NULL,
+    multiplier NUMERIC(5, 2) NOT NULL DEFAULT 1 CHECK (multiplier > 0),
+    max_withdrawal NUMERIC(10, 2) NULL,
+    monthly_withdrawal_limit NUMERIC(12, 2) NULL
+);
+
+INSERT INTO tier (code, name, rank, min_points, min_spend, multiplier, max_withdrawal, monthly_withdrawal_limit)
+VALUES
+    ('BRONZE', 'Bronze', 1, 0, NULL, 1.00, 1000, 5000),
+    ('SILVER', 'Silver', 2, 1000, 50000, 1.10, 5000, 20000),
+    ('GOLD', 'Gold', 3, 5000, 200000, 1.25, NULL, NULL);
+
+CREATE TABLE IF NOT EXISTS user_tier_history (
+    id INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    user_id INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    tier_id INTEGER NOT NULL,
+    FOREIGN KEY (tier_id) REFERENCES tier(id),
+    points NUMERIC(12, 2) NOT NULL,
+    spend NUMERIC(12, 2) NOT NULL,
+    assigned_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS user_tier_history_user_idx ON user_tier_history (user_id, id DESC);
+
+-- credited сумма, фактически зачисленная на баланс с учётом множителя уровня
+ALTER TABLE "order"
+    ADD COLUMN credited NUMERIC(10, 2) NULL,
+    ADD COLUMN multiplier NUMERIC(5, 2) NULL;
+
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id DESC
+    LIMIT 1
+) h ON true
+WHERE o.status = 'PROCESSED' AND o.accrual > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    o.accrual AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id DESC
+    LIMIT 1
+) h ON true
+WHERE o.status = 'PROCESSED' AND o.accrual > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history;
+
+ALTER TABLE "order"
+    DROP COLUMN IF EXISTS multiplier,
+    DROP COLUMN IF EXISTS credited;
+
+DROP TABLE IF EXISTS user_tier_history;
+DROP TABLE IF EXISTS tier;
+-- +goose StatementEnd
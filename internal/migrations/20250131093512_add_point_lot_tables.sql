@@ -0,0 +1,101 @@
+-- +goose Up
+-- +goose StatementBegin
+-- point_lot партия начисленных баллов; списания расходуют партии с ближайшим сроком сгорания первыми
+CREATE TABLE IF NOT EXISTS point_lot (
+    id INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    user_id INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    source VARCHAR(20) NOT NULL,
+    reference TEXT NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+    remaining NUMERIC(10, 2) NOT NULL CHECK (remaining >= 0),
+    accrued_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    notified_at TIMESTAMPTZ NULL,
+    expired_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS point_lot_open_idx ON point_lot (user_id, expires_at, id) WHERE remaining > 0;
+CREATE INDEX IF NOT EXISTS point_lot_expires_idx ON point_lot (expires_at) WHERE remaining > 0;
+
+CREATE TABLE IF NOT EXISTS point_expiry (
+    id INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    user_id INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    lot_id INTEGER NOT NULL,
+    FOREIGN KEY (lot_id) REFERENCES point_lot(id) ON DELETE CASCADE,
+    amount NUMERIC(10, 2) NOT NULL,
+    expired_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS point_expiry_user_idx ON point_expiry (user_id, expired_at);
+
+-- накопленный до появления партий остаток переносим одной партией со сроком год от миграции
+INSERT INTO point_lot (user_id, source, reference, amount, remaining, expires_at)
+SELECT user_id, 'migration', 'opening balance', current, current, now() + INTERVAL '12 months'
+FROM balance
+WHERE current > 0 AND user_id IS NOT NULL;
+
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id DESC
+    LIMIT 1
+) h ON true
+WHERE o.status = 'PROCESSED' AND o.accrual > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history
+UNION ALL
+SELECT
+    e.user_id,
+    e.expired_at AS occurred_at,
+    'expiry' AS kind,
+    l.reference,
+    -e.amount AS amount
+FROM point_expiry e
+JOIN point_lot l ON l.id = e.lot_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id DESC
+    LIMIT 1
+) h ON true
+WHERE o.status = 'PROCESSED' AND o.accrual > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history;
+
+DROP TABLE IF EXISTS point_expiry;
+DROP TABLE IF EXISTS point_lot;
+-- +goose StatementEnd
@@ -0,0 +1,111 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS campaign (
+    id INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    name VARCHAR(100) NOT NULL,
+    rule JSONB NOT NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NULL,
+    user_cap NUMERIC(12, 2) NULL,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+-- campaign_grant фиксирует, какое правило сколько баллов начислило по заказу; кампанию с начислениями удалить нельзя
+CREATE TABLE IF NOT EXISTS campaign_grant (
+    id INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    campaign_id INTEGER NOT NULL,
+    FOREIGN KEY (campaign_id) REFERENCES campaign(id) ON DELETE RESTRICT,
+    user_id INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    order_number VARCHAR(100) NOT NULL,
+    rule_type VARCHAR(20) NOT NULL,
+    points NUMERIC(10, 2) NOT NULL CHECK (points > 0),
+    granted_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (campaign_id, order_number)
+);
+
+CREATE INDEX IF NOT EXISTS campaign_grant_user_idx ON campaign_grant (user_id, campaign_id);
+
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id DESC
+    LIMIT 1
+) h ON true
+WHERE o.status = 'PROCESSED' AND o.accrual > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history
+UNION ALL
+SELECT
+    e.user_id,
+    e.expired_at AS occurred_at,
+    'expiry' AS kind,
+    l.reference,
+    -e.amount AS amount
+FROM point_expiry e
+JOIN point_lot l ON l.id = e.lot_id
+UNION ALL
+SELECT
+    user_id,
+    granted_at AS occurred_at,
+    'campaign' AS kind,
+    order_number::text AS reference,
+    points AS amount
+FROM campaign_grant;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id DESC
+    LIMIT 1
+) h ON true
+WHERE o.status = 'PROCESSED' AND o.accrual > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history
+UNION ALL
+SELECT
+    e.user_id,
+    e.expired_at AS occurred_at,
+    'expiry' AS kind,
+    l.reference,
+    -e.amount AS amount
+FROM point_expiry e
+JOIN point_lot l ON l.id = e.lot_id;
+
+DROP TABLE IF EXISTS campaign_grant;
+DROP TABLE IF EXISTS campaign;
+-- +goose StatementEnd
@@ -0,0 +1,137 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE "user" ADD COLUMN IF NOT EXISTS invite_code VARCHAR(16) NULL;
+UPDATE "user" SET invite_code = upper(substr(md5(random()::text || id::text), 1, 10)) WHERE invite_code IS NULL;
+ALTER TABLE "user"
+    ALTER COLUMN invite_code SET DEFAULT upper(substr(md5(random()::text || clock_timestamp()::text), 1, 10)),
+    ALTER COLUMN invite_code SET NOT NULL,
+    ADD CONSTRAINT user_invite_code_key UNIQUE (invite_code);
+
+-- referral приглашение: PENDING до первого обработанного заказа приглашённого, HELD пока заказ может измениться,
+-- затем REWARDED с начислением обоим или REJECTED с причиной
+CREATE TABLE IF NOT EXISTS referral (
+    id INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    referrer_id INTEGER NOT NULL,
+    FOREIGN KEY (referrer_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    referee_id INTEGER NOT NULL UNIQUE,
+    FOREIGN KEY (referee_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    status VARCHAR(10) NOT NULL DEFAULT 'PENDING',
+    reason VARCHAR(100) NULL,
+    order_number VARCHAR(100) NULL,
+    referrer_points NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    referee_points NUMERIC(10, 2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    release_at TIMESTAMPTZ NULL,
+    rewarded_at TIMESTAMPTZ NULL,
+    CHECK (referrer_id <> referee_id)
+);
+
+CREATE INDEX IF NOT EXISTS referral_referrer_idx ON referral (referrer_id, status);
+CREATE INDEX IF NOT EXISTS referral_release_idx ON referral (release_at) WHERE status = 'HELD';
+
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id DESC
+    LIMIT 1
+) h ON true
+WHERE o.status = 'PROCESSED' AND o.accrual > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history
+UNION ALL
+SELECT
+    e.user_id,
+    e.expired_at AS occurred_at,
+    'expiry' AS kind,
+    l.reference,
+    -e.amount AS amount
+FROM point_expiry e
+JOIN point_lot l ON l.id = e.lot_id
+UNION ALL
+SELECT
+    user_id,
+    granted_at AS occurred_at,
+    'campaign' AS kind,
+    order_number::text AS reference,
+    points AS amount
+FROM campaign_grant
+UNION ALL
+SELECT
+    referrer_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referrer_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    referee_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referee_points AS amount
+FROM referral
+WHERE status = 'REWARDED';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id DESC
+    LIMIT 1
+) h ON true
+WHERE o.status = 'PROCESSED' AND o.accrual > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history
+UNION ALL
+SELECT
+    e.user_id,
+    e.expired_at AS occurred_at,
+    'expiry' AS kind,
+    l.reference,
+    -e.amount AS amount
+FROM point_expiry e
+JOIN point_lot l ON l.id = e.lot_id
+UNION ALL
+SELECT
+    user_id,
+    granted_at AS occurred_at,
+    'campaign' AS kind,
+    order_number::text AS reference,
+    points AS amount
+FROM campaign_grant;
+
+DROP TABLE IF EXISTS referral;
+ALTER TABLE "user" DROP COLUMN IF EXISTS invite_code;
+-- +goose StatementEnd
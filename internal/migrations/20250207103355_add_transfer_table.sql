@@ -0,0 +1,162 @@
+-- +goose Up
+-- +goose StatementBegin
+-- transfer перевод баллов между пользователями; крупные переводы ждут подтверждения отправителем до expires_at
+CREATE TABLE IF NOT EXISTS transfer (
+    id INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    sender_id INTEGER NOT NULL,
+    FOREIGN KEY (sender_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    recipient_id INTEGER NOT NULL,
+    FOREIGN KEY (recipient_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+    comment VARCHAR(140) NULL,
+    status VARCHAR(10) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NULL,
+    completed_at TIMESTAMPTZ NULL,
+    CHECK (sender_id <> recipient_id)
+);
+
+CREATE INDEX IF NOT EXISTS transfer_sender_idx ON transfer (sender_id, created_at);
+CREATE INDEX IF NOT EXISTS transfer_recipient_idx ON transfer (recipient_id, created_at);
+
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id DESC
+    LIMIT 1
+) h ON true
+WHERE o.status = 'PROCESSED' AND o.accrual > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history
+UNION ALL
+SELECT
+    e.user_id,
+    e.expired_at AS occurred_at,
+    'expiry' AS kind,
+    l.reference,
+    -e.amount AS amount
+FROM point_expiry e
+JOIN point_lot l ON l.id = e.lot_id
+UNION ALL
+SELECT
+    user_id,
+    granted_at AS occurred_at,
+    'campaign' AS kind,
+    order_number::text AS reference,
+    points AS amount
+FROM campaign_grant
+UNION ALL
+SELECT
+    referrer_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referrer_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    referee_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referee_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    sender_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    -amount AS amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    recipient_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    amount
+FROM transfer
+WHERE status = 'COMPLETED';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id DESC
+    LIMIT 1
+) h ON true
+WHERE o.status = 'PROCESSED' AND o.accrual > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history
+UNION ALL
+SELECT
+    e.user_id,
+    e.expired_at AS occurred_at,
+    'expiry' AS kind,
+    l.reference,
+    -e.amount AS amount
+FROM point_expiry e
+JOIN point_lot l ON l.id = e.lot_id
+UNION ALL
+SELECT
+    user_id,
+    granted_at AS occurred_at,
+    'campaign' AS kind,
+    order_number::text AS reference,
+    points AS amount
+FROM campaign_grant
+UNION ALL
+SELECT
+    referrer_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referrer_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    referee_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referee_points AS amount
+FROM referral
+WHERE status = 'REWARDED';
+
+DROP TABLE IF EXISTS transfer;
+-- +goose StatementEnd
@@ -0,0 +1,189 @@
+-- +goose Up
+-- +goose StatementBegin
+-- списание проходит в две фазы: HELD резервирует баллы, затем CAPTURED списывает их,
+-- RELEASED и EXPIRED возвращают резерв на баланс; мгновенное списание сразу CAPTURED
+ALTER TABLE withdraw_history
+    ADD COLUMN IF NOT EXISTS status VARCHAR(10) NOT NULL DEFAULT 'CAPTURED',
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ NULL,
+    ADD COLUMN IF NOT EXISTS settled_at TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS withdraw_history_held_idx ON withdraw_history (expires_at) WHERE status = 'HELD';
+
+ALTER TABLE balance ADD COLUMN IF NOT EXISTS held NUMERIC(10, 2) NOT NULL DEFAULT 0;
+
+-- withdraw_lot из каких партий взяты баллы списания, чтобы при отмене вернуть их в те же партии
+CREATE TABLE IF NOT EXISTS withdraw_lot (
+    withdraw_id INTEGER NOT NULL,
+    FOREIGN KEY (withdraw_id) REFERENCES withdraw_history(id) ON DELETE CASCADE,
+    lot_id INTEGER NOT NULL,
+    FOREIGN KEY (lot_id) REFERENCES point_lot(id) ON DELETE CASCADE,
+    amount NUMERIC(10, 2) NOT NULL,
+    PRIMARY KEY (withdraw_id, lot_id)
+);
+
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id DESC
+    LIMIT 1
+) h ON true
+WHERE o.status = 'PROCESSED' AND o.accrual > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history
+WHERE status IN ('HELD', 'CAPTURED')
+UNION ALL
+SELECT
+    e.user_id,
+    e.expired_at AS occurred_at,
+    'expiry' AS kind,
+    l.reference,
+    -e.amount AS amount
+FROM point_expiry e
+JOIN point_lot l ON l.id = e.lot_id
+UNION ALL
+SELECT
+    user_id,
+    granted_at AS occurred_at,
+    'campaign' AS kind,
+    order_number::text AS reference,
+    points AS amount
+FROM campaign_grant
+UNION ALL
+SELECT
+    referrer_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referrer_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    referee_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referee_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    sender_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    -amount AS amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    recipient_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    amount
+FROM transfer
+WHERE status = 'COMPLETED';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id DESC
+    LIMIT 1
+) h ON true
+WHERE o.status = 'PROCESSED' AND o.accrual > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history
+UNION ALL
+SELECT
+    e.user_id,
+    e.expired_at AS occurred_at,
+    'expiry' AS kind,
+    l.reference,
+    -e.amount AS amount
+FROM point_expiry e
+JOIN point_lot l ON l.id = e.lot_id
+UNION ALL
+SELECT
+    user_id,
+    granted_at AS occurred_at,
+    'campaign' AS kind,
+    order_number::text AS reference,
+    points AS amount
+FROM campaign_grant
+UNION ALL
+SELECT
+    referrer_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referrer_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    referee_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referee_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    sender_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    -amount AS amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    recipient_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    amount
+FROM transfer
+WHERE status = 'COMPLETED';
+
+DROP TABLE IF EXISTS withdraw_lot;
+ALTER TABLE balance DROP COLUMN IF EXISTS held;
+DROP INDEX IF EXISTS withdraw_history_held_idx;
+ALTER TABLE withdraw_history
+    DROP COLUMN IF EXISTS settled_at,
+    DROP COLUMN IF EXISTS expires_at,
+    DROP COLUMN IF EXISTS status;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- до ограничения по одному заказу могли пройти несколько списаний: они остаются в истории и балансе,
+-- а все, кроме первого, помечаются legacy_duplicate и не участвуют в уникальности
+ALTER TABLE withdraw_history ADD COLUMN IF NOT EXISTS legacy_duplicate BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE withdraw_history w SET legacy_duplicate = true
+WHERE w.status IN ('HELD', 'CAPTURED') AND EXISTS (
+    SELECT 1 FROM withdraw_history f
+    WHERE f.order_number = w.order_number AND f.status IN ('HELD', 'CAPTURED') AND f.id < w.id
+);
+
+-- по номеру заказа допускается одно действующее списание; отменённый или просроченный резерв
+-- не мешает списать баллы под тот же заказ заново
+CREATE UNIQUE INDEX IF NOT EXISTS withdraw_history_order_key ON withdraw_history (order_number)
+    WHERE status IN ('HELD', 'CAPTURED') AND NOT legacy_duplicate;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS withdraw_history_order_key;
+ALTER TABLE withdraw_history DROP COLUMN IF EXISTS legacy_duplicate;
+-- +goose StatementEnd
@@ -0,0 +1,210 @@
+-- +goose Up
+-- +goose StatementBegin
+-- order_verification обработанные заказы, которые до verify_until перепроверяются в системе начислений
+CREATE TABLE IF NOT EXISTS order_verification (
+    order_number VARCHAR(100) PRIMARY KEY,
+    FOREIGN KEY (order_number) REFERENCES "order"(number) ON DELETE CASCADE,
+    verify_until TIMESTAMPTZ NOT NULL,
+    checked_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS order_verification_checked_idx ON order_verification (checked_at NULLS FIRST);
+
+-- accrual_adjustment компенсирующие корректировки после изменения начисления задним числом.
+-- APPLIED и BLOCKED уже изменили баланс, REVIEW ждёт решения администратора, DISMISSED отклонена
+CREATE TABLE IF NOT EXISTS accrual_adjustment (
+    id INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    order_number VARCHAR(100) NOT NULL,
+    FOREIGN KEY (order_number) REFERENCES "order"(number) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    previous_status VARCHAR(100) NOT NULL,
+    new_status VARCHAR(100) NOT NULL,
+    previous_accrual NUMERIC(10, 2) NOT NULL,
+    new_accrual NUMERIC(10, 2) NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL,
+    status VARCHAR(10) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    applied_at TIMESTAMPTZ NULL,
+    resolved_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS accrual_adjustment_order_idx ON accrual_adjustment (order_number);
+CREATE INDEX IF NOT EXISTS accrual_adjustment_open_idx ON accrual_adjustment (status, id)
+    WHERE status IN ('REVIEW', 'BLOCKED');
+
+-- withdrawals_blocked списания, резервы и переводы запрещены, пока администратор не разберёт долг
+ALTER TABLE balance ADD COLUMN IF NOT EXISTS withdrawals_blocked BOOLEAN NOT NULL DEFAULT false;
+
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id
+    LIMIT 1
+) h ON true
+WHERE (o.status = 'PROCESSED' AND o.accrual > 0) OR o.credited > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history
+WHERE status IN ('HELD', 'CAPTURED')
+UNION ALL
+SELECT
+    e.user_id,
+    e.expired_at AS occurred_at,
+    'expiry' AS kind,
+    l.reference,
+    -e.amount AS amount
+FROM point_expiry e
+JOIN point_lot l ON l.id = e.lot_id
+UNION ALL
+SELECT
+    user_id,
+    granted_at AS occurred_at,
+    'campaign' AS kind,
+    order_number::text AS reference,
+    points AS amount
+FROM campaign_grant
+UNION ALL
+SELECT
+    referrer_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referrer_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    referee_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referee_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    sender_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    -amount AS amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    recipient_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    user_id,
+    applied_at AS occurred_at,
+    'adjustment' AS kind,
+    order_number::text AS reference,
+    amount
+FROM accrual_adjustment
+WHERE applied_at IS NOT NULL AND status <> 'DISMISSED';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id DESC
+    LIMIT 1
+) h ON true
+WHERE o.status = 'PROCESSED' AND o.accrual > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history
+WHERE status IN ('HELD', 'CAPTURED')
+UNION ALL
+SELECT
+    e.user_id,
+    e.expired_at AS occurred_at,
+    'expiry' AS kind,
+    l.reference,
+    -e.amount AS amount
+FROM point_expiry e
+JOIN point_lot l ON l.id = e.lot_id
+UNION ALL
+SELECT
+    user_id,
+    granted_at AS occurred_at,
+    'campaign' AS kind,
+    order_number::text AS reference,
+    points AS amount
+FROM campaign_grant
+UNION ALL
+SELECT
+    referrer_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referrer_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    referee_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referee_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    sender_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    -amount AS amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    recipient_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    amount
+FROM transfer
+WHERE status = 'COMPLETED';
+
+ALTER TABLE balance DROP COLUMN IF EXISTS withdrawals_blocked;
+DROP TABLE IF EXISTS accrual_adjustment;
+DROP TABLE IF EXISTS order_verification;
+-- +goose StatementEnd
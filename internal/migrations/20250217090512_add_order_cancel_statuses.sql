@@ -0,0 +1,8 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- новые значения перечисления нельзя использовать в той же транзакции, поэтому миграция без транзакции
+ALTER TYPE status ADD VALUE IF NOT EXISTS 'CANCELLED';
+ALTER TYPE status ADD VALUE IF NOT EXISTS 'RETURNED';
+
+-- +goose Down
+-- значения из перечисления PostgreSQL не удаляет; отменённые и возвращённые заказы остаются как есть
@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+-- program программа лояльности бренда. host и accrual_addr необязательны: без host программа выбирается
+-- заголовком X-Program или токеном, без accrual_addr используется адрес системы начислений из конфигурации
+CREATE TABLE IF NOT EXISTS program (
+    id INTEGER PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    code VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(100) NOT NULL,
+    host VARCHAR(255) NULL UNIQUE,
+    accrual_addr VARCHAR(255) NULL,
+    token_issuer VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- существующие пользователи, заказы и кампании переходят в программу по умолчанию
+INSERT INTO program (id, code, name, token_issuer) VALUES (1, 'default', 'Gophermart', 'gophermart')
+ON CONFLICT DO NOTHING;
+SELECT setval(pg_get_serial_sequence('program', 'id'), (SELECT MAX(id) FROM program));
+
+ALTER TABLE "user" ADD COLUMN program_id INTEGER NOT NULL DEFAULT 1 REFERENCES program(id);
+ALTER TABLE "user" ALTER COLUMN program_id DROP DEFAULT;
+ALTER TABLE "user" DROP CONSTRAINT IF EXISTS user_login_key;
+ALTER TABLE "user" ADD CONSTRAINT user_program_login_key UNIQUE (program_id, login);
+
+-- номер заказа остаётся уникальным глобально: на него ссылаются история, опрос, списания и сверка
+ALTER TABLE "order" ADD COLUMN program_id INTEGER NOT NULL DEFAULT 1 REFERENCES program(id);
+ALTER TABLE "order" ALTER COLUMN program_id DROP DEFAULT;
+CREATE INDEX IF NOT EXISTS order_program_user_idx ON "order" (program_id, user_id);
+
+ALTER TABLE balance ADD COLUMN program_id INTEGER NOT NULL DEFAULT 1 REFERENCES program(id);
+ALTER TABLE balance ALTER COLUMN program_id DROP DEFAULT;
+
+ALTER TABLE campaign ADD COLUMN program_id INTEGER NOT NULL DEFAULT 1 REFERENCES program(id);
+ALTER TABLE campaign ALTER COLUMN program_id DROP DEFAULT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE campaign DROP COLUMN IF EXISTS program_id;
+ALTER TABLE balance DROP COLUMN IF EXISTS program_id;
+DROP INDEX IF EXISTS order_program_user_idx;
+ALTER TABLE "order" DROP COLUMN IF EXISTS program_id;
+ALTER TABLE "user" DROP CONSTRAINT IF EXISTS user_program_login_key;
+ALTER TABLE "user" DROP COLUMN IF EXISTS program_id;
+ALTER TABLE "user" ADD CONSTRAINT user_login_key UNIQUE (login);
+DROP TABLE IF EXISTS program;
+-- +goose StatementEnd
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+-- merchant магазин-партнёр программы. code совпадает с merchant_id заказов v2, ключ API хранится только хешем.
+-- namespaced магазины нумеруют чеки сами: их заказы хранятся под номером "<code>:<номер чека>"
+CREATE TABLE IF NOT EXISTS merchant (
+    id INTEGER PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    program_id INTEGER NOT NULL REFERENCES program(id),
+    code VARCHAR(100) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    api_key_hash CHAR(64) NOT NULL UNIQUE,
+    namespaced BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT merchant_program_code_key UNIQUE (program_id, code)
+);
+
+-- merchant_receipt чек, зарегистрированный магазином. Чек считается востребованным,
+-- когда существует заказ с номером order_number
+CREATE TABLE IF NOT EXISTS merchant_receipt (
+    id INTEGER PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY,
+    merchant_id INTEGER NOT NULL REFERENCES merchant(id),
+    receipt VARCHAR(100) NOT NULL,
+    order_number VARCHAR NOT NULL UNIQUE,
+    purchase_total NUMERIC(12, 2) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    items JSONB NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT merchant_receipt_key UNIQUE (merchant_id, receipt)
+);
+
+CREATE INDEX IF NOT EXISTS merchant_receipt_created_idx ON merchant_receipt (merchant_id, created_at, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS merchant_receipt;
+DROP TABLE IF EXISTS merchant;
+-- +goose StatementEnd
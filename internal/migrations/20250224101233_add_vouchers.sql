@@ -0,0 +1,249 @@
+-- +goose Up
+-- +goose StatementBegin
+-- voucher_batch партия подарочных кодов одного номинала. max_uses сколько раз можно погасить каждый код,
+-- одному пользователю код засчитывается только один раз. Аннулированная партия не гасится
+CREATE TABLE IF NOT EXISTS voucher_batch (
+    id INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    program_id INTEGER NOT NULL REFERENCES program(id),
+    name VARCHAR(100) NOT NULL,
+    amount NUMERIC(10, 2) NOT NULL CHECK (amount > 0),
+    codes_count INTEGER NOT NULL CHECK (codes_count > 0),
+    max_uses INTEGER NOT NULL DEFAULT 1 CHECK (max_uses > 0),
+    expires_at TIMESTAMPTZ NULL,
+    voided_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS voucher_batch_program_idx ON voucher_batch (program_id, id);
+
+CREATE TABLE IF NOT EXISTS voucher (
+    id INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    batch_id INTEGER NOT NULL,
+    FOREIGN KEY (batch_id) REFERENCES voucher_batch(id) ON DELETE CASCADE,
+    code VARCHAR(20) NOT NULL UNIQUE,
+    uses INTEGER NOT NULL DEFAULT 0 CHECK (uses >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS voucher_batch_idx ON voucher (batch_id, id);
+
+CREATE TABLE IF NOT EXISTS voucher_redemption (
+    id INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    voucher_id INTEGER NOT NULL,
+    FOREIGN KEY (voucher_id) REFERENCES voucher(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    amount NUMERIC(10, 2) NOT NULL,
+    redeemed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT voucher_redemption_user_key UNIQUE (voucher_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS voucher_redemption_user_idx ON voucher_redemption (user_id, redeemed_at);
+
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id
+    LIMIT 1
+) h ON true
+WHERE (o.status IN ('PROCESSED', 'RETURNED') AND o.accrual > 0) OR o.credited > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history
+WHERE status IN ('HELD', 'CAPTURED')
+UNION ALL
+SELECT
+    e.user_id,
+    e.expired_at AS occurred_at,
+    'expiry' AS kind,
+    l.reference,
+    -e.amount AS amount
+FROM point_expiry e
+JOIN point_lot l ON l.id = e.lot_id
+UNION ALL
+SELECT
+    user_id,
+    granted_at AS occurred_at,
+    'campaign' AS kind,
+    order_number::text AS reference,
+    points AS amount
+FROM campaign_grant
+UNION ALL
+SELECT
+    referrer_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referrer_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    referee_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referee_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    sender_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    -amount AS amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    recipient_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    user_id,
+    applied_at AS occurred_at,
+    'adjustment' AS kind,
+    order_number::text AS reference,
+    amount
+FROM accrual_adjustment
+WHERE applied_at IS NOT NULL AND status <> 'DISMISSED'
+UNION ALL
+SELECT
+    user_id,
+    created_at AS occurred_at,
+    'return' AS kind,
+    order_number::text AS reference,
+    -amount AS amount
+FROM order_action
+WHERE action = 'RETURN' AND amount > 0
+UNION ALL
+SELECT
+    r.user_id,
+    r.redeemed_at AS occurred_at,
+    'voucher' AS kind,
+    v.code::text AS reference,
+    r.amount
+FROM voucher_redemption r
+JOIN voucher v ON v.id = r.voucher_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id
+    LIMIT 1
+) h ON true
+WHERE (o.status IN ('PROCESSED', 'RETURNED') AND o.accrual > 0) OR o.credited > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history
+WHERE status IN ('HELD', 'CAPTURED')
+UNION ALL
+SELECT
+    e.user_id,
+    e.expired_at AS occurred_at,
+    'expiry' AS kind,
+    l.reference,
+    -e.amount AS amount
+FROM point_expiry e
+JOIN point_lot l ON l.id = e.lot_id
+UNION ALL
+SELECT
+    user_id,
+    granted_at AS occurred_at,
+    'campaign' AS kind,
+    order_number::text AS reference,
+    points AS amount
+FROM campaign_grant
+UNION ALL
+SELECT
+    referrer_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referrer_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    referee_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referee_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    sender_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    -amount AS amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    recipient_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    user_id,
+    applied_at AS occurred_at,
+    'adjustment' AS kind,
+    order_number::text AS reference,
+    amount
+FROM accrual_adjustment
+WHERE applied_at IS NOT NULL AND status <> 'DISMISSED'
+UNION ALL
+SELECT
+    user_id,
+    created_at AS occurred_at,
+    'return' AS kind,
+    order_number::text AS reference,
+    -amount AS amount
+FROM order_action
+WHERE action = 'RETURN' AND amount > 0;
+
+DROP TABLE IF EXISTS voucher_redemption;
+DROP TABLE IF EXISTS voucher;
+DROP TABLE IF EXISTS voucher_batch;
+-- +goose StatementEnd
@@ -0,0 +1,259 @@
+-- +goose Up
+-- +goose StatementBegin
+-- reward награда из каталога программы. stock NULL означает неограниченный запас,
+-- user_limit NULL означает, что один пользователь может получать награду сколько угодно раз
+CREATE TABLE IF NOT EXISTS reward (
+    id INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    program_id INTEGER NOT NULL REFERENCES program(id),
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(500) NULL,
+    price NUMERIC(10, 2) NOT NULL CHECK (price > 0),
+    stock INTEGER NULL CHECK (stock >= 0),
+    user_limit INTEGER NULL CHECK (user_limit > 0),
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS reward_program_idx ON reward (program_id, id) WHERE active;
+
+-- reward_redemption полученная пользователем награда; code предъявляется при выдаче.
+-- price списанная цена на момент получения
+CREATE TABLE IF NOT EXISTS reward_redemption (
+    id INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    reward_id INTEGER NOT NULL,
+    FOREIGN KEY (reward_id) REFERENCES reward(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    code VARCHAR(20) NOT NULL UNIQUE,
+    price NUMERIC(10, 2) NOT NULL,
+    redeemed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS reward_redemption_user_idx ON reward_redemption (user_id, redeemed_at, id);
+CREATE INDEX IF NOT EXISTS reward_redemption_reward_idx ON reward_redemption (reward_id, user_id);
+
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id
+    LIMIT 1
+) h ON true
+WHERE (o.status IN ('PROCESSED', 'RETURNED') AND o.accrual > 0) OR o.credited > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history
+WHERE status IN ('HELD', 'CAPTURED')
+UNION ALL
+SELECT
+    e.user_id,
+    e.expired_at AS occurred_at,
+    'expiry' AS kind,
+    l.reference,
+    -e.amount AS amount
+FROM point_expiry e
+JOIN point_lot l ON l.id = e.lot_id
+UNION ALL
+SELECT
+    user_id,
+    granted_at AS occurred_at,
+    'campaign' AS kind,
+    order_number::text AS reference,
+    points AS amount
+FROM campaign_grant
+UNION ALL
+SELECT
+    referrer_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referrer_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    referee_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referee_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    sender_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    -amount AS amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    recipient_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    user_id,
+    applied_at AS occurred_at,
+    'adjustment' AS kind,
+    order_number::text AS reference,
+    amount
+FROM accrual_adjustment
+WHERE applied_at IS NOT NULL AND status <> 'DISMISSED'
+UNION ALL
+SELECT
+    user_id,
+    created_at AS occurred_at,
+    'return' AS kind,
+    order_number::text AS reference,
+    -amount AS amount
+FROM order_action
+WHERE action = 'RETURN' AND amount > 0
+UNION ALL
+SELECT
+    r.user_id,
+    r.redeemed_at AS occurred_at,
+    'voucher' AS kind,
+    v.code::text AS reference,
+    r.amount
+FROM voucher_redemption r
+JOIN voucher v ON v.id = r.voucher_id
+UNION ALL
+SELECT
+    user_id,
+    redeemed_at AS occurred_at,
+    'reward' AS kind,
+    code::text AS reference,
+    -price AS amount
+FROM reward_redemption;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id
+    LIMIT 1
+) h ON true
+WHERE (o.status IN ('PROCESSED', 'RETURNED') AND o.accrual > 0) OR o.credited > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history
+WHERE status IN ('HELD', 'CAPTURED')
+UNION ALL
+SELECT
+    e.user_id,
+    e.expired_at AS occurred_at,
+    'expiry' AS kind,
+    l.reference,
+    -e.amount AS amount
+FROM point_expiry e
+JOIN point_lot l ON l.id = e.lot_id
+UNION ALL
+SELECT
+    user_id,
+    granted_at AS occurred_at,
+    'campaign' AS kind,
+    order_number::text AS reference,
+    points AS amount
+FROM campaign_grant
+UNION ALL
+SELECT
+    referrer_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referrer_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    referee_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referee_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    sender_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    -amount AS amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    recipient_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    user_id,
+    applied_at AS occurred_at,
+    'adjustment' AS kind,
+    order_number::text AS reference,
+    amount
+FROM accrual_adjustment
+WHERE applied_at IS NOT NULL AND status <> 'DISMISSED'
+UNION ALL
+SELECT
+    user_id,
+    created_at AS occurred_at,
+    'return' AS kind,
+    order_number::text AS reference,
+    -amount AS amount
+FROM order_action
+WHERE action = 'RETURN' AND amount > 0
+UNION ALL
+SELECT
+    r.user_id,
+    r.redeemed_at AS occurred_at,
+    'voucher' AS kind,
+    v.code::text AS reference,
+    r.amount
+FROM voucher_redemption r
+JOIN voucher v ON v.id = r.voucher_id;
+
+DROP TABLE IF EXISTS reward_redemption;
+DROP TABLE IF EXISTS reward;
+-- +goose StatementEnd
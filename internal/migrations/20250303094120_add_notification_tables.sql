@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+-- notification_preference куда и о чём уведомлять пользователя. Без строки уведомления не отправляются,
+-- channels включённые каналы доставки, events типы событий из user_event
+CREATE TABLE IF NOT EXISTS notification_preference (
+    user_id INTEGER PRIMARY KEY,
+    FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    email VARCHAR(254) NULL,
+    phone VARCHAR(16) NULL,
+    locale VARCHAR(5) NOT NULL DEFAULT 'ru',
+    channels TEXT[] NOT NULL DEFAULT '{}',
+    events TEXT[] NOT NULL DEFAULT '{order_status,balance,points_expiring}',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- notification очередь доставки: текст рендерится при постановке в очередь на языке пользователя,
+-- неудачные отправки повторяются с next_attempt_at до исчерпания попыток
+CREATE TABLE IF NOT EXISTS notification (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    user_id INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    event_id BIGINT NOT NULL,
+    FOREIGN KEY (event_id) REFERENCES user_event(id) ON DELETE CASCADE,
+    channel VARCHAR(10) NOT NULL,
+    recipient VARCHAR(254) NOT NULL,
+    subject TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'SENT', 'FAILED')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_error TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sent_at TIMESTAMPTZ NULL,
+    CONSTRAINT notification_event_channel_key UNIQUE (event_id, channel)
+);
+
+CREATE INDEX IF NOT EXISTS notification_pending_idx ON notification (next_attempt_at) WHERE status = 'PENDING';
+CREATE INDEX IF NOT EXISTS notification_user_sent_idx ON notification (user_id, sent_at) WHERE status = 'SENT';
+
+-- notification_cursor последнее событие user_event, разобранное в очередь уведомлений.
+-- Стартуем с текущего конца журнала, чтобы не рассылать уведомления о старых событиях
+CREATE TABLE IF NOT EXISTS notification_cursor (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    last_event_id BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO notification_cursor (last_event_id)
+SELECT COALESCE(MAX(id), 0) FROM user_event
+ON CONFLICT DO NOTHING;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS notification_cursor;
+DROP TABLE IF EXISTS notification;
+DROP TABLE IF EXISTS notification_preference;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- контактные данные профиля. token_version увеличивается при смене пароля и удалении,
+-- токены с другой версией больше не принимаются. deleted_at время обезличивания учётной записи:
+-- логин, пароль и контакты стираются, а заказы, баланс и история операций остаются
+ALTER TABLE "user"
+    ADD COLUMN IF NOT EXISTS email VARCHAR(254) NULL,
+    ADD COLUMN IF NOT EXISTS phone VARCHAR(16) NULL,
+    ADD COLUMN IF NOT EXISTS display_name VARCHAR(100) NULL,
+    ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "user"
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS token_version,
+    DROP COLUMN IF EXISTS display_name,
+    DROP COLUMN IF EXISTS phone,
+    DROP COLUMN IF EXISTS email;
+-- +goose StatementEnd
@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+-- data_export выгрузка персональных данных пользователя. Архив хранится до expires_at,
+-- после чего стирается, а строка остаётся со статусом EXPIRED
+CREATE TABLE IF NOT EXISTS data_export (
+    id INTEGER PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    user_id INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    status VARCHAR(10) NOT NULL DEFAULT 'PROCESSING'
+        CHECK (status IN ('PROCESSING', 'READY', 'FAILED', 'EXPIRED')),
+    archive BYTEA NULL,
+    size INTEGER NOT NULL DEFAULT 0,
+    error TEXT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ NULL,
+    expires_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS data_export_user_idx ON data_export (user_id, created_at);
+CREATE INDEX IF NOT EXISTS data_export_expires_idx ON data_export (expires_at) WHERE status = 'READY';
+
+-- audit_log действия с персональными данными: кто, что и над каким объектом сделал.
+-- Записи не содержат самих данных и остаются после обезличивания пользователя
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGINT PRIMARY KEY GENERATED ALWAYS AS IDENTITY,
+    user_id INTEGER NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE,
+    action VARCHAR(50) NOT NULL,
+    object_id INTEGER NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_user_idx ON audit_log (user_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS data_export;
+-- +goose StatementEnd
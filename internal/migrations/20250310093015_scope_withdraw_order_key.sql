@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- номер заказа списания уникален в пределах программы, как и номера заказов
+ALTER TABLE withdraw_history ADD COLUMN program_id INTEGER NULL REFERENCES program(id);
+UPDATE withdraw_history w SET program_id = u.program_id FROM "user" u WHERE u.id = w.user_id;
+ALTER TABLE withdraw_history ALTER COLUMN program_id SET NOT NULL;
+
+DROP INDEX IF EXISTS withdraw_history_order_key;
+CREATE UNIQUE INDEX IF NOT EXISTS withdraw_history_order_key ON withdraw_history (program_id, order_number)
+    WHERE status IN ('HELD', 'CAPTURED') AND NOT legacy_duplicate;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- разные программы могли списать под один номер заказа: без program_id такие списания становятся дублями
+UPDATE withdraw_history w SET legacy_duplicate = true
+WHERE w.status IN ('HELD', 'CAPTURED') AND NOT w.legacy_duplicate AND EXISTS (
+    SELECT 1 FROM withdraw_history f
+    WHERE f.order_number = w.order_number AND f.status IN ('HELD', 'CAPTURED') AND NOT f.legacy_duplicate
+        AND f.id < w.id
+);
+
+DROP INDEX IF EXISTS withdraw_history_order_key;
+CREATE UNIQUE INDEX IF NOT EXISTS withdraw_history_order_key ON withdraw_history (order_number)
+    WHERE status IN ('HELD', 'CAPTURED') AND NOT legacy_duplicate;
+ALTER TABLE withdraw_history DROP COLUMN IF EXISTS program_id;
+-- +goose StatementEnd
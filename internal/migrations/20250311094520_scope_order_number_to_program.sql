@@ -0,0 +1,321 @@
+-- +goose Up
+-- +goose StatementBegin
+-- номер заказа уникален в пределах программы, поэтому таблицы, ссылающиеся на заказ,
+-- получают program_id и ссылаются на пару (program_id, number)
+ALTER TABLE watched_order ADD COLUMN program_id INTEGER NULL;
+ALTER TABLE order_status_history ADD COLUMN program_id INTEGER NULL;
+ALTER TABLE order_verification ADD COLUMN program_id INTEGER NULL;
+ALTER TABLE accrual_adjustment ADD COLUMN program_id INTEGER NULL;
+ALTER TABLE order_action ADD COLUMN program_id INTEGER NULL;
+
+UPDATE watched_order t SET program_id = o.program_id FROM "order" o WHERE o.number = t.order_number;
+UPDATE order_status_history t SET program_id = o.program_id FROM "order" o WHERE o.number = t.order_number;
+UPDATE order_verification t SET program_id = o.program_id FROM "order" o WHERE o.number = t.order_number;
+UPDATE accrual_adjustment t SET program_id = o.program_id FROM "order" o WHERE o.number = t.order_number;
+UPDATE order_action t SET program_id = o.program_id FROM "order" o WHERE o.number = t.order_number;
+
+ALTER TABLE watched_order ALTER COLUMN program_id SET NOT NULL;
+ALTER TABLE order_status_history ALTER COLUMN program_id SET NOT NULL;
+ALTER TABLE order_verification ALTER COLUMN program_id SET NOT NULL;
+ALTER TABLE accrual_adjustment ALTER COLUMN program_id SET NOT NULL;
+ALTER TABLE order_action ALTER COLUMN program_id SET NOT NULL;
+
+ALTER TABLE watched_order DROP CONSTRAINT IF EXISTS watched_order_order_number_fkey;
+ALTER TABLE order_status_history DROP CONSTRAINT IF EXISTS order_status_history_order_number_fkey;
+ALTER TABLE order_verification DROP CONSTRAINT IF EXISTS order_verification_order_number_fkey;
+ALTER TABLE accrual_adjustment DROP CONSTRAINT IF EXISTS accrual_adjustment_order_number_fkey;
+ALTER TABLE order_action DROP CONSTRAINT IF EXISTS order_action_order_number_fkey;
+
+ALTER TABLE "order" DROP CONSTRAINT IF EXISTS order_number_key;
+ALTER TABLE "order" ADD CONSTRAINT order_program_number_key UNIQUE (program_id, number);
+
+ALTER TABLE watched_order DROP CONSTRAINT IF EXISTS watched_order_order_number_key;
+ALTER TABLE watched_order ADD CONSTRAINT watched_order_program_order_key UNIQUE (program_id, order_number);
+ALTER TABLE order_verification DROP CONSTRAINT IF EXISTS order_verification_pkey;
+ALTER TABLE order_verification ADD PRIMARY KEY (program_id, order_number);
+
+ALTER TABLE watched_order ADD CONSTRAINT watched_order_order_fkey FOREIGN KEY (program_id, order_number)
+    REFERENCES "order"(program_id, number) ON DELETE CASCADE;
+ALTER TABLE order_status_history ADD CONSTRAINT order_status_history_order_fkey FOREIGN KEY (program_id, order_number)
+    REFERENCES "order"(program_id, number) ON DELETE CASCADE;
+ALTER TABLE order_verification ADD CONSTRAINT order_verification_order_fkey FOREIGN KEY (program_id, order_number)
+    REFERENCES "order"(program_id, number) ON DELETE CASCADE;
+ALTER TABLE accrual_adjustment ADD CONSTRAINT accrual_adjustment_order_fkey FOREIGN KEY (program_id, order_number)
+    REFERENCES "order"(program_id, number) ON DELETE CASCADE;
+ALTER TABLE order_action ADD CONSTRAINT order_action_order_fkey FOREIGN KEY (program_id, order_number)
+    REFERENCES "order"(program_id, number) ON DELETE CASCADE;
+
+DROP INDEX IF EXISTS order_status_history_order_number_idx;
+CREATE INDEX IF NOT EXISTS order_status_history_order_number_idx ON order_status_history (program_id, order_number, id);
+DROP INDEX IF EXISTS accrual_adjustment_order_idx;
+CREATE INDEX IF NOT EXISTS accrual_adjustment_order_idx ON accrual_adjustment (program_id, order_number);
+DROP INDEX IF EXISTS order_action_order_idx;
+CREATE INDEX IF NOT EXISTS order_action_order_idx ON order_action (program_id, order_number, id);
+
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE program_id = o.program_id AND order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id
+    LIMIT 1
+) h ON true
+WHERE (o.status IN ('PROCESSED', 'RETURNED') AND o.accrual > 0) OR o.credited > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history
+WHERE status IN ('HELD', 'CAPTURED')
+UNION ALL
+SELECT
+    e.user_id,
+    e.expired_at AS occurred_at,
+    'expiry' AS kind,
+    l.reference,
+    -e.amount AS amount
+FROM point_expiry e
+JOIN point_lot l ON l.id = e.lot_id
+UNION ALL
+SELECT
+    user_id,
+    granted_at AS occurred_at,
+    'campaign' AS kind,
+    order_number::text AS reference,
+    points AS amount
+FROM campaign_grant
+UNION ALL
+SELECT
+    referrer_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referrer_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    referee_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referee_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    sender_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    -amount AS amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    recipient_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    user_id,
+    applied_at AS occurred_at,
+    'adjustment' AS kind,
+    order_number::text AS reference,
+    amount
+FROM accrual_adjustment
+WHERE applied_at IS NOT NULL AND status <> 'DISMISSED'
+UNION ALL
+SELECT
+    user_id,
+    created_at AS occurred_at,
+    'return' AS kind,
+    order_number::text AS reference,
+    -amount AS amount
+FROM order_action
+WHERE action = 'RETURN' AND amount > 0
+UNION ALL
+SELECT
+    r.user_id,
+    r.redeemed_at AS occurred_at,
+    'voucher' AS kind,
+    v.code::text AS reference,
+    r.amount
+FROM voucher_redemption r
+JOIN voucher v ON v.id = r.voucher_id
+UNION ALL
+SELECT
+    user_id,
+    redeemed_at AS occurred_at,
+    'reward' AS kind,
+    code::text AS reference,
+    -price AS amount
+FROM reward_redemption;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE VIEW balance_ledger AS
+SELECT
+    o.user_id,
+    COALESCE(h.changed_at, o.uploaded_at::timestamptz) AS occurred_at,
+    'accrual' AS kind,
+    o.number::text AS reference,
+    COALESCE(o.credited, o.accrual) AS amount
+FROM "order" o
+LEFT JOIN LATERAL (
+    SELECT changed_at FROM order_status_history
+    WHERE order_number = o.number AND status = 'PROCESSED'
+    ORDER BY id
+    LIMIT 1
+) h ON true
+WHERE (o.status IN ('PROCESSED', 'RETURNED') AND o.accrual > 0) OR o.credited > 0
+UNION ALL
+SELECT
+    user_id,
+    processed_at::timestamptz AS occurred_at,
+    'withdrawal' AS kind,
+    order_number::text AS reference,
+    -sum AS amount
+FROM withdraw_history
+WHERE status IN ('HELD', 'CAPTURED')
+UNION ALL
+SELECT
+    e.user_id,
+    e.expired_at AS occurred_at,
+    'expiry' AS kind,
+    l.reference,
+    -e.amount AS amount
+FROM point_expiry e
+JOIN point_lot l ON l.id = e.lot_id
+UNION ALL
+SELECT
+    user_id,
+    granted_at AS occurred_at,
+    'campaign' AS kind,
+    order_number::text AS reference,
+    points AS amount
+FROM campaign_grant
+UNION ALL
+SELECT
+    referrer_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referrer_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    referee_id AS user_id,
+    rewarded_at AS occurred_at,
+    'referral' AS kind,
+    order_number::text AS reference,
+    referee_points AS amount
+FROM referral
+WHERE status = 'REWARDED'
+UNION ALL
+SELECT
+    sender_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    -amount AS amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    recipient_id AS user_id,
+    completed_at AS occurred_at,
+    'transfer' AS kind,
+    id::text AS reference,
+    amount
+FROM transfer
+WHERE status = 'COMPLETED'
+UNION ALL
+SELECT
+    user_id,
+    applied_at AS occurred_at,
+    'adjustment' AS kind,
+    order_number::text AS reference,
+    amount
+FROM accrual_adjustment
+WHERE applied_at IS NOT NULL AND status <> 'DISMISSED'
+UNION ALL
+SELECT
+    user_id,
+    created_at AS occurred_at,
+    'return' AS kind,
+    order_number::text AS reference,
+    -amount AS amount
+FROM order_action
+WHERE action = 'RETURN' AND amount > 0
+UNION ALL
+SELECT
+    r.user_id,
+    r.redeemed_at AS occurred_at,
+    'voucher' AS kind,
+    v.code::text AS reference,
+    r.amount
+FROM voucher_redemption r
+JOIN voucher v ON v.id = r.voucher_id
+UNION ALL
+SELECT
+    user_id,
+    redeemed_at AS occurred_at,
+    'reward' AS kind,
+    code::text AS reference,
+    -price AS amount
+FROM reward_redemption;
+
+DROP INDEX IF EXISTS order_action_order_idx;
+CREATE INDEX IF NOT EXISTS order_action_order_idx ON order_action (order_number, id);
+DROP INDEX IF EXISTS accrual_adjustment_order_idx;
+CREATE INDEX IF NOT EXISTS accrual_adjustment_order_idx ON accrual_adjustment (order_number);
+DROP INDEX IF EXISTS order_status_history_order_number_idx;
+CREATE INDEX IF NOT EXISTS order_status_history_order_number_idx ON order_status_history (order_number, id);
+
+ALTER TABLE watched_order DROP CONSTRAINT IF EXISTS watched_order_order_fkey;
+ALTER TABLE order_status_history DROP CONSTRAINT IF EXISTS order_status_history_order_fkey;
+ALTER TABLE order_verification DROP CONSTRAINT IF EXISTS order_verification_order_fkey;
+ALTER TABLE accrual_adjustment DROP CONSTRAINT IF EXISTS accrual_adjustment_order_fkey;
+ALTER TABLE order_action DROP CONSTRAINT IF EXISTS order_action_order_fkey;
+
+ALTER TABLE order_verification DROP CONSTRAINT IF EXISTS order_verification_pkey;
+ALTER TABLE order_verification ADD PRIMARY KEY (order_number);
+ALTER TABLE watched_order DROP CONSTRAINT IF EXISTS watched_order_program_order_key;
+ALTER TABLE watched_order ADD CONSTRAINT watched_order_order_number_key UNIQUE (order_number);
+
+ALTER TABLE "order" DROP CONSTRAINT IF EXISTS order_program_number_key;
+ALTER TABLE "order" ADD CONSTRAINT order_number_key UNIQUE (number);
+
+ALTER TABLE watched_order ADD CONSTRAINT watched_order_order_number_fkey FOREIGN KEY (order_number)
+    REFERENCES "order"(number) ON DELETE CASCADE;
+ALTER TABLE order_status_history ADD CONSTRAINT order_status_history_order_number_fkey FOREIGN KEY (order_number)
+    REFERENCES "order"(number) ON DELETE CASCADE;
+ALTER TABLE order_verification ADD CONSTRAINT order_verification_order_number_fkey FOREIGN KEY (order_number)
+    REFERENCES "order"(number) ON DELETE CASCADE;
+ALTER TABLE accrual_adjustment ADD CONSTRAINT accrual_adjustment_order_number_fkey FOREIGN KEY (order_number)
+    REFERENCES "order"(number) ON DELETE CASCADE;
+ALTER TABLE order_action ADD CONSTRAINT order_action_order_number_fkey FOREIGN KEY (order_number)
+    REFERENCES "order"(number) ON DELETE CASCADE;
+
+ALTER TABLE watched_order DROP COLUMN IF EXISTS program_id;
+ALTER TABLE order_status_history DROP COLUMN IF EXISTS program_id;
+ALTER TABLE order_verification DROP COLUMN IF EXISTS program_id;
+ALTER TABLE accrual_adjustment DROP COLUMN IF EXISTS program_id;
+ALTER TABLE order_action DROP COLUMN IF EXISTS program_id;
+-- +goose StatementEnd
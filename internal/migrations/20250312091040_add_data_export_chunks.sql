@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- data_export_chunk архив выгрузки частями по порядку seq: архив пишется и отдаётся по частям,
+-- не собираясь целиком в памяти
+CREATE TABLE IF NOT EXISTS data_export_chunk (
+    export_id INTEGER NOT NULL,
+    FOREIGN KEY (export_id) REFERENCES data_export(id) ON DELETE CASCADE,
+    seq INTEGER NOT NULL,
+    data BYTEA NOT NULL,
+    PRIMARY KEY (export_id, seq)
+);
+
+INSERT INTO data_export_chunk (export_id, seq, data)
+SELECT id, 0, archive FROM data_export WHERE archive IS NOT NULL;
+
+ALTER TABLE data_export DROP COLUMN IF EXISTS archive;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE data_export ADD COLUMN archive BYTEA NULL;
+
+UPDATE data_export e SET archive = c.data
+FROM (
+    SELECT export_id, string_agg(data, ''::bytea ORDER BY seq) AS data FROM data_export_chunk GROUP BY export_id
+) c
+WHERE c.export_id = e.id;
+
+DROP TABLE IF EXISTS data_export_chunk;
+-- +goose StatementEnd
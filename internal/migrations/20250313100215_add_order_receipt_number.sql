@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- receipt_number чек namespaced магазина: под ним заказ зарегистрирован в системе начислений
+ALTER TABLE "order" ADD COLUMN IF NOT EXISTS receipt_number VARCHAR(100) NULL;
+
+UPDATE "order" SET receipt_number = substr(number, length(merchant_id) + 2)
+WHERE merchant_id IS NOT NULL AND left(number, length(merchant_id) + 1) = merchant_id || ':';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "order" DROP COLUMN IF EXISTS receipt_number;
+-- +goose StatementEnd
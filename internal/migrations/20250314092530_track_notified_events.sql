@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+-- notified_at событие разобрано в очередь уведомлений. Отметка ставится на каждую строку:
+-- курсор по id пропускал события, закоммиченные позже событий с большим id
+ALTER TABLE user_event ADD COLUMN IF NOT EXISTS notified_at TIMESTAMPTZ NULL;
+
+UPDATE user_event SET notified_at = created_at
+WHERE id <= (SELECT last_event_id FROM notification_cursor);
+
+CREATE INDEX IF NOT EXISTS user_event_unnotified_idx ON user_event (id) WHERE notified_at IS NULL;
+
+DROP TABLE IF EXISTS notification_cursor;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS notification_cursor (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    last_event_id BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO notification_cursor (last_event_id)
+SELECT COALESCE(
+    (SELECT MIN(id) - 1 FROM user_event WHERE notified_at IS NULL),
+    (SELECT MAX(id) FROM user_event),
+    0
+)
+ON CONFLICT DO NOTHING;
+
+DROP INDEX IF EXISTS user_event_unnotified_idx;
+ALTER TABLE user_event DROP COLUMN IF EXISTS notified_at;
+-- +goose StatementEnd
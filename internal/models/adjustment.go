@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+const (
+	AdjustmentApplied   = "APPLIED"
+	AdjustmentBlocked   = "BLOCKED"
+	AdjustmentReview    = "REVIEW"
+	AdjustmentDismissed = "DISMISSED"
+)
+
+// Политики для отзыва начисления, которое больше текущего баланса.
+const (
+	NegativeAllowDebt        = "allow_debt"
+	NegativeBlockWithdrawals = "block_withdrawals"
+	NegativeReview           = "review"
+)
+
+type AccrualAdjustment struct {
+	ID              int        `json:"id"`
+	OrderNumber     string     `json:"order"`
+	UserID          int        `json:"user_id"`
+	PreviousStatus  string     `json:"previous_status"`
+	NewStatus       string     `json:"new_status"`
+	PreviousAccrual float64    `json:"previous_accrual"`
+	NewAccrual      float64    `json:"new_accrual"`
+	Amount          float64    `json:"amount"`
+	Status          string     `json:"status"`
+	CreatedAt       time.Time  `json:"created_at"`
+	AppliedAt       *time.Time `json:"applied_at,omitempty"`
+	ResolvedAt      *time.Time `json:"resolved_at,omitempty"`
+}
+
+type AdjustmentResult struct {
+	Adjustment *AccrualAdjustment
+	Balance    *Balance
+}
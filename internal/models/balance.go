@@ -5,6 +5,26 @@ import "time"
 type Balance struct {
 	Current   float64 `json:"current"`
 	Withdrawn float64 `json:"withdrawn"`
+	// Held баллы, зарезервированные незавершёнными списаниями; в Current они уже не входят
+	Held         float64           `json:"held"`
+	Tier         *UserTier         `json:"tier,omitempty"`
+	ExpiringSoon []*ExpiringPoints `json:"expiring_soon,omitempty"`
+}
+
+type ExpiringPoints struct {
+	Amount    float64   `json:"amount"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ExpiringPointsNotice struct {
+	UserID int
+	ExpiringPoints
+}
+
+type PointsExpiry struct {
+	UserID  int
+	Amount  float64
+	Balance Balance
 }
 
 type WithdrawRequest struct {
@@ -12,8 +32,35 @@ type WithdrawRequest struct {
 	Sum   float64 `json:"sum"`
 }
 
+const (
+	WithdrawalHeld     = "HELD"
+	WithdrawalCaptured = "CAPTURED"
+	WithdrawalReleased = "RELEASED"
+	WithdrawalExpired  = "EXPIRED"
+)
+
 type WithdrawHistoryItem struct {
-	Order       string    `json:"order"`
-	ProcessedAt time.Time `json:"processed_at"`
-	Sum         float64   `json:"sum"`
+	ID          int        `json:"-"`
+	Order       string     `json:"order"`
+	ProcessedAt time.Time  `json:"processed_at"`
+	Sum         float64    `json:"sum"`
+	Status      string     `json:"status"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	SettledAt   *time.Time `json:"settled_at,omitempty"`
+}
+
+type Hold struct {
+	ID        int        `json:"id"`
+	Order     string     `json:"order"`
+	Sum       float64    `json:"sum"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	SettledAt *time.Time `json:"settled_at,omitempty"`
+}
+
+type HoldSettlement struct {
+	UserID  int
+	Hold    *Hold
+	Balance *Balance
 }
@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+const (
+	BatchLineAccepted  = "accepted"
+	BatchLineDuplicate = "duplicate"
+	BatchLineConflict  = "conflict"
+	BatchLineInvalid   = "invalid"
+
+	BatchJobProcessing = "PROCESSING"
+	BatchJobDone       = "DONE"
+	BatchJobFailed     = "FAILED"
+)
+
+type BatchLine struct {
+	Line   int
+	Number string
+}
+
+type BatchLineResult struct {
+	Line   int    `json:"line"`
+	Number string `json:"number"`
+	Result string `json:"result"`
+}
+
+type BatchSummary struct {
+	Total     int `json:"total"`
+	Accepted  int `json:"accepted"`
+	Duplicate int `json:"duplicate"`
+	Conflict  int `json:"conflict"`
+	Invalid   int `json:"invalid"`
+}
+
+type BatchResult struct {
+	Summary BatchSummary       `json:"summary"`
+	Results []*BatchLineResult `json:"results"`
+}
+
+func (br *BatchResult) Add(line *BatchLine, result string) {
+	br.Results = append(br.Results, &BatchLineResult{
+		Line:   line.Line,
+		Number: line.Number,
+		Result: result,
+	})
+
+	br.Summary.Total++
+	switch result {
+	case BatchLineAccepted:
+		br.Summary.Accepted++
+	case BatchLineDuplicate:
+		br.Summary.Duplicate++
+	case BatchLineConflict:
+		br.Summary.Conflict++
+	case BatchLineInvalid:
+		br.Summary.Invalid++
+	}
+}
+
+type OrderBatchJob struct {
+	ID         int          `json:"id"`
+	UserID     int          `json:"-"`
+	Status     string       `json:"status"`
+	Total      int          `json:"total"`
+	Result     *BatchResult `json:"result,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+}
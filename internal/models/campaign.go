@@ -0,0 +1,80 @@
+package models
+
+import "time"
+
+const (
+	// CampaignRuleMultiplier добавляет к начислению по заказу (Multiplier-1)*accrual
+	CampaignRuleMultiplier = "multiplier"
+	// CampaignRuleFixed начисляет Points за каждый подходящий заказ
+	CampaignRuleFixed = "fixed"
+	// CampaignRuleFirstOrder начисляет Points за первый обработанный заказ пользователя
+	CampaignRuleFirstOrder = "first_order"
+	// CampaignRuleNthOrder начисляет Points за N-й обработанный заказ в календарном месяце
+	CampaignRuleNthOrder = "nth_order"
+)
+
+type CampaignRule struct {
+	Type       string  `json:"type"`
+	Multiplier float64 `json:"multiplier,omitempty"`
+	Points     float64 `json:"points,omitempty"`
+	N          int     `json:"n,omitempty"`
+	MerchantID string  `json:"merchant_id,omitempty"`
+}
+
+type Campaign struct {
+	ID        int          `json:"id"`
+	Name      string       `json:"name"`
+	Rule      CampaignRule `json:"rule"`
+	StartsAt  time.Time    `json:"starts_at"`
+	EndsAt    *time.Time   `json:"ends_at,omitempty"`
+	UserCap   *float64     `json:"user_cap,omitempty"`
+	Active    bool         `json:"active"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	ProgramID int          `json:"-"`
+}
+
+type CampaignRequest struct {
+	Name     string       `json:"name"`
+	Rule     CampaignRule `json:"rule"`
+	StartsAt time.Time    `json:"starts_at"`
+	EndsAt   *time.Time   `json:"ends_at,omitempty"`
+	UserCap  *float64     `json:"user_cap,omitempty"`
+	Active   *bool        `json:"active,omitempty"`
+}
+
+type CampaignOrder struct {
+	ProgramID   int
+	UserID      int
+	Number      string
+	Accrual     float64
+	MerchantID  string
+	ProcessedAt time.Time
+	// OrdersTotal и OrdersInMonth учитывают и сам заказ
+	OrdersTotal   int
+	OrdersInMonth int
+	Granted       map[int]float64
+}
+
+type CampaignGrant struct {
+	ID          int        `json:"-"`
+	CampaignID  int        `json:"campaign_id"`
+	UserID      int        `json:"user_id"`
+	OrderNumber string     `json:"order_number"`
+	RuleType    string     `json:"rule_type"`
+	Points      float64    `json:"points"`
+	GrantedAt   *time.Time `json:"granted_at,omitempty"`
+}
+
+type CampaignDryRunRequest struct {
+	UserID      int        `json:"user_id"`
+	OrderNumber string     `json:"order_number"`
+	Accrual     float64    `json:"accrual"`
+	MerchantID  string     `json:"merchant_id,omitempty"`
+	ProcessedAt *time.Time `json:"processed_at,omitempty"`
+}
+
+type CampaignDryRunResult struct {
+	Grants []*CampaignGrant `json:"grants"`
+	Total  float64          `json:"total"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	DataExportProcessing = "PROCESSING"
+	DataExportReady      = "READY"
+	DataExportFailed     = "FAILED"
+	DataExportExpired    = "EXPIRED"
+
+	AuditDataExportRequested  = "data_export_requested"
+	AuditDataExportDownloaded = "data_export_downloaded"
+)
+
+type DataExport struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Status     string     `json:"status"`
+	Size       int        `json:"size,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+type PersonalData struct {
+	GeneratedAt time.Time
+	Profile     *Profile
+	Balance     *Balance
+	Orders      []*Order
+	Withdrawals []*WithdrawHistoryItem
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+const merchantOrderSeparator = ":"
+
+// Merchant Code дописывается к номерам заказов магазина.
+type Merchant struct {
+	ID         int       `json:"id"`
+	ProgramID  int       `json:"-"`
+	Code       string    `json:"code"`
+	Name       string    `json:"name"`
+	Namespaced bool      `json:"namespaced"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type MerchantRequest struct {
+	Code       string `json:"code"`
+	Name       string `json:"name"`
+	Namespaced bool   `json:"namespaced,omitempty"`
+}
+
+type MerchantCredentials struct {
+	Merchant
+	APIKey string `json:"api_key"`
+}
+
+type MerchantOrderRequest struct {
+	Number        string       `json:"number"`
+	Customer      string       `json:"customer,omitempty"`
+	PurchaseTotal float64      `json:"purchase_total"`
+	Currency      string       `json:"currency"`
+	Items         []*OrderItem `json:"items,omitempty"`
+}
+
+type MerchantReceipt struct {
+	ID            int        `json:"-"`
+	Number        string     `json:"number"`
+	OrderNumber   string     `json:"-"`
+	PurchaseTotal float64    `json:"purchase_total"`
+	Currency      string     `json:"currency"`
+	CreatedAt     time.Time  `json:"created_at"`
+	Claimed       bool       `json:"claimed"`
+	ClaimedBy     string     `json:"claimed_by,omitempty"`
+	ClaimedAt     *time.Time `json:"claimed_at,omitempty"`
+	Status        string     `json:"status,omitempty"`
+	Accrual       *float64   `json:"accrual,omitempty"`
+}
+
+type MerchantReceiptFilter struct {
+	ListPage
+	Claimed *bool
+}
+
+type MerchantSummary struct {
+	From      *time.Time `json:"from,omitempty"`
+	To        *time.Time `json:"to,omitempty"`
+	Receipts  int        `json:"receipts"`
+	Claimed   int        `json:"claimed"`
+	Processed int        `json:"processed"`
+	Accrual   float64    `json:"accrual"`
+}
+
+func MerchantOrderNumber(merchant *Merchant, receipt string) string {
+	if !merchant.Namespaced {
+		return receipt
+	}
+	return merchant.Code + merchantOrderSeparator + receipt
+}
+
+// IsMerchantOrderNumber номер с кодом магазина присваивается только при заявке на чек namespaced магазина.
+func IsMerchantOrderNumber(orderNumber string) bool {
+	return strings.Contains(orderNumber, merchantOrderSeparator)
+}
+
+func MaskLogin(login string) string {
+	runes := []rune(login)
+	visible := 2
+	if len(runes) <= 3 {
+		visible = 1
+	}
+	if len(runes) < visible {
+		return "***"
+	}
+	return string(runes[:visible]) + "***"
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerchantOrderNumber(t *testing.T) {
+	testCases := []struct {
+		name     string
+		merchant *Merchant
+		receipt  string
+		expected string
+	}{
+		{name: "Shared Numbering", merchant: &Merchant{Code: "shop"}, receipt: "12345678903", expected: "12345678903"},
+		{
+			name:     "Namespaced",
+			merchant: &Merchant{Code: "shop", Namespaced: true},
+			receipt:  "12345678903",
+			expected: "shop:12345678903",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			number := MerchantOrderNumber(test.merchant, test.receipt)
+			assert.Equal(t, test.expected, number)
+			assert.Equal(t, test.merchant.Namespaced, IsMerchantOrderNumber(number))
+		})
+	}
+}
+
+func TestMaskLogin(t *testing.T) {
+	testCases := []struct {
+		login    string
+		expected string
+	}{
+		{login: "alexander", expected: "al***"},
+		{login: "bob", expected: "b***"},
+		{login: "юлия", expected: "юл***"},
+		{login: "", expected: "***"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.login, func(t *testing.T) {
+			assert.Equal(t, test.expected, MaskLogin(test.login))
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+const (
+	NotificationChannelEmail = "email"
+	NotificationChannelSMS   = "sms"
+
+	NotificationStatusPending = "PENDING"
+	NotificationStatusSent    = "SENT"
+	NotificationStatusFailed  = "FAILED"
+
+	DefaultNotificationLocale = "ru"
+)
+
+var (
+	NotificationChannels = map[string]struct{}{
+		NotificationChannelEmail: {},
+		NotificationChannelSMS:   {},
+	}
+	NotificationEvents = map[string]struct{}{
+		EventOrderStatus:    {},
+		EventBalance:        {},
+		EventPointsExpiring: {},
+	}
+	NotificationLocales = map[string]struct{}{
+		"ru": {},
+		"en": {},
+	}
+)
+
+type NotificationPreferences struct {
+	UserID    int        `json:"-"`
+	Email     *string    `json:"email,omitempty"`
+	Phone     *string    `json:"phone,omitempty"`
+	Locale    string     `json:"locale"`
+	Channels  []string   `json:"channels"`
+	Events    []string   `json:"events"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+func DefaultNotificationPreferences(userID int) *NotificationPreferences {
+	return &NotificationPreferences{
+		UserID:   userID,
+		Locale:   DefaultNotificationLocale,
+		Channels: []string{},
+		Events:   []string{EventOrderStatus, EventBalance, EventPointsExpiring},
+	}
+}
+
+func (p *NotificationPreferences) Recipient(channel string) string {
+	switch channel {
+	case NotificationChannelEmail:
+		if p.Email != nil {
+			return *p.Email
+		}
+	case NotificationChannelSMS:
+		if p.Phone != nil {
+			return *p.Phone
+		}
+	}
+	return ""
+}
+
+func (p *NotificationPreferences) Wants(eventType string) bool {
+	for _, e := range p.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type Notification struct {
+	ID            int64
+	UserID        int
+	EventID       int64
+	Channel       string
+	Recipient     string
+	Subject       string
+	Body          string
+	Status        string
+	Attempts      int
+	NextAttemptAt time.Time
+}
+
+type NotificationEvent struct {
+	Event       *Event
+	Preferences *NotificationPreferences
+}
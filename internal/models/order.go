@@ -51,6 +51,38 @@ type WatchedOrder struct {
 	UserID             int
 	AccrualOrderStatus string
 	AccrualPoints      float64
+	Attempts           int
+	// Registered ложно, пока состав покупки не передан в систему начислений
+	Registered bool
+	Items      []*OrderItem
+	ProgramID  int
+	// ReceiptNumber чек namespaced магазина, под которым заказ известен системе начислений
+	ReceiptNumber string
+	// AccrualAddr система начислений программы заказа, пустая для адреса из конфигурации
+	AccrualAddr string
+}
+
+// AccrualNumber номер, под которым заказ зарегистрирован в системе начислений.
+func (o *WatchedOrder) AccrualNumber() string {
+	if o.ReceiptNumber != "" {
+		return o.ReceiptNumber
+	}
+	return o.OrderNumber
+}
+
+type OrderStatusChange struct {
+	Status    string    `json:"status"`
+	Accrual   *float64  `json:"accrual,omitempty"`
+	Attempt   int       `json:"attempt"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type OrderDetailsResponse struct {
+	Order    *Order               `json:"order"`
+	Attempts int                  `json:"attempts"`
+	Watching bool                 `json:"watching"`
+	Timeline []*OrderStatusChange `json:"timeline"`
+	Actions  []*OrderAction       `json:"actions,omitempty"`
 }
 
 type AccrualOrderResponse struct {
@@ -58,3 +90,32 @@ type AccrualOrderResponse struct {
 	Status  string  `json:"status"`
 	Accrual float64 `json:"accrual,omitempty"`
 }
+
+type OrderItem struct {
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+	Quantity    int     `json:"quantity"`
+}
+
+type OrderPurchase struct {
+	MerchantID    string       `json:"merchant_id"`
+	ReceiptNumber string       `json:"-"`
+	PurchaseTotal float64      `json:"purchase_total"`
+	Currency      string       `json:"currency"`
+	Items         []*OrderItem `json:"items,omitempty"`
+}
+
+type OrderUploadRequest struct {
+	Number string `json:"number"`
+	OrderPurchase
+}
+
+type AccrualGood struct {
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+}
+
+type AccrualRegisterRequest struct {
+	Order string         `json:"order"`
+	Goods []*AccrualGood `json:"goods"`
+}
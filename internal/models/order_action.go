@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+const (
+	OrderCancelled = "CANCELLED"
+	OrderReturned  = "RETURNED"
+)
+
+const (
+	OrderActionCancel = "CANCEL"
+	OrderActionReturn = "RETURN"
+
+	ActorUser  = "user"
+	ActorAdmin = "admin"
+)
+
+type OrderAction struct {
+	ID          int       `json:"id"`
+	OrderNumber string    `json:"order"`
+	Action      string    `json:"action"`
+	ActorType   string    `json:"actor_type"`
+	ActorID     *int      `json:"actor_id,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	Amount      float64   `json:"amount"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type OrderReturnRequest struct {
+	Reason string `json:"reason"`
+}
+
+type OrderReturnResult struct {
+	Action  *OrderAction
+	UserID  int
+	Balance *Balance
+}
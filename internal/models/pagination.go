@@ -0,0 +1,115 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+)
+
+var (
+	ErrBadPage error = errors.New("bad page parameters")
+
+	OrderStatuses = map[string]struct{}{
+		"NEW":        {},
+		"PROCESSING": {},
+		"INVALID":    {},
+		"PROCESSED":  {},
+		"CANCELLED":  {},
+		"RETURNED":   {},
+	}
+)
+
+type Cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Value string `json:"v"`
+	ID    int    `json:"i"`
+}
+
+func (c *Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(encoded string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding cursor %w", err)
+	}
+
+	var cursor Cursor
+	if err = json.Unmarshal(raw, &cursor); err != nil {
+		return nil, fmt.Errorf("error unmarshal cursor %w", err)
+	}
+
+	return &cursor, nil
+}
+
+type ListPage struct {
+	Limit  int
+	Sort   string
+	Desc   bool
+	Cursor *Cursor
+}
+
+func NewListPage(limit int, sort string, cursor string, defaultSort string, sorts ...string) (*ListPage, error) {
+	page := &ListPage{
+		Limit: DefaultPageLimit,
+		Sort:  defaultSort,
+		Desc:  true,
+	}
+
+	if limit != 0 {
+		if limit < 1 || limit > MaxPageLimit {
+			return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrBadPage, MaxPageLimit)
+		}
+		page.Limit = limit
+	}
+
+	if sort != "" {
+		page.Desc = strings.HasPrefix(sort, "-")
+		page.Sort = strings.TrimPrefix(sort, "-")
+		if !slices.Contains(sorts, page.Sort) {
+			return nil, fmt.Errorf("%w: unknown sort %s", ErrBadPage, sort)
+		}
+	}
+
+	if cursor != "" {
+		decoded, err := DecodeCursor(cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrBadPage, err)
+		}
+		if decoded.Sort != page.Sort || decoded.Desc != page.Desc {
+			return nil, fmt.Errorf("%w: cursor does not match sort", ErrBadPage)
+		}
+		page.Cursor = decoded
+	}
+
+	return page, nil
+}
+
+type OrderListFilter struct {
+	ListPage
+	Statuses   []string
+	From       *time.Time
+	To         *time.Time
+	MinAccrual *float64
+	MaxAccrual *float64
+}
+
+type WithdrawListFilter struct {
+	ListPage
+	From   *time.Time
+	To     *time.Time
+	MinSum *float64
+	MaxSum *float64
+}
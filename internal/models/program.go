@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Program пустой AccrualAddr означает систему начислений из конфигурации.
+type Program struct {
+	ID          int       `json:"id"`
+	Code        string    `json:"code"`
+	Name        string    `json:"name"`
+	Host        string    `json:"host,omitempty"`
+	AccrualAddr string    `json:"accrual_addr,omitempty"`
+	TokenIssuer string    `json:"token_issuer"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ProgramRequest struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Host        string `json:"host,omitempty"`
+	AccrualAddr string `json:"accrual_addr,omitempty"`
+	TokenIssuer string `json:"token_issuer,omitempty"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+const (
+	ReferralPending  = "PENDING"
+	ReferralHeld     = "HELD"
+	ReferralRewarded = "REWARDED"
+	ReferralRejected = "REJECTED"
+)
+
+type Referral struct {
+	ID             int
+	ReferrerID     int
+	RefereeID      int
+	Status         string
+	Reason         string
+	OrderNumber    string
+	ReferrerPoints float64
+	RefereePoints  float64
+	ReleaseAt      *time.Time
+}
+
+type ReferralReward struct {
+	*Referral
+	ReferrerBalance *Balance
+	RefereeBalance  *Balance
+}
+
+type ReferralStats struct {
+	InviteCode   string  `json:"invite_code"`
+	Invited      int     `json:"invited"`
+	Pending      int     `json:"pending"`
+	Held         int     `json:"held"`
+	Rewarded     int     `json:"rewarded"`
+	Rejected     int     `json:"rejected"`
+	PointsEarned float64 `json:"points_earned"`
+	Limit        int     `json:"limit"`
+}
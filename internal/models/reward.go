@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type Reward struct {
+	ID          int       `json:"id"`
+	ProgramID   int       `json:"-"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Price       float64   `json:"price"`
+	Stock       *int      `json:"stock,omitempty"`
+	UserLimit   *int      `json:"user_limit,omitempty"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type RewardRequest struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Price       float64 `json:"price"`
+	Stock       *int    `json:"stock,omitempty"`
+	UserLimit   *int    `json:"user_limit,omitempty"`
+	Active      *bool   `json:"active,omitempty"`
+}
+
+type RewardRedemption struct {
+	ID         int       `json:"-"`
+	RewardID   int       `json:"reward_id"`
+	RewardName string    `json:"reward_name"`
+	Code       string    `json:"code"`
+	Price      float64   `json:"price"`
+	RedeemedAt time.Time `json:"redeemed_at"`
+	// Balance баланс после списания, только в ответе на получение награды
+	Balance *Balance `json:"balance,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	StatementFormatJSON = "json"
+	StatementFormatCSV  = "csv"
+	StatementFormatPDF  = "pdf"
+)
+
+type StatementEntry struct {
+	OccurredAt time.Time `json:"occurred_at"`
+	Kind       string    `json:"kind"`
+	Reference  string    `json:"reference"`
+	Amount     float64   `json:"amount"`
+}
+
+type Statement struct {
+	From           *time.Time `json:"from,omitempty"`
+	To             time.Time  `json:"to"`
+	OpeningBalance float64    `json:"opening_balance"`
+	Credits        float64    `json:"credits"`
+	Debits         float64    `json:"debits"`
+	ClosingBalance float64    `json:"closing_balance"`
+}
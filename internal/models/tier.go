@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+type Tier struct {
+	ID                     int      `json:"-"`
+	Code                   string   `json:"code"`
+	Name                   string   `json:"name"`
+	Rank                   int      `json:"-"`
+	MinPoints              float64  `json:"-"`
+	MinSpend               *float64 `json:"-"`
+	Multiplier             float64  `json:"multiplier"`
+	MaxWithdrawal          *float64 `json:"max_withdrawal,omitempty"`
+	MonthlyWithdrawalLimit *float64 `json:"monthly_withdrawal_limit,omitempty"`
+}
+
+func (t *Tier) Qualifies(points, spend float64) bool {
+	return points >= t.MinPoints || (t.MinSpend != nil && spend >= *t.MinSpend)
+}
+
+func QualifyingTier(tiers []*Tier, points, spend float64) *Tier {
+	var best *Tier
+	for _, tier := range tiers {
+		if tier.Qualifies(points, spend) && (best == nil || tier.Rank > best.Rank) {
+			best = tier
+		}
+	}
+	return best
+}
+
+type UserTier struct {
+	*Tier
+	AssignedAt *time.Time `json:"assigned_at,omitempty"`
+}
+
+type TierStats struct {
+	UserID        int
+	Points        float64
+	Spend         float64
+	CurrentTierID int
+}
+
+type TierChange struct {
+	Code       string    `json:"code"`
+	Name       string    `json:"name"`
+	Points     float64   `json:"points"`
+	Spend      float64   `json:"spend"`
+	AssignedAt time.Time `json:"assigned_at"`
+}
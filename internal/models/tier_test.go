@@ -0,0 +1,44 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQualifyingTier(t *testing.T) {
+	silverSpend, goldSpend := 50000.0, 200000.0
+	tiers := []*Tier{
+		{ID: 1, Code: "BRONZE", Rank: 1},
+		{ID: 2, Code: "SILVER", Rank: 2, MinPoints: 1000, MinSpend: &silverSpend},
+		{ID: 3, Code: "GOLD", Rank: 3, MinPoints: 5000, MinSpend: &goldSpend},
+	}
+
+	testCases := []struct {
+		name     string
+		tiers    []*Tier
+		points   float64
+		spend    float64
+		expected string
+	}{
+		{name: "New User", tiers: tiers, expected: "BRONZE"},
+		{name: "Silver By Points", tiers: tiers, points: 1000, expected: "SILVER"},
+		{name: "Silver By Spend", tiers: tiers, points: 10, spend: 60000, expected: "SILVER"},
+		{name: "Gold By Points Beats Silver Spend", tiers: tiers, points: 5000, spend: 60000, expected: "GOLD"},
+		{name: "Gold By Spend", tiers: tiers, spend: 200000, expected: "GOLD"},
+		{name: "No Tiers", expected: ""},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			tier := QualifyingTier(test.tiers, test.points, test.spend)
+			if test.expected == "" {
+				assert.Nil(t, tier)
+				return
+			}
+			if assert.NotNil(t, tier) {
+				assert.Equal(t, test.expected, tier.Code)
+			}
+		})
+	}
+}
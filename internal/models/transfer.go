@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+const (
+	TransferPending   = "PENDING"
+	TransferCompleted = "COMPLETED"
+	TransferExpired   = "EXPIRED"
+)
+
+const (
+	TransferIn  = "in"
+	TransferOut = "out"
+)
+
+type TransferRequest struct {
+	To      string  `json:"to"`
+	Amount  float64 `json:"amount"`
+	Comment string  `json:"comment,omitempty"`
+}
+
+type Transfer struct {
+	ID           int       `json:"id"`
+	Direction    string    `json:"direction"`
+	Counterparty string    `json:"counterparty"`
+	Amount       float64   `json:"amount"`
+	Comment      string    `json:"comment,omitempty"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+	// ExpiresAt до какого момента отправитель может подтвердить перевод
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+type TransferLimits struct {
+	DailyLimit float64
+	DayStart   time.Time
+	// LotExpiry срок сгорания баллов, которым не нашлось партии у отправителя
+	LotExpiry time.Time
+}
+
+type TransferResult struct {
+	Transfer         *Transfer
+	RecipientID      int
+	SenderBalance    *Balance
+	RecipientBalance *Balance
+}
+
+type TransferListFilter struct {
+	ListPage
+	Direction string
+}
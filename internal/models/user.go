@@ -1,5 +1,10 @@
 package models
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 type AuthInfo struct {
 	IsAuthenticated bool
 	Token           string
@@ -9,11 +14,48 @@ type User struct {
 	ID          int    `json:"id"`
 	Login       string `json:"login"`
 	Password    string `json:"omitempty"`
+	InviteCode  string `json:"invite_code,omitempty"`
+	ProgramID   int    `json:"program_id"`
 	AuthInfo    *AuthInfo
 	BalanceInfo *Balance
+	// TokenVersion версия, с которой выпускаются токены пользователя; токены старых версий отозваны
+	TokenVersion int `json:"-"`
 }
 
 type UserLogPassRequest struct {
-	Login    string `json:"login"`
-	Password string `json:"password"`
+	Login        string `json:"login"`
+	Password     string `json:"password"`
+	ReferralCode string `json:"referral_code,omitempty"`
+}
+
+type Profile struct {
+	Login       string  `json:"login"`
+	Email       *string `json:"email,omitempty"`
+	Phone       *string `json:"phone,omitempty"`
+	DisplayName *string `json:"display_name,omitempty"`
+}
+
+type ProfileUpdate struct {
+	Email       OptionalString `json:"email"`
+	Phone       OptionalString `json:"phone"`
+	DisplayName OptionalString `json:"display_name"`
+}
+
+// OptionalString отличает отсутствие поля (Set false) от null (Value nil).
+type OptionalString struct {
+	Set   bool
+	Value *string
+}
+
+func (o *OptionalString) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if err := json.Unmarshal(data, &o.Value); err != nil {
+		return fmt.Errorf("error unmarshal optional string %w", err)
+	}
+	return nil
+}
+
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
 }
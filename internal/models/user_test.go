@@ -0,0 +1,26 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileUpdateUnmarshal(t *testing.T) {
+	var update ProfileUpdate
+	require.NoError(t, json.Unmarshal([]byte(`{"email": "user@example.com", "phone": null}`), &update))
+
+	assert.True(t, update.Email.Set)
+	require.NotNil(t, update.Email.Value)
+	assert.Equal(t, "user@example.com", *update.Email.Value)
+
+	assert.True(t, update.Phone.Set)
+	assert.Nil(t, update.Phone.Value)
+
+	assert.False(t, update.DisplayName.Set)
+	assert.Nil(t, update.DisplayName.Value)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"display_name": 42}`), &update))
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// voucherCodeAlphabet без 0, O, 1 и I, которые путают при вводе с печатной карты
+	voucherCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	voucherCodeLength   = 12
+	voucherCodeGroup    = 4
+)
+
+type VoucherBatch struct {
+	ID          int        `json:"id"`
+	ProgramID   int        `json:"-"`
+	Name        string     `json:"name"`
+	Amount      float64    `json:"amount"`
+	Count       int        `json:"count"`
+	MaxUses     int        `json:"max_uses"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	VoidedAt    *time.Time `json:"voided_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Redemptions int        `json:"redemptions"`
+}
+
+type VoucherBatchRequest struct {
+	Name      string     `json:"name"`
+	Amount    float64    `json:"amount"`
+	Count     int        `json:"count"`
+	MaxUses   int        `json:"max_uses,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type Voucher struct {
+	Code string
+	Uses int
+}
+
+type VoucherRedeemRequest struct {
+	Code string `json:"code"`
+}
+
+type VoucherRedemption struct {
+	Code       string    `json:"code"`
+	Amount     float64   `json:"amount"`
+	RedeemedAt time.Time `json:"redeemed_at"`
+	Balance    *Balance  `json:"balance"`
+}
+
+func NewVoucherCode() (string, error) {
+	b := make([]byte, voucherCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error getting rand.Read(): %w", err)
+	}
+	// длина алфавита делит 256, поэтому остаток не смещает распределение
+	for i := range b {
+		b[i] = voucherCodeAlphabet[int(b[i])%len(voucherCodeAlphabet)]
+	}
+
+	return formatVoucherCode(string(b)), nil
+}
+
+func NormalizeVoucherCode(code string) (string, bool) {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(code) != voucherCodeLength {
+		return "", false
+	}
+	for _, c := range code {
+		if !strings.ContainsRune(voucherCodeAlphabet, c) {
+			return "", false
+		}
+	}
+
+	return formatVoucherCode(code), true
+}
+
+func formatVoucherCode(code string) string {
+	groups := make([]string, 0, voucherCodeLength/voucherCodeGroup)
+	for i := 0; i < len(code); i += voucherCodeGroup {
+		groups = append(groups, code[i:i+voucherCodeGroup])
+	}
+	return strings.Join(groups, "-")
+}
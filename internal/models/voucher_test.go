@@ -0,0 +1,42 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewVoucherCode(t *testing.T) {
+	code, err := NewVoucherCode()
+	require.NoError(t, err)
+	assert.Len(t, code, 14)
+
+	normalized, ok := NormalizeVoucherCode(code)
+	assert.True(t, ok)
+	assert.Equal(t, code, normalized)
+}
+
+func TestNormalizeVoucherCode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		code     string
+		expected string
+		ok       bool
+	}{
+		{name: "Canonical", code: "ABCD-EFGH-JKLM", expected: "ABCD-EFGH-JKLM", ok: true},
+		{name: "Lower Case Without Dashes", code: "abcdefghjklm", expected: "ABCD-EFGH-JKLM", ok: true},
+		{name: "Spaces", code: " abcd efgh jklm ", expected: "ABCD-EFGH-JKLM", ok: true},
+		{name: "Ambiguous Letter", code: "ABCD-EFGH-JKLO", ok: false},
+		{name: "Too Short", code: "ABCD-EFGH", ok: false},
+		{name: "Empty", code: "", ok: false},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			code, ok := NormalizeVoucherCode(test.code)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.expected, code)
+		})
+	}
+}
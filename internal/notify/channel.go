@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+)
+
+type Message struct {
+	Channel string `json:"channel"`
+	To      string `json:"to"`
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"body"`
+}
+
+type Channel interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// NewChannels FileSink перехватывает все каналы.
+func NewChannels(cfg *config.NotifyConfig) (map[string]Channel, error) {
+	channels := make(map[string]Channel)
+	if cfg.FileSink != "" {
+		sink := NewFileSink(cfg.FileSink)
+		channels[models.NotificationChannelEmail] = sink
+		channels[models.NotificationChannelSMS] = sink
+		return channels, nil
+	}
+
+	if cfg.SMTPAddr != "" {
+		smtpChannel, err := NewSMTPChannel(cfg.SMTPAddr, cfg.From, cfg.SMTPUsername, cfg.SMTPPassword)
+		if err != nil {
+			return nil, fmt.Errorf("error creating smtp channel %w", err)
+		}
+		channels[models.NotificationChannelEmail] = smtpChannel
+	}
+
+	return channels, nil
+}
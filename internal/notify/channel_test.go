@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.jsonl")
+	channels, err := NewChannels(&config.NotifyConfig{FileSink: path, SMTPAddr: "localhost:25"})
+	require.NoError(t, err)
+
+	sent := []*Message{
+		{Channel: models.NotificationChannelEmail, To: "user@example.com", Subject: "Тема", Body: "Текст\nписьма"},
+		{Channel: models.NotificationChannelSMS, To: "+79991234567", Body: "SMS"},
+	}
+	for _, msg := range sent {
+		require.NoError(t, channels[msg.Channel].Send(context.Background(), msg))
+	}
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var got []*Message
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg Message
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &msg))
+		got = append(got, &msg)
+	}
+	require.NoError(t, scanner.Err())
+
+	assert.Equal(t, sent, got)
+}
+
+func TestNewChannels(t *testing.T) {
+	channels, err := NewChannels(&config.NotifyConfig{})
+	require.NoError(t, err)
+	assert.Empty(t, channels)
+
+	channels, err = NewChannels(&config.NotifyConfig{SMTPAddr: "localhost:1025", From: "no-reply@example.com"})
+	require.NoError(t, err)
+	assert.IsType(t, &SMTPChannel{}, channels[models.NotificationChannelEmail])
+	assert.NotContains(t, channels, models.NotificationChannelSMS)
+
+	_, err = NewChannels(&config.NotifyConfig{SMTPAddr: "localhost"})
+	assert.Error(t, err)
+}
+
+func TestSMTPMail(t *testing.T) {
+	c, err := NewSMTPChannel("localhost:1025", "no-reply@example.com", "", "")
+	require.NoError(t, err)
+
+	mail := string(c.mail(&Message{
+		To:      "user@example.com",
+		Subject: "Заказ обработан",
+		Body:    "Строка 1\nСтрока 2\n",
+	}, time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)))
+
+	headers, body, ok := strings.Cut(mail, "\r\n\r\n")
+	require.True(t, ok)
+	assert.Contains(t, headers, "From: no-reply@example.com\r\n")
+	assert.Contains(t, headers, "To: user@example.com\r\n")
+	assert.Contains(t, headers, "Subject: =?utf-8?q?")
+	assert.Contains(t, headers, "Date: Mon, 03 Mar 2025 10:00:00 +0000")
+	assert.Contains(t, headers, "Content-Type: text/plain; charset=utf-8")
+	assert.Equal(t, "Строка 1\r\nСтрока 2\r\n", body)
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+const fileSinkPerm = 0o600
+
+type FileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileSink(path string) *FileSink {
+	return &FileSink{
+		path: path,
+	}
+}
+
+func (s *FileSink) Send(_ context.Context, msg *Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("error marshal notification %w", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// файл открывается на каждую запись, чтобы его можно было удалить или ротировать на ходу
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, fileSinkPerm)
+	if err != nil {
+		return fmt.Errorf("error opening notification sink file %w", err)
+	}
+	if _, err = f.Write(line); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("error writing notification sink file %w", err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("error closing notification sink file %w", err)
+	}
+
+	return nil
+}
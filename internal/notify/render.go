@@ -0,0 +1,111 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+)
+
+//go:embed templates/*.tmpl
+var templatesFS embed.FS
+
+var ErrUnknownEvent error = errors.New("no notification template for event")
+
+type Renderer struct {
+	locales map[string]*template.Template
+}
+
+func NewRenderer() (*Renderer, error) {
+	files, err := templatesFS.ReadDir("templates")
+	if err != nil {
+		return nil, fmt.Errorf("error reading notification templates %w", err)
+	}
+
+	r := &Renderer{locales: make(map[string]*template.Template, len(files))}
+	for _, f := range files {
+		locale := strings.TrimSuffix(f.Name(), ".tmpl")
+		tmpl, err := template.New(locale).Funcs(template.FuncMap{
+			"points": formatPoints,
+			"date":   dateFormatter(locale),
+		}).ParseFS(templatesFS, "templates/"+f.Name())
+		if err != nil {
+			return nil, fmt.Errorf("error parsing notification templates for %s: %w", locale, err)
+		}
+		r.locales[locale] = tmpl
+	}
+
+	return r, nil
+}
+
+func (r *Renderer) Render(locale, channel string, event *models.Event) (*Message, error) {
+	tmpl, ok := r.locales[locale]
+	if !ok {
+		tmpl = r.locales[models.DefaultNotificationLocale]
+	}
+
+	var data any
+	switch event.Type {
+	case models.EventOrderStatus:
+		data = &models.OrderStatusEvent{}
+	case models.EventBalance:
+		data = &models.BalanceEvent{}
+	case models.EventPointsExpiring:
+		data = &models.PointsExpiringEvent{}
+	default:
+		return nil, fmt.Errorf("%w %s", ErrUnknownEvent, event.Type)
+	}
+	if err := json.Unmarshal(event.Payload, data); err != nil {
+		return nil, fmt.Errorf("error unmarshal %s event payload %w", event.Type, err)
+	}
+
+	msg := &Message{Channel: channel}
+	var err error
+	// SMS без темы, в письме тема обязательна
+	if channel == models.NotificationChannelEmail {
+		if msg.Subject, err = execute(tmpl, event.Type+".subject", data); err != nil {
+			return nil, err
+		}
+	}
+	if msg.Body, err = execute(tmpl, event.Type+"."+channel, data); err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func execute(tmpl *template.Template, name string, data any) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("error executing notification template %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+func formatPoints(v any) string {
+	switch p := v.(type) {
+	case float64:
+		return fmt.Sprintf("%.2f", p)
+	case *float64:
+		if p != nil {
+			return fmt.Sprintf("%.2f", *p)
+		}
+	}
+	return ""
+}
+
+func dateFormatter(locale string) func(t time.Time) string {
+	layout := "02.01.2006"
+	if locale == "en" {
+		layout = "Jan 2, 2006"
+	}
+	return func(t time.Time) string {
+		return t.Format(layout)
+	}
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	renderer, err := NewRenderer()
+	require.NoError(t, err)
+
+	event := func(eventType string, payload any) *models.Event {
+		raw, err := json.Marshal(payload)
+		require.NoError(t, err)
+		return &models.Event{ID: 1, UserID: 1, Type: eventType, Payload: raw}
+	}
+	accrual := 42.5
+	processed := event(models.EventOrderStatus,
+		&models.OrderStatusEvent{Number: "12345678903", Status: "PROCESSED", Accrual: &accrual})
+	expiring := event(models.EventPointsExpiring,
+		&models.PointsExpiringEvent{Amount: 15, ExpiresAt: time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)})
+
+	testCases := []struct {
+		name            string
+		locale          string
+		channel         string
+		event           *models.Event
+		expectedSubject string
+		expectedBody    string
+	}{
+		{
+			name:            "Processed Order Email RU",
+			locale:          "ru",
+			channel:         models.NotificationChannelEmail,
+			event:           processed,
+			expectedSubject: "Заказ 12345678903 обработан",
+			expectedBody:    "Здравствуйте!\n\nСтатус заказа 12345678903: обработан.\nНачислено баллов: 42.50.\n",
+		},
+		{
+			name:         "Invalid Order SMS EN",
+			locale:       "en",
+			channel:      models.NotificationChannelSMS,
+			event:        event(models.EventOrderStatus, &models.OrderStatusEvent{Number: "513", Status: "INVALID"}),
+			expectedBody: "Order 513 rejected",
+		},
+		{
+			name:            "Balance Unknown Locale Falls Back",
+			locale:          "de",
+			channel:         models.NotificationChannelEmail,
+			event:           event(models.EventBalance, &models.BalanceEvent{Current: 100, Withdrawn: 20.25}),
+			expectedSubject: "Баланс баллов изменился",
+			expectedBody:    "Здравствуйте!\n\nБаланс изменился. Доступно баллов: 100.00, всего списано: 20.25.\n",
+		},
+		{
+			name:         "Points Expiring SMS EN",
+			locale:       "en",
+			channel:      models.NotificationChannelSMS,
+			event:        expiring,
+			expectedBody: "15.00 pts expire on Mar 15, 2025",
+		},
+		{
+			name:         "Points Expiring SMS RU",
+			locale:       "ru",
+			channel:      models.NotificationChannelSMS,
+			event:        expiring,
+			expectedBody: "15.00 б. сгорят 15.03.2025",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			msg, err := renderer.Render(test.locale, test.channel, test.event)
+			require.NoError(t, err)
+
+			assert.Equal(t, test.channel, msg.Channel)
+			assert.Equal(t, test.expectedSubject, msg.Subject)
+			assert.Equal(t, test.expectedBody, msg.Body)
+		})
+	}
+
+	t.Run("Unknown Event", func(t *testing.T) {
+		_, err := renderer.Render("ru", models.NotificationChannelSMS, event("unknown", struct{}{}))
+		assert.ErrorIs(t, err, ErrUnknownEvent)
+	})
+}
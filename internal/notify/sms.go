@@ -0,0 +1,27 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+)
+
+type SMSProvider interface {
+	SendSMS(ctx context.Context, phone, text string) error
+}
+
+type SMSChannel struct {
+	provider SMSProvider
+}
+
+func NewSMSChannel(provider SMSProvider) *SMSChannel {
+	return &SMSChannel{
+		provider: provider,
+	}
+}
+
+func (c *SMSChannel) Send(ctx context.Context, msg *Message) error {
+	if err := c.provider.SendSMS(ctx, msg.To, msg.Body); err != nil {
+		return fmt.Errorf("error sending sms %w", err)
+	}
+	return nil
+}
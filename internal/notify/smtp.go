@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type SMTPChannel struct {
+	addr string
+	host string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTPChannel(addr, from, username, password string) (*SMTPChannel, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing smtp address %w", err)
+	}
+
+	c := &SMTPChannel{
+		addr: addr,
+		host: host,
+		from: from,
+	}
+	if username != "" {
+		c.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return c, nil
+}
+
+func (c *SMTPChannel) Send(ctx context.Context, msg *Message) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return fmt.Errorf("error dialing smtp server %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, c.host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("error creating smtp client %w", err)
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: c.host, MinVersion: tls.VersionTLS12}); err != nil {
+			return fmt.Errorf("error starting smtp tls %w", err)
+		}
+	}
+	if c.auth != nil {
+		if err = client.Auth(c.auth); err != nil {
+			return fmt.Errorf("error smtp auth %w", err)
+		}
+	}
+	if err = client.Mail(c.from); err != nil {
+		return fmt.Errorf("error smtp mail command %w", err)
+	}
+	if err = client.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("error smtp rcpt command %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("error smtp data command %w", err)
+	}
+	if _, err = w.Write(c.mail(msg, time.Now())); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("error writing smtp message %w", err)
+	}
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("error finishing smtp message %w", err)
+	}
+	if err = client.Quit(); err != nil {
+		return fmt.Errorf("error smtp quit command %w", err)
+	}
+
+	return nil
+}
+
+func (c *SMTPChannel) mail(msg *Message, now time.Time) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: " + c.from + "\r\n")
+	buf.WriteString("To: " + msg.To + "\r\n")
+	buf.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	buf.WriteString("Date: " + now.Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return buf.Bytes()
+}
@@ -0,0 +1,332 @@
+package openapi
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/problem"
+	"github.com/Melikhov-p/go-loyalty-system/internal/statement"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestModelsMatchSchemas проверяет, что ответы обработчиков сериализуются по схемам спецификации.
+func TestModelsMatchSchemas(t *testing.T) {
+	doc, err := Load()
+	require.NoError(t, err)
+
+	now := time.Now()
+	accrual := 500.5
+	email, phone := "user@example.com", "+79991234567"
+	order := &models.Order{
+		Number:     "12345678903",
+		Status:     "PROCESSED",
+		Accrual:    &sql.NullFloat64{Float64: accrual, Valid: true},
+		UploadedAt: now,
+	}
+	batchResult := &models.BatchResult{}
+	batchResult.Add(&models.BatchLine{Line: 1, Number: "12345678903"}, models.BatchLineAccepted)
+
+	var statementBody bytes.Buffer
+	writer, _, err := statement.NewWriter(models.StatementFormatJSON, &statementBody)
+	require.NoError(t, err)
+	require.NoError(t, writer.WriteHeader(&models.Statement{To: now, OpeningBalance: 10}))
+	require.NoError(t, writer.WriteEntry(&models.StatementEntry{OccurredAt: now, Kind: "accrual", Amount: 1}))
+	require.NoError(t, writer.Close())
+
+	testCases := []struct {
+		name   string
+		schema string
+		value  any
+	}{
+		{name: "Order", schema: "Order", value: order},
+		{
+			name:   "Order Details",
+			schema: "OrderDetails",
+			value: &models.OrderDetailsResponse{
+				Order:    order,
+				Attempts: 2,
+				Timeline: []*models.OrderStatusChange{{Status: "NEW", Attempt: 0, ChangedAt: now}},
+			},
+		},
+		{
+			name:   "Order Upload",
+			schema: "OrderUpload",
+			value: &models.OrderUploadRequest{
+				Number: "12345678903",
+				OrderPurchase: models.OrderPurchase{
+					MerchantID:    "shop-1",
+					PurchaseTotal: 1500,
+					Currency:      "RUB",
+					Items:         []*models.OrderItem{{Description: "Чайник", Price: 750, Quantity: 2}},
+				},
+			},
+		},
+		{name: "Balance", schema: "Balance", value: &models.Balance{Current: 1, Withdrawn: 2, Held: 3}},
+		{
+			name:   "Balance With Tier",
+			schema: "Balance",
+			value: &models.Balance{Current: 1, Withdrawn: 2, Tier: &models.UserTier{
+				Tier:       &models.Tier{Code: "SILVER", Name: "Silver", Multiplier: 1.1, MaxWithdrawal: &accrual},
+				AssignedAt: &now,
+			}},
+		},
+		{
+			name:   "Balance With Expiring Points",
+			schema: "Balance",
+			value: &models.Balance{Current: 1, ExpiringSoon: []*models.ExpiringPoints{
+				{Amount: 1, ExpiresAt: now.AddDate(0, 0, 7)},
+			}},
+		},
+		{
+			name:   "Tier Change",
+			schema: "TierChange",
+			value:  &models.TierChange{Code: "GOLD", Name: "Gold", Points: 5000, AssignedAt: now},
+		},
+		{
+			name:   "Withdrawal",
+			schema: "Withdrawal",
+			value: &models.WithdrawHistoryItem{
+				Order:       "2377225624",
+				Sum:         1,
+				ProcessedAt: now,
+				Status:      models.WithdrawalCaptured,
+			},
+		},
+		{
+			name:   "Released Hold",
+			schema: "Hold",
+			value: &models.Hold{
+				ID:        1,
+				Order:     "2377225624",
+				Sum:       150,
+				Status:    models.WithdrawalReleased,
+				CreatedAt: now,
+				ExpiresAt: &now,
+				SettledAt: &now,
+			},
+		},
+		{
+			name:   "Referral Stats",
+			schema: "ReferralStats",
+			value: &models.ReferralStats{
+				InviteCode:   "A1B2C3D4E5",
+				Invited:      2,
+				Rewarded:     1,
+				PointsEarned: 100,
+				Limit:        50,
+			},
+		},
+		{
+			name:   "Campaign",
+			schema: "Campaign",
+			value: &models.Campaign{
+				ID:   1,
+				Name: "Двойные баллы",
+				Rule: models.CampaignRule{
+					Type:       models.CampaignRuleMultiplier,
+					Multiplier: 2,
+					MerchantID: "shop-1",
+				},
+				StartsAt:  now,
+				UserCap:   &accrual,
+				Active:    true,
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+		},
+		{
+			name:   "Campaign Dry Run",
+			schema: "CampaignDryRun",
+			value: &models.CampaignDryRunResult{
+				Grants: []*models.CampaignGrant{{
+					CampaignID:  1,
+					UserID:      1,
+					OrderNumber: "12345678903",
+					RuleType:    models.CampaignRuleFixed,
+					Points:      100,
+				}},
+				Total: 100,
+			},
+		},
+		{
+			name:   "Pending Transfer",
+			schema: "Transfer",
+			value: &models.Transfer{
+				ID:           1,
+				Direction:    models.TransferOut,
+				Counterparty: "mom",
+				Amount:       1500,
+				Comment:      "С днём рождения",
+				Status:       models.TransferPending,
+				CreatedAt:    now,
+				ExpiresAt:    &now,
+			},
+		},
+		{
+			name:   "Blocked Adjustment",
+			schema: "AccrualAdjustment",
+			value: &models.AccrualAdjustment{
+				ID:              1,
+				OrderNumber:     "12345678903",
+				UserID:          1,
+				PreviousStatus:  "PROCESSED",
+				NewStatus:       "INVALID",
+				PreviousAccrual: 500,
+				Amount:          -500,
+				Status:          models.AdjustmentBlocked,
+				CreatedAt:       now,
+				AppliedAt:       &now,
+			},
+		},
+		{
+			name:   "Program",
+			schema: "Program",
+			value: &models.Program{
+				ID:          2,
+				Code:        "brand",
+				Name:        "Brand",
+				Host:        "loyalty.brand.example",
+				TokenIssuer: "brand",
+				CreatedAt:   now,
+			},
+		},
+		{
+			name:   "Merchant Credentials",
+			schema: "MerchantCredentials",
+			value: &models.MerchantCredentials{
+				Merchant: models.Merchant{ID: 1, Code: "shop", Name: "Shop", Namespaced: true, CreatedAt: now},
+				APIKey:   "0123456789abcdef0123456789abcdef",
+			},
+		},
+		{
+			name:   "Claimed Merchant Receipt",
+			schema: "MerchantReceipt",
+			value: &models.MerchantReceipt{
+				Number:        "12345678903",
+				PurchaseTotal: 1500,
+				Currency:      "RUB",
+				CreatedAt:     now,
+				Claimed:       true,
+				ClaimedBy:     models.MaskLogin("alexander"),
+				ClaimedAt:     &now,
+				Status:        "PROCESSED",
+				Accrual:       &accrual,
+			},
+		},
+		{
+			name:   "Merchant Summary",
+			schema: "MerchantSummary",
+			value:  &models.MerchantSummary{To: &now, Receipts: 10, Claimed: 4, Processed: 3, Accrual: 1200},
+		},
+		{
+			name:   "Voided Voucher Batch",
+			schema: "VoucherBatch",
+			value: &models.VoucherBatch{
+				ID:          1,
+				Name:        "Gift card",
+				Amount:      500,
+				Count:       100,
+				MaxUses:     1,
+				ExpiresAt:   &now,
+				VoidedAt:    &now,
+				CreatedAt:   now,
+				Redemptions: 12,
+			},
+		},
+		{
+			name:   "Voucher Redemption",
+			schema: "VoucherRedemption",
+			value: &models.VoucherRedemption{
+				Code:       "ABCD-EFGH-JKLM",
+				Amount:     500,
+				RedeemedAt: now,
+				Balance:    &models.Balance{Current: 750, Withdrawn: 100},
+			},
+		},
+		{
+			name:   "Reward Without Limits",
+			schema: "Reward",
+			value: &models.Reward{
+				ID:        1,
+				Name:      "Coffee",
+				Price:     150,
+				Active:    true,
+				CreatedAt: now,
+				UpdatedAt: now,
+			},
+		},
+		{
+			name:   "Reward Redemption",
+			schema: "RewardRedemption",
+			value: &models.RewardRedemption{
+				RewardID:   1,
+				RewardName: "Coffee",
+				Code:       "ABCD-EFGH-JKLM",
+				Price:      150,
+				RedeemedAt: now,
+				Balance:    &models.Balance{Current: 350, Withdrawn: 250},
+			},
+		},
+		{
+			name:   "Default Notification Preferences",
+			schema: "NotificationPreferences",
+			value:  models.DefaultNotificationPreferences(1),
+		},
+		{
+			name:   "Notification Preferences",
+			schema: "NotificationPreferences",
+			value: &models.NotificationPreferences{
+				Email:     &email,
+				Phone:     &phone,
+				Locale:    "en",
+				Channels:  []string{models.NotificationChannelEmail, models.NotificationChannelSMS},
+				Events:    []string{models.EventOrderStatus},
+				UpdatedAt: &now,
+			},
+		},
+		{
+			name:   "Profile",
+			schema: "Profile",
+			value:  &models.Profile{Login: "user", Email: &email, Phone: &phone},
+		},
+		{name: "Batch Result", schema: "BatchResult", value: batchResult},
+		{
+			name:   "Batch Job",
+			schema: "BatchJob",
+			value:  &models.OrderBatchJob{ID: 1, Status: models.BatchJobDone, Result: batchResult, CreatedAt: now},
+		},
+		{
+			name:   "Data Export",
+			schema: "DataExport",
+			value: &models.DataExport{
+				ID: 1, Status: models.DataExportReady, Size: 2048, CreatedAt: now, FinishedAt: &now, ExpiresAt: &now,
+			},
+		},
+		{name: "Statement", schema: "Statement", value: json.RawMessage(statementBody.Bytes())},
+		{
+			name:   "Problem",
+			schema: "Problem",
+			value:  problem.New(http.StatusBadRequest, problem.CodeValidationFailed, "", problem.FieldError{Field: "login"}),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			schema, ok := doc.Components.Schemas[test.schema]
+			require.True(t, ok, "schema %s is not described", test.schema)
+
+			raw, err := json.Marshal(test.value)
+			require.NoError(t, err)
+
+			var value any
+			require.NoError(t, json.Unmarshal(raw, &value))
+			assert.NoError(t, schema.Value.VisitJSON(value))
+		})
+	}
+}
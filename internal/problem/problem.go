@@ -0,0 +1,225 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"github.com/Melikhov-p/go-loyalty-system/internal/services"
+)
+
+const ContentType = "application/problem+json"
+
+type Code string
+
+const (
+	CodeInternal             Code = "internal_error"
+	CodeMethodNotAllowed     Code = "method_not_allowed"
+	CodeUnauthorized         Code = "unauthorized"
+	CodeAlreadyAuthenticated Code = "already_authenticated"
+	CodeInvalidCredentials   Code = "invalid_credentials"
+	CodeLoginTaken           Code = "login_taken"
+	CodeMalformedJSON        Code = "malformed_json"
+	CodeEmptyBody            Code = "empty_body"
+	CodeValidationFailed     Code = "validation_failed"
+	CodeInvalidOrderNumber   Code = "invalid_order_number"
+	CodeOrderTaken           Code = "order_uploaded_by_another_user"
+	CodeOrderNotFound        Code = "order_not_found"
+	CodeNotEnoughPoints      Code = "not_enough_points"
+	CodeBadQueryParameter    Code = "bad_query_parameter"
+	CodeUnsupportedMediaType Code = "unsupported_media_type"
+	CodePayloadTooLarge      Code = "payload_too_large"
+	CodeBatchJobNotFound     Code = "batch_job_not_found"
+	CodeBadCookie            Code = "bad_cookie"
+	CodeNotFound             Code = "not_found"
+	CodeUnsupportedVersion   Code = "unsupported_api_version"
+	CodeWithdrawalLimit      Code = "withdrawal_limit_exceeded"
+	CodeCampaignNotFound     Code = "campaign_not_found"
+	CodeCampaignInUse        Code = "campaign_in_use"
+	CodeInvalidReferralCode  Code = "invalid_referral_code"
+	CodeRecipientNotFound    Code = "recipient_not_found"
+	CodeSelfTransfer         Code = "self_transfer"
+	CodeTransferNotFound     Code = "transfer_not_found"
+	CodeTransferExpired      Code = "transfer_expired"
+	CodeTransferLimit        Code = "transfer_limit_exceeded"
+	CodeHoldNotFound         Code = "hold_not_found"
+	CodeHoldNotActive        Code = "hold_not_active"
+	CodeWithdrawalExists     Code = "withdrawal_exists"
+	CodeWithdrawalsBlocked   Code = "withdrawals_blocked"
+	CodeAdjustmentNotFound   Code = "adjustment_not_found"
+	CodeAdjustmentResolved   Code = "adjustment_resolved"
+	CodeOrderStatusConflict  Code = "order_status_conflict"
+	CodeProgramNotFound      Code = "program_not_found"
+	CodeProgramExists        Code = "program_exists"
+	CodeMerchantExists       Code = "merchant_exists"
+	CodeReceiptExists        Code = "receipt_exists"
+	CodeCustomerNotFound     Code = "customer_not_found"
+	CodeVoucherBatchNotFound Code = "voucher_batch_not_found"
+	CodeVoucherNotFound      Code = "voucher_not_found"
+	CodeVoucherExpired       Code = "voucher_expired"
+	CodeVoucherUsedUp        Code = "voucher_used_up"
+	CodeVoucherRedeemed      Code = "voucher_already_redeemed"
+	CodeRewardNotFound       Code = "reward_not_found"
+	CodeRewardOutOfStock     Code = "reward_out_of_stock"
+	CodeRewardLimit          Code = "reward_limit_reached"
+	CodeWrongPassword        Code = "wrong_current_password"
+	CodeExportNotFound       Code = "data_export_not_found"
+	CodeExportNotReady       Code = "data_export_not_ready"
+	CodeExportExpired        Code = "data_export_expired"
+)
+
+var titles = map[Code]string{
+	CodeInternal:             "Internal server error",
+	CodeMethodNotAllowed:     "Method not allowed",
+	CodeUnauthorized:         "Authentication required",
+	CodeAlreadyAuthenticated: "User is already authenticated",
+	CodeInvalidCredentials:   "Invalid login or password",
+	CodeLoginTaken:           "Login is already taken",
+	CodeMalformedJSON:        "Request body is not valid JSON",
+	CodeEmptyBody:            "Request body is empty",
+	CodeValidationFailed:     "Request validation failed",
+	CodeInvalidOrderNumber:   "Order number is invalid",
+	CodeOrderTaken:           "Order was uploaded by another user",
+	CodeOrderNotFound:        "Order not found",
+	CodeNotEnoughPoints:      "Not enough points on balance",
+	CodeBadQueryParameter:    "Bad query parameter",
+	CodeUnsupportedMediaType: "Unsupported media type",
+	CodePayloadTooLarge:      "Request body is too large",
+	CodeBatchJobNotFound:     "Batch job not found",
+	CodeBadCookie:            "Authentication cookie is malformed",
+	CodeNotFound:             "Resource not found",
+	CodeUnsupportedVersion:   "API version is not supported",
+	CodeWithdrawalLimit:      "Withdrawal exceeds tier limit",
+	CodeCampaignNotFound:     "Campaign not found",
+	CodeCampaignInUse:        "Campaign has granted points",
+	CodeInvalidReferralCode:  "Referral code is unknown",
+	CodeRecipientNotFound:    "Transfer recipient not found",
+	CodeSelfTransfer:         "Can not transfer points to yourself",
+	CodeTransferNotFound:     "Pending transfer not found",
+	CodeTransferExpired:      "Transfer confirmation expired",
+	CodeTransferLimit:        "Transfer exceeds daily limit",
+	CodeHoldNotFound:         "Hold not found",
+	CodeHoldNotActive:        "Hold is already settled",
+	CodeWithdrawalExists:     "Order already has a withdrawal",
+	CodeWithdrawalsBlocked:   "Withdrawals are blocked pending review",
+	CodeAdjustmentNotFound:   "Adjustment not found",
+	CodeAdjustmentResolved:   "Adjustment is already resolved",
+	CodeOrderStatusConflict:  "Order status does not allow this action",
+	CodeProgramNotFound:      "Loyalty program not found",
+	CodeProgramExists:        "Loyalty program already exists",
+	CodeMerchantExists:       "Merchant already exists",
+	CodeReceiptExists:        "Receipt is already registered",
+	CodeCustomerNotFound:     "Customer not found",
+	CodeVoucherBatchNotFound: "Voucher batch not found",
+	CodeVoucherNotFound:      "Voucher code not found",
+	CodeVoucherExpired:       "Voucher is expired or voided",
+	CodeVoucherUsedUp:        "Voucher has no uses left",
+	CodeVoucherRedeemed:      "Voucher is already redeemed",
+	CodeRewardNotFound:       "Reward not found",
+	CodeRewardOutOfStock:     "Reward is out of stock",
+	CodeRewardLimit:          "Reward limit per user is reached",
+	CodeWrongPassword:        "Current password is incorrect",
+	CodeExportNotFound:       "Data export not found",
+	CodeExportNotReady:       "Data export archive is not ready",
+	CodeExportExpired:        "Data export archive has expired",
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Code      Code         `json:"code"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+var mapping = []struct {
+	err    error
+	status int
+	code   Code
+}{
+	{repository.ErrUserWithLoginExist, http.StatusConflict, CodeLoginTaken},
+	{repository.ErrOrderNumberExist, http.StatusConflict, CodeOrderTaken},
+	{repository.ErrOrderNumberNotFound, http.StatusNotFound, CodeOrderNotFound},
+	{repository.ErrOrderStatusConflict, http.StatusConflict, CodeOrderStatusConflict},
+	{repository.ErrBatchJobNotFound, http.StatusNotFound, CodeBatchJobNotFound},
+	{repository.ErrCampaignNotFound, http.StatusNotFound, CodeCampaignNotFound},
+	{repository.ErrCampaignInUse, http.StatusConflict, CodeCampaignInUse},
+	{repository.ErrReferralCodeNotFound, http.StatusUnprocessableEntity, CodeInvalidReferralCode},
+	{repository.ErrTransferNotFound, http.StatusNotFound, CodeTransferNotFound},
+	{repository.ErrTransferExpired, http.StatusGone, CodeTransferExpired},
+	{repository.ErrTransferDailyLimit, http.StatusUnprocessableEntity, CodeTransferLimit},
+	{repository.ErrHoldNotFound, http.StatusNotFound, CodeHoldNotFound},
+	{repository.ErrHoldNotActive, http.StatusConflict, CodeHoldNotActive},
+	{repository.ErrWithdrawalExists, http.StatusConflict, CodeWithdrawalExists},
+	{repository.ErrAdjustmentNotFound, http.StatusNotFound, CodeAdjustmentNotFound},
+	{repository.ErrAdjustmentResolved, http.StatusConflict, CodeAdjustmentResolved},
+	{repository.ErrProgramNotFound, http.StatusNotFound, CodeProgramNotFound},
+	{repository.ErrProgramExists, http.StatusConflict, CodeProgramExists},
+	{repository.ErrMerchantExists, http.StatusConflict, CodeMerchantExists},
+	{repository.ErrReceiptExists, http.StatusConflict, CodeReceiptExists},
+	{repository.ErrVoucherBatchNotFound, http.StatusNotFound, CodeVoucherBatchNotFound},
+	{repository.ErrVoucherNotFound, http.StatusNotFound, CodeVoucherNotFound},
+	{repository.ErrVoucherExpired, http.StatusGone, CodeVoucherExpired},
+	{repository.ErrVoucherUsedUp, http.StatusConflict, CodeVoucherUsedUp},
+	{repository.ErrVoucherRedeemed, http.StatusConflict, CodeVoucherRedeemed},
+	{repository.ErrRewardNotFound, http.StatusNotFound, CodeRewardNotFound},
+	{repository.ErrRewardOutOfStock, http.StatusConflict, CodeRewardOutOfStock},
+	{repository.ErrRewardLimitReached, http.StatusConflict, CodeRewardLimit},
+	{repository.ErrExportNotFound, http.StatusNotFound, CodeExportNotFound},
+	{repository.ErrExportExpired, http.StatusGone, CodeExportExpired},
+	{repository.ErrUnknownSort, http.StatusBadRequest, CodeBadQueryParameter},
+	{services.ErrNotEnough, http.StatusPaymentRequired, CodeNotEnoughPoints},
+	{services.ErrWithdrawalLimit, http.StatusUnprocessableEntity, CodeWithdrawalLimit},
+	{services.ErrWithdrawalsBlocked, http.StatusForbidden, CodeWithdrawalsBlocked},
+	{services.ErrRecipientNotFound, http.StatusUnprocessableEntity, CodeRecipientNotFound},
+	{services.ErrCustomerNotFound, http.StatusUnprocessableEntity, CodeCustomerNotFound},
+	{services.ErrSelfTransfer, http.StatusUnprocessableEntity, CodeSelfTransfer},
+	{services.ErrIncorrectPass, http.StatusUnauthorized, CodeInvalidCredentials},
+	{services.ErrWrongPassword, http.StatusForbidden, CodeWrongPassword},
+	{services.ErrExportNotReady, http.StatusConflict, CodeExportNotReady},
+}
+
+func New(status int, code Code, detail string, fields ...FieldError) *Problem {
+	return &Problem{
+		Type:   "urn:gophermart:problem:" + string(code),
+		Title:  titles[code],
+		Status: status,
+		Code:   code,
+		Detail: detail,
+		Errors: fields,
+	}
+}
+
+// FromError неизвестные ошибки становятся internal_error без деталей.
+func FromError(err error) *Problem {
+	for _, m := range mapping {
+		if errors.Is(err, m.err) {
+			return New(m.status, m.code, m.err.Error())
+		}
+	}
+
+	return New(http.StatusInternalServerError, CodeInternal, "")
+}
+
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	p.Instance = r.URL.Path
+	if requestID, ok := r.Context().Value(contextkeys.ContextRequestIDKey).(string); ok {
+		p.RequestID = requestID
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+
+	_ = json.NewEncoder(w).Encode(p)
+}
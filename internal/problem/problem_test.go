@@ -0,0 +1,114 @@
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/contextkeys"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"github.com/Melikhov-p/go-loyalty-system/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromError(t *testing.T) {
+	testCases := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   Code
+	}{
+		{
+			name:           "Wrapped Not Enough",
+			err:            fmt.Errorf("error withdraw %w", services.ErrNotEnough),
+			expectedStatus: http.StatusPaymentRequired,
+			expectedCode:   CodeNotEnoughPoints,
+		},
+		{
+			name:           "Order Not Found",
+			err:            repository.ErrOrderNumberNotFound,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   CodeOrderNotFound,
+		},
+		{
+			name:           "Duplicate Withdrawal",
+			err:            fmt.Errorf("error withdraw %w", repository.ErrWithdrawalExists),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   CodeWithdrawalExists,
+		},
+		{
+			name:           "Receipt Registered Twice",
+			err:            fmt.Errorf("error registering receipt %w", repository.ErrReceiptExists),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   CodeReceiptExists,
+		},
+		{
+			name:           "Voucher Redeemed Twice",
+			err:            fmt.Errorf("error redeeming voucher %w", repository.ErrVoucherRedeemed),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   CodeVoucherRedeemed,
+		},
+		{
+			name:           "Reward Out Of Stock",
+			err:            fmt.Errorf("error redeeming reward 1: %w", repository.ErrRewardOutOfStock),
+			expectedStatus: http.StatusConflict,
+			expectedCode:   CodeRewardOutOfStock,
+		},
+		{
+			name:           "Wrong Current Password",
+			err:            fmt.Errorf("error changing password %w", services.ErrWrongPassword),
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   CodeWrongPassword,
+		},
+		{
+			name:           "Expired Data Export",
+			err:            fmt.Errorf("error getting data export archive 1: %w", repository.ErrExportExpired),
+			expectedStatus: http.StatusGone,
+			expectedCode:   CodeExportExpired,
+		},
+		{
+			name:           "Data Export Not Ready",
+			err:            services.ErrExportNotReady,
+			expectedStatus: http.StatusConflict,
+			expectedCode:   CodeExportNotReady,
+		},
+		{
+			name:           "Unknown Error",
+			err:            fmt.Errorf("connection refused"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   CodeInternal,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			p := FromError(test.err)
+			assert.Equal(t, test.expectedStatus, p.Status)
+			assert.Equal(t, test.expectedCode, p.Code)
+			assert.Equal(t, "urn:gophermart:problem:"+string(test.expectedCode), p.Type)
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/api/user/register", nil)
+	r = r.WithContext(context.WithValue(r.Context(), contextkeys.ContextRequestIDKey, "req-1"))
+	w := httptest.NewRecorder()
+
+	Write(w, r, New(http.StatusBadRequest, CodeValidationFailed, "",
+		FieldError{Field: "login", Message: "must not be empty"}))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+
+	var got Problem
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	assert.Equal(t, CodeValidationFailed, got.Code)
+	assert.Equal(t, "/api/user/register", got.Instance)
+	assert.Equal(t, "req-1", got.RequestID)
+	assert.Equal(t, []FieldError{{Field: "login", Message: "must not be empty"}}, got.Errors)
+}
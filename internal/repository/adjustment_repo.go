@@ -0,0 +1,416 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"go.uber.org/zap"
+)
+
+const lotSourceAdjustment = "adjustment"
+
+type AdjustmentRepo struct {
+	logger *zap.Logger
+	cfg    *config.Config
+	db     *sql.DB
+}
+
+func NewAdjustmentRepo(logger *zap.Logger, cfg *config.Config, db *sql.DB) *AdjustmentRepo {
+	return &AdjustmentRepo{
+		logger: logger,
+		cfg:    cfg,
+		db:     db,
+	}
+}
+
+func (ar *AdjustmentRepo) ScheduleVerification(
+	ctx context.Context,
+	programID int,
+	orderNumber string,
+	until time.Time,
+) error {
+	query := `INSERT INTO order_verification (program_id, order_number, verify_until) VALUES ($1, $2, $3)
+	ON CONFLICT (program_id, order_number) DO UPDATE SET verify_until = EXCLUDED.verify_until`
+
+	if _, err := ar.db.ExecContext(ctx, query, programID, orderNumber, until); err != nil {
+		return fmt.Errorf("error executing context for schedule verification of order %s: %w", orderNumber, err)
+	}
+
+	return nil
+}
+
+func (ar *AdjustmentRepo) GetOrdersToVerify(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+) ([]*models.WatchedOrder, error) {
+	deleteQuery := `DELETE FROM order_verification WHERE verify_until <= $1`
+	query := `SELECT o.number, o.user_id, o.status, COALESCE(o.accrual, 0), o.program_id,
+		COALESCE(o.receipt_number, ''), COALESCE(p.accrual_addr, '')
+	FROM order_verification v
+	JOIN "order" o ON o.program_id = v.program_id AND o.number = v.order_number
+	JOIN program p ON p.id = o.program_id
+	WHERE NOT EXISTS (
+		SELECT 1 FROM accrual_adjustment a
+		WHERE a.program_id = v.program_id AND a.order_number = v.order_number AND a.status = 'REVIEW'
+	)
+	ORDER BY v.checked_at NULLS FIRST, v.order_number
+	LIMIT $1`
+
+	if _, err := ar.db.ExecContext(ctx, deleteQuery, now); err != nil {
+		return nil, fmt.Errorf("error executing context for delete finished verifications %w", err)
+	}
+
+	rows, err := ar.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for orders to verify %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var orders []*models.WatchedOrder
+	for rows.Next() {
+		var order models.WatchedOrder
+		err = rows.Scan(&order.OrderNumber, &order.UserID, &order.AccrualOrderStatus, &order.AccrualPoints,
+			&order.ProgramID, &order.ReceiptNumber, &order.AccrualAddr)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row for order to verify %w", err)
+		}
+		orders = append(orders, &order)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return orders, nil
+}
+
+func (ar *AdjustmentRepo) MarkVerified(ctx context.Context, programID int, orderNumber string, now time.Time) error {
+	query := `UPDATE order_verification SET checked_at = $3 WHERE program_id = $1 AND order_number = $2`
+
+	if _, err := ar.db.ExecContext(ctx, query, programID, orderNumber, now); err != nil {
+		return fmt.Errorf("error executing context for mark order %s verified: %w", orderNumber, err)
+	}
+
+	return nil
+}
+
+// ApplyAccrualChange отзыв больше текущего баланса обрабатывается по policy.
+func (ar *AdjustmentRepo) ApplyAccrualChange(
+	ctx context.Context,
+	order *models.WatchedOrder,
+	policy string,
+	expiresAt, now time.Time,
+) (*models.AdjustmentResult, error) {
+	lockQuery := `SELECT status, COALESCE(accrual, 0), COALESCE(credited, accrual, 0), COALESCE(multiplier, 1)
+	FROM "order" WHERE number = $1 AND program_id = $2
+	FOR UPDATE`
+	orderQuery := `UPDATE "order" SET status = $3, accrual = $4 WHERE number = $1 AND program_id = $2`
+	historyQuery := `INSERT INTO order_status_history (order_number, program_id, status, accrual)
+	VALUES ($1, $2, $3, $4)`
+	adjustedQuery := `SELECT COALESCE(SUM(amount), 0) FROM accrual_adjustment
+	WHERE order_number = $1 AND program_id = $2 AND applied_at IS NOT NULL AND status <> 'DISMISSED'`
+	currentQuery := `SELECT current FROM balance WHERE user_id = $1 AND program_id = $2 FOR UPDATE`
+	insertQuery := `INSERT INTO accrual_adjustment (order_number, user_id, previous_status, new_status,
+		previous_accrual, new_accrual, amount, status, created_at, applied_at, program_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	RETURNING id`
+
+	tx, err := ar.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for accrual change %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	adj := &models.AccrualAdjustment{
+		OrderNumber: order.OrderNumber,
+		UserID:      order.UserID,
+		NewStatus:   order.AccrualOrderStatus,
+		NewAccrual:  order.AccrualPoints,
+		CreatedAt:   now,
+	}
+	var credited, multiplier float64
+	err = tx.QueryRowContext(ctx, lockQuery, order.OrderNumber, order.ProgramID).
+		Scan(&adj.PreviousStatus, &adj.PreviousAccrual, &credited, &multiplier)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrderNumberNotFound
+		}
+		return nil, fmt.Errorf("error scanning row for order %s: %w", order.OrderNumber, err)
+	}
+
+	result := &models.AdjustmentResult{}
+	if adj.PreviousStatus == adj.NewStatus && adj.PreviousAccrual == adj.NewAccrual {
+		return result, nil
+	}
+
+	// по недействительному заказу ничего не зачисляется, промежуточные статусы не корректируются
+	var due float64
+	switch adj.NewStatus {
+	case "PROCESSED":
+		due = math.Round(adj.NewAccrual*multiplier*100) / 100
+	case "INVALID":
+	default:
+		return result, nil
+	}
+
+	_, err = tx.ExecContext(ctx, orderQuery, order.OrderNumber, order.ProgramID, adj.NewStatus, adj.NewAccrual)
+	if err != nil {
+		return nil, fmt.Errorf("error executing context for update order %s: %w", order.OrderNumber, err)
+	}
+	_, err = tx.ExecContext(ctx, historyQuery, order.OrderNumber, order.ProgramID, adj.NewStatus, adj.NewAccrual)
+	if err != nil {
+		return nil, fmt.Errorf("error executing context for order status history %w", err)
+	}
+	events := []pendingEvent{orderStatusEvent(order.UserID, order.OrderNumber, adj.NewStatus, adj.NewAccrual)}
+
+	var adjusted float64
+	if err = tx.QueryRowContext(ctx, adjustedQuery, order.OrderNumber, order.ProgramID).Scan(&adjusted); err != nil {
+		return nil, fmt.Errorf("error scanning row for order adjustments %w", err)
+	}
+
+	adj.Amount = math.Round((due-credited-adjusted)*100) / 100
+	if adj.Amount == 0 {
+		if _, err = addEvents(ctx, tx, events...); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	var current float64
+	if err = tx.QueryRowContext(ctx, currentQuery, order.UserID, order.ProgramID).Scan(&current); err != nil {
+		return nil, fmt.Errorf("error scanning row for user balance %w", err)
+	}
+
+	adj.Status = models.AdjustmentApplied
+	if current+adj.Amount < 0 {
+		switch policy {
+		case models.NegativeReview:
+			adj.Status = models.AdjustmentReview
+		case models.NegativeBlockWithdrawals:
+			adj.Status = models.AdjustmentBlocked
+		}
+	}
+
+	if adj.Status != models.AdjustmentReview {
+		adj.AppliedAt = &now
+		result.Balance, err = adjustBalance(ctx, tx, order.UserID, order.OrderNumber, adj.Amount, expiresAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, insertQuery, adj.OrderNumber, adj.UserID, adj.PreviousStatus, adj.NewStatus,
+		adj.PreviousAccrual, adj.NewAccrual, adj.Amount, adj.Status, now, adj.AppliedAt, order.ProgramID).Scan(&adj.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error executing context for insert adjustment %w", err)
+	}
+	result.Adjustment = adj
+
+	if adj.Status == models.AdjustmentBlocked {
+		if result.Balance, err = syncWithdrawalBlock(ctx, tx, order.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	if result.Balance != nil {
+		events = append(events, balanceEvent(order.UserID, result.Balance))
+	}
+	if _, err = addEvents(ctx, tx, events...); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// adjustBalance баланс может уйти в минус, партии списываются только в пределах остатка.
+func adjustBalance(
+	ctx context.Context,
+	tx *sql.Tx,
+	userID int,
+	orderNumber string,
+	amount float64,
+	expiresAt time.Time,
+) (*models.Balance, error) {
+	query := `UPDATE balance SET current = current + $1 WHERE user_id = $2 RETURNING current, withdrawn, held`
+
+	var balance models.Balance
+	if err := tx.QueryRowContext(ctx, query, amount, userID).
+		Scan(&balance.Current, &balance.Withdrawn, &balance.Held); err != nil {
+		return nil, fmt.Errorf("error executing context for adjust balance %w", err)
+	}
+
+	if amount > 0 {
+		if err := addLot(ctx, tx, userID, lotSourceAdjustment, orderNumber, amount, expiresAt); err != nil {
+			return nil, fmt.Errorf("error opening point lot for adjustment %w", err)
+		}
+		return &balance, nil
+	}
+	if _, err := consumeLots(ctx, tx, userID, -amount); err != nil {
+		return nil, fmt.Errorf("error consuming point lots for adjustment %w", err)
+	}
+
+	return &balance, nil
+}
+
+func syncWithdrawalBlock(ctx context.Context, tx *sql.Tx, userID int) (*models.Balance, error) {
+	query := `UPDATE balance SET withdrawals_blocked = EXISTS (
+		SELECT 1 FROM accrual_adjustment WHERE user_id = $1 AND status = 'BLOCKED'
+	)
+	WHERE user_id = $1
+	RETURNING current, withdrawn, held`
+
+	var balance models.Balance
+	err := tx.QueryRowContext(ctx, query, userID).Scan(&balance.Current, &balance.Withdrawn, &balance.Held)
+	if err != nil {
+		return nil, fmt.Errorf("error executing context for withdrawal block %w", err)
+	}
+
+	return &balance, nil
+}
+
+func (ar *AdjustmentRepo) GetAdjustments(ctx context.Context, status string) ([]*models.AccrualAdjustment, error) {
+	query := `SELECT id, order_number, user_id, previous_status, new_status, previous_accrual, new_accrual,
+		amount, status, created_at, applied_at, resolved_at
+	FROM accrual_adjustment
+	WHERE $1 = '' OR status = $1
+	ORDER BY id DESC`
+
+	rows, err := ar.db.QueryContext(ctx, query, status)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for adjustments %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	adjustments := make([]*models.AccrualAdjustment, 0)
+	for rows.Next() {
+		var adj *models.AccrualAdjustment
+		if adj, err = scanAdjustment(rows); err != nil {
+			return nil, err
+		}
+		adjustments = append(adjustments, adj)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return adjustments, nil
+}
+
+// ResolveAdjustment одобрение BLOCKED оставляет отзыв в силе, отклонение возвращает баллы.
+func (ar *AdjustmentRepo) ResolveAdjustment(
+	ctx context.Context,
+	id int,
+	approve bool,
+	expiresAt, now time.Time,
+) (*models.AdjustmentResult, error) {
+	lockQuery := `SELECT id, order_number, user_id, previous_status, new_status, previous_accrual, new_accrual,
+		amount, status, created_at, applied_at, resolved_at
+	FROM accrual_adjustment
+	WHERE id = $1
+	FOR UPDATE`
+	resolveQuery := `UPDATE accrual_adjustment SET status = $2, applied_at = $3, resolved_at = $4 WHERE id = $1`
+
+	tx, err := ar.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for resolve adjustment %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	adj, err := scanAdjustment(tx.QueryRowContext(ctx, lockQuery, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAdjustmentNotFound
+		}
+		return nil, err
+	}
+
+	result := &models.AdjustmentResult{Adjustment: adj}
+	wasBlocked := adj.Status == models.AdjustmentBlocked
+	switch {
+	case adj.Status == models.AdjustmentReview && approve:
+		adj.Status = models.AdjustmentApplied
+		adj.AppliedAt = &now
+		result.Balance, err = adjustBalance(ctx, tx, adj.UserID, adj.OrderNumber, adj.Amount, expiresAt)
+	case adj.Status == models.AdjustmentReview:
+		adj.Status = models.AdjustmentDismissed
+	case adj.Status == models.AdjustmentBlocked && approve:
+		adj.Status = models.AdjustmentApplied
+	case adj.Status == models.AdjustmentBlocked:
+		adj.Status = models.AdjustmentDismissed
+		_, err = adjustBalance(ctx, tx, adj.UserID, adj.OrderNumber, -adj.Amount, expiresAt)
+	default:
+		return nil, fmt.Errorf("adjustment %d is %s: %w", id, adj.Status, ErrAdjustmentResolved)
+	}
+	if err != nil {
+		return nil, err
+	}
+	adj.ResolvedAt = &now
+
+	if _, err = tx.ExecContext(ctx, resolveQuery, id, adj.Status, adj.AppliedAt, now); err != nil {
+		return nil, fmt.Errorf("error executing context for resolve adjustment %d: %w", id, err)
+	}
+	if wasBlocked {
+		if result.Balance, err = syncWithdrawalBlock(ctx, tx, adj.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	if result.Balance != nil {
+		if _, err = addEvents(ctx, tx, balanceEvent(adj.UserID, result.Balance)); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func scanAdjustment(row interface{ Scan(dest ...any) error }) (*models.AccrualAdjustment, error) {
+	var adj models.AccrualAdjustment
+	var appliedAt, resolvedAt sql.NullTime
+	err := row.Scan(&adj.ID, &adj.OrderNumber, &adj.UserID, &adj.PreviousStatus, &adj.NewStatus,
+		&adj.PreviousAccrual, &adj.NewAccrual, &adj.Amount, &adj.Status, &adj.CreatedAt, &appliedAt, &resolvedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error scanning row for adjustment %w", err)
+	}
+	if appliedAt.Valid {
+		adj.AppliedAt = &appliedAt.Time
+	}
+	if resolvedAt.Valid {
+		adj.ResolvedAt = &resolvedAt.Time
+	}
+
+	return &adj, nil
+}
+
+func (br *BalanceRepo) WithdrawalsBlocked(ctx context.Context, userID int) (bool, error) {
+	query := `SELECT COALESCE((SELECT withdrawals_blocked FROM balance WHERE user_id = $1), false)`
+
+	var blocked bool
+	if err := br.db.QueryRowContext(ctx, query, userID).Scan(&blocked); err != nil {
+		return false, fmt.Errorf("error scanning row for withdrawals block %w", err)
+	}
+
+	return blocked, nil
+}
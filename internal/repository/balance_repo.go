@@ -9,6 +9,7 @@ import (
 
 	"github.com/Melikhov-p/go-loyalty-system/internal/config"
 	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
 	"go.uber.org/zap"
 )
 
@@ -26,13 +27,13 @@ func NewEmptyBalance() *models.Balance {
 }
 
 func (br *BalanceRepo) GetUserBalance(ctx context.Context, user *models.User) error {
-	query := `SELECT current, withdrawn FROM balance WHERE user_id=$1`
+	query := `SELECT current, withdrawn, held FROM balance WHERE user_id=$1 AND program_id=$2`
 
 	ctx, cancel := context.WithTimeout(ctx, br.cfg.DB.ContextTimeout)
 	defer cancel()
 
-	row := br.db.QueryRowContext(ctx, query, user.ID)
-	if err := row.Scan(&user.BalanceInfo.Current, &user.BalanceInfo.Withdrawn); err != nil {
+	row := br.db.QueryRowContext(ctx, query, user.ID, user.ProgramID)
+	if err := row.Scan(&user.BalanceInfo.Current, &user.BalanceInfo.Withdrawn, &user.BalanceInfo.Held); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			err = br.AddNewBalanceForUser(ctx, user)
 			if err != nil {
@@ -47,12 +48,12 @@ func (br *BalanceRepo) GetUserBalance(ctx context.Context, user *models.User) er
 }
 
 func (br *BalanceRepo) AddNewBalanceForUser(ctx context.Context, user *models.User) error {
-	query := `INSERT INTO balance (user_id) VALUES ($1)`
+	query := `INSERT INTO balance (user_id, program_id) VALUES ($1, $2)`
 
 	ctx, cancel := context.WithTimeout(ctx, br.cfg.DB.ContextTimeout)
 	defer cancel()
 
-	_, err := br.db.ExecContext(ctx, query, user.ID)
+	_, err := br.db.ExecContext(ctx, query, user.ID, user.ProgramID)
 	if err != nil {
 		return fmt.Errorf("error executing context for create balance for user %w", err)
 	}
@@ -67,7 +68,7 @@ func (br *BalanceRepo) Withdraw(
 	user *models.User,
 	sum float64,
 ) (*models.Balance, error) {
-	tx, err := br.db.Begin()
+	tx, err := br.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error starting transaction for withdraw %w", err)
 	}
@@ -79,79 +80,230 @@ func (br *BalanceRepo) Withdraw(
 		}
 	}()
 
+	// остаток проверяется в том же UPDATE, как при резерве
 	withdrawQuery := `UPDATE balance
 	SET
 		current = current - $1,
 		withdrawn = withdrawn + $1
 	WHERE
-		user_id = $2`
+		user_id = $2 AND program_id = $3 AND current >= $1
+	RETURNING current, withdrawn, held`
 
-	_, err = tx.ExecContext(ctx, withdrawQuery, sum, user.ID)
+	newBalance := &models.Balance{}
+	err = tx.QueryRowContext(ctx, withdrawQuery, sum, user.ID, user.ProgramID).
+		Scan(&newBalance.Current, &newBalance.Withdrawn, &newBalance.Held)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrInsufficientBalance
+			return nil, err
+		}
 		return nil, fmt.Errorf("error executing context for withdraw query %w", err)
 	}
 
-	historyQuery := `INSERT into withdraw_history (order_number, sum, processed_at, user_id) VALUES ($1, $2, $3, $4)`
+	lots, err := consumeLots(ctx, tx, user.ID, sum)
+	if err != nil {
+		return nil, fmt.Errorf("error consuming point lots for withdraw %w", err)
+	}
+
+	historyQuery := `INSERT into withdraw_history (order_number, sum, processed_at, user_id, program_id)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id`
 
-	_, err = tx.ExecContext(
+	var withdrawID int
+	err = tx.QueryRowContext(
 		ctx,
 		historyQuery,
 		order.Number,
 		sum,
 		time.Now().Format(time.DateTime),
-		user.ID)
+		user.ID,
+		user.ProgramID).Scan(&withdrawID)
 	if err != nil {
+		if isWithdrawOrderTaken(err) {
+			return nil, ErrWithdrawalExists
+		}
 		return nil, fmt.Errorf("error executing context for history query %w", err)
 	}
-
-	newBalance := &models.Balance{
-		Current:   user.BalanceInfo.Current - sum,
-		Withdrawn: user.BalanceInfo.Withdrawn + sum,
+	if err = recordWithdrawLots(ctx, tx, withdrawID, lots); err != nil {
+		return nil, err
 	}
 
 	return newBalance, nil
 }
 
-func (br *BalanceRepo) GetUserHistory(ctx context.Context, user *models.User) ([]*models.WithdrawHistoryItem, error) {
-	query := `SELECT order_number, sum, processed_at FROM withdraw_history WHERE user_id = $1`
+func isWithdrawOrderTaken(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "withdraw_history_order_key"
+}
 
-	rows, err := br.db.QueryContext(ctx, query, user.ID)
+var withdrawSortKeys = map[string]sortKey{
+	"processed_at": {expr: "processed_at", cast: "timestamp"},
+	"sum":          {expr: "sum", cast: "numeric"},
+}
+
+func (br *BalanceRepo) GetUserHistory(
+	ctx context.Context,
+	user *models.User,
+	filter *models.WithdrawListFilter,
+) ([]*models.WithdrawHistoryItem, *models.Cursor, error) {
+	key, ok := withdrawSortKeys[filter.Sort]
+	if !ok {
+		return nil, nil, ErrUnknownSort
+	}
+
+	qb := &queryBuilder{}
+	qb.where("program_id = " + qb.arg(user.ProgramID))
+	qb.where("user_id = " + qb.arg(user.ID))
+	if filter.From != nil {
+		qb.where("processed_at >= " + qb.arg(*filter.From))
+	}
+	if filter.To != nil {
+		qb.where("processed_at < " + qb.arg(*filter.To))
+	}
+	if filter.MinSum != nil {
+		qb.where("sum >= " + qb.arg(*filter.MinSum))
+	}
+	if filter.MaxSum != nil {
+		qb.where("sum <= " + qb.arg(*filter.MaxSum))
+	}
+	orderBy := qb.keyset(key, &filter.ListPage)
+
+	query := `SELECT id, order_number, sum, processed_at, status, expires_at, settled_at, ` + key.expr + `::text
+	FROM withdraw_history
+	WHERE ` + qb.whereSQL() + orderBy
+
+	rows, err := br.db.QueryContext(ctx, query, qb.args...)
 	if err != nil {
-		return nil, fmt.Errorf("error query context for user balance history %w", err)
+		return nil, nil, fmt.Errorf("error query context for user balance history %w", err)
 	}
 	defer func() {
 		_ = rows.Close()
 	}()
 
-	var history []*models.WithdrawHistoryItem
+	var (
+		history []*models.WithdrawHistoryItem
+		lastKey string
+		cursor  *models.Cursor
+	)
 	for rows.Next() {
 		var item models.WithdrawHistoryItem
-		if err = rows.Scan(&item.Order, &item.Sum, &item.ProcessedAt); err != nil {
-			return nil, fmt.Errorf("error scannning row for balance history %w", err)
+		var sortValue string
+		var expiresAt, settledAt sql.NullTime
+		err = rows.Scan(&item.ID, &item.Order, &item.Sum, &item.ProcessedAt, &item.Status, &expiresAt, &settledAt,
+			&sortValue)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error scannning row for balance history %w", err)
+		}
+		if expiresAt.Valid {
+			item.ExpiresAt = &expiresAt.Time
+		}
+		if settledAt.Valid {
+			item.SettledAt = &settledAt.Time
+		}
+		if len(history) == filter.Limit {
+			cursor = nextCursor(&filter.ListPage, lastKey, history[len(history)-1].ID)
+			break
 		}
 		history = append(history, &item)
+		lastKey = sortValue
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("got rows.Err() %w", err)
+		return nil, nil, fmt.Errorf("got rows.Err() %w", err)
 	}
 
 	if len(history) == 0 {
-		return nil, ErrEmptyBalanceHistory
+		return nil, nil, ErrEmptyBalanceHistory
+	}
+
+	return history, cursor, nil
+}
+
+func (br *BalanceRepo) IncreaseBalanceByUserID(
+	ctx context.Context,
+	programID, id int,
+	diff float64,
+) (*models.Balance, error) {
+	query := `UPDATE balance SET current = current + $1 WHERE user_id = $2 AND program_id = $3
+	RETURNING current, withdrawn`
+
+	var balance models.Balance
+	row := br.db.QueryRowContext(ctx, query, diff, id, programID)
+	if err := row.Scan(&balance.Current, &balance.Withdrawn); err != nil {
+		return nil, fmt.Errorf("error executing context for update user balance %w", err)
 	}
 
-	return history, nil
+	return &balance, nil
 }
 
-func (br *BalanceRepo) IncreaseBalanceByUserID(ctx context.Context, id int, diff float64) error {
-	query := `UPDATE balance SET current = current + $1 WHERE user_id = $2`
+// CreditOrderAccrual сохраняет зачисленную сумму в заказе, чтобы выписка сходилась с балансом.
+func (br *BalanceRepo) CreditOrderAccrual(
+	ctx context.Context,
+	order *models.WatchedOrder,
+	multiplier float64,
+	expiresAt time.Time,
+) (*models.Balance, error) {
+	creditQuery := `UPDATE "order" SET credited = ROUND($1::numeric * $2::numeric, 2), multiplier = $2
+	WHERE number = $3 AND program_id = $4 RETURNING credited`
+	balanceQuery := `UPDATE balance SET current = current + $1 WHERE user_id = $2 AND program_id = $3
+	RETURNING current, withdrawn`
 
-	_, err := br.db.ExecContext(ctx, query, diff, id)
+	tx, err := br.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("error executing context for update user balance %w", err)
+		return nil, fmt.Errorf("error starting transaction for credit order accrual %w", err)
 	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
 
-	return nil
+	var credited float64
+	err = tx.QueryRowContext(ctx, creditQuery, order.AccrualPoints, multiplier, order.OrderNumber, order.ProgramID).
+		Scan(&credited)
+	if err != nil {
+		return nil, fmt.Errorf("error executing context for credit order %s: %w", order.OrderNumber, err)
+	}
+
+	var balance models.Balance
+	err = tx.QueryRowContext(ctx, balanceQuery, credited, order.UserID, order.ProgramID).
+		Scan(&balance.Current, &balance.Withdrawn)
+	if err != nil {
+		return nil, fmt.Errorf("error executing context for update user balance %w", err)
+	}
+
+	err = addLot(ctx, tx, order.UserID, lotSourceAccrual, order.OrderNumber, credited, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("error opening point lot for order %s: %w", order.OrderNumber, err)
+	}
+
+	if _, err = addEvents(ctx, tx, balanceEvent(order.UserID, &balance)); err != nil {
+		return nil, err
+	}
+
+	return &balance, nil
+}
+
+func (br *BalanceRepo) GetWithdrawnSince(
+	ctx context.Context,
+	programID, userID int,
+	since time.Time,
+) (float64, error) {
+	query := `SELECT
+		(SELECT COALESCE(SUM(sum), 0) FROM withdraw_history
+			WHERE program_id = $3 AND user_id = $1 AND processed_at >= $2 AND status IN ('HELD', 'CAPTURED'))
+		+ (SELECT COALESCE(SUM(rr.price), 0) FROM reward_redemption rr
+			JOIN reward r ON r.id = rr.reward_id
+			WHERE r.program_id = $3 AND rr.user_id = $1 AND rr.redeemed_at >= $2)`
+
+	var withdrawn float64
+	if err := br.db.QueryRowContext(ctx, query, userID, since, programID).Scan(&withdrawn); err != nil {
+		return 0, fmt.Errorf("error scanning row for withdrawn sum %w", err)
+	}
+
+	return withdrawn, nil
 }
 
 func NewBalanceRepo(logger *zap.Logger, cfg *config.Config, db *sql.DB) *BalanceRepo {
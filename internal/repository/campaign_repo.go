@@ -0,0 +1,305 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+const lotSourceCampaign = "campaign"
+
+type CampaignRepo struct {
+	logger *zap.Logger
+	cfg    *config.Config
+	db     *sql.DB
+}
+
+func NewCampaignRepo(logger *zap.Logger, cfg *config.Config, db *sql.DB) *CampaignRepo {
+	return &CampaignRepo{
+		logger: logger,
+		cfg:    cfg,
+		db:     db,
+	}
+}
+
+const campaignColumns = `id, name, rule, starts_at, ends_at, user_cap, active, created_at, updated_at, program_id`
+
+func scanCampaign(row interface{ Scan(dest ...any) error }) (*models.Campaign, error) {
+	var (
+		c       models.Campaign
+		rule    []byte
+		endsAt  sql.NullTime
+		userCap sql.NullFloat64
+	)
+	if err := row.Scan(
+		&c.ID, &c.Name, &rule, &c.StartsAt, &endsAt, &userCap, &c.Active, &c.CreatedAt, &c.UpdatedAt, &c.ProgramID,
+	); err != nil {
+		return nil, fmt.Errorf("error scanning campaign columns %w", err)
+	}
+	if err := json.Unmarshal(rule, &c.Rule); err != nil {
+		return nil, fmt.Errorf("error unmarshal rule of campaign %d: %w", c.ID, err)
+	}
+	if endsAt.Valid {
+		c.EndsAt = &endsAt.Time
+	}
+	c.UserCap = nullFloatPtr(userCap)
+
+	return &c, nil
+}
+
+func (cr *CampaignRepo) CreateCampaign(ctx context.Context, c *models.Campaign) error {
+	query := `INSERT INTO campaign (name, rule, starts_at, ends_at, user_cap, active, program_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id, created_at, updated_at`
+
+	rule, err := json.Marshal(&c.Rule)
+	if err != nil {
+		return fmt.Errorf("error marshal campaign rule %w", err)
+	}
+
+	err = cr.db.QueryRowContext(ctx, query, c.Name, rule, c.StartsAt, c.EndsAt, c.UserCap, c.Active, c.ProgramID).
+		Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error executing context for create campaign %w", err)
+	}
+
+	return nil
+}
+
+func (cr *CampaignRepo) UpdateCampaign(ctx context.Context, c *models.Campaign) error {
+	query := `UPDATE campaign
+	SET name = $1, rule = $2, starts_at = $3, ends_at = $4, user_cap = $5, active = $6, updated_at = now()
+	WHERE id = $7 AND program_id = $8
+	RETURNING created_at, updated_at`
+
+	rule, err := json.Marshal(&c.Rule)
+	if err != nil {
+		return fmt.Errorf("error marshal campaign rule %w", err)
+	}
+
+	err = cr.db.QueryRowContext(ctx, query, c.Name, rule, c.StartsAt, c.EndsAt, c.UserCap, c.Active, c.ID, c.ProgramID).
+		Scan(&c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCampaignNotFound
+		}
+		return fmt.Errorf("error executing context for update campaign %w", err)
+	}
+
+	return nil
+}
+
+func (cr *CampaignRepo) DeleteCampaign(ctx context.Context, programID, id int) error {
+	query := `DELETE FROM campaign WHERE id = $1 AND program_id = $2`
+
+	res, err := cr.db.ExecContext(ctx, query, id, programID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrCampaignInUse
+		}
+		return fmt.Errorf("error executing context for delete campaign %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("error getting affected rows for delete campaign %w", err)
+	}
+	if affected == 0 {
+		return ErrCampaignNotFound
+	}
+
+	return nil
+}
+
+func (cr *CampaignRepo) GetCampaign(ctx context.Context, programID, id int) (*models.Campaign, error) {
+	query := `SELECT ` + campaignColumns + ` FROM campaign WHERE id = $1 AND program_id = $2`
+
+	c, err := scanCampaign(cr.db.QueryRowContext(ctx, query, id, programID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCampaignNotFound
+		}
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (cr *CampaignRepo) GetCampaigns(ctx context.Context, programID int, at *time.Time) ([]*models.Campaign, error) {
+	query := `SELECT ` + campaignColumns + ` FROM campaign
+	WHERE program_id = $2
+		AND ($1::timestamptz IS NULL OR (active AND starts_at <= $1 AND (ends_at IS NULL OR ends_at > $1)))
+	ORDER BY id`
+
+	rows, err := cr.db.QueryContext(ctx, query, at, programID)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for campaigns %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	campaigns := []*models.Campaign{}
+	for rows.Next() {
+		var c *models.Campaign
+		if c, err = scanCampaign(rows); err != nil {
+			return nil, err
+		}
+		campaigns = append(campaigns, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return campaigns, nil
+}
+
+// FillCampaignOrder счётчики учитывают и сам заказ.
+func (cr *CampaignRepo) FillCampaignOrder(ctx context.Context, order *models.CampaignOrder) error {
+	countQuery := `SELECT
+		COUNT(*) FILTER (WHERE number <> $2),
+		COUNT(*) FILTER (WHERE number <> $2 AND date_trunc('month', uploaded_at) = date_trunc('month', $3::timestamp)),
+		COALESCE(MAX(merchant_id) FILTER (WHERE number = $2), '')
+	FROM "order"
+	WHERE user_id = $1 AND status = 'PROCESSED'`
+	grantedQuery := `SELECT campaign_id, SUM(points) FROM campaign_grant WHERE user_id = $1 GROUP BY campaign_id`
+
+	var merchantID string
+	err := cr.db.QueryRowContext(ctx, countQuery, order.UserID, order.Number, order.ProcessedAt).
+		Scan(&order.OrdersTotal, &order.OrdersInMonth, &merchantID)
+	if err != nil {
+		return fmt.Errorf("error scanning row for campaign order stats %w", err)
+	}
+	if order.MerchantID == "" {
+		order.MerchantID = merchantID
+	}
+	order.OrdersTotal++
+	order.OrdersInMonth++
+
+	rows, err := cr.db.QueryContext(ctx, grantedQuery, order.UserID)
+	if err != nil {
+		return fmt.Errorf("error query context for granted campaign points %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	order.Granted = make(map[int]float64)
+	for rows.Next() {
+		var campaignID int
+		var points float64
+		if err = rows.Scan(&campaignID, &points); err != nil {
+			return fmt.Errorf("error scanning row for granted campaign points %w", err)
+		}
+		order.Granted[campaignID] = points
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return nil
+}
+
+// AddGrants повторный бонус кампании по тому же заказу пропускается.
+func (cr *CampaignRepo) AddGrants(
+	ctx context.Context,
+	userID int,
+	grants []*models.CampaignGrant,
+	expiresAt time.Time,
+) ([]*models.CampaignGrant, *models.Balance, error) {
+	grantQuery := `INSERT INTO campaign_grant (campaign_id, user_id, order_number, rule_type, points)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (campaign_id, order_number) DO NOTHING
+	RETURNING id, granted_at`
+	balanceQuery := `UPDATE balance SET current = current + $1 WHERE user_id = $2 RETURNING current, withdrawn`
+
+	tx, err := cr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error starting transaction for campaign grants %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	var (
+		added []*models.CampaignGrant
+		total float64
+	)
+	for _, g := range grants {
+		err = tx.QueryRowContext(ctx, grantQuery, g.CampaignID, userID, g.OrderNumber, g.RuleType, g.Points).
+			Scan(&g.ID, &g.GrantedAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				err = nil
+				continue
+			}
+			return nil, nil, fmt.Errorf("error executing context for campaign grant %w", err)
+		}
+
+		err = addLot(ctx, tx, userID, lotSourceCampaign, g.OrderNumber, g.Points, expiresAt)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error opening point lot for campaign %d: %w", g.CampaignID, err)
+		}
+		added = append(added, g)
+		total += g.Points
+	}
+
+	var balance models.Balance
+	err = tx.QueryRowContext(ctx, balanceQuery, total, userID).Scan(&balance.Current, &balance.Withdrawn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error executing context for update user balance %w", err)
+	}
+
+	if len(added) > 0 {
+		if _, err = addEvents(ctx, tx, balanceEvent(userID, &balance)); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return added, &balance, nil
+}
+
+func (cr *CampaignRepo) GetCampaignGrants(ctx context.Context, campaignID int) ([]*models.CampaignGrant, error) {
+	query := `SELECT id, campaign_id, user_id, order_number, rule_type, points, granted_at FROM campaign_grant
+	WHERE campaign_id = $1
+	ORDER BY id DESC`
+
+	rows, err := cr.db.QueryContext(ctx, query, campaignID)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for campaign grants %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	grants := []*models.CampaignGrant{}
+	for rows.Next() {
+		var g models.CampaignGrant
+		err = rows.Scan(&g.ID, &g.CampaignID, &g.UserID, &g.OrderNumber, &g.RuleType, &g.Points, &g.GrantedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row for campaign grant %w", err)
+		}
+		grants = append(grants, &g)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return grants, nil
+}
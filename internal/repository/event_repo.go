@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"go.uber.org/zap"
+)
+
+const EventsChannel = "user_events"
+
+const maxEventsReplay = 1000
+
+type EventRepo struct {
+	logger *zap.Logger
+	cfg    *config.Config
+	db     *sql.DB
+}
+
+// eventLockNamespace первый ключ advisory-блокировки, под которой добавляются события пользователя.
+const eventLockNamespace = 26
+
+type pendingEvent struct {
+	userID    int
+	eventType string
+	payload   any
+}
+
+func balanceEvent(userID int, balance *models.Balance) pendingEvent {
+	return pendingEvent{
+		userID:    userID,
+		eventType: models.EventBalance,
+		payload:   &models.BalanceEvent{Current: balance.Current, Withdrawn: balance.Withdrawn},
+	}
+}
+
+func orderStatusEvent(userID int, number, status string, accrual float64) pendingEvent {
+	event := &models.OrderStatusEvent{Number: number, Status: status}
+	if status == "PROCESSED" {
+		event.Accrual = &accrual
+	}
+
+	return pendingEvent{userID: userID, eventType: models.EventOrderStatus, payload: event}
+}
+
+// addEvents вызывается последним в транзакции изменения. Блокировка пользователя держится до коммита,
+// поэтому id его событий становятся видимыми по возрастанию и возобновление по id не пропускает событий.
+func addEvents(ctx context.Context, tx *sql.Tx, pending ...pendingEvent) ([]*models.Event, error) {
+	lockQuery := `SELECT pg_advisory_xact_lock($1, $2)`
+	query := `INSERT INTO user_event (user_id, type, payload) VALUES ($1, $2, $3) RETURNING id, created_at`
+
+	// пользователи блокируются по возрастанию id, чтобы транзакции не ждали друг друга по кругу
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].userID < pending[j].userID })
+
+	events := make([]*models.Event, 0, len(pending))
+	for i, p := range pending {
+		if i == 0 || pending[i-1].userID != p.userID {
+			if _, err := tx.ExecContext(ctx, lockQuery, eventLockNamespace, p.userID); err != nil {
+				return nil, fmt.Errorf("error locking events of user %d: %w", p.userID, err)
+			}
+		}
+
+		payload, err := json.Marshal(p.payload)
+		if err != nil {
+			return nil, fmt.Errorf("error marshal event payload %w", err)
+		}
+		event := &models.Event{
+			UserID:  p.userID,
+			Type:    p.eventType,
+			Payload: payload,
+		}
+		if err = tx.QueryRowContext(ctx, query, p.userID, p.eventType, payload).
+			Scan(&event.ID, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning row for new user event %w", err)
+		}
+
+		var notification []byte
+		if notification, err = json.Marshal(event); err != nil {
+			return nil, fmt.Errorf("error marshal event for notification %w", err)
+		}
+		if _, err = tx.ExecContext(ctx, `SELECT pg_notify($1, $2)`, EventsChannel, string(notification)); err != nil {
+			return nil, fmt.Errorf("error executing context for notify event %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func (er *EventRepo) AddEvent(
+	ctx context.Context,
+	userID int,
+	eventType string,
+	payload json.RawMessage,
+) (*models.Event, error) {
+	tx, err := er.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for add event %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	events, err := addEvents(ctx, tx, pendingEvent{userID: userID, eventType: eventType, payload: payload})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error commit transaction for add event %w", err)
+	}
+
+	return events[0], nil
+}
+
+func (er *EventRepo) GetUserEventsAfter(ctx context.Context, userID int, afterID int64) ([]*models.Event, error) {
+	query := `SELECT id, type, payload, created_at FROM user_event
+	WHERE user_id = $1 AND id > $2
+	ORDER BY id
+	LIMIT $3`
+
+	rows, err := er.db.QueryContext(ctx, query, userID, afterID, maxEventsReplay)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for user events %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var events []*models.Event
+	for rows.Next() {
+		event := models.Event{UserID: userID}
+		if err = rows.Scan(&event.ID, &event.Type, &event.Payload, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning row for user event %w", err)
+		}
+		events = append(events, &event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return events, nil
+}
+
+func NewEventRepo(logger *zap.Logger, cfg *config.Config, db *sql.DB) *EventRepo {
+	return &EventRepo{
+		logger: logger,
+		cfg:    cfg,
+		db:     db,
+	}
+}
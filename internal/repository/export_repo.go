@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"go.uber.org/zap"
+)
+
+type ExportRepo struct {
+	logger *zap.Logger
+	cfg    *config.Config
+	db     *sql.DB
+}
+
+const insertAuditQuery = `INSERT INTO audit_log (user_id, action, object_id) VALUES ($1, $2, $3)`
+
+func NewExportRepo(logger *zap.Logger, cfg *config.Config, db *sql.DB) *ExportRepo {
+	return &ExportRepo{
+		logger: logger,
+		cfg:    cfg,
+		db:     db,
+	}
+}
+
+func (er *ExportRepo) CreateExport(ctx context.Context, userID int) (*models.DataExport, error) {
+	query := `INSERT INTO data_export (user_id) VALUES ($1) RETURNING id, status, created_at`
+
+	tx, err := er.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for data export %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	export := &models.DataExport{UserID: userID}
+	if err = tx.QueryRowContext(ctx, query, userID).Scan(&export.ID, &export.Status, &export.CreatedAt); err != nil {
+		return nil, fmt.Errorf("error scanning row for new data export %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, insertAuditQuery, userID, models.AuditDataExportRequested, export.ID); err != nil {
+		return nil, fmt.Errorf("error executing context for data export audit %w", err)
+	}
+
+	return export, nil
+}
+
+func (er *ExportRepo) AddChunk(ctx context.Context, exportID, seq int, data []byte) error {
+	query := `INSERT INTO data_export_chunk (export_id, seq, data) VALUES ($1, $2, $3)`
+
+	if _, err := er.db.ExecContext(ctx, query, exportID, seq, data); err != nil {
+		return fmt.Errorf("error executing context for data export %d chunk %d: %w", exportID, seq, err)
+	}
+
+	return nil
+}
+
+func (er *ExportRepo) FinishExport(ctx context.Context, export *models.DataExport) error {
+	query := `UPDATE data_export SET status = $1, size = $2, error = $3, finished_at = now(), expires_at = $4
+	WHERE id = $5`
+	chunksQuery := `DELETE FROM data_export_chunk WHERE export_id = $1`
+
+	var expiresAt sql.NullTime
+	if export.ExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: *export.ExpiresAt, Valid: true}
+	}
+
+	tx, err := er.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction for finish data export %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, query, export.Status, export.Size, sql.NullString{
+		String: export.Error,
+		Valid:  export.Error != "",
+	}, expiresAt, export.ID)
+	if err != nil {
+		return fmt.Errorf("error executing context for finish data export %w", err)
+	}
+	if export.Status == models.DataExportFailed {
+		if _, err = tx.ExecContext(ctx, chunksQuery, export.ID); err != nil {
+			return fmt.Errorf("error executing context for delete failed data export chunks %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (er *ExportRepo) GetExport(ctx context.Context, id, userID int) (*models.DataExport, error) {
+	query := `SELECT status, size, error, created_at, finished_at, expires_at FROM data_export
+	WHERE id = $1 AND user_id = $2`
+
+	export := &models.DataExport{
+		ID:     id,
+		UserID: userID,
+	}
+
+	var (
+		exportErr  sql.NullString
+		finishedAt sql.NullTime
+		expiresAt  sql.NullTime
+	)
+	row := er.db.QueryRowContext(ctx, query, id, userID)
+	if err := row.Scan(
+		&export.Status, &export.Size, &exportErr, &export.CreatedAt, &finishedAt, &expiresAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrExportNotFound
+		}
+		return nil, fmt.Errorf("error scanning row for data export %w", err)
+	}
+
+	export.Error = exportErr.String
+	if finishedAt.Valid {
+		export.FinishedAt = &finishedAt.Time
+	}
+	if expiresAt.Valid {
+		export.ExpiresAt = &expiresAt.Time
+	}
+
+	return export, nil
+}
+
+func (er *ExportRepo) StartDownload(ctx context.Context, export *models.DataExport, now time.Time) error {
+	query := `SELECT 1 FROM data_export WHERE id = $1 AND user_id = $2 AND status = 'READY' AND expires_at > $3`
+
+	var ready int
+	if err := er.db.QueryRowContext(ctx, query, export.ID, export.UserID, now).Scan(&ready); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrExportExpired
+		}
+		return fmt.Errorf("error scanning row for data export archive %w", err)
+	}
+
+	_, err := er.db.ExecContext(ctx, insertAuditQuery, export.UserID, models.AuditDataExportDownloaded, export.ID)
+	if err != nil {
+		return fmt.Errorf("error executing context for data export download audit %w", err)
+	}
+
+	return nil
+}
+
+// GetChunk после последней части возвращает ErrExportChunkNotFound.
+func (er *ExportRepo) GetChunk(ctx context.Context, exportID, seq int) ([]byte, error) {
+	query := `SELECT data FROM data_export_chunk WHERE export_id = $1 AND seq = $2`
+
+	var data []byte
+	if err := er.db.QueryRowContext(ctx, query, exportID, seq).Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrExportChunkNotFound
+		}
+		return nil, fmt.Errorf("error scanning row for data export %d chunk %d: %w", exportID, seq, err)
+	}
+
+	return data, nil
+}
+
+func (er *ExportRepo) ExpireExports(ctx context.Context, now time.Time) (int64, error) {
+	query := `WITH expired AS (
+		UPDATE data_export SET status = 'EXPIRED' WHERE status = 'READY' AND expires_at <= $1 RETURNING id
+	), chunks AS (
+		DELETE FROM data_export_chunk WHERE export_id IN (SELECT id FROM expired)
+	)
+	SELECT COUNT(*) FROM expired`
+
+	var expired int64
+	if err := er.db.QueryRowContext(ctx, query, now).Scan(&expired); err != nil {
+		return 0, fmt.Errorf("error executing context for expire data exports %w", err)
+	}
+
+	return expired, nil
+}
+
+func (er *ExportRepo) FailStaleExports(ctx context.Context, startedBefore time.Time) (int64, error) {
+	query := `WITH failed AS (
+		UPDATE data_export SET status = 'FAILED', error = 'export was interrupted, request a new one',
+			finished_at = now()
+		WHERE status = 'PROCESSING' AND created_at < $1
+		RETURNING id
+	), chunks AS (
+		DELETE FROM data_export_chunk WHERE export_id IN (SELECT id FROM failed)
+	)
+	SELECT COUNT(*) FROM failed`
+
+	var failed int64
+	if err := er.db.QueryRowContext(ctx, query, startedBefore).Scan(&failed); err != nil {
+		return 0, fmt.Errorf("error executing context for failing stale data exports %w", err)
+	}
+
+	return failed, nil
+}
@@ -0,0 +1,342 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+)
+
+func recordWithdrawLots(ctx context.Context, tx *sql.Tx, withdrawID int, lots []consumedLot) error {
+	query := `INSERT INTO withdraw_lot (withdraw_id, lot_id, amount) VALUES ($1, $2, $3)`
+
+	for _, lot := range lots {
+		if _, err := tx.ExecContext(ctx, query, withdrawID, lot.id, lot.taken); err != nil {
+			return fmt.Errorf("error executing context for withdraw lot %d: %w", lot.id, err)
+		}
+	}
+
+	return nil
+}
+
+// HoldPoints остаток проверяется в том же UPDATE, что и резерв.
+func (br *BalanceRepo) HoldPoints(
+	ctx context.Context,
+	user *models.User,
+	order string,
+	sum float64,
+	expiresAt time.Time,
+) (*models.Hold, error) {
+	balanceQuery := `UPDATE balance SET current = current - $1, held = held + $1
+	WHERE user_id = $2 AND program_id = $3 AND current >= $1
+	RETURNING current, withdrawn, held`
+	holdQuery := `INSERT INTO withdraw_history
+	(order_number, sum, processed_at, user_id, status, expires_at, program_id)
+	VALUES ($1, $2, $3, $4, 'HELD', $5, $6)
+	RETURNING id`
+
+	tx, err := br.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for hold %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	var balance models.Balance
+	err = tx.QueryRowContext(ctx, balanceQuery, sum, user.ID, user.ProgramID).
+		Scan(&balance.Current, &balance.Withdrawn, &balance.Held)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrInsufficientBalance
+			return nil, err
+		}
+		return nil, fmt.Errorf("error executing context for hold balance %w", err)
+	}
+
+	now := time.Now()
+	hold := &models.Hold{
+		Order:     order,
+		Sum:       sum,
+		Status:    models.WithdrawalHeld,
+		CreatedAt: now,
+		ExpiresAt: &expiresAt,
+	}
+	err = tx.QueryRowContext(ctx, holdQuery, order, sum, now.Format(time.DateTime), user.ID, expiresAt, user.ProgramID).
+		Scan(&hold.ID)
+	if err != nil {
+		if isWithdrawOrderTaken(err) {
+			return nil, ErrWithdrawalExists
+		}
+		return nil, fmt.Errorf("error executing context for hold history %w", err)
+	}
+
+	lots, err := consumeLots(ctx, tx, user.ID, sum)
+	if err != nil {
+		return nil, fmt.Errorf("error consuming point lots for hold %w", err)
+	}
+	if err = recordWithdrawLots(ctx, tx, hold.ID, lots); err != nil {
+		return nil, err
+	}
+
+	if _, err = addEvents(ctx, tx, balanceEvent(user.ID, &balance)); err != nil {
+		return nil, err
+	}
+
+	return hold, nil
+}
+
+func lockHold(ctx context.Context, tx *sql.Tx, programID, userID, holdID int) (*models.Hold, error) {
+	query := `SELECT order_number::text, sum, status, processed_at, expires_at, settled_at FROM withdraw_history
+	WHERE id = $1 AND user_id = $2 AND program_id = $3
+	FOR UPDATE`
+
+	hold := &models.Hold{ID: holdID}
+	var expiresAt, settledAt sql.NullTime
+	err := tx.QueryRowContext(ctx, query, holdID, userID, programID).
+		Scan(&hold.Order, &hold.Sum, &hold.Status, &hold.CreatedAt, &expiresAt, &settledAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrHoldNotFound
+		}
+		return nil, fmt.Errorf("error scanning row for hold %d: %w", holdID, err)
+	}
+	if expiresAt.Valid {
+		hold.ExpiresAt = &expiresAt.Time
+	}
+	if settledAt.Valid {
+		hold.SettledAt = &settledAt.Time
+	}
+
+	return hold, nil
+}
+
+// releaseHold баллы уже сгоревшей партии сгорят при следующем запуске сжигания.
+func releaseHold(
+	ctx context.Context,
+	tx *sql.Tx,
+	programID, userID int,
+	hold *models.Hold,
+	status string,
+	now time.Time,
+) (*models.Balance, error) {
+	lotsQuery := `UPDATE point_lot p SET remaining = p.remaining + wl.amount
+	FROM withdraw_lot wl
+	WHERE wl.withdraw_id = $1 AND p.id = wl.lot_id`
+	holdQuery := `UPDATE withdraw_history SET status = $2, settled_at = $3 WHERE id = $1 AND program_id = $4`
+	balanceQuery := `UPDATE balance SET current = current + $1, held = held - $1
+	WHERE user_id = $2 AND program_id = $3
+	RETURNING current, withdrawn, held`
+
+	if _, err := tx.ExecContext(ctx, lotsQuery, hold.ID); err != nil {
+		return nil, fmt.Errorf("error executing context for restore point lots %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, holdQuery, hold.ID, status, now, programID); err != nil {
+		return nil, fmt.Errorf("error executing context for release hold %d: %w", hold.ID, err)
+	}
+
+	var balance models.Balance
+	err := tx.QueryRowContext(ctx, balanceQuery, hold.Sum, userID, programID).
+		Scan(&balance.Current, &balance.Withdrawn, &balance.Held)
+	if err != nil {
+		return nil, fmt.Errorf("error executing context for release balance %w", err)
+	}
+	hold.Status = status
+	hold.SettledAt = &now
+
+	return &balance, nil
+}
+
+// CaptureHold просроченный резерв освобождается и возвращается ErrHoldNotActive.
+func (br *BalanceRepo) CaptureHold(
+	ctx context.Context,
+	user *models.User,
+	holdID int,
+	now time.Time,
+) (*models.HoldSettlement, error) {
+	captureQuery := `UPDATE withdraw_history SET status = 'CAPTURED', settled_at = $2 WHERE id = $1 AND program_id = $3`
+	balanceQuery := `UPDATE balance SET held = held - $1, withdrawn = withdrawn + $1
+	WHERE user_id = $2 AND program_id = $3
+	RETURNING current, withdrawn, held`
+	currentQuery := `SELECT current, withdrawn, held FROM balance WHERE user_id = $1 AND program_id = $2`
+
+	tx, err := br.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for capture hold %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	hold, err := lockHold(ctx, tx, user.ProgramID, user.ID, holdID)
+	if err != nil {
+		return nil, err
+	}
+
+	settlement := &models.HoldSettlement{UserID: user.ID, Hold: hold, Balance: &models.Balance{}}
+	switch {
+	case hold.Status == models.WithdrawalCaptured:
+		err = tx.QueryRowContext(ctx, currentQuery, user.ID, user.ProgramID).
+			Scan(&settlement.Balance.Current, &settlement.Balance.Withdrawn, &settlement.Balance.Held)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row for user balance %w", err)
+		}
+		return settlement, nil
+	case hold.Status != models.WithdrawalHeld:
+		err = nil
+		return nil, fmt.Errorf("hold %d is %s: %w", holdID, hold.Status, ErrHoldNotActive)
+	case hold.ExpiresAt != nil && !now.Before(*hold.ExpiresAt):
+		var balance *models.Balance
+		balance, err = releaseHold(ctx, tx, user.ProgramID, user.ID, hold, models.WithdrawalExpired, now)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = addEvents(ctx, tx, balanceEvent(user.ID, balance)); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("hold %d is %s: %w", holdID, hold.Status, ErrHoldNotActive)
+	}
+
+	if _, err = tx.ExecContext(ctx, captureQuery, holdID, now, user.ProgramID); err != nil {
+		return nil, fmt.Errorf("error executing context for capture hold %d: %w", holdID, err)
+	}
+	err = tx.QueryRowContext(ctx, balanceQuery, hold.Sum, user.ID, user.ProgramID).
+		Scan(&settlement.Balance.Current, &settlement.Balance.Withdrawn, &settlement.Balance.Held)
+	if err != nil {
+		return nil, fmt.Errorf("error executing context for capture balance %w", err)
+	}
+	hold.Status = models.WithdrawalCaptured
+	hold.SettledAt = &now
+
+	if _, err = addEvents(ctx, tx, balanceEvent(user.ID, settlement.Balance)); err != nil {
+		return nil, err
+	}
+
+	return settlement, nil
+}
+
+func (br *BalanceRepo) ReleaseHold(
+	ctx context.Context,
+	user *models.User,
+	holdID int,
+	now time.Time,
+) (*models.HoldSettlement, error) {
+	currentQuery := `SELECT current, withdrawn, held FROM balance WHERE user_id = $1 AND program_id = $2`
+
+	tx, err := br.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for release hold %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	hold, err := lockHold(ctx, tx, user.ProgramID, user.ID, holdID)
+	if err != nil {
+		return nil, err
+	}
+
+	settlement := &models.HoldSettlement{UserID: user.ID, Hold: hold, Balance: &models.Balance{}}
+	switch hold.Status {
+	case models.WithdrawalReleased:
+		err = tx.QueryRowContext(ctx, currentQuery, user.ID, user.ProgramID).
+			Scan(&settlement.Balance.Current, &settlement.Balance.Withdrawn, &settlement.Balance.Held)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row for user balance %w", err)
+		}
+		return settlement, nil
+	case models.WithdrawalHeld:
+	default:
+		err = nil
+		return nil, fmt.Errorf("hold %d is %s: %w", holdID, hold.Status, ErrHoldNotActive)
+	}
+
+	settlement.Balance, err = releaseHold(ctx, tx, user.ProgramID, user.ID, hold, models.WithdrawalReleased, now)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = addEvents(ctx, tx, balanceEvent(user.ID, settlement.Balance)); err != nil {
+		return nil, err
+	}
+
+	return settlement, nil
+}
+
+func (br *BalanceRepo) ExpireHolds(ctx context.Context, now time.Time) ([]*models.HoldSettlement, error) {
+	selectQuery := `SELECT id, user_id, program_id, order_number::text, sum, processed_at, expires_at
+	FROM withdraw_history
+	WHERE status = 'HELD' AND expires_at <= $1
+	ORDER BY expires_at, id
+	FOR UPDATE SKIP LOCKED`
+
+	tx, err := br.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for expire holds %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, selectQuery, now)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for stale holds %w", err)
+	}
+
+	var (
+		settlements []*models.HoldSettlement
+		programIDs  []int
+	)
+	for rows.Next() {
+		hold := &models.Hold{Status: models.WithdrawalHeld}
+		var (
+			userID    int
+			programID int
+			expiresAt time.Time
+		)
+		err = rows.Scan(&hold.ID, &userID, &programID, &hold.Order, &hold.Sum, &hold.CreatedAt, &expiresAt)
+		if err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("error scanning row for stale hold %w", err)
+		}
+		hold.ExpiresAt = &expiresAt
+		settlements = append(settlements, &models.HoldSettlement{UserID: userID, Hold: hold})
+		programIDs = append(programIDs, programID)
+	}
+	_ = rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	pending := make([]pendingEvent, 0, len(settlements))
+	for i, s := range settlements {
+		s.Balance, err = releaseHold(ctx, tx, programIDs[i], s.UserID, s.Hold, models.WithdrawalExpired, now)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, balanceEvent(s.UserID, s.Balance))
+	}
+	if _, err = addEvents(ctx, tx, pending...); err != nil {
+		return nil, err
+	}
+
+	return settlements, nil
+}
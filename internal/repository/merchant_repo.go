@@ -0,0 +1,367 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+const merchantColumns = `id, program_id, code, name, namespaced, created_at`
+
+type MerchantRepo struct {
+	logger *zap.Logger
+	cfg    *config.Config
+	db     *sql.DB
+}
+
+func NewMerchantRepo(logger *zap.Logger, cfg *config.Config, db *sql.DB) *MerchantRepo {
+	return &MerchantRepo{
+		logger: logger,
+		cfg:    cfg,
+		db:     db,
+	}
+}
+
+func scanMerchant(row interface{ Scan(dest ...any) error }) (*models.Merchant, error) {
+	var m models.Merchant
+	if err := row.Scan(&m.ID, &m.ProgramID, &m.Code, &m.Name, &m.Namespaced, &m.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMerchantNotFound
+		}
+		return nil, fmt.Errorf("error scanning row for merchant %w", err)
+	}
+
+	return &m, nil
+}
+
+func (mr *MerchantRepo) CreateMerchant(ctx context.Context, m *models.Merchant, apiKeyHash string) error {
+	query := `INSERT INTO merchant (program_id, code, name, api_key_hash, namespaced)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING ` + merchantColumns
+
+	created, err := scanMerchant(mr.db.QueryRowContext(ctx, query,
+		m.ProgramID, m.Code, m.Name, apiKeyHash, m.Namespaced))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrMerchantExists
+		}
+		return fmt.Errorf("error creating merchant %w", err)
+	}
+	*m = *created
+
+	return nil
+}
+
+func (mr *MerchantRepo) GetMerchants(ctx context.Context, programID int) ([]*models.Merchant, error) {
+	query := `SELECT ` + merchantColumns + ` FROM merchant WHERE program_id = $1 ORDER BY id`
+
+	rows, err := mr.db.QueryContext(ctx, query, programID)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for merchants %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	merchants := make([]*models.Merchant, 0)
+	for rows.Next() {
+		var m *models.Merchant
+		if m, err = scanMerchant(rows); err != nil {
+			return nil, err
+		}
+		merchants = append(merchants, m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return merchants, nil
+}
+
+func (mr *MerchantRepo) GetMerchantByKeyHash(ctx context.Context, apiKeyHash string) (*models.Merchant, error) {
+	query := `SELECT ` + merchantColumns + ` FROM merchant WHERE api_key_hash = $1`
+
+	return scanMerchant(mr.db.QueryRowContext(ctx, query, apiKeyHash))
+}
+
+func (mr *MerchantRepo) GetMerchantByCode(ctx context.Context, programID int, code string) (*models.Merchant, error) {
+	query := `SELECT ` + merchantColumns + ` FROM merchant WHERE program_id = $1 AND code = $2`
+
+	return scanMerchant(mr.db.QueryRowContext(ctx, query, programID, code))
+}
+
+func (mr *MerchantRepo) CreateReceipt(
+	ctx context.Context,
+	merchant *models.Merchant,
+	receipt *models.MerchantReceipt,
+	items []*models.OrderItem,
+	customer *models.User,
+) error {
+	query := `INSERT INTO merchant_receipt (merchant_id, receipt, order_number, purchase_total, currency, items)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at`
+
+	var rawItems []byte
+	if len(items) > 0 {
+		var err error
+		if rawItems, err = json.Marshal(items); err != nil {
+			return fmt.Errorf("error marshal receipt items %w", err)
+		}
+	}
+
+	tx, err := mr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction for merchant receipt %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, query, merchant.ID, receipt.Number, receipt.OrderNumber,
+		receipt.PurchaseTotal, receipt.Currency, rawItems).Scan(&receipt.ID, &receipt.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			err = ErrReceiptExists
+			return err
+		}
+		return fmt.Errorf("error executing context for insert merchant receipt %w", err)
+	}
+
+	if customer == nil {
+		return nil
+	}
+
+	purchase := &models.OrderPurchase{
+		MerchantID:    merchant.Code,
+		PurchaseTotal: receipt.PurchaseTotal,
+		Currency:      receipt.Currency,
+		Items:         items,
+	}
+	if merchant.Namespaced {
+		purchase.ReceiptNumber = receipt.Number
+	}
+	args, err := insertOrderArgs(receipt.OrderNumber, customer, purchase)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, insertOrderQuery, args...); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			err = ErrOrderNumberExist
+			return err
+		}
+		return fmt.Errorf("error executing context for create order on behalf of customer %w", err)
+	}
+	registered := len(items) == 0
+	watchedArgs := []any{receipt.OrderNumber, customer.ID, registered, customer.ProgramID}
+	if _, err = tx.ExecContext(ctx, insertWatchedOrderQuery, watchedArgs...); err != nil {
+		return fmt.Errorf("error executing context for create watched order %w", err)
+	}
+
+	now := time.Now()
+	receipt.Claimed = true
+	receipt.ClaimedBy = models.MaskLogin(customer.Login)
+	receipt.ClaimedAt = &now
+	receipt.Status = "NEW"
+
+	return nil
+}
+
+func (mr *MerchantRepo) GetReceiptPurchase(
+	ctx context.Context,
+	programID int,
+	orderNumber string,
+) (*models.OrderPurchase, error) {
+	query := `SELECT m.code, r.purchase_total, r.currency, r.items
+	FROM merchant_receipt r
+	JOIN merchant m ON m.id = r.merchant_id
+	WHERE r.order_number = $1 AND m.program_id = $2`
+
+	var (
+		purchase models.OrderPurchase
+		items    []byte
+	)
+	err := mr.db.QueryRowContext(ctx, query, orderNumber, programID).
+		Scan(&purchase.MerchantID, &purchase.PurchaseTotal, &purchase.Currency, &items)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrReceiptNotFound
+		}
+		return nil, fmt.Errorf("error scanning row for receipt of order %s: %w", orderNumber, err)
+	}
+	if len(items) > 0 {
+		if err = json.Unmarshal(items, &purchase.Items); err != nil {
+			return nil, fmt.Errorf("error unmarshal items of receipt %s: %w", orderNumber, err)
+		}
+	}
+
+	return &purchase, nil
+}
+
+func (mr *MerchantRepo) GetReceiptPurchases(
+	ctx context.Context,
+	programID int,
+	orderNumbers []string,
+) (map[string]*models.OrderPurchase, error) {
+	query := `SELECT r.order_number, m.code, r.purchase_total, r.currency, r.items
+	FROM merchant_receipt r
+	JOIN merchant m ON m.id = r.merchant_id
+	WHERE r.order_number = ANY($1::text[]) AND m.program_id = $2`
+
+	rows, err := mr.db.QueryContext(ctx, query, orderNumbers, programID)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for receipts of orders %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	purchases := make(map[string]*models.OrderPurchase)
+	for rows.Next() {
+		var (
+			number   string
+			purchase models.OrderPurchase
+			items    []byte
+		)
+		err = rows.Scan(&number, &purchase.MerchantID, &purchase.PurchaseTotal, &purchase.Currency, &items)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row for receipt of orders %w", err)
+		}
+		if len(items) > 0 {
+			if err = json.Unmarshal(items, &purchase.Items); err != nil {
+				return nil, fmt.Errorf("error unmarshal items of receipt %s: %w", number, err)
+			}
+		}
+		purchases[number] = &purchase
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() for receipts of orders %w", err)
+	}
+
+	return purchases, nil
+}
+
+// GetReceipts заказ учитывается, только если он создан в программе магазина.
+func (mr *MerchantRepo) GetReceipts(
+	ctx context.Context,
+	merchant *models.Merchant,
+	filter *models.MerchantReceiptFilter,
+) ([]*models.MerchantReceipt, *models.Cursor, error) {
+	qb := &queryBuilder{}
+	program := qb.arg(merchant.ProgramID)
+	merchantID := qb.arg(merchant.ID)
+	if filter.Claimed != nil {
+		qb.where("claimed = " + qb.arg(*filter.Claimed))
+	} else {
+		qb.where("true")
+	}
+	orderBy := qb.keyset(sortKey{expr: "created_at", cast: "timestamptz"}, &filter.ListPage)
+
+	query := `SELECT id, receipt, purchase_total, currency, created_at, claimed, login, uploaded_at, status, accrual,
+		created_at::text
+	FROM (
+		SELECT
+			r.id,
+			r.receipt,
+			r.purchase_total,
+			r.currency,
+			r.created_at,
+			o.number IS NOT NULL AS claimed,
+			u.login,
+			o.uploaded_at,
+			o.status::text AS status,
+			o.accrual
+		FROM merchant_receipt r
+		LEFT JOIN "order" o ON o.number = r.order_number AND o.program_id = ` + program + `
+		LEFT JOIN "user" u ON u.id = o.user_id
+		WHERE r.merchant_id = ` + merchantID + `
+	) mr
+	WHERE ` + qb.whereSQL() + orderBy
+
+	rows, err := mr.db.QueryContext(ctx, query, qb.args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error query context for merchant receipts %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var (
+		receipts []*models.MerchantReceipt
+		lastKey  string
+		cursor   *models.Cursor
+	)
+	for rows.Next() {
+		var (
+			r          models.MerchantReceipt
+			login      sql.NullString
+			uploadedAt sql.NullTime
+			status     sql.NullString
+			accrual    sql.NullFloat64
+			sortValue  string
+		)
+		err = rows.Scan(&r.ID, &r.Number, &r.PurchaseTotal, &r.Currency, &r.CreatedAt, &r.Claimed,
+			&login, &uploadedAt, &status, &accrual, &sortValue)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error scanning row for merchant receipt %w", err)
+		}
+		if len(receipts) == filter.Limit {
+			cursor = nextCursor(&filter.ListPage, lastKey, receipts[len(receipts)-1].ID)
+			break
+		}
+		if r.Claimed {
+			r.ClaimedBy = models.MaskLogin(login.String)
+			r.ClaimedAt = &uploadedAt.Time
+			r.Status = status.String
+			r.Accrual = nullFloatPtr(accrual)
+		}
+		receipts = append(receipts, &r)
+		lastKey = sortValue
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return receipts, cursor, nil
+}
+
+func (mr *MerchantRepo) GetSummary(
+	ctx context.Context,
+	merchant *models.Merchant,
+	from, to *time.Time,
+) (*models.MerchantSummary, error) {
+	query := `SELECT
+		COUNT(*),
+		COUNT(o.number),
+		COUNT(o.number) FILTER (WHERE o.status = 'PROCESSED'),
+		COALESCE(SUM(o.accrual) FILTER (WHERE o.status = 'PROCESSED'), 0)
+	FROM merchant_receipt r
+	LEFT JOIN "order" o ON o.number = r.order_number AND o.program_id = $2
+	WHERE r.merchant_id = $1
+		AND ($3::timestamptz IS NULL OR r.created_at >= $3)
+		AND ($4::timestamptz IS NULL OR r.created_at < $4)`
+
+	summary := &models.MerchantSummary{From: from, To: to}
+	err := mr.db.QueryRowContext(ctx, query, merchant.ID, merchant.ProgramID, from, to).
+		Scan(&summary.Receipts, &summary.Claimed, &summary.Processed, &summary.Accrual)
+	if err != nil {
+		return nil, fmt.Errorf("error scanning row for merchant %d summary: %w", merchant.ID, err)
+	}
+
+	return summary, nil
+}
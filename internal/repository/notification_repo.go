@@ -0,0 +1,306 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"go.uber.org/zap"
+)
+
+type NotificationRepo struct {
+	logger *zap.Logger
+	cfg    *config.Config
+	db     *sql.DB
+}
+
+func NewNotificationRepo(logger *zap.Logger, cfg *config.Config, db *sql.DB) *NotificationRepo {
+	return &NotificationRepo{
+		logger: logger,
+		cfg:    cfg,
+		db:     db,
+	}
+}
+
+// scanPreferences database/sql не сканирует text[], поэтому массивы читаются через to_json.
+func scanPreferences(p *models.NotificationPreferences, email, phone sql.NullString, channels, events []byte) error {
+	if email.Valid {
+		p.Email = &email.String
+	}
+	if phone.Valid {
+		p.Phone = &phone.String
+	}
+	if err := json.Unmarshal(channels, &p.Channels); err != nil {
+		return fmt.Errorf("error unmarshal notification channels %w", err)
+	}
+	if err := json.Unmarshal(events, &p.Events); err != nil {
+		return fmt.Errorf("error unmarshal notification events %w", err)
+	}
+	return nil
+}
+
+func (nr *NotificationRepo) GetPreferences(ctx context.Context, userID int) (*models.NotificationPreferences, error) {
+	query := `SELECT email, phone, locale, to_json(channels), to_json(events), updated_at
+	FROM notification_preference WHERE user_id = $1`
+
+	var (
+		p                = models.NotificationPreferences{UserID: userID}
+		email, phone     sql.NullString
+		channels, events []byte
+	)
+	err := nr.db.QueryRowContext(ctx, query, userID).
+		Scan(&email, &phone, &p.Locale, &channels, &events, &p.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.DefaultNotificationPreferences(userID), nil
+		}
+		return nil, fmt.Errorf("error scanning row for notification preferences %w", err)
+	}
+	if err = scanPreferences(&p, email, phone, channels, events); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (nr *NotificationRepo) SavePreferences(ctx context.Context, p *models.NotificationPreferences) error {
+	query := `INSERT INTO notification_preference (user_id, email, phone, locale, channels, events)
+	VALUES ($1, $2, $3, $4, $5::text[], $6::text[])
+	ON CONFLICT (user_id) DO UPDATE
+	SET email = EXCLUDED.email, phone = EXCLUDED.phone, locale = EXCLUDED.locale,
+		channels = EXCLUDED.channels, events = EXCLUDED.events, updated_at = now()
+	RETURNING updated_at`
+
+	err := nr.db.QueryRowContext(ctx, query, p.UserID, p.Email, p.Phone, p.Locale, p.Channels, p.Events).
+		Scan(&p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error saving notification preferences %w", err)
+	}
+
+	return nil
+}
+
+func (nr *NotificationRepo) GetNewEvents(ctx context.Context, limit int) ([]*models.NotificationEvent, error) {
+	query := `SELECT e.id, e.user_id, e.type, e.payload, e.created_at,
+		p.user_id IS NOT NULL, p.email, p.phone, COALESCE(p.locale, ''),
+		COALESCE(to_json(p.channels), '[]'), COALESCE(to_json(p.events), '[]')
+	FROM user_event e
+	LEFT JOIN notification_preference p ON p.user_id = e.user_id
+	WHERE e.notified_at IS NULL
+	ORDER BY e.id
+	LIMIT $1`
+
+	rows, err := nr.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for notification events %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var events []*models.NotificationEvent
+	for rows.Next() {
+		var (
+			e               models.Event
+			p               models.NotificationPreferences
+			hasPreferences  bool
+			email, phone    sql.NullString
+			channels, types []byte
+		)
+		err = rows.Scan(&e.ID, &e.UserID, &e.Type, &e.Payload, &e.CreatedAt,
+			&hasPreferences, &email, &phone, &p.Locale, &channels, &types)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row for notification event %w", err)
+		}
+		item := &models.NotificationEvent{Event: &e}
+		if hasPreferences {
+			p.UserID = e.UserID
+			if err = scanPreferences(&p, email, phone, channels, types); err != nil {
+				return nil, err
+			}
+			item.Preferences = &p
+		}
+		events = append(events, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return events, nil
+}
+
+// EnqueueNotifications ставит в очередь уведомления только тех событий, которые не отметила другая реплика.
+func (nr *NotificationRepo) EnqueueNotifications(
+	ctx context.Context,
+	eventIDs []int64,
+	notifications []*models.Notification,
+) (int, error) {
+	markQuery := `UPDATE user_event SET notified_at = now()
+	WHERE id = ANY($1::bigint[]) AND notified_at IS NULL
+	RETURNING id`
+	insertQuery := `INSERT INTO notification (user_id, event_id, channel, recipient, subject, body)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (event_id, channel) DO NOTHING`
+
+	tx, err := nr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction for enqueue notifications %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, markQuery, eventIDs)
+	if err != nil {
+		return 0, fmt.Errorf("error query context for mark notified events %w", err)
+	}
+	marked := make(map[int64]struct{}, len(eventIDs))
+	for rows.Next() {
+		var id int64
+		if err = rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("error scanning row for notified event %w", err)
+		}
+		marked[id] = struct{}{}
+	}
+	_ = rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	for _, n := range notifications {
+		if _, ok := marked[n.EventID]; !ok {
+			continue
+		}
+		if _, err = tx.ExecContext(ctx, insertQuery,
+			n.UserID, n.EventID, n.Channel, n.Recipient, n.Subject, n.Body); err != nil {
+			return 0, fmt.Errorf("error executing context for insert notification %w", err)
+		}
+	}
+
+	return len(marked), nil
+}
+
+// ClaimDue откладывает уведомления на lease, чтобы другие реплики их не отправили.
+func (nr *NotificationRepo) ClaimDue(
+	ctx context.Context,
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]*models.Notification, error) {
+	query := `UPDATE notification SET next_attempt_at = $2
+	WHERE id IN (
+		SELECT id FROM notification
+		WHERE status = 'PENDING' AND next_attempt_at <= $1
+		ORDER BY next_attempt_at, id
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING id, user_id, event_id, channel, recipient, subject, body, status, attempts`
+
+	rows, err := nr.db.QueryContext(ctx, query, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for due notifications %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var notifications []*models.Notification
+	for rows.Next() {
+		var n models.Notification
+		if err = rows.Scan(&n.ID, &n.UserID, &n.EventID, &n.Channel, &n.Recipient, &n.Subject, &n.Body,
+			&n.Status, &n.Attempts); err != nil {
+			return nil, fmt.Errorf("error scanning row for notification %w", err)
+		}
+		notifications = append(notifications, &n)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return notifications, nil
+}
+
+func (nr *NotificationRepo) GetSentSince(
+	ctx context.Context,
+	userIDs []int,
+	since time.Time,
+) (map[int]int, map[int]time.Time, error) {
+	query := `SELECT user_id, COUNT(*), MIN(sent_at) FROM notification
+	WHERE status = 'SENT' AND user_id = ANY($1::int[]) AND sent_at >= $2
+	GROUP BY user_id`
+
+	rows, err := nr.db.QueryContext(ctx, query, userIDs, since)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error query context for sent notifications %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	counts := make(map[int]int)
+	oldest := make(map[int]time.Time)
+	for rows.Next() {
+		var (
+			userID, count int
+			first         time.Time
+		)
+		if err = rows.Scan(&userID, &count, &first); err != nil {
+			return nil, nil, fmt.Errorf("error scanning row for sent notifications %w", err)
+		}
+		counts[userID] = count
+		oldest[userID] = first
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return counts, oldest, nil
+}
+
+func (nr *NotificationRepo) MarkSent(ctx context.Context, id int64, now time.Time) error {
+	query := `UPDATE notification SET status = 'SENT', attempts = attempts + 1, sent_at = $2, last_error = NULL
+	WHERE id = $1`
+
+	if _, err := nr.db.ExecContext(ctx, query, id, now); err != nil {
+		return fmt.Errorf("error executing context for sent notification %d: %w", id, err)
+	}
+	return nil
+}
+
+func (nr *NotificationRepo) MarkFailed(
+	ctx context.Context,
+	id int64,
+	reason string,
+	nextAttemptAt time.Time,
+	maxAttempts int,
+) error {
+	query := `UPDATE notification
+	SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3,
+		status = CASE WHEN attempts + 1 >= $4 THEN 'FAILED' ELSE 'PENDING' END
+	WHERE id = $1`
+
+	if _, err := nr.db.ExecContext(ctx, query, id, reason, nextAttemptAt, maxAttempts); err != nil {
+		return fmt.Errorf("error executing context for failed notification %d: %w", id, err)
+	}
+	return nil
+}
+
+func (nr *NotificationRepo) Postpone(ctx context.Context, id int64, nextAttemptAt time.Time) error {
+	query := `UPDATE notification SET next_attempt_at = $2 WHERE id = $1`
+
+	if _, err := nr.db.ExecContext(ctx, query, id, nextAttemptAt); err != nil {
+		return fmt.Errorf("error executing context for postpone notification %d: %w", id, err)
+	}
+	return nil
+}
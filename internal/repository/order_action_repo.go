@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+)
+
+const insertOrderActionQuery = `INSERT INTO order_action
+	(order_number, user_id, action, actor_type, actor_id, reason, amount, created_at, program_id)
+VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9)
+RETURNING id`
+
+func (or *OrderRepo) CancelOrder(
+	ctx context.Context,
+	programID, userID int,
+	number, reason string,
+	now time.Time,
+) (*models.OrderAction, error) {
+	lockQuery := `SELECT user_id, status FROM "order" WHERE number = $1 AND program_id = $2 FOR UPDATE`
+	orderQuery := `UPDATE "order" SET status = 'CANCELLED' WHERE number = $1 AND program_id = $2`
+	watchQuery := `UPDATE watched_order SET trackable = false WHERE order_number = $1 AND program_id = $2`
+	historyQuery := `INSERT INTO order_status_history (order_number, status, program_id) VALUES ($1, 'CANCELLED', $2)`
+
+	tx, err := or.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for cancel order %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	var (
+		ownerID int
+		status  string
+	)
+	if err = tx.QueryRowContext(ctx, lockQuery, number, programID).Scan(&ownerID, &status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrderNumberNotFound
+		}
+		return nil, fmt.Errorf("error scanning row for order %s: %w", number, err)
+	}
+	// чужой заказ для пользователя не существует
+	if ownerID != userID {
+		err = ErrOrderNumberNotFound
+		return nil, err
+	}
+	if status != "NEW" && status != "PROCESSING" {
+		err = fmt.Errorf("order %s is %s: %w", number, status, ErrOrderStatusConflict)
+		return nil, err
+	}
+
+	if _, err = tx.ExecContext(ctx, orderQuery, number, programID); err != nil {
+		return nil, fmt.Errorf("error executing context for cancel order %s: %w", number, err)
+	}
+	if _, err = tx.ExecContext(ctx, watchQuery, number, programID); err != nil {
+		return nil, fmt.Errorf("error stoping watch order %s: %w", number, err)
+	}
+	if _, err = tx.ExecContext(ctx, historyQuery, number, programID); err != nil {
+		return nil, fmt.Errorf("error executing context for order status history %w", err)
+	}
+
+	action := &models.OrderAction{
+		OrderNumber: number,
+		Action:      models.OrderActionCancel,
+		ActorType:   models.ActorUser,
+		ActorID:     &userID,
+		Reason:      reason,
+		CreatedAt:   now,
+	}
+	err = tx.QueryRowContext(ctx, insertOrderActionQuery, number, userID, action.Action, action.ActorType,
+		action.ActorID, reason, 0, now, programID).Scan(&action.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error executing context for insert order action %w", err)
+	}
+
+	if _, err = addEvents(ctx, tx, orderStatusEvent(userID, number, models.OrderCancelled, 0)); err != nil {
+		return nil, err
+	}
+
+	return action, nil
+}
+
+// ReturnOrder баланс владельца может уйти в минус.
+func (or *OrderRepo) ReturnOrder(
+	ctx context.Context,
+	programID int,
+	number, actorType string,
+	actorID *int,
+	reason string,
+	now time.Time,
+) (*models.OrderReturnResult, error) {
+	lockQuery := `SELECT user_id, status, COALESCE(credited, accrual, 0) FROM "order"
+	WHERE number = $1 AND program_id = $2
+	FOR UPDATE`
+	extraQuery := `SELECT
+		(SELECT COALESCE(SUM(amount), 0) FROM accrual_adjustment
+			WHERE order_number = $1 AND program_id = $2 AND applied_at IS NOT NULL AND status <> 'DISMISSED')
+		+ (SELECT COALESCE(SUM(g.points), 0) FROM campaign_grant g
+			JOIN campaign c ON c.id = g.campaign_id
+			WHERE g.order_number = $1 AND c.program_id = $2)`
+	orderQuery := `UPDATE "order" SET status = 'RETURNED' WHERE number = $1 AND program_id = $2`
+	historyQuery := `INSERT INTO order_status_history (order_number, status, program_id) VALUES ($1, 'RETURNED', $2)`
+	verificationQuery := `DELETE FROM order_verification WHERE order_number = $1 AND program_id = $2`
+	adjustmentsQuery := `UPDATE accrual_adjustment
+	SET status = CASE WHEN status = 'REVIEW' THEN 'DISMISSED' ELSE 'APPLIED' END, resolved_at = $3
+	WHERE order_number = $1 AND program_id = $2 AND status IN ('REVIEW', 'BLOCKED')`
+
+	tx, err := or.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for return order %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	result := &models.OrderReturnResult{}
+	var (
+		status          string
+		credited, extra float64
+	)
+	err = tx.QueryRowContext(ctx, lockQuery, number, programID).Scan(&result.UserID, &status, &credited)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrderNumberNotFound
+		}
+		return nil, fmt.Errorf("error scanning row for order %s: %w", number, err)
+	}
+	if status != "PROCESSED" {
+		err = fmt.Errorf("order %s is %s: %w", number, status, ErrOrderStatusConflict)
+		return nil, err
+	}
+
+	if err = tx.QueryRowContext(ctx, extraQuery, number, programID).Scan(&extra); err != nil {
+		return nil, fmt.Errorf("error scanning row for order %s credits: %w", number, err)
+	}
+
+	if _, err = tx.ExecContext(ctx, orderQuery, number, programID); err != nil {
+		return nil, fmt.Errorf("error executing context for return order %s: %w", number, err)
+	}
+	if _, err = tx.ExecContext(ctx, historyQuery, number, programID); err != nil {
+		return nil, fmt.Errorf("error executing context for order status history %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, verificationQuery, number, programID); err != nil {
+		return nil, fmt.Errorf("error executing context for stop order %s verification: %w", number, err)
+	}
+	if _, err = tx.ExecContext(ctx, adjustmentsQuery, number, programID, now); err != nil {
+		return nil, fmt.Errorf("error executing context for close order %s adjustments: %w", number, err)
+	}
+
+	action := &models.OrderAction{
+		OrderNumber: number,
+		Action:      models.OrderActionReturn,
+		ActorType:   actorType,
+		ActorID:     actorID,
+		Reason:      reason,
+		Amount:      math.Round((credited+extra)*100) / 100,
+		CreatedAt:   now,
+	}
+	if action.Amount > 0 {
+		// отзыв не открывает партий, срок сгорания не нужен
+		if result.Balance, err = adjustBalance(ctx, tx, result.UserID, number, -action.Amount, now); err != nil {
+			return nil, err
+		}
+	}
+	if result.Balance, err = syncWithdrawalBlock(ctx, tx, result.UserID); err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, insertOrderActionQuery, number, result.UserID, action.Action, action.ActorType,
+		action.ActorID, reason, action.Amount, now, programID).Scan(&action.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error executing context for insert order action %w", err)
+	}
+	result.Action = action
+
+	_, err = addEvents(ctx, tx,
+		orderStatusEvent(result.UserID, number, models.OrderReturned, 0),
+		balanceEvent(result.UserID, result.Balance))
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (or *OrderRepo) GetOrderActions(
+	ctx context.Context,
+	programID int,
+	number string,
+) ([]*models.OrderAction, error) {
+	query := `SELECT id, order_number, action, actor_type, actor_id, COALESCE(reason, ''), amount, created_at
+	FROM order_action
+	WHERE program_id = $1 AND order_number = $2
+	ORDER BY id`
+
+	rows, err := or.db.QueryContext(ctx, query, programID, number)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for order actions %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var actions []*models.OrderAction
+	for rows.Next() {
+		var (
+			action  models.OrderAction
+			actorID sql.NullInt64
+		)
+		err = rows.Scan(&action.ID, &action.OrderNumber, &action.Action, &action.ActorType, &actorID,
+			&action.Reason, &action.Amount, &action.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row for order action %w", err)
+		}
+		if actorID.Valid {
+			id := int(actorID.Int64)
+			action.ActorID = &id
+		}
+		actions = append(actions, &action)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err(): %w", err)
+	}
+
+	return actions, nil
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+)
+
+var ErrBatchJobNotFound error = errors.New("order batch job not found")
+
+// CreateOrdersBulk возвращает вставленные номера и владельцев уже существовавших.
+func (or *OrderRepo) CreateOrdersBulk(
+	ctx context.Context,
+	numbers []string,
+	purchases map[string]*models.OrderPurchase,
+	user *models.User,
+) (map[string]struct{}, map[string]int, error) {
+	insertQuery := `WITH ins AS (
+		INSERT INTO "order" (number, uploaded_at, user_id, program_id, merchant_id, purchase_total, currency, items)
+		SELECT n, $2, $3, $4, m, t, c, i::jsonb
+		FROM unnest($1::text[], $5::text[], $6::numeric[], $7::text[], $8::text[]) AS p(n, m, t, c, i)
+		ON CONFLICT (program_id, number) DO NOTHING
+		RETURNING number, status, items IS NULL AS registered
+	), hist AS (
+		INSERT INTO order_status_history (order_number, status, program_id) SELECT number, status::text, $4 FROM ins
+	), watched AS (
+		INSERT INTO watched_order (order_number, user_id, registered, program_id)
+		SELECT number, $3, registered, $4 FROM ins
+	)
+	SELECT number FROM ins`
+	ownersQuery := `SELECT number, user_id FROM "order" WHERE number = ANY($1::text[]) AND program_id = $2`
+
+	merchants := make([]*string, len(numbers))
+	totals := make([]*float64, len(numbers))
+	currencies := make([]*string, len(numbers))
+	items := make([]*string, len(numbers))
+	for i, number := range numbers {
+		purchase, ok := purchases[number]
+		if !ok {
+			continue
+		}
+		merchants[i], totals[i], currencies[i] = &purchase.MerchantID, &purchase.PurchaseTotal, &purchase.Currency
+		if len(purchase.Items) > 0 {
+			raw, err := json.Marshal(purchase.Items)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error marshal order items %w", err)
+			}
+			encoded := string(raw)
+			items[i] = &encoded
+		}
+	}
+
+	tx, err := or.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error starting transaction for bulk orders %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	rows, err := tx.QueryContext(ctx, insertQuery, numbers, time.Now().Format(time.DateTime), user.ID, user.ProgramID,
+		merchants, totals, currencies, items)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error query context for bulk orders insert %w", err)
+	}
+	inserted := make(map[string]struct{}, len(numbers))
+	for rows.Next() {
+		var number string
+		if err = rows.Scan(&number); err != nil {
+			_ = rows.Close()
+			return nil, nil, fmt.Errorf("error scanning inserted order number %w", err)
+		}
+		inserted[number] = struct{}{}
+	}
+	if err = rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, nil, fmt.Errorf("got rows.Err() for bulk insert %w", err)
+	}
+	_ = rows.Close()
+
+	owners := make(map[string]int)
+	if len(inserted) < len(numbers) {
+		existing := make([]string, 0, len(numbers)-len(inserted))
+		for _, number := range numbers {
+			if _, ok := inserted[number]; !ok {
+				existing = append(existing, number)
+			}
+		}
+
+		rows, err = tx.QueryContext(ctx, ownersQuery, existing, user.ProgramID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error query context for existing orders owners %w", err)
+		}
+		defer func() {
+			_ = rows.Close()
+		}()
+		for rows.Next() {
+			var (
+				number string
+				userID int
+			)
+			if err = rows.Scan(&number, &userID); err != nil {
+				return nil, nil, fmt.Errorf("error scanning existing order owner %w", err)
+			}
+			owners[number] = userID
+		}
+		if err = rows.Err(); err != nil {
+			return nil, nil, fmt.Errorf("got rows.Err() for existing orders %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("error commit bulk orders transaction %w", err)
+	}
+
+	return inserted, owners, nil
+}
+
+func (or *OrderRepo) CreateBatchJob(ctx context.Context, user *models.User, total int) (*models.OrderBatchJob, error) {
+	query := `INSERT INTO order_batch_job (user_id, total) VALUES ($1, $2) RETURNING id, status, created_at`
+
+	job := &models.OrderBatchJob{
+		UserID: user.ID,
+		Total:  total,
+	}
+
+	row := or.db.QueryRowContext(ctx, query, user.ID, total)
+	if err := row.Scan(&job.ID, &job.Status, &job.CreatedAt); err != nil {
+		return nil, fmt.Errorf("error scanning row for new order batch job %w", err)
+	}
+
+	return job, nil
+}
+
+func (or *OrderRepo) FinishBatchJob(ctx context.Context, job *models.OrderBatchJob) error {
+	query := `UPDATE order_batch_job SET status = $1, result = $2, error = $3, finished_at = now() WHERE id = $4`
+
+	var result []byte
+	if job.Result != nil {
+		var err error
+		if result, err = json.Marshal(job.Result); err != nil {
+			return fmt.Errorf("error marshal order batch job result %w", err)
+		}
+	}
+
+	_, err := or.db.ExecContext(ctx, query, job.Status, result, sql.NullString{
+		String: job.Error,
+		Valid:  job.Error != "",
+	}, job.ID)
+	if err != nil {
+		return fmt.Errorf("error executing context for finish order batch job %w", err)
+	}
+
+	return nil
+}
+
+// FailStaleBatchJobs строки загрузки не сохраняются, задание можно только загрузить заново.
+func (or *OrderRepo) FailStaleBatchJobs(ctx context.Context, startedBefore time.Time) (int64, error) {
+	query := `UPDATE order_batch_job SET status = 'FAILED', error = 'job was interrupted, upload the file again',
+	finished_at = now()
+	WHERE status = 'PROCESSING' AND created_at < $1`
+
+	res, err := or.db.ExecContext(ctx, query, startedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("error executing context for failing stale order batch jobs %w", err)
+	}
+	failed, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error getting rows affected for stale order batch jobs %w", err)
+	}
+
+	return failed, nil
+}
+
+func (or *OrderRepo) GetBatchJob(ctx context.Context, id int, user *models.User) (*models.OrderBatchJob, error) {
+	query := `SELECT status, total, result, error, created_at, finished_at FROM order_batch_job
+	WHERE id = $1 AND user_id = $2`
+
+	job := &models.OrderBatchJob{
+		ID:     id,
+		UserID: user.ID,
+	}
+
+	var (
+		result     []byte
+		jobErr     sql.NullString
+		finishedAt sql.NullTime
+	)
+	row := or.db.QueryRowContext(ctx, query, id, user.ID)
+	if err := row.Scan(&job.Status, &job.Total, &result, &jobErr, &job.CreatedAt, &finishedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrBatchJobNotFound
+		}
+		return nil, fmt.Errorf("error scanning row for order batch job %w", err)
+	}
+
+	if result != nil {
+		job.Result = &models.BatchResult{}
+		if err := json.Unmarshal(result, job.Result); err != nil {
+			return nil, fmt.Errorf("error unmarshal order batch job result %w", err)
+		}
+	}
+	job.Error = jobErr.String
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+
+	return job, nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -19,15 +20,59 @@ type OrderRepo struct {
 	db     *sql.DB
 }
 
-func (or *OrderRepo) CreateOrder(ctx context.Context, orderNumber string, user *models.User) error {
-	query := `INSERT INTO "order" (number, uploaded_at, user_id) VALUES ($1, $2, $3)`
+const (
+	insertOrderQuery = `WITH o AS (
+		INSERT INTO "order"
+		(number, uploaded_at, user_id, merchant_id, purchase_total, currency, items, program_id, receipt_number)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING number, status, program_id
+	)
+	INSERT INTO order_status_history (order_number, status, program_id) SELECT number, status::text, program_id FROM o`
+	insertWatchedOrderQuery = `INSERT INTO watched_order (order_number, user_id, registered, program_id)
+	VALUES ($1, $2, $3, $4)`
+)
+
+func insertOrderArgs(orderNumber string, user *models.User, purchase *models.OrderPurchase) ([]any, error) {
+	var (
+		merchantID, currency, receipt sql.NullString
+		total                         sql.NullFloat64
+		items                         []byte
+	)
+	if purchase != nil {
+		merchantID = sql.NullString{String: purchase.MerchantID, Valid: true}
+		currency = sql.NullString{String: purchase.Currency, Valid: true}
+		total = sql.NullFloat64{Float64: purchase.PurchaseTotal, Valid: true}
+		receipt = sql.NullString{String: purchase.ReceiptNumber, Valid: purchase.ReceiptNumber != ""}
+		if len(purchase.Items) > 0 {
+			var err error
+			if items, err = json.Marshal(purchase.Items); err != nil {
+				return nil, fmt.Errorf("error marshal order items %w", err)
+			}
+		}
+	}
+
+	return []any{
+		orderNumber, time.Now().Format(time.DateTime), user.ID, merchantID, total, currency, items, user.ProgramID,
+		receipt,
+	}, nil
+}
+
+func (or *OrderRepo) CreateOrder(
+	ctx context.Context,
+	orderNumber string,
+	user *models.User,
+	purchase *models.OrderPurchase,
+) error {
+	args, err := insertOrderArgs(orderNumber, user, purchase)
+	if err != nil {
+		return err
+	}
 
-	_, err := or.db.ExecContext(ctx, query, orderNumber, time.Now().Format(time.DateTime), user.ID)
+	_, err = or.db.ExecContext(ctx, insertOrderQuery, args...)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
 			var order *models.Order
-			order, err = or.GetOrderByNumber(ctx, orderNumber)
+			order, err = or.GetOrderByNumber(ctx, user.ProgramID, orderNumber)
 			if err != nil {
 				return fmt.Errorf(
 					"order number maybe exist, but got error with geting order with number %s: %w",
@@ -46,10 +91,14 @@ func (or *OrderRepo) CreateOrder(ctx context.Context, orderNumber string, user *
 	return nil
 }
 
-func (or *OrderRepo) CreateWatchedOrder(ctx context.Context, orderNumber string, user *models.User) error {
-	query := `INSERT INTO watched_order (order_number, user_id) VALUES ($1, $2)`
-
-	_, err := or.db.ExecContext(ctx, query, orderNumber, user.ID)
+// CreateWatchedOrder registered ложно, если состав покупки ещё не передан в систему начислений.
+func (or *OrderRepo) CreateWatchedOrder(
+	ctx context.Context,
+	orderNumber string,
+	user *models.User,
+	registered bool,
+) error {
+	_, err := or.db.ExecContext(ctx, insertWatchedOrderQuery, orderNumber, user.ID, registered, user.ProgramID)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
@@ -61,13 +110,14 @@ func (or *OrderRepo) CreateWatchedOrder(ctx context.Context, orderNumber string,
 	return nil
 }
 
-func (or *OrderRepo) GetOrderByNumber(ctx context.Context, orderNumber string) (*models.Order, error) {
-	query := `SELECT id, status, accrual, uploaded_at, user_id FROM "order" WHERE number=$1`
+// GetOrderByNumber заказ другой программы считается ненайденным.
+func (or *OrderRepo) GetOrderByNumber(ctx context.Context, programID int, orderNumber string) (*models.Order, error) {
+	query := `SELECT id, status, accrual, uploaded_at, user_id FROM "order" WHERE number=$1 AND program_id=$2`
 
 	order := NewEmptyOrder()
 	order.Number = orderNumber
 
-	row := or.db.QueryRowContext(ctx, query, orderNumber)
+	row := or.db.QueryRowContext(ctx, query, orderNumber, programID)
 
 	if err := row.Scan(&order.ID, &order.Status, &order.Accrual, &order.UploadedAt, &order.UserID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -79,40 +129,91 @@ func (or *OrderRepo) GetOrderByNumber(ctx context.Context, orderNumber string) (
 	return order, nil
 }
 
-func (or *OrderRepo) GetOrdersByUser(ctx context.Context, userID int) ([]*models.Order, error) {
-	query := `SELECT id, number, status, accrual, uploaded_at FROM "order" WHERE user_id=$1`
+var orderSortKeys = map[string]sortKey{
+	"uploaded_at": {expr: "uploaded_at", cast: "timestamp"},
+	"accrual":     {expr: "COALESCE(accrual, 0)", cast: "numeric"},
+}
 
-	rows, err := or.db.QueryContext(ctx, query, userID)
-	defer func() {
-		_ = rows.Close()
-	}()
+func (or *OrderRepo) GetOrdersByUser(
+	ctx context.Context,
+	programID, userID int,
+	filter *models.OrderListFilter,
+) ([]*models.Order, *models.Cursor, error) {
+	key, ok := orderSortKeys[filter.Sort]
+	if !ok {
+		return nil, nil, ErrUnknownSort
+	}
 
+	qb := &queryBuilder{}
+	qb.where("program_id = " + qb.arg(programID))
+	qb.where("user_id = " + qb.arg(userID))
+	if len(filter.Statuses) > 0 {
+		qb.where("status::text = ANY(" + qb.arg(filter.Statuses) + ")")
+	}
+	if filter.From != nil {
+		qb.where("uploaded_at >= " + qb.arg(*filter.From))
+	}
+	if filter.To != nil {
+		qb.where("uploaded_at < " + qb.arg(*filter.To))
+	}
+	if filter.MinAccrual != nil {
+		qb.where("COALESCE(accrual, 0) >= " + qb.arg(*filter.MinAccrual))
+	}
+	if filter.MaxAccrual != nil {
+		qb.where("COALESCE(accrual, 0) <= " + qb.arg(*filter.MaxAccrual))
+	}
+	orderBy := qb.keyset(key, &filter.ListPage)
+
+	query := `SELECT id, number, status, accrual, uploaded_at, ` + key.expr + `::text FROM "order"
+	WHERE ` + qb.whereSQL() + orderBy
+
+	rows, err := or.db.QueryContext(ctx, query, qb.args...)
 	if err != nil {
-		return nil, fmt.Errorf("error query for orders by userID %d: %w", userID, err)
+		return nil, nil, fmt.Errorf("error query for orders by userID %d: %w", userID, err)
 	}
+	defer func() {
+		_ = rows.Close()
+	}()
 
-	var orders []*models.Order
+	var (
+		orders  []*models.Order
+		lastKey string
+		cursor  *models.Cursor
+	)
 	for rows.Next() {
 		order := NewEmptyOrder()
 		order.UserID = userID
-		if err = rows.Scan(&order.ID, &order.Number, &order.Status, &order.Accrual, &order.UploadedAt); err != nil {
-			return nil, fmt.Errorf("error scanning row of order %w", err)
+		var sortValue string
+		if err = rows.Scan(
+			&order.ID, &order.Number, &order.Status, &order.Accrual, &order.UploadedAt, &sortValue,
+		); err != nil {
+			return nil, nil, fmt.Errorf("error scanning row of order %w", err)
+		}
+		if len(orders) == filter.Limit {
+			cursor = nextCursor(&filter.ListPage, lastKey, orders[len(orders)-1].ID)
+			break
 		}
 		orders = append(orders, order)
+		lastKey = sortValue
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("got rows.Err() : %w", err)
+		return nil, nil, fmt.Errorf("got rows.Err() : %w", err)
 	}
 
 	if len(orders) == 0 {
-		return nil, ErrOrdersNotFound
+		return nil, nil, ErrOrdersNotFound
 	}
-	return orders, nil
+	return orders, cursor, nil
 }
 
 func (or *OrderRepo) GetWatchedOrders(ctx context.Context) ([]*models.WatchedOrder, error) {
-	query := `SELECT id, order_number, user_id, accrual_order_status FROM watched_order WHERE trackable = true`
+	query := `SELECT w.id, w.order_number, w.user_id, w.accrual_order_status, w.attempts, w.registered, o.items,
+		o.program_id, COALESCE(o.receipt_number, ''), COALESCE(p.accrual_addr, '')
+	FROM watched_order w
+	JOIN "order" o ON o.program_id = w.program_id AND o.number = w.order_number
+	JOIN program p ON p.id = o.program_id
+	WHERE w.trackable = true`
 
 	rows, err := or.db.QueryContext(ctx, query)
 	defer func() {
@@ -125,9 +226,19 @@ func (or *OrderRepo) GetWatchedOrders(ctx context.Context) ([]*models.WatchedOrd
 	var watchedOrders []*models.WatchedOrder
 	for rows.Next() {
 		var order models.WatchedOrder
-		if err = rows.Scan(&order.ID, &order.OrderNumber, &order.UserID, &order.AccrualOrderStatus); err != nil {
+		var items []byte
+		if err = rows.Scan(
+			&order.ID, &order.OrderNumber, &order.UserID, &order.AccrualOrderStatus, &order.Attempts,
+			&order.Registered, &items, &order.ProgramID, &order.ReceiptNumber, &order.AccrualAddr,
+		); err != nil {
 			return nil, fmt.Errorf("error scanning row for watched order %w", err)
 		}
+		// состав покупки нужен только до регистрации заказа в системе начислений
+		if !order.Registered && items != nil {
+			if err = json.Unmarshal(items, &order.Items); err != nil {
+				return nil, fmt.Errorf("error unmarshal items of watched order %s: %w", order.OrderNumber, err)
+			}
+		}
 
 		watchedOrders = append(watchedOrders, &order)
 	}
@@ -139,9 +250,19 @@ func (or *OrderRepo) GetWatchedOrders(ctx context.Context) ([]*models.WatchedOrd
 	return watchedOrders, nil
 }
 
-func (or *OrderRepo) UpdateOrdersStatus(ctx context.Context, orders []*models.WatchedOrder) error {
+func (or *OrderRepo) UpdateOrdersStatus(
+	ctx context.Context,
+	orders []*models.WatchedOrder,
+) ([]*models.WatchedOrder, error) {
 	or.logger.Debug("repo get orders to update", zap.Any("ORDERS", orders))
-	query := `UPDATE "order" SET status = $1, accrual = $2 WHERE number = $3`
+	attemptQuery := `UPDATE watched_order SET attempts = attempts + 1, registered = registered OR $2
+	WHERE order_number = $1 AND program_id = $3 RETURNING attempts`
+	query := `UPDATE "order" SET status = $1, accrual = $2
+	WHERE number = $3 AND program_id = $4 AND (status IS DISTINCT FROM $1 OR accrual IS DISTINCT FROM $2)
+		AND status NOT IN ('CANCELLED', 'RETURNED')
+	RETURNING id`
+	historyQuery := `INSERT INTO order_status_history (order_number, status, accrual, attempt, program_id)
+	VALUES ($1, $2, $3, $4, $5)`
 
 	tx, err := or.db.Begin()
 	defer func() {
@@ -152,7 +273,7 @@ func (or *OrderRepo) UpdateOrdersStatus(ctx context.Context, orders []*models.Wa
 		}
 	}()
 	if err != nil {
-		return fmt.Errorf("error starting transaction for update order status %w", err)
+		return nil, fmt.Errorf("error starting transaction for update order status %w", err)
 	}
 
 	stmt, err := tx.PrepareContext(ctx, query)
@@ -160,23 +281,108 @@ func (or *OrderRepo) UpdateOrdersStatus(ctx context.Context, orders []*models.Wa
 		_ = stmt.Close()
 	}()
 	if err != nil {
-		return fmt.Errorf("error preparing context for update order status query %w", err)
+		return nil, fmt.Errorf("error preparing context for update order status query %w", err)
 	}
 
+	changed := make([]*models.WatchedOrder, 0, len(orders))
+	pending := make([]pendingEvent, 0, len(orders))
 	for _, order := range orders {
-		_, err = stmt.ExecContext(ctx, order.AccrualOrderStatus, order.AccrualPoints, order.OrderNumber)
+		err = tx.QueryRowContext(ctx, attemptQuery, order.OrderNumber, order.Registered, order.ProgramID).
+			Scan(&order.Attempts)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("error counting poll attempt for order %s: %w", order.OrderNumber, err)
+		}
+
+		var id int
+		err = stmt.QueryRowContext(
+			ctx, order.AccrualOrderStatus, order.AccrualPoints, order.OrderNumber, order.ProgramID,
+		).Scan(&id)
 		if err != nil {
-			return fmt.Errorf("error exectunig context for update order status %w", err)
+			if errors.Is(err, sql.ErrNoRows) {
+				err = nil
+				continue
+			}
+			return nil, fmt.Errorf("error exectunig context for update order status %w", err)
 		}
+
+		_, err = tx.ExecContext(ctx, historyQuery,
+			order.OrderNumber, order.AccrualOrderStatus, order.AccrualPoints, order.Attempts, order.ProgramID)
+		if err != nil {
+			return nil, fmt.Errorf("error executing context for order status history %w", err)
+		}
+		changed = append(changed, order)
+		pending = append(pending,
+			orderStatusEvent(order.UserID, order.OrderNumber, order.AccrualOrderStatus, order.AccrualPoints))
 	}
 
-	return nil
+	if _, err = addEvents(ctx, tx, pending...); err != nil {
+		return nil, err
+	}
+
+	return changed, nil
+}
+
+func (or *OrderRepo) GetOrderStatusHistory(
+	ctx context.Context,
+	programID int,
+	orderNumber string,
+) ([]*models.OrderStatusChange, error) {
+	query := `SELECT status, accrual, attempt, changed_at FROM order_status_history
+	WHERE program_id = $1 AND order_number = $2
+	ORDER BY id`
+
+	rows, err := or.db.QueryContext(ctx, query, programID, orderNumber)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for order status history %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var history []*models.OrderStatusChange
+	for rows.Next() {
+		var (
+			change  models.OrderStatusChange
+			accrual sql.NullFloat64
+		)
+		if err = rows.Scan(&change.Status, &accrual, &change.Attempt, &change.ChangedAt); err != nil {
+			return nil, fmt.Errorf("error scanning row for order status history %w", err)
+		}
+		if accrual.Valid {
+			change.Accrual = &accrual.Float64
+		}
+		history = append(history, &change)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err(): %w", err)
+	}
+
+	return history, nil
+}
+
+func (or *OrderRepo) GetWatchState(ctx context.Context, programID int, orderNumber string) (int, bool, error) {
+	query := `SELECT attempts, trackable FROM watched_order WHERE program_id = $1 AND order_number = $2`
+
+	var (
+		attempts  int
+		trackable bool
+	)
+	row := or.db.QueryRowContext(ctx, query, programID, orderNumber)
+	if err := row.Scan(&attempts, &trackable); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("error scanning row for watched order state %w", err)
+	}
+
+	return attempts, trackable, nil
 }
 
 func (or *OrderRepo) StopWatchOrder(ctx context.Context, order *models.WatchedOrder) error {
-	query := `UPDaTE watched_order SET trackable = false WHERE order_number = $1`
+	query := `UPDaTE watched_order SET trackable = false WHERE order_number = $1 AND program_id = $2`
 
-	_, err := or.db.ExecContext(ctx, query, order.OrderNumber)
+	_, err := or.db.ExecContext(ctx, query, order.OrderNumber, order.ProgramID)
 	if err != nil {
 		return fmt.Errorf("error stoping watch order %s: %w", order.OrderNumber, err)
 	}
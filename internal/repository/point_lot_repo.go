@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+)
+
+const (
+	lotSourceAccrual = "accrual"
+)
+
+func addLot(
+	ctx context.Context,
+	tx *sql.Tx,
+	userID int,
+	source, reference string,
+	amount float64,
+	expiresAt time.Time,
+) error {
+	if amount <= 0 {
+		return nil
+	}
+
+	query := `INSERT INTO point_lot (user_id, source, reference, amount, remaining, expires_at)
+	VALUES ($1, $2, $3, $4, $4, $5)`
+
+	if _, err := tx.ExecContext(ctx, query, userID, source, reference, amount, expiresAt); err != nil {
+		return fmt.Errorf("error executing context for add point lot %w", err)
+	}
+
+	return nil
+}
+
+type consumedLot struct {
+	id        int
+	taken     float64
+	expiresAt time.Time
+}
+
+// consumeLots если партий не хватает, списывается всё, что есть: источник истины баланс.
+func consumeLots(ctx context.Context, tx *sql.Tx, userID int, sum float64) ([]consumedLot, error) {
+	selectQuery := `SELECT id, remaining, expires_at FROM point_lot
+	WHERE user_id = $1 AND remaining > 0
+	ORDER BY expires_at, id
+	FOR UPDATE`
+	updateQuery := `UPDATE point_lot SET remaining = remaining - $1 WHERE id = $2`
+
+	rows, err := tx.QueryContext(ctx, selectQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for open point lots %w", err)
+	}
+
+	var parts []consumedLot
+	left := sum
+	for left > 0 && rows.Next() {
+		var part consumedLot
+		var remaining float64
+		if err = rows.Scan(&part.id, &remaining, &part.expiresAt); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("error scanning row for point lot %w", err)
+		}
+		part.taken = min(remaining, left)
+		parts = append(parts, part)
+		left -= part.taken
+	}
+	_ = rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	for _, part := range parts {
+		if _, err = tx.ExecContext(ctx, updateQuery, part.taken, part.id); err != nil {
+			return nil, fmt.Errorf("error executing context for consume point lot %d: %w", part.id, err)
+		}
+	}
+
+	return parts, nil
+}
+
+func (br *BalanceRepo) ExpireLots(ctx context.Context, now time.Time) ([]*models.PointsExpiry, error) {
+	query := `WITH expired AS (
+		UPDATE point_lot p SET remaining = 0, expired_at = $1
+		FROM (
+			SELECT id, remaining FROM point_lot
+			WHERE expires_at <= $1 AND remaining > 0
+			FOR UPDATE SKIP LOCKED
+		) old
+		WHERE p.id = old.id
+		RETURNING p.id, p.user_id, old.remaining
+	), entries AS (
+		INSERT INTO point_expiry (user_id, lot_id, amount, expired_at)
+		SELECT user_id, id, remaining, $1 FROM expired
+	), totals AS (
+		SELECT user_id, SUM(remaining) AS amount FROM expired GROUP BY user_id
+	)
+	UPDATE balance b SET current = b.current - t.amount
+	FROM totals t
+	WHERE b.user_id = t.user_id
+	RETURNING b.user_id, t.amount, b.current, b.withdrawn`
+
+	tx, err := br.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for expire point lots %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for expire point lots %w", err)
+	}
+
+	var (
+		expired []*models.PointsExpiry
+		pending []pendingEvent
+	)
+	for rows.Next() {
+		var e models.PointsExpiry
+		if err = rows.Scan(&e.UserID, &e.Amount, &e.Balance.Current, &e.Balance.Withdrawn); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("error scanning row for points expiry %w", err)
+		}
+		expired = append(expired, &e)
+		pending = append(pending, balanceEvent(e.UserID, &e.Balance))
+	}
+	_ = rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	if _, err = addEvents(ctx, tx, pending...); err != nil {
+		return nil, err
+	}
+
+	return expired, nil
+}
+
+func (br *BalanceRepo) TakeExpiringLots(
+	ctx context.Context,
+	now, until time.Time,
+) ([]*models.ExpiringPointsNotice, error) {
+	query := `WITH notified AS (
+		UPDATE point_lot SET notified_at = $1
+		WHERE id IN (
+			SELECT id FROM point_lot
+			WHERE expires_at > $1 AND expires_at <= $2 AND remaining > 0 AND notified_at IS NULL
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING user_id, remaining, expires_at
+	)
+	SELECT user_id, SUM(remaining), MIN(expires_at) FROM notified GROUP BY user_id`
+
+	tx, err := br.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for expiring point lots %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, query, now, until)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for expiring point lots %w", err)
+	}
+
+	var (
+		notices []*models.ExpiringPointsNotice
+		pending []pendingEvent
+	)
+	for rows.Next() {
+		var notice models.ExpiringPointsNotice
+		if err = rows.Scan(&notice.UserID, &notice.Amount, &notice.ExpiresAt); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("error scanning row for expiring points %w", err)
+		}
+		notices = append(notices, &notice)
+		pending = append(pending, pendingEvent{
+			userID:    notice.UserID,
+			eventType: models.EventPointsExpiring,
+			payload:   &models.PointsExpiringEvent{Amount: notice.Amount, ExpiresAt: notice.ExpiresAt},
+		})
+	}
+	_ = rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	if _, err = addEvents(ctx, tx, pending...); err != nil {
+		return nil, err
+	}
+
+	return notices, nil
+}
+
+func (br *BalanceRepo) GetExpiringPoints(
+	ctx context.Context,
+	userID int,
+	until time.Time,
+) ([]*models.ExpiringPoints, error) {
+	query := `SELECT SUM(remaining), MIN(expires_at) FROM point_lot
+	WHERE user_id = $1 AND remaining > 0 AND expires_at <= $2
+	GROUP BY date_trunc('day', expires_at)
+	ORDER BY 2`
+
+	rows, err := br.db.QueryContext(ctx, query, userID, until)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for expiring points %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var expiring []*models.ExpiringPoints
+	for rows.Next() {
+		var points models.ExpiringPoints
+		if err = rows.Scan(&points.Amount, &points.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("error scanning row for expiring points %w", err)
+		}
+		expiring = append(expiring, &points)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return expiring, nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+const programColumns = `id, code, name, COALESCE(host, ''), COALESCE(accrual_addr, ''), token_issuer, created_at`
+
+type ProgramRepo struct {
+	logger *zap.Logger
+	cfg    *config.Config
+	db     *sql.DB
+}
+
+func NewProgramRepo(logger *zap.Logger, cfg *config.Config, db *sql.DB) *ProgramRepo {
+	return &ProgramRepo{
+		logger: logger,
+		cfg:    cfg,
+		db:     db,
+	}
+}
+
+func scanProgram(row interface{ Scan(dest ...any) error }) (*models.Program, error) {
+	var p models.Program
+	if err := row.Scan(&p.ID, &p.Code, &p.Name, &p.Host, &p.AccrualAddr, &p.TokenIssuer, &p.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrProgramNotFound
+		}
+		return nil, fmt.Errorf("error scanning row for program %w", err)
+	}
+
+	return &p, nil
+}
+
+func (pr *ProgramRepo) GetProgramByID(ctx context.Context, id int) (*models.Program, error) {
+	query := `SELECT ` + programColumns + ` FROM program WHERE id = $1`
+
+	return scanProgram(pr.db.QueryRowContext(ctx, query, id))
+}
+
+func (pr *ProgramRepo) GetProgramByCode(ctx context.Context, code string) (*models.Program, error) {
+	query := `SELECT ` + programColumns + ` FROM program WHERE code = lower($1)`
+
+	return scanProgram(pr.db.QueryRowContext(ctx, query, code))
+}
+
+func (pr *ProgramRepo) GetProgramByHost(ctx context.Context, host string) (*models.Program, error) {
+	query := `SELECT ` + programColumns + ` FROM program WHERE host = lower($1)`
+
+	return scanProgram(pr.db.QueryRowContext(ctx, query, host))
+}
+
+func (pr *ProgramRepo) GetPrograms(ctx context.Context) ([]*models.Program, error) {
+	query := `SELECT ` + programColumns + ` FROM program ORDER BY id`
+
+	rows, err := pr.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for programs %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	programs := make([]*models.Program, 0)
+	for rows.Next() {
+		var p *models.Program
+		if p, err = scanProgram(rows); err != nil {
+			return nil, err
+		}
+		programs = append(programs, p)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return programs, nil
+}
+
+func (pr *ProgramRepo) CreateProgram(ctx context.Context, p *models.Program) error {
+	query := `INSERT INTO program (code, name, host, accrual_addr, token_issuer)
+	VALUES (lower($1), $2, lower(NULLIF($3, '')), NULLIF($4, ''), $5)
+	RETURNING ` + programColumns
+
+	created, err := scanProgram(pr.db.QueryRowContext(ctx, query,
+		p.Code, p.Name, p.Host, p.AccrualAddr, p.TokenIssuer))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrProgramExists
+		}
+		return fmt.Errorf("error creating program %w", err)
+	}
+	*p = *created
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+)
+
+type sortKey struct {
+	expr string
+	cast string
+}
+
+type queryBuilder struct {
+	conds []string
+	args  []any
+}
+
+func (qb *queryBuilder) arg(v any) string {
+	qb.args = append(qb.args, v)
+	return "$" + strconv.Itoa(len(qb.args))
+}
+
+func (qb *queryBuilder) where(cond string) {
+	qb.conds = append(qb.conds, cond)
+}
+
+func (qb *queryBuilder) whereSQL() string {
+	return strings.Join(qb.conds, " AND ")
+}
+
+func (qb *queryBuilder) keyset(key sortKey, page *models.ListPage) string {
+	direction, cmp := "ASC", ">"
+	if page.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	if page.Cursor != nil {
+		qb.where("(" + key.expr + ", id) " + cmp +
+			" (" + qb.arg(page.Cursor.Value) + "::text::" + key.cast + ", " + qb.arg(page.Cursor.ID) + ")")
+	}
+
+	// берём на одну строку больше, чтобы понять, есть ли следующая страница
+	return " ORDER BY " + key.expr + " " + direction + ", id " + direction + " LIMIT " + qb.arg(page.Limit+1)
+}
+
+func nextCursor(page *models.ListPage, lastValue string, lastID int) *models.Cursor {
+	return &models.Cursor{
+		Sort:  page.Sort,
+		Desc:  page.Desc,
+		Value: lastValue,
+		ID:    lastID,
+	}
+}
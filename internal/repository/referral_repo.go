@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"go.uber.org/zap"
+)
+
+const lotSourceReferral = "referral"
+
+type ReferralRepo struct {
+	logger *zap.Logger
+	cfg    *config.Config
+	db     *sql.DB
+}
+
+func NewReferralRepo(logger *zap.Logger, cfg *config.Config, db *sql.DB) *ReferralRepo {
+	return &ReferralRepo{
+		logger: logger,
+		cfg:    cfg,
+		db:     db,
+	}
+}
+
+func (rr *ReferralRepo) GetUserIDByInviteCode(ctx context.Context, programID int, code string) (int, error) {
+	query := `SELECT id FROM "user" WHERE invite_code = upper($1) AND program_id = $2`
+
+	var id int
+	if err := rr.db.QueryRowContext(ctx, query, code, programID).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrReferralCodeNotFound
+		}
+		return 0, fmt.Errorf("error scanning row for invite code %w", err)
+	}
+
+	return id, nil
+}
+
+// CreateReferral сверх limit приглашение сохраняется отклонённым.
+func (rr *ReferralRepo) CreateReferral(
+	ctx context.Context,
+	referrerID, refereeID int,
+	limit int,
+) (*models.Referral, error) {
+	lockQuery := `SELECT id FROM "user" WHERE id = $1 FOR UPDATE`
+	countQuery := `SELECT count(*) FROM referral WHERE referrer_id = $1 AND status <> 'REJECTED'`
+	insertQuery := `INSERT INTO referral (referrer_id, referee_id, status, reason) VALUES ($1, $2, $3, NULLIF($4, ''))
+	RETURNING id`
+
+	tx, err := rr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for create referral %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	// блокировка пригласившего сериализует параллельные регистрации по одному коду
+	var locked int
+	if err = tx.QueryRowContext(ctx, lockQuery, referrerID).Scan(&locked); err != nil {
+		return nil, fmt.Errorf("error locking referrer %d: %w", referrerID, err)
+	}
+
+	var active int
+	if err = tx.QueryRowContext(ctx, countQuery, referrerID).Scan(&active); err != nil {
+		return nil, fmt.Errorf("error counting referrals of user %d: %w", referrerID, err)
+	}
+
+	ref := &models.Referral{
+		ReferrerID: referrerID,
+		RefereeID:  refereeID,
+		Status:     models.ReferralPending,
+	}
+	if active >= limit {
+		ref.Status = models.ReferralRejected
+		ref.Reason = "referrer limit reached"
+	}
+
+	err = tx.QueryRowContext(ctx, insertQuery, referrerID, refereeID, ref.Status, ref.Reason).Scan(&ref.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error executing context for create referral %w", err)
+	}
+
+	return ref, nil
+}
+
+func (rr *ReferralRepo) HoldReferral(
+	ctx context.Context,
+	refereeID int,
+	orderNumber string,
+	releaseAt time.Time,
+) (bool, error) {
+	query := `UPDATE referral SET status = 'HELD', order_number = $2, release_at = $3
+	WHERE referee_id = $1 AND status = 'PENDING'`
+
+	res, err := rr.db.ExecContext(ctx, query, refereeID, orderNumber, releaseAt)
+	if err != nil {
+		return false, fmt.Errorf("error executing context for hold referral %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error getting affected rows for hold referral %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+func (rr *ReferralRepo) GetReferralStats(ctx context.Context, userID int) (*models.ReferralStats, error) {
+	query := `SELECT
+		u.invite_code,
+		count(r.id),
+		count(r.id) FILTER (WHERE r.status = 'PENDING'),
+		count(r.id) FILTER (WHERE r.status = 'HELD'),
+		count(r.id) FILTER (WHERE r.status = 'REWARDED'),
+		count(r.id) FILTER (WHERE r.status = 'REJECTED'),
+		COALESCE(SUM(r.referrer_points) FILTER (WHERE r.status = 'REWARDED'), 0)
+	FROM "user" u
+	LEFT JOIN referral r ON r.referrer_id = u.id
+	WHERE u.id = $1
+	GROUP BY u.invite_code`
+
+	var stats models.ReferralStats
+	err := rr.db.QueryRowContext(ctx, query, userID).Scan(
+		&stats.InviteCode,
+		&stats.Invited,
+		&stats.Pending,
+		&stats.Held,
+		&stats.Rewarded,
+		&stats.Rejected,
+		&stats.PointsEarned,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserIDNotFound
+		}
+		return nil, fmt.Errorf("error scanning row for referral stats %w", err)
+	}
+
+	return &stats, nil
+}
+
+// CreditReferralRewards приглашение отклоняется, если заказ перестал быть обработанным.
+func (br *BalanceRepo) CreditReferralRewards(
+	ctx context.Context,
+	now time.Time,
+	referrerPoints, refereePoints float64,
+	expiresAt time.Time,
+) ([]*models.ReferralReward, error) {
+	selectQuery := `SELECT r.id, r.referrer_id, r.referee_id, r.order_number, COALESCE(o.status = 'PROCESSED', false)
+	FROM referral r
+	LEFT JOIN "order" o ON o.number = r.order_number AND o.user_id = r.referee_id
+	WHERE r.status = 'HELD' AND r.release_at <= $1
+	ORDER BY r.release_at, r.id
+	FOR UPDATE OF r SKIP LOCKED`
+	rejectQuery := `UPDATE referral SET status = 'REJECTED', reason = $2 WHERE id = $1`
+	rewardQuery := `UPDATE referral
+	SET status = 'REWARDED', referrer_points = $2, referee_points = $3, rewarded_at = $4
+	WHERE id = $1`
+	balanceQuery := `UPDATE balance SET current = current + $1 WHERE user_id = $2 RETURNING current, withdrawn`
+
+	tx, err := br.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for referral rewards %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	rows, err := tx.QueryContext(ctx, selectQuery, now)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for held referrals %w", err)
+	}
+
+	type heldReferral struct {
+		ref       *models.Referral
+		processed bool
+	}
+	var held []heldReferral
+	for rows.Next() {
+		ref := &models.Referral{Status: models.ReferralHeld}
+		var processed bool
+		if err = rows.Scan(&ref.ID, &ref.ReferrerID, &ref.RefereeID, &ref.OrderNumber, &processed); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("error scanning row for held referral %w", err)
+		}
+		held = append(held, heldReferral{ref: ref, processed: processed})
+	}
+	_ = rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	var (
+		rewards []*models.ReferralReward
+		pending []pendingEvent
+	)
+	for _, h := range held {
+		if !h.processed {
+			h.ref.Status = models.ReferralRejected
+			h.ref.Reason = "order is no longer processed"
+			if _, err = tx.ExecContext(ctx, rejectQuery, h.ref.ID, h.ref.Reason); err != nil {
+				return nil, fmt.Errorf("error executing context for reject referral %d: %w", h.ref.ID, err)
+			}
+			continue
+		}
+
+		h.ref.Status = models.ReferralRewarded
+		h.ref.ReferrerPoints = referrerPoints
+		h.ref.RefereePoints = refereePoints
+		_, err = tx.ExecContext(ctx, rewardQuery, h.ref.ID, referrerPoints, refereePoints, now)
+		if err != nil {
+			return nil, fmt.Errorf("error executing context for reward referral %d: %w", h.ref.ID, err)
+		}
+
+		reward := &models.ReferralReward{
+			Referral:        h.ref,
+			ReferrerBalance: &models.Balance{},
+			RefereeBalance:  &models.Balance{},
+		}
+		for _, credit := range []struct {
+			userID  int
+			points  float64
+			balance *models.Balance
+		}{
+			{h.ref.ReferrerID, referrerPoints, reward.ReferrerBalance},
+			{h.ref.RefereeID, refereePoints, reward.RefereeBalance},
+		} {
+			err = tx.QueryRowContext(ctx, balanceQuery, credit.points, credit.userID).
+				Scan(&credit.balance.Current, &credit.balance.Withdrawn)
+			if err != nil {
+				return nil, fmt.Errorf("error executing context for update user balance %w", err)
+			}
+			err = addLot(ctx, tx, credit.userID, lotSourceReferral, h.ref.OrderNumber, credit.points, expiresAt)
+			if err != nil {
+				return nil, fmt.Errorf("error opening point lot for referral %d: %w", h.ref.ID, err)
+			}
+		}
+		rewards = append(rewards, reward)
+		pending = append(pending,
+			balanceEvent(h.ref.ReferrerID, reward.ReferrerBalance),
+			balanceEvent(h.ref.RefereeID, reward.RefereeBalance))
+	}
+
+	if _, err = addEvents(ctx, tx, pending...); err != nil {
+		return nil, err
+	}
+
+	return rewards, nil
+}
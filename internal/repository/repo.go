@@ -9,5 +9,47 @@ var ErrOrderNumberExist error = errors.New("order with provided number already e
 var ErrOrderByUserExist error = errors.New("order with provided number already created by this user")
 var ErrOrdersNotFound error = errors.New("orders by user not found")
 var ErrOrderNumberNotFound error = errors.New("order with provided number is not found")
+var ErrOrderStatusConflict error = errors.New("order status does not allow this action")
 
 var ErrEmptyBalanceHistory error = errors.New("empty history of withdraws")
+
+var ErrUnknownSort error = errors.New("unknown sort field")
+
+var ErrCampaignNotFound error = errors.New("campaign not found")
+var ErrCampaignInUse error = errors.New("campaign has granted points and can not be deleted")
+
+var ErrReferralCodeNotFound error = errors.New("referral code not found")
+
+var ErrTransferNotFound error = errors.New("transfer not found")
+var ErrTransferExpired error = errors.New("transfer confirmation expired")
+var ErrTransferDailyLimit error = errors.New("transfer exceeds daily limit")
+var ErrInsufficientBalance error = errors.New("balance is not enough for transfer")
+
+var ErrHoldNotFound error = errors.New("hold not found")
+var ErrHoldNotActive error = errors.New("hold is already settled")
+var ErrWithdrawalExists error = errors.New("withdrawal for this order already exists")
+
+var ErrAdjustmentNotFound error = errors.New("adjustment not found")
+var ErrAdjustmentResolved error = errors.New("adjustment is already resolved")
+
+var ErrProgramNotFound error = errors.New("loyalty program not found")
+var ErrProgramExists error = errors.New("loyalty program with provided code or host already exists")
+
+var ErrMerchantNotFound error = errors.New("merchant not found")
+var ErrMerchantExists error = errors.New("merchant with provided code already exists")
+var ErrReceiptExists error = errors.New("receipt with provided number already registered")
+var ErrReceiptNotFound error = errors.New("receipt not found")
+
+var ErrVoucherBatchNotFound error = errors.New("voucher batch not found")
+var ErrVoucherNotFound error = errors.New("voucher not found")
+var ErrVoucherExpired error = errors.New("voucher is expired or voided")
+var ErrVoucherUsedUp error = errors.New("voucher has no uses left")
+var ErrVoucherRedeemed error = errors.New("voucher is already redeemed by this user")
+
+var ErrRewardNotFound error = errors.New("reward not found")
+var ErrRewardOutOfStock error = errors.New("reward is out of stock")
+var ErrRewardLimitReached error = errors.New("user has reached the limit for this reward")
+
+var ErrExportNotFound error = errors.New("data export not found")
+var ErrExportExpired error = errors.New("data export archive is expired")
+var ErrExportChunkNotFound error = errors.New("data export archive chunk not found")
@@ -0,0 +1,270 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"go.uber.org/zap"
+)
+
+const rewardColumns = `id, program_id, name, COALESCE(description, ''), price, stock, user_limit, active,
+	created_at, updated_at`
+
+type RewardRepo struct {
+	logger *zap.Logger
+	cfg    *config.Config
+	db     *sql.DB
+}
+
+func NewRewardRepo(logger *zap.Logger, cfg *config.Config, db *sql.DB) *RewardRepo {
+	return &RewardRepo{
+		logger: logger,
+		cfg:    cfg,
+		db:     db,
+	}
+}
+
+func scanReward(row interface{ Scan(dest ...any) error }) (*models.Reward, error) {
+	var (
+		r                models.Reward
+		stock, userLimit sql.NullInt64
+	)
+	if err := row.Scan(&r.ID, &r.ProgramID, &r.Name, &r.Description, &r.Price, &stock, &userLimit, &r.Active,
+		&r.CreatedAt, &r.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRewardNotFound
+		}
+		return nil, fmt.Errorf("error scanning row for reward %w", err)
+	}
+	r.Stock = nullIntPtr(stock)
+	r.UserLimit = nullIntPtr(userLimit)
+
+	return &r, nil
+}
+
+func nullIntPtr(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
+
+func (rr *RewardRepo) CreateReward(ctx context.Context, r *models.Reward) error {
+	query := `INSERT INTO reward (program_id, name, description, price, stock, user_limit, active)
+	VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7)
+	RETURNING ` + rewardColumns
+
+	created, err := scanReward(rr.db.QueryRowContext(ctx, query,
+		r.ProgramID, r.Name, r.Description, r.Price, r.Stock, r.UserLimit, r.Active))
+	if err != nil {
+		return fmt.Errorf("error creating reward %w", err)
+	}
+	*r = *created
+
+	return nil
+}
+
+func (rr *RewardRepo) UpdateReward(ctx context.Context, r *models.Reward) error {
+	query := `UPDATE reward
+	SET name = $1, description = NULLIF($2, ''), price = $3, stock = $4, user_limit = $5, active = $6,
+		updated_at = now()
+	WHERE id = $7 AND program_id = $8
+	RETURNING ` + rewardColumns
+
+	updated, err := scanReward(rr.db.QueryRowContext(ctx, query,
+		r.Name, r.Description, r.Price, r.Stock, r.UserLimit, r.Active, r.ID, r.ProgramID))
+	if err != nil {
+		return fmt.Errorf("error updating reward %d: %w", r.ID, err)
+	}
+	*r = *updated
+
+	return nil
+}
+
+func (rr *RewardRepo) GetRewards(ctx context.Context, programID int, activeOnly bool) ([]*models.Reward, error) {
+	query := `SELECT ` + rewardColumns + ` FROM reward
+	WHERE program_id = $1 AND (active OR NOT $2)
+	ORDER BY price, id`
+
+	rows, err := rr.db.QueryContext(ctx, query, programID, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for rewards %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	rewards := make([]*models.Reward, 0)
+	for rows.Next() {
+		var r *models.Reward
+		if r, err = scanReward(rows); err != nil {
+			return nil, err
+		}
+		rewards = append(rewards, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return rewards, nil
+}
+
+func (rr *RewardRepo) GetReward(ctx context.Context, programID, id int) (*models.Reward, error) {
+	query := `SELECT ` + rewardColumns + ` FROM reward WHERE id = $1 AND program_id = $2`
+
+	return scanReward(rr.db.QueryRowContext(ctx, query, id, programID))
+}
+
+// RedeemReward строка награды блокируется, поэтому запас и лимит не превышаются.
+func (rr *RewardRepo) RedeemReward(
+	ctx context.Context,
+	user *models.User,
+	rewardID int,
+	now time.Time,
+) (*models.RewardRedemption, error) {
+	lockQuery := `SELECT name, price, stock, user_limit FROM reward
+	WHERE id = $1 AND program_id = $2 AND active
+	FOR UPDATE`
+	countQuery := `SELECT COUNT(*) FROM reward_redemption WHERE reward_id = $1 AND user_id = $2`
+	balanceQuery := `UPDATE balance SET current = current - $1, withdrawn = withdrawn + $1
+	WHERE user_id = $2 AND current >= $1
+	RETURNING current, withdrawn, held`
+	stockQuery := `UPDATE reward SET stock = stock - 1 WHERE id = $1 AND stock IS NOT NULL`
+	insertQuery := `INSERT INTO reward_redemption (reward_id, user_id, code, price, redeemed_at)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (code) DO NOTHING
+	RETURNING id`
+
+	tx, err := rr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for redeem reward %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	redemption := &models.RewardRedemption{RewardID: rewardID, RedeemedAt: now}
+	var stock, userLimit sql.NullInt64
+	err = tx.QueryRowContext(ctx, lockQuery, rewardID, user.ProgramID).
+		Scan(&redemption.RewardName, &redemption.Price, &stock, &userLimit)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrRewardNotFound
+			return nil, err
+		}
+		return nil, fmt.Errorf("error scanning row for reward %d: %w", rewardID, err)
+	}
+	if stock.Valid && stock.Int64 <= 0 {
+		err = ErrRewardOutOfStock
+		return nil, err
+	}
+	if userLimit.Valid {
+		var redeemed int64
+		if err = tx.QueryRowContext(ctx, countQuery, rewardID, user.ID).Scan(&redeemed); err != nil {
+			return nil, fmt.Errorf("error scanning row for user redemptions of reward %d: %w", rewardID, err)
+		}
+		if redeemed >= userLimit.Int64 {
+			err = ErrRewardLimitReached
+			return nil, err
+		}
+	}
+
+	var balance models.Balance
+	err = tx.QueryRowContext(ctx, balanceQuery, redemption.Price, user.ID).
+		Scan(&balance.Current, &balance.Withdrawn, &balance.Held)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrInsufficientBalance
+			return nil, err
+		}
+		return nil, fmt.Errorf("error executing context for reward debit %w", err)
+	}
+	if _, err = consumeLots(ctx, tx, user.ID, redemption.Price); err != nil {
+		return nil, fmt.Errorf("error consuming point lots for reward %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, stockQuery, rewardID); err != nil {
+		return nil, fmt.Errorf("error executing context for reward %d stock: %w", rewardID, err)
+	}
+
+	// совпадение кода с уже выданным маловероятно, но тогда код просто генерируется заново
+	for redemption.ID == 0 {
+		if redemption.Code, err = models.NewVoucherCode(); err != nil {
+			return nil, fmt.Errorf("error generating reward code %w", err)
+		}
+		err = tx.QueryRowContext(ctx, insertQuery, rewardID, user.ID, redemption.Code, redemption.Price, now).
+			Scan(&redemption.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("error executing context for insert reward redemption %w", err)
+		}
+	}
+	redemption.Balance = &balance
+
+	if _, err = addEvents(ctx, tx, balanceEvent(user.ID, &balance)); err != nil {
+		return nil, err
+	}
+
+	return redemption, nil
+}
+
+func (rr *RewardRepo) GetRedemptions(
+	ctx context.Context,
+	userID int,
+	page *models.ListPage,
+) ([]*models.RewardRedemption, *models.Cursor, error) {
+	qb := &queryBuilder{}
+	qb.where("user_id = " + qb.arg(userID))
+	orderBy := qb.keyset(sortKey{expr: "redeemed_at", cast: "timestamptz"}, page)
+
+	query := `SELECT id, reward_id, reward_name, code, price, redeemed_at, redeemed_at::text
+	FROM (
+		SELECT rr.id, rr.user_id, rr.reward_id, r.name AS reward_name, rr.code, rr.price, rr.redeemed_at
+		FROM reward_redemption rr
+		JOIN reward r ON r.id = rr.reward_id
+	) h
+	WHERE ` + qb.whereSQL() + orderBy
+
+	rows, err := rr.db.QueryContext(ctx, query, qb.args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error query context for reward redemptions %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var (
+		redemptions []*models.RewardRedemption
+		lastKey     string
+		cursor      *models.Cursor
+	)
+	for rows.Next() {
+		var (
+			r         models.RewardRedemption
+			sortValue string
+		)
+		err = rows.Scan(&r.ID, &r.RewardID, &r.RewardName, &r.Code, &r.Price, &r.RedeemedAt, &sortValue)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error scanning row for reward redemption %w", err)
+		}
+		if len(redemptions) == page.Limit {
+			cursor = nextCursor(page, lastKey, redemptions[len(redemptions)-1].ID)
+			break
+		}
+		redemptions = append(redemptions, &r)
+		lastKey = sortValue
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return redemptions, cursor, nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+)
+
+func (br *BalanceRepo) GetStatementTotals(ctx context.Context, user *models.User, statement *models.Statement) error {
+	query := `SELECT
+		COALESCE(SUM(l.amount) FILTER (WHERE $2::timestamptz IS NOT NULL AND l.occurred_at < $2), 0),
+		COALESCE(SUM(l.amount) FILTER (WHERE l.amount > 0 AND ($2::timestamptz IS NULL OR l.occurred_at >= $2)), 0),
+		COALESCE(-SUM(l.amount) FILTER (WHERE l.amount < 0 AND ($2::timestamptz IS NULL OR l.occurred_at >= $2)), 0)
+	FROM balance_ledger l
+	JOIN "user" u ON u.id = l.user_id
+	WHERE l.user_id = $1 AND u.program_id = $4 AND l.occurred_at < $3`
+
+	ctx, cancel := context.WithTimeout(ctx, br.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	row := br.db.QueryRowContext(ctx, query, user.ID, statement.From, statement.To, user.ProgramID)
+	if err := row.Scan(&statement.OpeningBalance, &statement.Credits, &statement.Debits); err != nil {
+		return fmt.Errorf("error scanning row for statement totals %w", err)
+	}
+	statement.ClosingBalance = statement.OpeningBalance + statement.Credits - statement.Debits
+
+	return nil
+}
+
+// StreamStatementEntries передаёт движения в fn построчно, не накапливая их в памяти.
+func (br *BalanceRepo) StreamStatementEntries(
+	ctx context.Context,
+	user *models.User,
+	from *time.Time,
+	to time.Time,
+	fn func(entry *models.StatementEntry) error,
+) error {
+	query := `SELECT l.occurred_at, l.kind, l.reference, l.amount FROM balance_ledger l
+	JOIN "user" u ON u.id = l.user_id
+	WHERE l.user_id = $1 AND u.program_id = $4
+	AND ($2::timestamptz IS NULL OR l.occurred_at >= $2) AND l.occurred_at < $3
+	ORDER BY l.occurred_at, l.kind, l.reference`
+
+	rows, err := br.db.QueryContext(ctx, query, user.ID, from, to, user.ProgramID)
+	if err != nil {
+		return fmt.Errorf("error query context for statement entries %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var entry models.StatementEntry
+	for rows.Next() {
+		if err = rows.Scan(&entry.OccurredAt, &entry.Kind, &entry.Reference, &entry.Amount); err != nil {
+			return fmt.Errorf("error scanning row for statement entry %w", err)
+		}
+		if err = fn(&entry); err != nil {
+			return fmt.Errorf("error handling statement entry %w", err)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"go.uber.org/zap"
+)
+
+type TierRepo struct {
+	logger *zap.Logger
+	cfg    *config.Config
+	db     *sql.DB
+}
+
+func NewTierRepo(logger *zap.Logger, cfg *config.Config, db *sql.DB) *TierRepo {
+	return &TierRepo{
+		logger: logger,
+		cfg:    cfg,
+		db:     db,
+	}
+}
+
+const tierColumns = `t.id, t.code, t.name, t.rank, t.min_points, t.min_spend, t.multiplier,
+	t.max_withdrawal, t.monthly_withdrawal_limit`
+
+func scanTier(row interface{ Scan(dest ...any) error }, tier *models.Tier, extra ...any) error {
+	var minSpend, maxWithdrawal, monthlyLimit sql.NullFloat64
+	dest := append([]any{
+		&tier.ID, &tier.Code, &tier.Name, &tier.Rank, &tier.MinPoints, &minSpend, &tier.Multiplier,
+		&maxWithdrawal, &monthlyLimit,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return fmt.Errorf("error scanning tier columns %w", err)
+	}
+
+	tier.MinSpend = nullFloatPtr(minSpend)
+	tier.MaxWithdrawal = nullFloatPtr(maxWithdrawal)
+	tier.MonthlyWithdrawalLimit = nullFloatPtr(monthlyLimit)
+	return nil
+}
+
+func nullFloatPtr(v sql.NullFloat64) *float64 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Float64
+}
+
+func (tr *TierRepo) GetTiers(ctx context.Context) ([]*models.Tier, error) {
+	query := `SELECT ` + tierColumns + ` FROM tier t ORDER BY t.rank`
+
+	rows, err := tr.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for tiers %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var tiers []*models.Tier
+	for rows.Next() {
+		var tier models.Tier
+		if err = scanTier(rows, &tier); err != nil {
+			return nil, fmt.Errorf("error scanning row for tier %w", err)
+		}
+		tiers = append(tiers, &tier)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return tiers, nil
+}
+
+func (tr *TierRepo) GetUserTier(ctx context.Context, userID int) (*models.UserTier, error) {
+	query := `WITH h AS (
+		SELECT tier_id, assigned_at FROM user_tier_history WHERE user_id = $1 ORDER BY id DESC LIMIT 1
+	)
+	SELECT ` + tierColumns + `, h.assigned_at FROM tier t
+	LEFT JOIN h ON h.tier_id = t.id
+	WHERE t.id = COALESCE((SELECT tier_id FROM h), (SELECT id FROM tier ORDER BY rank LIMIT 1))`
+
+	var tier models.Tier
+	var assignedAt sql.NullTime
+	if err := scanTier(tr.db.QueryRowContext(ctx, query, userID), &tier, &assignedAt); err != nil {
+		return nil, fmt.Errorf("error scanning row for user tier %w", err)
+	}
+
+	userTier := &models.UserTier{Tier: &tier}
+	if assignedAt.Valid {
+		userTier.AssignedAt = &assignedAt.Time
+	}
+	return userTier, nil
+}
+
+func (tr *TierRepo) GetTierStats(ctx context.Context, since time.Time) ([]*models.TierStats, error) {
+	query := `SELECT u.id,
+		COALESCE((SELECT SUM(l.amount) FROM balance_ledger l
+			WHERE l.user_id = u.id AND l.kind = 'accrual' AND l.occurred_at >= $1), 0),
+		COALESCE((SELECT SUM(o.purchase_total) FROM "order" o
+			WHERE o.user_id = u.id AND o.status = 'PROCESSED' AND o.uploaded_at >= $1), 0),
+		COALESCE((SELECT h.tier_id FROM user_tier_history h
+			WHERE h.user_id = u.id ORDER BY h.id DESC LIMIT 1), 0)
+	FROM "user" u`
+
+	rows, err := tr.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for tier stats %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var stats []*models.TierStats
+	for rows.Next() {
+		var s models.TierStats
+		if err = rows.Scan(&s.UserID, &s.Points, &s.Spend, &s.CurrentTierID); err != nil {
+			return nil, fmt.Errorf("error scanning row for tier stats %w", err)
+		}
+		stats = append(stats, &s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return stats, nil
+}
+
+func (tr *TierRepo) AssignTier(ctx context.Context, stats *models.TierStats, tier *models.Tier) error {
+	query := `INSERT INTO user_tier_history (user_id, tier_id, points, spend) VALUES ($1, $2, $3, $4)`
+
+	if _, err := tr.db.ExecContext(ctx, query, stats.UserID, tier.ID, stats.Points, stats.Spend); err != nil {
+		return fmt.Errorf("error executing context for assign tier %w", err)
+	}
+
+	return nil
+}
+
+func (tr *TierRepo) GetUserTierHistory(ctx context.Context, userID int) ([]*models.TierChange, error) {
+	query := `SELECT t.code, t.name, h.points, h.spend, h.assigned_at FROM user_tier_history h
+	JOIN tier t ON t.id = h.tier_id
+	WHERE h.user_id = $1
+	ORDER BY h.id DESC`
+
+	rows, err := tr.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for user tier history %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var history []*models.TierChange
+	for rows.Next() {
+		var change models.TierChange
+		if err = rows.Scan(&change.Code, &change.Name, &change.Points, &change.Spend, &change.AssignedAt); err != nil {
+			return nil, fmt.Errorf("error scanning row for user tier history %w", err)
+		}
+		history = append(history, &change)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return history, nil
+}
@@ -0,0 +1,374 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"go.uber.org/zap"
+)
+
+const lotSourceTransfer = "transfer"
+
+type TransferRepo struct {
+	logger *zap.Logger
+	cfg    *config.Config
+	db     *sql.DB
+}
+
+func NewTransferRepo(logger *zap.Logger, cfg *config.Config, db *sql.DB) *TransferRepo {
+	return &TransferRepo{
+		logger: logger,
+		cfg:    cfg,
+		db:     db,
+	}
+}
+
+func (tr *TransferRepo) CreatePendingTransfer(
+	ctx context.Context,
+	senderID, recipientID int,
+	t *models.Transfer,
+	expiresAt time.Time,
+) error {
+	query := `INSERT INTO transfer (sender_id, recipient_id, amount, comment, status, expires_at)
+	VALUES ($1, $2, $3, NULLIF($4, ''), 'PENDING', $5)
+	RETURNING id, created_at`
+
+	err := tr.db.QueryRowContext(ctx, query, senderID, recipientID, t.Amount, t.Comment, expiresAt).
+		Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error executing context for create pending transfer %w", err)
+	}
+	t.Direction = models.TransferOut
+	t.Status = models.TransferPending
+	t.ExpiresAt = &expiresAt
+
+	return nil
+}
+
+func (tr *TransferRepo) CreateTransfer(
+	ctx context.Context,
+	senderID, recipientID int,
+	t *models.Transfer,
+	limits *models.TransferLimits,
+) (*models.TransferResult, error) {
+	insertQuery := `INSERT INTO transfer (sender_id, recipient_id, amount, comment, status, completed_at)
+	VALUES ($1, $2, $3, NULLIF($4, ''), 'COMPLETED', now())
+	RETURNING id, created_at, completed_at`
+
+	tx, err := tr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for transfer %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	// сначала блокируем балансы, чтобы параллельные переводы отправителя проверяли лимит по одним данным
+	result, err := moveTransferPoints(ctx, tx, senderID, recipientID, t.Amount, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	var completedAt time.Time
+	err = tx.QueryRowContext(ctx, insertQuery, senderID, recipientID, t.Amount, t.Comment).
+		Scan(&t.ID, &t.CreatedAt, &completedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error executing context for create transfer %w", err)
+	}
+	t.Direction = models.TransferOut
+	t.Status = models.TransferCompleted
+	t.CompletedAt = &completedAt
+
+	if err = addTransferLots(ctx, tx, recipientID, t.ID, result.lots, t.Amount, limits.LotExpiry); err != nil {
+		return nil, err
+	}
+	_, err = addEvents(ctx, tx, balanceEvent(senderID, result.sender), balanceEvent(recipientID, result.recipient))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TransferResult{
+		Transfer:         t,
+		RecipientID:      recipientID,
+		SenderBalance:    result.sender,
+		RecipientBalance: result.recipient,
+	}, nil
+}
+
+func (tr *TransferRepo) ConfirmTransfer(
+	ctx context.Context,
+	senderID, transferID int,
+	now time.Time,
+	limits *models.TransferLimits,
+) (*models.TransferResult, error) {
+	selectQuery := `SELECT
+		t.recipient_id, u.login, t.amount, COALESCE(t.comment, ''), t.status, t.created_at, t.expires_at
+	FROM transfer t
+	JOIN "user" u ON u.id = t.recipient_id
+	WHERE t.id = $1 AND t.sender_id = $2
+	FOR UPDATE OF t`
+	expireQuery := `UPDATE transfer SET status = 'EXPIRED' WHERE id = $1`
+	completeQuery := `UPDATE transfer SET status = 'COMPLETED', completed_at = $2 WHERE id = $1`
+
+	tx, err := tr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for confirm transfer %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	t := &models.Transfer{ID: transferID, Direction: models.TransferOut}
+	var (
+		recipientID int
+		expiresAt   sql.NullTime
+	)
+	err = tx.QueryRowContext(ctx, selectQuery, transferID, senderID).Scan(
+		&recipientID, &t.Counterparty, &t.Amount, &t.Comment, &t.Status, &t.CreatedAt, &expiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+			return nil, ErrTransferNotFound
+		}
+		return nil, fmt.Errorf("error scanning row for transfer %d: %w", transferID, err)
+	}
+	if t.Status != models.TransferPending {
+		// повторное подтверждение проведённого перевода ничего не меняет
+		err = nil
+		return nil, ErrTransferNotFound
+	}
+	if expiresAt.Valid && !now.Before(expiresAt.Time) {
+		if _, err = tx.ExecContext(ctx, expireQuery, transferID); err != nil {
+			return nil, fmt.Errorf("error executing context for expire transfer %d: %w", transferID, err)
+		}
+		return nil, ErrTransferExpired
+	}
+
+	result, err := moveTransferPoints(ctx, tx, senderID, recipientID, t.Amount, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.ExecContext(ctx, completeQuery, transferID, now); err != nil {
+		return nil, fmt.Errorf("error executing context for complete transfer %d: %w", transferID, err)
+	}
+	t.Status = models.TransferCompleted
+	t.CompletedAt = &now
+
+	if err = addTransferLots(ctx, tx, recipientID, t.ID, result.lots, t.Amount, limits.LotExpiry); err != nil {
+		return nil, err
+	}
+	_, err = addEvents(ctx, tx, balanceEvent(senderID, result.sender), balanceEvent(recipientID, result.recipient))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TransferResult{
+		Transfer:         t,
+		RecipientID:      recipientID,
+		SenderBalance:    result.sender,
+		RecipientBalance: result.recipient,
+	}, nil
+}
+
+type transferMove struct {
+	sender    *models.Balance
+	recipient *models.Balance
+	lots      []consumedLot
+}
+
+// moveTransferPoints блокирует оба баланса в порядке user_id.
+func moveTransferPoints(
+	ctx context.Context,
+	tx *sql.Tx,
+	senderID, recipientID int,
+	amount float64,
+	limits *models.TransferLimits,
+) (*transferMove, error) {
+	lockQuery := `SELECT user_id, current FROM balance WHERE user_id IN ($1, $2) ORDER BY user_id FOR UPDATE`
+	sentQuery := `SELECT COALESCE(SUM(amount), 0) FROM transfer
+	WHERE sender_id = $1 AND status = 'COMPLETED' AND completed_at >= $2`
+	balanceQuery := `UPDATE balance SET current = current + $1 WHERE user_id = $2 RETURNING current, withdrawn`
+
+	rows, err := tx.QueryContext(ctx, lockQuery, senderID, recipientID)
+	if err != nil {
+		return nil, fmt.Errorf("error locking balances for transfer %w", err)
+	}
+	var senderCurrent float64
+	locked := 0
+	for rows.Next() {
+		var userID int
+		var current float64
+		if err = rows.Scan(&userID, &current); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("error scanning row for locked balance %w", err)
+		}
+		if userID == senderID {
+			senderCurrent = current
+		}
+		locked++
+	}
+	_ = rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+	if locked != 2 {
+		return nil, fmt.Errorf("error locking balances for transfer: %w", ErrUserIDNotFound)
+	}
+
+	if senderCurrent < amount {
+		return nil, ErrInsufficientBalance
+	}
+
+	var sent float64
+	if err = tx.QueryRowContext(ctx, sentQuery, senderID, limits.DayStart).Scan(&sent); err != nil {
+		return nil, fmt.Errorf("error scanning row for sent today %w", err)
+	}
+	if sent+amount > limits.DailyLimit {
+		return nil, fmt.Errorf("sent %.2f of %.2f today: %w", sent, limits.DailyLimit, ErrTransferDailyLimit)
+	}
+
+	move := &transferMove{sender: &models.Balance{}, recipient: &models.Balance{}}
+	err = tx.QueryRowContext(ctx, balanceQuery, -amount, senderID).Scan(&move.sender.Current, &move.sender.Withdrawn)
+	if err != nil {
+		return nil, fmt.Errorf("error executing context for debit sender balance %w", err)
+	}
+	err = tx.QueryRowContext(ctx, balanceQuery, amount, recipientID).
+		Scan(&move.recipient.Current, &move.recipient.Withdrawn)
+	if err != nil {
+		return nil, fmt.Errorf("error executing context for credit recipient balance %w", err)
+	}
+
+	if move.lots, err = consumeLots(ctx, tx, senderID, amount); err != nil {
+		return nil, fmt.Errorf("error consuming sender point lots %w", err)
+	}
+
+	return move, nil
+}
+
+// addTransferLots переносит сроки сгорания партий отправителя, чтобы перевод не продлевал жизнь баллов.
+func addTransferLots(
+	ctx context.Context,
+	tx *sql.Tx,
+	recipientID, transferID int,
+	lots []consumedLot,
+	amount float64,
+	defaultExpiry time.Time,
+) error {
+	reference := fmt.Sprint(transferID)
+	left := amount
+	for _, lot := range lots {
+		if err := addLot(ctx, tx, recipientID, lotSourceTransfer, reference, lot.taken, lot.expiresAt); err != nil {
+			return fmt.Errorf("error opening point lot for transfer %d: %w", transferID, err)
+		}
+		left -= lot.taken
+	}
+	if err := addLot(ctx, tx, recipientID, lotSourceTransfer, reference, left, defaultExpiry); err != nil {
+		return fmt.Errorf("error opening point lot for transfer %d: %w", transferID, err)
+	}
+
+	return nil
+}
+
+var transferSortKeys = map[string]sortKey{
+	"created_at": {expr: "created_at", cast: "timestamptz"},
+	"amount":     {expr: "amount", cast: "numeric"},
+}
+
+func (tr *TransferRepo) GetUserTransfers(
+	ctx context.Context,
+	userID int,
+	filter *models.TransferListFilter,
+) ([]*models.Transfer, *models.Cursor, error) {
+	key, ok := transferSortKeys[filter.Sort]
+	if !ok {
+		return nil, nil, ErrUnknownSort
+	}
+
+	qb := &queryBuilder{}
+	user := qb.arg(userID)
+	if filter.Direction != "" {
+		qb.where("direction = " + qb.arg(filter.Direction))
+	} else {
+		qb.where("true")
+	}
+	orderBy := qb.keyset(key, &filter.ListPage)
+
+	query := `SELECT id, direction, counterparty, amount, comment, status, created_at, expires_at, completed_at, ` +
+		key.expr + `::text
+	FROM (
+		SELECT
+			t.id,
+			CASE WHEN t.sender_id = ` + user + ` THEN 'out' ELSE 'in' END AS direction,
+			u.login AS counterparty,
+			t.amount,
+			COALESCE(t.comment, '') AS comment,
+			t.status,
+			t.created_at,
+			t.expires_at,
+			t.completed_at
+		FROM transfer t
+		JOIN "user" u ON u.id = CASE WHEN t.sender_id = ` + user + ` THEN t.recipient_id ELSE t.sender_id END
+		WHERE t.sender_id = ` + user + ` OR (t.recipient_id = ` + user + ` AND t.status = 'COMPLETED')
+	) tr
+	WHERE ` + qb.whereSQL() + orderBy
+
+	rows, err := tr.db.QueryContext(ctx, query, qb.args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error query context for user transfers %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var (
+		transfers []*models.Transfer
+		lastKey   string
+		cursor    *models.Cursor
+	)
+	for rows.Next() {
+		var (
+			t           models.Transfer
+			expiresAt   sql.NullTime
+			completedAt sql.NullTime
+			sortValue   string
+		)
+		err = rows.Scan(&t.ID, &t.Direction, &t.Counterparty, &t.Amount, &t.Comment, &t.Status,
+			&t.CreatedAt, &expiresAt, &completedAt, &sortValue)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error scanning row for transfer %w", err)
+		}
+		if len(transfers) == filter.Limit {
+			cursor = nextCursor(&filter.ListPage, lastKey, transfers[len(transfers)-1].ID)
+			break
+		}
+		if expiresAt.Valid && t.Status == models.TransferPending {
+			t.ExpiresAt = &expiresAt.Time
+		}
+		if completedAt.Valid {
+			t.CompletedAt = &completedAt.Time
+		}
+		transfers = append(transfers, &t)
+		lastKey = sortValue
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return transfers, cursor, nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Melikhov-p/go-loyalty-system/internal/config"
 	"github.com/Melikhov-p/go-loyalty-system/internal/models"
@@ -31,17 +32,18 @@ func (ur *UserRepo) NewEmptyUser() *models.User {
 	}
 }
 
-func (ur *UserRepo) GetUserWithID(ctx context.Context, userID int) (*models.User, error) {
-	query := `SELECT login FROM "user" WHERE id=$1`
+func (ur *UserRepo) GetUserWithID(ctx context.Context, programID, userID int) (*models.User, error) {
+	query := `SELECT login, token_version FROM "user" WHERE id=$1 AND program_id=$2 AND deleted_at IS NULL`
 
 	user := ur.NewEmptyUser()
 	user.ID = userID
+	user.ProgramID = programID
 
 	ctxTimeout, cancel := context.WithTimeout(ctx, ur.cfg.DB.ContextTimeout)
 	defer cancel()
 
-	row := ur.db.QueryRowContext(ctxTimeout, query, userID)
-	if err := row.Scan(&user.Login); err != nil {
+	row := ur.db.QueryRowContext(ctxTimeout, query, userID, programID)
+	if err := row.Scan(&user.Login, &user.TokenVersion); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserIDNotFound
 		}
@@ -52,8 +54,8 @@ func (ur *UserRepo) GetUserWithID(ctx context.Context, userID int) (*models.User
 	return user, nil
 }
 
-func (ur *UserRepo) AddUser(ctx context.Context, login string, passHash string) (*models.User, error) {
-	query := `INSERT INTO "user" (login, password) VALUES ($1, $2) RETURNING id`
+func (ur *UserRepo) AddUser(ctx context.Context, programID int, login string, passHash string) (*models.User, error) {
+	query := `INSERT INTO "user" (program_id, login, password) VALUES ($1, $2, $3) RETURNING id, invite_code`
 
 	ctxTimeout, cancel := context.WithTimeout(ctx, ur.cfg.DB.ContextTimeout)
 	defer cancel()
@@ -66,15 +68,16 @@ func (ur *UserRepo) AddUser(ctx context.Context, login string, passHash string)
 		return nil, fmt.Errorf("error starting transaction for create user %w", err)
 	}
 
-	row := tx.QueryRowContext(ctxTimeout, query, login, passHash)
+	row := tx.QueryRowContext(ctxTimeout, query, programID, login, passHash)
 
 	user := ur.NewEmptyUser()
 	user.Login = login
+	user.ProgramID = programID
 
 	var id int64
-	if err = row.Scan(&id); err != nil {
+	if err = row.Scan(&id, &user.InviteCode); err != nil {
 		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName != "user_invite_code_key" {
 			return nil, ErrUserWithLoginExist
 		}
 		return nil, fmt.Errorf("error getting last insert id of user %w", err)
@@ -86,21 +89,25 @@ func (ur *UserRepo) AddUser(ctx context.Context, login string, passHash string)
 	return user, nil
 }
 
-func (ur *UserRepo) GetUserWithLogin(ctx context.Context, login string) (*models.User, error) {
-	query := `SELECT id, password FROM "user" WHERE login=$1`
+func (ur *UserRepo) GetUserWithLogin(ctx context.Context, programID int, login string) (*models.User, error) {
+	query := `SELECT id, password, token_version FROM "user"
+	WHERE program_id=$1 AND login=$2 AND deleted_at IS NULL`
 
 	ctxWithTimeout, cancel := context.WithTimeout(ctx, ur.cfg.DB.ContextTimeout)
 	defer cancel()
 
 	user := ur.NewEmptyUser()
-	row := ur.db.QueryRowContext(ctxWithTimeout, query, login)
-	if err := row.Scan(&user.ID, &user.Password); err != nil {
+	user.Login = login
+	user.ProgramID = programID
+	row := ur.db.QueryRowContext(ctxWithTimeout, query, programID, login)
+	if err := row.Scan(&user.ID, &user.Password, &user.TokenVersion); err != nil {
 		return nil, fmt.Errorf("error scanning row for login user %w", err)
 	}
 
 	return user, nil
 }
 
+// DeleteUserWithID только для отката неудачной регистрации.
 func (ur *UserRepo) DeleteUserWithID(ctx context.Context, id int) error {
 	query := `DELETE FROM "user" WHERE id=$1`
 
@@ -115,6 +122,113 @@ func (ur *UserRepo) DeleteUserWithID(ctx context.Context, id int) error {
 	return nil
 }
 
+func (ur *UserRepo) GetProfile(ctx context.Context, programID, userID int) (*models.Profile, error) {
+	query := `SELECT login, email, phone, display_name FROM "user"
+	WHERE id = $1 AND program_id = $2 AND deleted_at IS NULL`
+
+	var p models.Profile
+	row := ur.db.QueryRowContext(ctx, query, userID, programID)
+	if err := row.Scan(&p.Login, &p.Email, &p.Phone, &p.DisplayName); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserIDNotFound
+		}
+		return nil, fmt.Errorf("error scanning row for user profile %w", err)
+	}
+
+	return &p, nil
+}
+
+func (ur *UserRepo) UpdateProfile(
+	ctx context.Context,
+	programID, userID int,
+	update *models.ProfileUpdate,
+) (*models.Profile, error) {
+	query := `UPDATE "user"
+	SET email = CASE WHEN $2 THEN $3 ELSE email END,
+		phone = CASE WHEN $4 THEN $5 ELSE phone END,
+		display_name = CASE WHEN $6 THEN $7 ELSE display_name END
+	WHERE id = $1 AND program_id = $8 AND deleted_at IS NULL
+	RETURNING login, email, phone, display_name`
+
+	var p models.Profile
+	err := ur.db.QueryRowContext(ctx, query, userID,
+		update.Email.Set, update.Email.Value,
+		update.Phone.Set, update.Phone.Value,
+		update.DisplayName.Set, update.DisplayName.Value, programID).
+		Scan(&p.Login, &p.Email, &p.Phone, &p.DisplayName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserIDNotFound
+		}
+		return nil, fmt.Errorf("error updating user profile %w", err)
+	}
+
+	return &p, nil
+}
+
+func (ur *UserRepo) ChangePassword(ctx context.Context, programID, userID int, passHash string) (int, error) {
+	query := `UPDATE "user" SET password = $2, token_version = token_version + 1
+	WHERE id = $1 AND program_id = $3 AND deleted_at IS NULL
+	RETURNING token_version`
+
+	var version int
+	if err := ur.db.QueryRowContext(ctx, query, userID, passHash, programID).Scan(&version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrUserIDNotFound
+		}
+		return 0, fmt.Errorf("error changing user password %w", err)
+	}
+
+	return version, nil
+}
+
+// AnonymizeUser финансовая история остаётся, стираются только персональные данные.
+func (ur *UserRepo) AnonymizeUser(ctx context.Context, programID, userID int, now time.Time) error {
+	userQuery := `UPDATE "user"
+	SET login = 'deleted-' || gen_random_uuid(), password = '', email = NULL, phone = NULL, display_name = NULL,
+		invite_code = NULL, token_version = token_version + 1, deleted_at = $2
+	WHERE id = $1 AND program_id = $3 AND deleted_at IS NULL`
+	preferencesQuery := `DELETE FROM notification_preference WHERE user_id = $1`
+	notificationsQuery := `DELETE FROM notification WHERE user_id = $1`
+	exportsQuery := `DELETE FROM data_export WHERE user_id = $1`
+
+	tx, err := ur.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction for anonymize user %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, userQuery, userID, now, programID)
+	if err != nil {
+		return fmt.Errorf("error executing context for anonymize user %w", err)
+	}
+	var affected int64
+	if affected, err = res.RowsAffected(); err != nil {
+		return fmt.Errorf("error getting rows affected for anonymize user %w", err)
+	}
+	if affected == 0 {
+		err = ErrUserIDNotFound
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, preferencesQuery, userID); err != nil {
+		return fmt.Errorf("error executing context for delete notification preferences %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, notificationsQuery, userID); err != nil {
+		return fmt.Errorf("error executing context for delete notifications %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, exportsQuery, userID); err != nil {
+		return fmt.Errorf("error executing context for delete data exports %w", err)
+	}
+
+	return nil
+}
+
 func NewUserRepo(logger *zap.Logger, cfg *config.Config, db *sql.DB) *UserRepo {
 	return &UserRepo{
 		logger: logger,
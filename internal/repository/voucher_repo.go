@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+const lotSourceVoucher = "voucher"
+
+const voucherBatchColumns = `b.id, b.program_id, b.name, b.amount, b.codes_count, b.max_uses, b.expires_at, b.voided_at,
+	b.created_at,
+	(SELECT COUNT(*) FROM voucher_redemption r JOIN voucher v ON v.id = r.voucher_id WHERE v.batch_id = b.id)`
+
+type VoucherRepo struct {
+	logger *zap.Logger
+	cfg    *config.Config
+	db     *sql.DB
+}
+
+func NewVoucherRepo(logger *zap.Logger, cfg *config.Config, db *sql.DB) *VoucherRepo {
+	return &VoucherRepo{
+		logger: logger,
+		cfg:    cfg,
+		db:     db,
+	}
+}
+
+func scanVoucherBatch(row interface{ Scan(dest ...any) error }) (*models.VoucherBatch, error) {
+	var (
+		b                   models.VoucherBatch
+		expiresAt, voidedAt sql.NullTime
+	)
+	if err := row.Scan(&b.ID, &b.ProgramID, &b.Name, &b.Amount, &b.Count, &b.MaxUses, &expiresAt, &voidedAt,
+		&b.CreatedAt, &b.Redemptions); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrVoucherBatchNotFound
+		}
+		return nil, fmt.Errorf("error scanning row for voucher batch %w", err)
+	}
+	if expiresAt.Valid {
+		b.ExpiresAt = &expiresAt.Time
+	}
+	if voidedAt.Valid {
+		b.VoidedAt = &voidedAt.Time
+	}
+
+	return &b, nil
+}
+
+func (vr *VoucherRepo) CreateBatch(ctx context.Context, b *models.VoucherBatch) error {
+	batchQuery := `INSERT INTO voucher_batch (program_id, name, amount, codes_count, max_uses, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at`
+	codesQuery := `INSERT INTO voucher (batch_id, code)
+	SELECT $1, c FROM unnest($2::text[]) AS c
+	ON CONFLICT (code) DO NOTHING`
+
+	tx, err := vr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction for voucher batch %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	err = tx.QueryRowContext(ctx, batchQuery, b.ProgramID, b.Name, b.Amount, b.Count, b.MaxUses, b.ExpiresAt).
+		Scan(&b.ID, &b.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error executing context for insert voucher batch %w", err)
+	}
+
+	for missing := b.Count; missing > 0; {
+		codes := make([]string, missing)
+		for i := range codes {
+			if codes[i], err = models.NewVoucherCode(); err != nil {
+				return fmt.Errorf("error generating voucher code %w", err)
+			}
+		}
+
+		var res sql.Result
+		if res, err = tx.ExecContext(ctx, codesQuery, b.ID, codes); err != nil {
+			return fmt.Errorf("error executing context for insert voucher codes %w", err)
+		}
+		var inserted int64
+		if inserted, err = res.RowsAffected(); err != nil {
+			return fmt.Errorf("error getting inserted voucher codes count %w", err)
+		}
+		missing -= int(inserted)
+	}
+
+	return nil
+}
+
+func (vr *VoucherRepo) GetBatches(ctx context.Context, programID int) ([]*models.VoucherBatch, error) {
+	query := `SELECT ` + voucherBatchColumns + ` FROM voucher_batch b WHERE b.program_id = $1 ORDER BY b.id DESC`
+
+	rows, err := vr.db.QueryContext(ctx, query, programID)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for voucher batches %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	batches := make([]*models.VoucherBatch, 0)
+	for rows.Next() {
+		var b *models.VoucherBatch
+		if b, err = scanVoucherBatch(rows); err != nil {
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return batches, nil
+}
+
+func (vr *VoucherRepo) GetBatch(ctx context.Context, programID, id int) (*models.VoucherBatch, error) {
+	query := `SELECT ` + voucherBatchColumns + ` FROM voucher_batch b WHERE b.id = $1 AND b.program_id = $2`
+
+	return scanVoucherBatch(vr.db.QueryRowContext(ctx, query, id, programID))
+}
+
+func (vr *VoucherRepo) VoidBatch(ctx context.Context, programID, id int, now time.Time) (*models.VoucherBatch, error) {
+	query := `UPDATE voucher_batch SET voided_at = COALESCE(voided_at, $3) WHERE id = $1 AND program_id = $2`
+
+	res, err := vr.db.ExecContext(ctx, query, id, programID, now)
+	if err != nil {
+		return nil, fmt.Errorf("error executing context for void voucher batch %d: %w", id, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("error getting rows affected for void voucher batch %w", err)
+	}
+	if affected == 0 {
+		return nil, ErrVoucherBatchNotFound
+	}
+
+	return vr.GetBatch(ctx, programID, id)
+}
+
+func (vr *VoucherRepo) GetVouchers(ctx context.Context, batchID int) ([]*models.Voucher, error) {
+	query := `SELECT code, uses FROM voucher WHERE batch_id = $1 ORDER BY id`
+
+	rows, err := vr.db.QueryContext(ctx, query, batchID)
+	if err != nil {
+		return nil, fmt.Errorf("error query context for vouchers %w", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var vouchers []*models.Voucher
+	for rows.Next() {
+		var v models.Voucher
+		if err = rows.Scan(&v.Code, &v.Uses); err != nil {
+			return nil, fmt.Errorf("error scanning row for voucher %w", err)
+		}
+		vouchers = append(vouchers, &v)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("got rows.Err() %w", err)
+	}
+
+	return vouchers, nil
+}
+
+// RedeemVoucher код блокируется на время погашения, поэтому лимит использований не превышается.
+func (vr *VoucherRepo) RedeemVoucher(
+	ctx context.Context,
+	user *models.User,
+	code string,
+	now, lotExpiresAt time.Time,
+) (*models.VoucherRedemption, error) {
+	lockQuery := `SELECT v.id, v.uses, b.amount, b.max_uses, b.expires_at, b.voided_at
+	FROM voucher v
+	JOIN voucher_batch b ON b.id = v.batch_id
+	WHERE v.code = $1 AND b.program_id = $2
+	FOR UPDATE OF v FOR SHARE OF b`
+	redemptionQuery := `INSERT INTO voucher_redemption (voucher_id, user_id, amount, redeemed_at)
+	VALUES ($1, $2, $3, $4)`
+	usesQuery := `UPDATE voucher SET uses = uses + 1 WHERE id = $1`
+	balanceQuery := `UPDATE balance SET current = current + $1 WHERE user_id = $2 RETURNING current, withdrawn, held`
+
+	tx, err := vr.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction for redeem voucher %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			_ = tx.Commit()
+		}
+	}()
+
+	var (
+		voucherID, uses, maxUses int
+		expiresAt, voidedAt      sql.NullTime
+	)
+	redemption := &models.VoucherRedemption{Code: code, RedeemedAt: now}
+	err = tx.QueryRowContext(ctx, lockQuery, code, user.ProgramID).
+		Scan(&voucherID, &uses, &redemption.Amount, &maxUses, &expiresAt, &voidedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrVoucherNotFound
+			return nil, err
+		}
+		return nil, fmt.Errorf("error scanning row for voucher %w", err)
+	}
+	if voidedAt.Valid || (expiresAt.Valid && !now.Before(expiresAt.Time)) {
+		err = ErrVoucherExpired
+		return nil, err
+	}
+	if uses >= maxUses {
+		err = ErrVoucherUsedUp
+		return nil, err
+	}
+
+	if _, err = tx.ExecContext(ctx, redemptionQuery, voucherID, user.ID, redemption.Amount, now); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			err = ErrVoucherRedeemed
+			return nil, err
+		}
+		return nil, fmt.Errorf("error executing context for insert voucher redemption %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, usesQuery, voucherID); err != nil {
+		return nil, fmt.Errorf("error executing context for voucher uses %w", err)
+	}
+
+	var balance models.Balance
+	err = tx.QueryRowContext(ctx, balanceQuery, redemption.Amount, user.ID).
+		Scan(&balance.Current, &balance.Withdrawn, &balance.Held)
+	if err != nil {
+		return nil, fmt.Errorf("error executing context for credit voucher %w", err)
+	}
+	if err = addLot(ctx, tx, user.ID, lotSourceVoucher, code, redemption.Amount, lotExpiresAt); err != nil {
+		return nil, fmt.Errorf("error opening point lot for voucher %w", err)
+	}
+	redemption.Balance = &balance
+
+	if _, err = addEvents(ctx, tx, balanceEvent(user.ID, &balance)); err != nil {
+		return nil, err
+	}
+
+	return redemption, nil
+}
@@ -2,41 +2,154 @@ package router
 
 import (
 	"database/sql"
+	"fmt"
 
 	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/events"
 	handlersPkg "github.com/Melikhov-p/go-loyalty-system/internal/handlers"
 	"github.com/Melikhov-p/go-loyalty-system/internal/middlewares"
+	"github.com/Melikhov-p/go-loyalty-system/internal/openapi"
+	"github.com/Melikhov-p/go-loyalty-system/internal/services"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
-func CreateRouter(cfg *config.Config, logger *zap.Logger, db *sql.DB) chi.Router {
+func CreateRouter(
+	cfg *config.Config,
+	logger *zap.Logger,
+	db *sql.DB,
+	broker *events.Broker,
+	jobs *services.Jobs,
+) (chi.Router, error) {
 	r := chi.NewRouter()
 
+	doc, err := openapi.Load()
+	if err != nil {
+		return nil, fmt.Errorf("error loading openapi spec %w", err)
+	}
+	validator, err := middlewares.NewValidator(logger, doc)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request validator %w", err)
+	}
+	docs, err := handlersPkg.NewDocsHandlers(logger, doc)
+	if err != nil {
+		return nil, fmt.Errorf("error creating docs handlers %w", err)
+	}
+
 	mdlwr := middlewares.NewMiddleware(logger, cfg, db)
+	versions := middlewares.NewVersionNegotiator(r)
 	r.Use(
+		mdlwr.WithRequestID,
+		versions.WithAPIVersion,
 		mdlwr.WithLogging,
+		mdlwr.WithProgram,
 		mdlwr.WithAuth,
 		mdlwr.GzipMiddleware,
+		validator.WithValidation,
 	)
 
-	handlers := handlersPkg.SetupHandlers(logger, cfg, db)
+	handlers := handlersPkg.SetupHandlers(logger, cfg, db, broker, jobs)
+
+	r.NotFound(handlersPkg.NotFound)
+	r.MethodNotAllowed(handlersPkg.MethodNotAllowed)
 
 	r.Route("/api", func(r chi.Router) {
+		r.Get("/openapi.json", docs.GetSpec)
+		r.Get("/docs", docs.GetDocs)
+		r.Get("/rewards", handlers.ForReward.ListRewards)
 		r.Route("/user", func(r chi.Router) {
 			r.Post("/register", handlers.ForUser.UserRegister)
 			r.Post("/login", handlers.ForUser.UserLogin)
+			r.Delete("/", handlers.ForUser.DeleteUser)
+			r.Get("/profile", handlers.ForUser.GetProfile)
+			r.Patch("/profile", handlers.ForUser.UpdateProfile)
+			r.Post("/password", handlers.ForUser.ChangePassword)
 			r.Route("/orders", func(r chi.Router) {
 				r.Post("/", handlers.ForOrder.CreateOrder)
 				r.Get("/", handlers.ForOrder.GetOrders)
+				r.Get("/stream", handlers.ForOrder.StreamOrders)
+				r.Post("/batch", handlers.ForOrder.CreateOrdersBatch)
+				r.Get("/batch/{id}", handlers.ForOrder.GetOrdersBatchJob)
+				r.Get("/{number}", handlers.ForOrder.GetOrder)
+				r.Delete("/{number}", handlers.ForOrder.CancelOrder)
 			})
 			r.Route("/balance", func(r chi.Router) {
 				r.Get("/", handlers.ForBalance.GetBalance)
 				r.Post("/withdraw", handlers.ForBalance.RequestWithdraw)
+				r.Post("/transfer", handlers.ForBalance.Transfer)
+				r.Post("/transfer/{id}/confirm", handlers.ForBalance.ConfirmTransfer)
+				r.Post("/holds", handlers.ForBalance.HoldPoints)
+				r.Post("/holds/{id}/capture", handlers.ForBalance.CaptureHold)
+				r.Post("/holds/{id}/release", handlers.ForBalance.ReleaseHold)
 			})
 			r.Get("/withdrawals", handlers.ForBalance.GetWithdrawals)
+			r.Get("/transfers", handlers.ForBalance.GetTransfers)
+			r.Get("/statement", handlers.ForBalance.GetStatement)
+			r.Get("/tier/history", handlers.ForBalance.GetTierHistory)
+			r.Get("/referrals", handlers.ForUser.GetReferralStats)
+			r.Post("/vouchers/redeem", handlers.ForVoucher.Redeem)
+			r.Get("/rewards", handlers.ForReward.GetRedemptions)
+			r.Post("/rewards/{id}/redeem", handlers.ForReward.Redeem)
+			r.Get("/notifications", handlers.ForNotification.GetPreferences)
+			r.Put("/notifications", handlers.ForNotification.UpdatePreferences)
+			r.Post("/export", handlers.ForExport.CreateExport)
+			r.Get("/export/{id}", handlers.ForExport.GetExport)
+			r.Get("/export/{id}/download", handlers.ForExport.DownloadExport)
+		})
+		r.Route("/admin/campaigns", func(r chi.Router) {
+			r.Use(mdlwr.WithAdminKey)
+			r.Get("/", handlers.ForCampaign.ListCampaigns)
+			r.Post("/", handlers.ForCampaign.CreateCampaign)
+			r.Post("/dry-run", handlers.ForCampaign.DryRunCampaigns)
+			r.Get("/{id}", handlers.ForCampaign.GetCampaign)
+			r.Put("/{id}", handlers.ForCampaign.UpdateCampaign)
+			r.Delete("/{id}", handlers.ForCampaign.DeleteCampaign)
+			r.Get("/{id}/grants", handlers.ForCampaign.GetCampaignGrants)
+		})
+		r.Route("/admin/adjustments", func(r chi.Router) {
+			r.Use(mdlwr.WithAdminKey)
+			r.Get("/", handlers.ForAdjustment.ListAdjustments)
+			r.Post("/{id}/approve", handlers.ForAdjustment.ApproveAdjustment)
+			r.Post("/{id}/dismiss", handlers.ForAdjustment.DismissAdjustment)
+		})
+		r.Route("/admin/programs", func(r chi.Router) {
+			r.Use(mdlwr.WithAdminKey)
+			r.Get("/", handlers.ForProgram.ListPrograms)
+			r.Post("/", handlers.ForProgram.CreateProgram)
+		})
+		r.Route("/admin/merchants", func(r chi.Router) {
+			r.Use(mdlwr.WithAdminKey)
+			r.Get("/", handlers.ForMerchant.ListMerchants)
+			r.Post("/", handlers.ForMerchant.CreateMerchant)
+		})
+		r.Route("/admin/vouchers", func(r chi.Router) {
+			r.Use(mdlwr.WithAdminKey)
+			r.Get("/", handlers.ForVoucher.ListBatches)
+			r.Post("/", handlers.ForVoucher.CreateBatch)
+			r.Post("/{id}/void", handlers.ForVoucher.VoidBatch)
+			r.Get("/{id}/codes.csv", handlers.ForVoucher.ExportCodes)
+		})
+		r.Route("/admin/rewards", func(r chi.Router) {
+			r.Use(mdlwr.WithAdminKey)
+			r.Get("/", handlers.ForReward.ListAllRewards)
+			r.Post("/", handlers.ForReward.CreateReward)
+			r.Put("/{id}", handlers.ForReward.UpdateReward)
+		})
+		r.Route("/admin/orders", func(r chi.Router) {
+			r.Use(mdlwr.WithAdminKey)
+			r.Post("/{number}/return", handlers.ForOrder.ReturnOrder)
+		})
+		r.Route("/merchant", func(r chi.Router) {
+			r.Use(mdlwr.WithMerchantKey)
+			r.Post("/orders", handlers.ForMerchant.RegisterOrder)
+			r.Get("/orders", handlers.ForMerchant.ListOrders)
+			r.Get("/summary", handlers.ForMerchant.GetSummary)
+		})
+		// в v2 только изменившиеся эндпоинты, остальное обслуживает v1 через WithAPIVersion
+		r.Route("/v2/user", func(r chi.Router) {
+			r.Post("/orders", handlers.ForOrder.CreateOrderV2)
 		})
 	})
 
-	return r
+	return r, nil
 }
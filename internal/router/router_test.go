@@ -0,0 +1,48 @@
+package router
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/openapi"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestRoutesMatchSpec падает, если маршруты роутера и операции спецификации OpenAPI расходятся.
+func TestRoutesMatchSpec(t *testing.T) {
+	r, err := CreateRouter(&config.Config{}, zap.NewNop(), nil, nil, nil)
+	require.NoError(t, err)
+
+	doc, err := openapi.Load()
+	require.NoError(t, err)
+
+	routed := make(map[string]bool)
+	err = chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		path := strings.TrimPrefix(route, "/api")
+		if path != "/" {
+			path = strings.TrimSuffix(path, "/")
+		}
+		routed[method+" "+path] = true
+		return nil
+	})
+	require.NoError(t, err)
+
+	specified := make(map[string]bool)
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			specified[method+" "+path] = true
+		}
+	}
+
+	for operation := range routed {
+		assert.True(t, specified[operation], "route %s is not described in openapi spec", operation)
+	}
+	for operation := range specified {
+		assert.True(t, routed[operation], "operation %s from openapi spec is not routed", operation)
+	}
+}
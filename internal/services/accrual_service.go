@@ -26,12 +26,44 @@ func NewAccrualService(logger *zap.Logger, cfg *config.Config) *AccrualService {
 	}
 }
 
+// RegisterOrder повторная регистрация (409) считается успешной.
+func (as *AccrualService) RegisterOrder(order *models.WatchedOrder) (time.Duration, error) {
+	r := resty.New()
+
+	body := &models.AccrualRegisterRequest{
+		Order: order.AccrualNumber(),
+		Goods: make([]*models.AccrualGood, 0, len(order.Items)),
+	}
+	for _, item := range order.Items {
+		body.Goods = append(body.Goods, &models.AccrualGood{
+			Description: item.Description,
+			Price:       item.Price * float64(item.Quantity),
+		})
+	}
+
+	url := fmt.Sprintf("%s/api/orders", as.accrualAddr(order))
+	resp, err := r.R().SetHeader("Content-Type", "application/json").SetBody(body).Post(url)
+	if err != nil {
+		return 0, fmt.Errorf("error registering order %w", err)
+	}
+
+	switch resp.StatusCode() {
+	case http.StatusOK, http.StatusAccepted, http.StatusConflict:
+		order.Registered = true
+		return 0, nil
+	case http.StatusTooManyRequests:
+		return as.retryAfter(resp), ErrRetryAfter
+	default:
+		return 0, fmt.Errorf("register response error %v", resp.Status())
+	}
+}
+
 func (as *AccrualService) CheckOrdersStatus(
 	order *models.WatchedOrder,
 ) (*models.WatchedOrder, time.Duration, error) {
 	r := resty.New()
 
-	url := fmt.Sprintf("%s/api/orders/%s", as.cfg.AccrualAddr, order.OrderNumber)
+	url := fmt.Sprintf("%s/api/orders/%s", as.accrualAddr(order), order.AccrualNumber())
 	var accrualResp models.AccrualOrderResponse
 
 	resp, err := r.R().SetResult(&accrualResp).Get(url)
@@ -40,23 +72,32 @@ func (as *AccrualService) CheckOrdersStatus(
 	}
 	if resp.IsError() {
 		if resp.StatusCode() == http.StatusTooManyRequests {
-			var seconds int
-			secondsStr := resp.Header().Get("Retry-After")
-			seconds, err = strconv.Atoi(secondsStr)
-			if err != nil {
-				as.logger.Error("error parse seconds Retry-After to string")
-				seconds = defaultRetryAfter
-			}
-
-			return nil, time.Second * time.Duration(seconds), ErrRetryAfter
+			return nil, as.retryAfter(resp), ErrRetryAfter
 		}
 		return nil, 0, fmt.Errorf("response error %v", resp.Status())
 	}
 
-	if accrualResp.Status != order.AccrualOrderStatus {
+	if accrualResp.Status != order.AccrualOrderStatus || accrualResp.Accrual != order.AccrualPoints {
 		order.AccrualOrderStatus = accrualResp.Status
 		order.AccrualPoints = accrualResp.Accrual
 	}
 
 	return order, 0, nil
 }
+
+func (as *AccrualService) accrualAddr(order *models.WatchedOrder) string {
+	if order.AccrualAddr != "" {
+		return order.AccrualAddr
+	}
+	return as.cfg.AccrualAddr
+}
+
+func (as *AccrualService) retryAfter(resp *resty.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header().Get("Retry-After"))
+	if err != nil {
+		as.logger.Error("error parse seconds Retry-After to string")
+		seconds = defaultRetryAfter
+	}
+
+	return time.Second * time.Duration(seconds)
+}
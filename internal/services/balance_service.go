@@ -3,11 +3,14 @@ package services
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Melikhov-p/go-loyalty-system/internal/config"
 	"github.com/Melikhov-p/go-loyalty-system/internal/models"
 	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"github.com/Melikhov-p/go-loyalty-system/internal/statement"
 	"go.uber.org/zap"
 )
 
@@ -15,6 +18,7 @@ type BalanceService struct {
 	logger      *zap.Logger
 	cfg         *config.Config
 	BalanceRepo *repository.BalanceRepo
+	TierRepo    *repository.TierRepo
 }
 
 func NewBalanceService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *BalanceService {
@@ -22,6 +26,7 @@ func NewBalanceService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *Bala
 		logger:      logger,
 		cfg:         cfg,
 		BalanceRepo: repository.NewBalanceRepo(logger, cfg, db),
+		TierRepo:    repository.NewTierRepo(logger, cfg, db),
 	}
 }
 
@@ -56,33 +61,160 @@ func (bs *BalanceService) Withdraw(
 	ctx, cancel := context.WithTimeout(ctx, bs.cfg.DB.ContextTimeout)
 	defer cancel()
 
+	if err := bs.checkWithdrawalLimits(ctx, user, sum); err != nil {
+		return nil, err
+	}
+
 	newBalance, err := bs.BalanceRepo.Withdraw(ctx, order, user, sum)
 	if err != nil {
+		if errors.Is(err, repository.ErrInsufficientBalance) {
+			return nil, fmt.Errorf("error withdraw: %w", ErrNotEnough)
+		}
 		return nil, fmt.Errorf("error withdraw %w", err)
 	}
 
 	return newBalance, nil
 }
 
+func (bs *BalanceService) checkWithdrawalLimits(ctx context.Context, user *models.User, sum float64) error {
+	if err := bs.checkWithdrawalsBlocked(ctx, user.ID); err != nil {
+		return err
+	}
+
+	tier, err := bs.TierRepo.GetUserTier(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("error getting user tier %w", err)
+	}
+
+	if tier.MaxWithdrawal != nil && sum > *tier.MaxWithdrawal {
+		return fmt.Errorf("tier %s allows at most %.2f per withdrawal: %w",
+			tier.Code, *tier.MaxWithdrawal, ErrWithdrawalLimit)
+	}
+
+	if tier.MonthlyWithdrawalLimit != nil {
+		now := time.Now()
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+		var withdrawn float64
+		withdrawn, err = bs.BalanceRepo.GetWithdrawnSince(ctx, user.ProgramID, user.ID, monthStart)
+		if err != nil {
+			return fmt.Errorf("error getting withdrawn sum for month %w", err)
+		}
+		if withdrawn+sum > *tier.MonthlyWithdrawalLimit {
+			return fmt.Errorf("tier %s allows at most %.2f per month: %w",
+				tier.Code, *tier.MonthlyWithdrawalLimit, ErrWithdrawalLimit)
+		}
+	}
+
+	return nil
+}
+
+func (bs *BalanceService) checkWithdrawalsBlocked(ctx context.Context, userID int) error {
+	blocked, err := bs.BalanceRepo.WithdrawalsBlocked(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error checking withdrawals block %w", err)
+	}
+	if blocked {
+		return ErrWithdrawalsBlocked
+	}
+
+	return nil
+}
+
 func (bs *BalanceService) GetUserWithdrawHistory(
 	ctx context.Context,
 	user *models.User,
-) ([]*models.WithdrawHistoryItem, error) {
+	filter *models.WithdrawListFilter,
+) ([]*models.WithdrawHistoryItem, *models.Cursor, error) {
 	ctx, cancel := context.WithTimeout(ctx, bs.cfg.DB.ContextTimeout)
 	defer cancel()
 
-	history, err := bs.BalanceRepo.GetUserHistory(ctx, user)
+	history, cursor, err := bs.BalanceRepo.GetUserHistory(ctx, user, filter)
 	if err != nil {
-		return nil, fmt.Errorf("error getting user balance history %w", err)
+		return nil, nil, fmt.Errorf("error getting user balance history %w", err)
 	}
 
-	return history, nil
+	return history, cursor, nil
 }
 
-func (bs *BalanceService) IncreaseBalance(ctx context.Context, userID int, diff float64) error {
-	err := bs.BalanceRepo.IncreaseBalanceByUserID(ctx, userID, diff)
+func (bs *BalanceService) CreditOrderAccrual(ctx context.Context, order *models.WatchedOrder) (*models.Balance, error) {
+	tier, err := bs.TierRepo.GetUserTier(ctx, order.UserID)
 	if err != nil {
-		return fmt.Errorf("error increasing user balance %w", err)
+		return nil, fmt.Errorf("error getting user tier %w", err)
+	}
+
+	expiresAt := time.Now().AddDate(0, bs.cfg.Points.ExpiryMonths, 0)
+	balance, err := bs.BalanceRepo.CreditOrderAccrual(ctx, order, tier.Multiplier, expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("error crediting order accrual %w", err)
+	}
+
+	return balance, nil
+}
+
+func (bs *BalanceService) CreditReferralRewards(ctx context.Context) ([]*models.ReferralReward, error) {
+	now := time.Now()
+	rewards, err := bs.BalanceRepo.CreditReferralRewards(ctx, now,
+		bs.cfg.Referral.ReferrerPoints, bs.cfg.Referral.RefereePoints, now.AddDate(0, bs.cfg.Points.ExpiryMonths, 0))
+	if err != nil {
+		return nil, fmt.Errorf("error crediting referral rewards %w", err)
+	}
+
+	return rewards, nil
+}
+
+func (bs *BalanceService) GetExpiringPoints(ctx context.Context, user *models.User) ([]*models.ExpiringPoints, error) {
+	ctx, cancel := context.WithTimeout(ctx, bs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	expiring, err := bs.BalanceRepo.GetExpiringPoints(ctx, user.ID, time.Now().Add(bs.cfg.Points.ExpiringSoonWindow))
+	if err != nil {
+		return nil, fmt.Errorf("error getting expiring points %w", err)
+	}
+
+	return expiring, nil
+}
+
+func (bs *BalanceService) IncreaseBalance(
+	ctx context.Context,
+	programID, userID int,
+	diff float64,
+) (*models.Balance, error) {
+	balance, err := bs.BalanceRepo.IncreaseBalanceByUserID(ctx, programID, userID, diff)
+	if err != nil {
+		return nil, fmt.Errorf("error increasing user balance %w", err)
+	}
+
+	return balance, nil
+}
+
+func (bs *BalanceService) WriteStatement(
+	ctx context.Context,
+	user *models.User,
+	from *time.Time,
+	to time.Time,
+	writer statement.Writer,
+) error {
+	stmt := &models.Statement{
+		From: from,
+		To:   to,
+	}
+
+	if err := bs.BalanceRepo.GetStatementTotals(ctx, user, stmt); err != nil {
+		return fmt.Errorf("error getting statement totals %w", err)
+	}
+
+	if err := writer.WriteHeader(stmt); err != nil {
+		return fmt.Errorf("error writing statement header %w", err)
+	}
+
+	err := bs.BalanceRepo.StreamStatementEntries(ctx, user, from, to, writer.WriteEntry)
+	if err != nil {
+		return fmt.Errorf("error streaming statement entries %w", err)
+	}
+
+	if err = writer.Close(); err != nil {
+		return fmt.Errorf("error closing statement writer %w", err)
 	}
 
 	return nil
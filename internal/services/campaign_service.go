@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/campaign"
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"go.uber.org/zap"
+)
+
+type CampaignService struct {
+	logger       *zap.Logger
+	cfg          *config.Config
+	CampaignRepo *repository.CampaignRepo
+}
+
+func NewCampaignService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *CampaignService {
+	return &CampaignService{
+		logger:       logger,
+		cfg:          cfg,
+		CampaignRepo: repository.NewCampaignRepo(logger, cfg, db),
+	}
+}
+
+func campaignFromRequest(programID, id int, req *models.CampaignRequest) *models.Campaign {
+	c := &models.Campaign{
+		ID:        id,
+		ProgramID: programID,
+		Name:      req.Name,
+		Rule:      req.Rule,
+		StartsAt:  req.StartsAt,
+		EndsAt:    req.EndsAt,
+		UserCap:   req.UserCap,
+		Active:    true,
+	}
+	if req.Active != nil {
+		c.Active = *req.Active
+	}
+	return c
+}
+
+func (cs *CampaignService) CreateCampaign(
+	ctx context.Context,
+	programID int,
+	req *models.CampaignRequest,
+) (*models.Campaign, error) {
+	ctx, cancel := context.WithTimeout(ctx, cs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	c := campaignFromRequest(programID, 0, req)
+	if err := cs.CampaignRepo.CreateCampaign(ctx, c); err != nil {
+		return nil, fmt.Errorf("error creating campaign %w", err)
+	}
+
+	return c, nil
+}
+
+func (cs *CampaignService) UpdateCampaign(
+	ctx context.Context,
+	programID, id int,
+	req *models.CampaignRequest,
+) (*models.Campaign, error) {
+	ctx, cancel := context.WithTimeout(ctx, cs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	c := campaignFromRequest(programID, id, req)
+	if err := cs.CampaignRepo.UpdateCampaign(ctx, c); err != nil {
+		return nil, fmt.Errorf("error updating campaign %d: %w", id, err)
+	}
+
+	return c, nil
+}
+
+func (cs *CampaignService) DeleteCampaign(ctx context.Context, programID, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, cs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	if err := cs.CampaignRepo.DeleteCampaign(ctx, programID, id); err != nil {
+		return fmt.Errorf("error deleting campaign %d: %w", id, err)
+	}
+
+	return nil
+}
+
+func (cs *CampaignService) GetCampaign(ctx context.Context, programID, id int) (*models.Campaign, error) {
+	ctx, cancel := context.WithTimeout(ctx, cs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	c, err := cs.CampaignRepo.GetCampaign(ctx, programID, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting campaign %d: %w", id, err)
+	}
+
+	return c, nil
+}
+
+func (cs *CampaignService) GetCampaigns(ctx context.Context, programID int) ([]*models.Campaign, error) {
+	ctx, cancel := context.WithTimeout(ctx, cs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	campaigns, err := cs.CampaignRepo.GetCampaigns(ctx, programID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error getting campaigns %w", err)
+	}
+
+	return campaigns, nil
+}
+
+func (cs *CampaignService) GetCampaignGrants(
+	ctx context.Context,
+	programID, id int,
+) ([]*models.CampaignGrant, error) {
+	ctx, cancel := context.WithTimeout(ctx, cs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	if _, err := cs.CampaignRepo.GetCampaign(ctx, programID, id); err != nil {
+		return nil, fmt.Errorf("error getting campaign %d: %w", id, err)
+	}
+
+	grants, err := cs.CampaignRepo.GetCampaignGrants(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("error getting grants of campaign %d: %w", id, err)
+	}
+
+	return grants, nil
+}
+
+func (cs *CampaignService) DryRun(
+	ctx context.Context,
+	programID int,
+	req *models.CampaignDryRunRequest,
+) ([]*models.CampaignGrant, error) {
+	ctx, cancel := context.WithTimeout(ctx, cs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	order := &models.CampaignOrder{
+		ProgramID:   programID,
+		UserID:      req.UserID,
+		Number:      req.OrderNumber,
+		Accrual:     req.Accrual,
+		MerchantID:  req.MerchantID,
+		ProcessedAt: time.Now(),
+	}
+	if req.ProcessedAt != nil {
+		order.ProcessedAt = *req.ProcessedAt
+	}
+
+	return cs.evaluate(ctx, order)
+}
+
+// ApplyToOrder если бонусов нет, возвращает nil баланс.
+func (cs *CampaignService) ApplyToOrder(
+	ctx context.Context,
+	watched *models.WatchedOrder,
+) ([]*models.CampaignGrant, *models.Balance, error) {
+	now := time.Now()
+	order := &models.CampaignOrder{
+		ProgramID:   watched.ProgramID,
+		UserID:      watched.UserID,
+		Number:      watched.OrderNumber,
+		Accrual:     watched.AccrualPoints,
+		ProcessedAt: now,
+	}
+
+	grants, err := cs.evaluate(ctx, order)
+	if err != nil || len(grants) == 0 {
+		return nil, nil, err
+	}
+
+	added, balance, err := cs.CampaignRepo.AddGrants(ctx, order.UserID, grants,
+		now.AddDate(0, cs.cfg.Points.ExpiryMonths, 0))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error adding campaign grants for order %s: %w", order.Number, err)
+	}
+	if len(added) == 0 {
+		return nil, nil, nil
+	}
+
+	return added, balance, nil
+}
+
+func (cs *CampaignService) evaluate(
+	ctx context.Context,
+	order *models.CampaignOrder,
+) ([]*models.CampaignGrant, error) {
+	campaigns, err := cs.CampaignRepo.GetCampaigns(ctx, order.ProgramID, &order.ProcessedAt)
+	if err != nil {
+		return nil, fmt.Errorf("error getting running campaigns %w", err)
+	}
+	if len(campaigns) == 0 {
+		return nil, nil
+	}
+
+	if err = cs.CampaignRepo.FillCampaignOrder(ctx, order); err != nil {
+		return nil, fmt.Errorf("error collecting campaign stats for order %s: %w", order.Number, err)
+	}
+
+	return campaign.Evaluate(campaigns, order), nil
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"go.uber.org/zap"
+)
+
+type EventService struct {
+	logger    *zap.Logger
+	cfg       *config.Config
+	EventRepo *repository.EventRepo
+}
+
+func NewEventService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *EventService {
+	return &EventService{
+		logger:    logger,
+		cfg:       cfg,
+		EventRepo: repository.NewEventRepo(logger, cfg, db),
+	}
+}
+
+func (es *EventService) GetUserEventsAfter(
+	ctx context.Context,
+	user *models.User,
+	lastEventID int64,
+) ([]*models.Event, error) {
+	ctx, cancel := context.WithTimeout(ctx, es.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	events, err := es.EventRepo.GetUserEventsAfter(ctx, user.ID, lastEventID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user events after %d: %w", lastEventID, err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"go.uber.org/zap"
+)
+
+type ExpiryService struct {
+	logger      *zap.Logger
+	cfg         *config.Config
+	BalanceRepo *repository.BalanceRepo
+}
+
+func NewExpiryService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *ExpiryService {
+	return &ExpiryService{
+		logger:      logger,
+		cfg:         cfg,
+		BalanceRepo: repository.NewBalanceRepo(logger, cfg, db),
+	}
+}
+
+func (es *ExpiryService) ExpirePoints(ctx context.Context) error {
+	now := time.Now()
+
+	notices, err := es.BalanceRepo.TakeExpiringLots(ctx, now, now.Add(es.cfg.Points.ExpiringSoonWindow))
+	if err != nil {
+		return fmt.Errorf("error taking expiring point lots %w", err)
+	}
+	for _, notice := range notices {
+		es.logger.Debug("points expiring", zap.Int("USERID", notice.UserID), zap.Float64("AMOUNT", notice.Amount))
+	}
+
+	expired, err := es.BalanceRepo.ExpireLots(ctx, now)
+	if err != nil {
+		return fmt.Errorf("error expiring point lots %w", err)
+	}
+	for _, e := range expired {
+		es.logger.Debug("points expired", zap.Int("USERID", e.UserID), zap.Float64("AMOUNT", e.Amount))
+	}
+
+	return nil
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/export"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"go.uber.org/zap"
+)
+
+type ExportService struct {
+	logger      *zap.Logger
+	cfg         *config.Config
+	ExportRepo  *repository.ExportRepo
+	UserRepo    *repository.UserRepo
+	OrderRepo   *repository.OrderRepo
+	BalanceRepo *repository.BalanceRepo
+}
+
+func NewExportService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *ExportService {
+	return &ExportService{
+		logger:      logger,
+		cfg:         cfg,
+		ExportRepo:  repository.NewExportRepo(logger, cfg, db),
+		UserRepo:    repository.NewUserRepo(logger, cfg, db),
+		OrderRepo:   repository.NewOrderRepo(logger, cfg, db),
+		BalanceRepo: repository.NewBalanceRepo(logger, cfg, db),
+	}
+}
+
+func (es *ExportService) StartExport(
+	ctx context.Context,
+	jobs *Jobs,
+	user *models.User,
+) (*models.DataExport, error) {
+	ctx, cancel := context.WithTimeout(ctx, es.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	job, err := es.ExportRepo.CreateExport(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error creating data export %w", err)
+	}
+
+	running := *job
+	exportUser := *user
+	jobs.Go(func(appCtx context.Context) {
+		jobCtx, jobCancel := context.WithTimeout(appCtx, es.cfg.Jobs.Timeout)
+		defer jobCancel()
+
+		var jobErr error
+		if running.Size, jobErr = es.buildArchive(jobCtx, &exportUser, running.ID); jobErr != nil {
+			es.logger.Error("error building data export", zap.Int("EXPORT_ID", running.ID), zap.Error(jobErr))
+			running.Status = models.DataExportFailed
+			running.Error = "failed to collect personal data"
+		} else {
+			expiresAt := time.Now().Add(es.cfg.Export.TTL)
+			running.Status = models.DataExportReady
+			running.ExpiresAt = &expiresAt
+		}
+
+		finishCtx, finishCancel := context.WithTimeout(context.WithoutCancel(appCtx), es.cfg.DB.ContextTimeout)
+		defer finishCancel()
+		if jobErr = es.ExportRepo.FinishExport(finishCtx, &running); jobErr != nil {
+			es.logger.Error("error finishing data export", zap.Int("EXPORT_ID", running.ID), zap.Error(jobErr))
+		}
+	})
+
+	return job, nil
+}
+
+func (es *ExportService) FailStaleExports(ctx context.Context) error {
+	failed, err := es.ExportRepo.FailStaleExports(ctx, time.Now().Add(-es.cfg.Jobs.Timeout))
+	if err != nil {
+		return fmt.Errorf("error failing stale data exports %w", err)
+	}
+	if failed > 0 {
+		es.logger.Warn("stale data exports failed", zap.Int64("COUNT", failed))
+	}
+
+	return nil
+}
+
+func (es *ExportService) GetExport(ctx context.Context, user *models.User, id int) (*models.DataExport, error) {
+	ctx, cancel := context.WithTimeout(ctx, es.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	job, err := es.ExportRepo.GetExport(ctx, id, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting data export %d: %w", id, err)
+	}
+
+	return job, nil
+}
+
+func (es *ExportService) GetArchive(ctx context.Context, user *models.User, id int) (*models.DataExport, error) {
+	ctx, cancel := context.WithTimeout(ctx, es.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	job, err := es.ExportRepo.GetExport(ctx, id, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting data export %d: %w", id, err)
+	}
+
+	now := time.Now()
+	switch {
+	case job.Status == models.DataExportExpired,
+		job.Status == models.DataExportReady && job.ExpiresAt != nil && !now.Before(*job.ExpiresAt):
+		return nil, repository.ErrExportExpired
+	case job.Status != models.DataExportReady:
+		return nil, ErrExportNotReady
+	}
+
+	if err = es.ExportRepo.StartDownload(ctx, job, now); err != nil {
+		return nil, fmt.Errorf("error starting data export %d download: %w", id, err)
+	}
+
+	return job, nil
+}
+
+func (es *ExportService) WriteArchive(ctx context.Context, job *models.DataExport, w io.Writer) error {
+	for seq := 0; ; seq++ {
+		chunk, err := es.getChunk(ctx, job.ID, seq)
+		if errors.Is(err, repository.ErrExportChunkNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write(chunk); err != nil {
+			return fmt.Errorf("error writing data export %d archive %w", job.ID, err)
+		}
+	}
+}
+
+func (es *ExportService) getChunk(ctx context.Context, exportID, seq int) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, es.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	chunk, err := es.ExportRepo.GetChunk(ctx, exportID, seq)
+	if err != nil {
+		return nil, fmt.Errorf("error getting data export %d archive chunk: %w", exportID, err)
+	}
+
+	return chunk, nil
+}
+
+func (es *ExportService) ExpireExports(ctx context.Context) error {
+	expired, err := es.ExportRepo.ExpireExports(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("error expiring data exports %w", err)
+	}
+	if expired > 0 {
+		es.logger.Debug("data exports expired", zap.Int64("COUNT", expired))
+	}
+
+	return nil
+}
+
+// buildArchive общее время ограничивает ctx задания, у каждого запроса свой таймаут.
+func (es *ExportService) buildArchive(ctx context.Context, user *models.User, exportID int) (int, error) {
+	storage := &chunkWriter{
+		ctx:      ctx,
+		es:       es,
+		exportID: exportID,
+		buf:      make([]byte, 0, exportChunkSize),
+	}
+
+	aw, err := export.NewWriter(storage, user.ID, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("error writing data export archive %w", err)
+	}
+	if err = es.writeData(ctx, user, aw); err != nil {
+		return 0, err
+	}
+	if err = aw.Close(); err != nil {
+		return 0, fmt.Errorf("error writing data export archive %w", err)
+	}
+	if err = storage.flush(); err != nil {
+		return 0, err
+	}
+
+	return storage.size, nil
+}
+
+func (es *ExportService) writeData(ctx context.Context, user *models.User, aw *export.Writer) error {
+	var profile *models.Profile
+	if err := es.withTimeout(ctx, func(ctx context.Context) (err error) {
+		if profile, err = es.UserRepo.GetProfile(ctx, user.ProgramID, user.ID); err != nil {
+			return fmt.Errorf("error getting profile for data export %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err := aw.WriteFile("profile.json", profile); err != nil {
+		return fmt.Errorf("error writing data export archive %w", err)
+	}
+
+	user.BalanceInfo = repository.NewEmptyBalance()
+	if err := es.withTimeout(ctx, func(ctx context.Context) error {
+		if err := es.BalanceRepo.GetUserBalance(ctx, user); err != nil {
+			return fmt.Errorf("error getting balance for data export %w", err)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err := aw.WriteFile("balance.json", user.BalanceInfo); err != nil {
+		return fmt.Errorf("error writing data export archive %w", err)
+	}
+
+	if err := aw.BeginList("orders.json"); err != nil {
+		return fmt.Errorf("error writing data export archive %w", err)
+	}
+	orderFilter := &models.OrderListFilter{
+		ListPage: models.ListPage{Limit: models.MaxPageLimit, Sort: "uploaded_at"},
+	}
+	for {
+		var (
+			orders []*models.Order
+			cursor *models.Cursor
+		)
+		if err := es.withTimeout(ctx, func(ctx context.Context) (err error) {
+			orders, cursor, err = es.OrderRepo.GetOrdersByUser(ctx, user.ProgramID, user.ID, orderFilter)
+			if err != nil && !errors.Is(err, repository.ErrOrdersNotFound) {
+				return fmt.Errorf("error getting orders for data export %w", err)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, order := range orders {
+			if err := aw.Append(order); err != nil {
+				return fmt.Errorf("error writing data export archive %w", err)
+			}
+		}
+		if cursor == nil {
+			break
+		}
+		orderFilter.Cursor = cursor
+	}
+
+	if err := aw.BeginList("withdrawals.json"); err != nil {
+		return fmt.Errorf("error writing data export archive %w", err)
+	}
+	withdrawFilter := &models.WithdrawListFilter{
+		ListPage: models.ListPage{Limit: models.MaxPageLimit, Sort: "processed_at"},
+	}
+	for {
+		var (
+			history []*models.WithdrawHistoryItem
+			cursor  *models.Cursor
+		)
+		if err := es.withTimeout(ctx, func(ctx context.Context) (err error) {
+			history, cursor, err = es.BalanceRepo.GetUserHistory(ctx, user, withdrawFilter)
+			if err != nil && !errors.Is(err, repository.ErrEmptyBalanceHistory) {
+				return fmt.Errorf("error getting withdrawals for data export %w", err)
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, item := range history {
+			if err := aw.Append(item); err != nil {
+				return fmt.Errorf("error writing data export archive %w", err)
+			}
+		}
+		if cursor == nil {
+			break
+		}
+		withdrawFilter.Cursor = cursor
+	}
+
+	return nil
+}
+
+func (es *ExportService) withTimeout(ctx context.Context, query func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, es.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	return query(ctx)
+}
+
+const exportChunkSize = 1 << 20
+
+type chunkWriter struct {
+	ctx      context.Context
+	es       *ExportService
+	exportID int
+	buf      []byte
+	seq      int
+	size     int
+}
+
+func (cw *chunkWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		take := min(exportChunkSize-len(cw.buf), len(p))
+		cw.buf = append(cw.buf, p[:take]...)
+		p = p[take:]
+		if len(cw.buf) == exportChunkSize {
+			if err := cw.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+func (cw *chunkWriter) flush() error {
+	if len(cw.buf) == 0 {
+		return nil
+	}
+
+	if err := cw.es.withTimeout(cw.ctx, func(ctx context.Context) error {
+		return cw.es.ExportRepo.AddChunk(ctx, cw.exportID, cw.seq, cw.buf)
+	}); err != nil {
+		return fmt.Errorf("error saving data export archive %w", err)
+	}
+	cw.size += len(cw.buf)
+	cw.seq++
+	cw.buf = cw.buf[:0]
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"go.uber.org/zap"
+)
+
+type HoldService struct {
+	logger         *zap.Logger
+	cfg            *config.Config
+	BalanceService *BalanceService
+}
+
+func NewHoldService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *HoldService {
+	return &HoldService{
+		logger:         logger,
+		cfg:            cfg,
+		BalanceService: NewBalanceService(logger, cfg, db),
+	}
+}
+
+func (hs *HoldService) HoldPoints(
+	ctx context.Context,
+	user *models.User,
+	req *models.WithdrawRequest,
+) (*models.Hold, error) {
+	ctx, cancel := context.WithTimeout(ctx, hs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	if err := hs.BalanceService.checkWithdrawalLimits(ctx, user, req.Sum); err != nil {
+		return nil, err
+	}
+
+	hold, err := hs.BalanceService.BalanceRepo.HoldPoints(ctx, user, req.Order, req.Sum,
+		time.Now().Add(hs.cfg.Hold.TTL))
+	if err != nil {
+		if errors.Is(err, repository.ErrInsufficientBalance) {
+			return nil, fmt.Errorf("error holding points: %w", ErrNotEnough)
+		}
+		return nil, fmt.Errorf("error holding points %w", err)
+	}
+
+	return hold, nil
+}
+
+func (hs *HoldService) CaptureHold(ctx context.Context, user *models.User, holdID int) (*models.Hold, error) {
+	ctx, cancel := context.WithTimeout(ctx, hs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	settlement, err := hs.BalanceService.BalanceRepo.CaptureHold(ctx, user, holdID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error capturing hold %d: %w", holdID, err)
+	}
+
+	return settlement.Hold, nil
+}
+
+func (hs *HoldService) ReleaseHold(ctx context.Context, user *models.User, holdID int) (*models.Hold, error) {
+	ctx, cancel := context.WithTimeout(ctx, hs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	settlement, err := hs.BalanceService.BalanceRepo.ReleaseHold(ctx, user, holdID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error releasing hold %d: %w", holdID, err)
+	}
+
+	return settlement.Hold, nil
+}
+
+func (hs *HoldService) ExpireHolds(ctx context.Context) error {
+	expired, err := hs.BalanceService.BalanceRepo.ExpireHolds(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("error expiring holds %w", err)
+	}
+	for _, e := range expired {
+		hs.logger.Debug("hold expired", zap.Int("USERID", e.UserID), zap.Int("HOLD", e.Hold.ID))
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// Jobs получают контекст приложения, а при остановке приложение дожидается их завершения.
+type Jobs struct {
+	ctx context.Context
+	wg  sync.WaitGroup
+}
+
+func NewJobs(ctx context.Context) *Jobs {
+	return &Jobs{
+		ctx: ctx,
+	}
+}
+
+func (j *Jobs) Go(run func(ctx context.Context)) {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		run(j.ctx)
+	}()
+}
+
+func (j *Jobs) Wait() {
+	j.wg.Wait()
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/auth"
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"go.uber.org/zap"
+)
+
+type MerchantService struct {
+	logger       *zap.Logger
+	cfg          *config.Config
+	MerchantRepo *repository.MerchantRepo
+	UserRepo     *repository.UserRepo
+}
+
+func NewMerchantService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *MerchantService {
+	return &MerchantService{
+		logger:       logger,
+		cfg:          cfg,
+		MerchantRepo: repository.NewMerchantRepo(logger, cfg, db),
+		UserRepo:     repository.NewUserRepo(logger, cfg, db),
+	}
+}
+
+func (ms *MerchantService) CreateMerchant(
+	ctx context.Context,
+	programID int,
+	req *models.MerchantRequest,
+) (*models.MerchantCredentials, error) {
+	ctx, cancel := context.WithTimeout(ctx, ms.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	apiKey, err := auth.GenerateSecretKey()
+	if err != nil {
+		return nil, fmt.Errorf("error generating merchant api key %w", err)
+	}
+
+	merchant := &models.Merchant{
+		ProgramID:  programID,
+		Code:       req.Code,
+		Name:       req.Name,
+		Namespaced: req.Namespaced,
+	}
+	if err = ms.MerchantRepo.CreateMerchant(ctx, merchant, auth.HashFor(apiKey)); err != nil {
+		return nil, fmt.Errorf("error creating merchant %s: %w", req.Code, err)
+	}
+
+	return &models.MerchantCredentials{Merchant: *merchant, APIKey: apiKey}, nil
+}
+
+func (ms *MerchantService) GetMerchants(ctx context.Context, programID int) ([]*models.Merchant, error) {
+	ctx, cancel := context.WithTimeout(ctx, ms.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	merchants, err := ms.MerchantRepo.GetMerchants(ctx, programID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting merchants %w", err)
+	}
+
+	return merchants, nil
+}
+
+func (ms *MerchantService) GetMerchantByKey(ctx context.Context, apiKey string) (*models.Merchant, error) {
+	ctx, cancel := context.WithTimeout(ctx, ms.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	merchant, err := ms.MerchantRepo.GetMerchantByKeyHash(ctx, auth.HashFor(apiKey))
+	if err != nil {
+		return nil, fmt.Errorf("error getting merchant by api key %w", err)
+	}
+
+	return merchant, nil
+}
+
+func (ms *MerchantService) RegisterOrder(
+	ctx context.Context,
+	merchant *models.Merchant,
+	req *models.MerchantOrderRequest,
+) (*models.MerchantReceipt, error) {
+	ctx, cancel := context.WithTimeout(ctx, ms.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	var customer *models.User
+	if req.Customer != "" {
+		var err error
+		customer, err = ms.UserRepo.GetUserWithLogin(ctx, merchant.ProgramID, req.Customer)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrCustomerNotFound
+			}
+			return nil, fmt.Errorf("error getting customer %w", err)
+		}
+	}
+
+	receipt := &models.MerchantReceipt{
+		Number:        req.Number,
+		OrderNumber:   models.MerchantOrderNumber(merchant, req.Number),
+		PurchaseTotal: req.PurchaseTotal,
+		Currency:      req.Currency,
+	}
+	if err := ms.MerchantRepo.CreateReceipt(ctx, merchant, receipt, req.Items, customer); err != nil {
+		return nil, fmt.Errorf("error registering receipt %s of merchant %s: %w", req.Number, merchant.Code, err)
+	}
+
+	return receipt, nil
+}
+
+func (ms *MerchantService) GetReceipts(
+	ctx context.Context,
+	merchant *models.Merchant,
+	filter *models.MerchantReceiptFilter,
+) ([]*models.MerchantReceipt, *models.Cursor, error) {
+	ctx, cancel := context.WithTimeout(ctx, ms.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	receipts, cursor, err := ms.MerchantRepo.GetReceipts(ctx, merchant, filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting receipts of merchant %s: %w", merchant.Code, err)
+	}
+	if receipts == nil {
+		receipts = []*models.MerchantReceipt{}
+	}
+
+	return receipts, cursor, nil
+}
+
+func (ms *MerchantService) GetSummary(
+	ctx context.Context,
+	merchant *models.Merchant,
+	from, to *time.Time,
+) (*models.MerchantSummary, error) {
+	ctx, cancel := context.WithTimeout(ctx, ms.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	summary, err := ms.MerchantRepo.GetSummary(ctx, merchant, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("error getting summary of merchant %s: %w", merchant.Code, err)
+	}
+
+	return summary, nil
+}
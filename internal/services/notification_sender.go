@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/notify"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	notificationEventsBatch = 500
+	notificationSendBatch   = 100
+	// notificationLease на сколько уведомление скрывается от других реплик, пока идёт его отправка
+	notificationLease       = 5 * time.Minute
+	notificationSendTimeout = 30 * time.Second
+)
+
+var ErrChannelNotConfigured error = errors.New("notification channel is not configured")
+
+// NotificationSender события берутся из user_event, поэтому уведомления не теряются при перезапуске.
+type NotificationSender struct {
+	logger           *zap.Logger
+	cfg              *config.Config
+	NotificationRepo *repository.NotificationRepo
+	Renderer         *notify.Renderer
+	// Channels каналы доставки по имени; SMS-шлюз подключается сюда через notify.NewSMSChannel
+	Channels map[string]notify.Channel
+}
+
+func NewNotificationSender(logger *zap.Logger, cfg *config.Config, db *sql.DB) (*NotificationSender, error) {
+	renderer, err := notify.NewRenderer()
+	if err != nil {
+		return nil, fmt.Errorf("error creating notification renderer %w", err)
+	}
+	channels, err := notify.NewChannels(cfg.Notify)
+	if err != nil {
+		return nil, fmt.Errorf("error creating notification channels %w", err)
+	}
+
+	return &NotificationSender{
+		logger:           logger,
+		cfg:              cfg,
+		NotificationRepo: repository.NewNotificationRepo(logger, cfg, db),
+		Renderer:         renderer,
+		Channels:         channels,
+	}, nil
+}
+
+func (ns *NotificationSender) ProcessNotifications(ctx context.Context) error {
+	if err := ns.enqueue(ctx); err != nil {
+		return err
+	}
+	return ns.deliver(ctx)
+}
+
+func (ns *NotificationSender) enqueue(ctx context.Context) error {
+	events, err := ns.NotificationRepo.GetNewEvents(ctx, notificationEventsBatch)
+	if err != nil {
+		return fmt.Errorf("error getting events for notifications %w", err)
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	eventIDs := make([]int64, 0, len(events))
+	var notifications []*models.Notification
+	for _, e := range events {
+		eventIDs = append(eventIDs, e.Event.ID)
+		p := e.Preferences
+		if p == nil || !p.Wants(e.Event.Type) {
+			continue
+		}
+		for _, channel := range p.Channels {
+			recipient := p.Recipient(channel)
+			if recipient == "" {
+				continue
+			}
+			msg, err := ns.Renderer.Render(p.Locale, channel, e.Event)
+			if err != nil {
+				ns.logger.Error("error rendering notification",
+					zap.Int64("EVENT_ID", e.Event.ID), zap.String("CHANNEL", channel), zap.Error(err))
+				continue
+			}
+			notifications = append(notifications, &models.Notification{
+				UserID:    e.Event.UserID,
+				EventID:   e.Event.ID,
+				Channel:   channel,
+				Recipient: recipient,
+				Subject:   msg.Subject,
+				Body:      msg.Body,
+			})
+		}
+	}
+
+	marked, err := ns.NotificationRepo.EnqueueNotifications(ctx, eventIDs, notifications)
+	if err != nil {
+		return fmt.Errorf("error enqueueing notifications %w", err)
+	}
+	if marked > 0 {
+		ns.logger.Debug("notifications enqueued", zap.Int("EVENTS", marked), zap.Int("COUNT", len(notifications)))
+	}
+
+	return nil
+}
+
+func (ns *NotificationSender) deliver(ctx context.Context) error {
+	now := time.Now()
+	due, err := ns.NotificationRepo.ClaimDue(ctx, now, notificationLease, notificationSendBatch)
+	if err != nil {
+		return fmt.Errorf("error claiming due notifications %w", err)
+	}
+	if len(due) == 0 {
+		return nil
+	}
+
+	userIDs := make([]int, 0, len(due))
+	for _, n := range due {
+		userIDs = append(userIDs, n.UserID)
+	}
+	window := ns.cfg.Notify.RateWindow
+	sent, oldest, err := ns.NotificationRepo.GetSentSince(ctx, userIDs, now.Add(-window))
+	if err != nil {
+		return fmt.Errorf("error getting sent notifications %w", err)
+	}
+
+	for _, n := range due {
+		if sent[n.UserID] >= ns.cfg.Notify.RateLimit {
+			// место в окне освобождается, когда из него выходит самое раннее отправленное уведомление
+			if err = ns.NotificationRepo.Postpone(ctx, n.ID, oldest[n.UserID].Add(window)); err != nil {
+				return fmt.Errorf("error postponing notification %w", err)
+			}
+			continue
+		}
+
+		if sendErr := ns.send(ctx, n); sendErr != nil {
+			ns.logger.Debug("error sending notification", zap.Int64("ID", n.ID), zap.Int("USERID", n.UserID),
+				zap.String("CHANNEL", n.Channel), zap.Int("ATTEMPT", n.Attempts+1), zap.Error(sendErr))
+
+			backoff := ns.cfg.Notify.RetryBackoff * time.Duration(1<<n.Attempts)
+			if err = ns.NotificationRepo.MarkFailed(ctx, n.ID, sendErr.Error(), time.Now().Add(backoff),
+				ns.cfg.Notify.MaxAttempts); err != nil {
+				return fmt.Errorf("error marking notification failed %w", err)
+			}
+			continue
+		}
+
+		if err = ns.NotificationRepo.MarkSent(ctx, n.ID, time.Now()); err != nil {
+			return fmt.Errorf("error marking notification sent %w", err)
+		}
+		if sent[n.UserID] == 0 {
+			oldest[n.UserID] = time.Now()
+		}
+		sent[n.UserID]++
+	}
+
+	return nil
+}
+
+func (ns *NotificationSender) send(ctx context.Context, n *models.Notification) error {
+	channel, ok := ns.Channels[n.Channel]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrChannelNotConfigured, n.Channel)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notificationSendTimeout)
+	defer cancel()
+
+	if err := channel.Send(ctx, &notify.Message{
+		Channel: n.Channel,
+		To:      n.Recipient,
+		Subject: n.Subject,
+		Body:    n.Body,
+	}); err != nil {
+		return fmt.Errorf("error sending %s notification %w", n.Channel, err)
+	}
+
+	return nil
+}
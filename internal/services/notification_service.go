@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"go.uber.org/zap"
+)
+
+type NotificationService struct {
+	logger           *zap.Logger
+	cfg              *config.Config
+	NotificationRepo *repository.NotificationRepo
+}
+
+func NewNotificationService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *NotificationService {
+	return &NotificationService{
+		logger:           logger,
+		cfg:              cfg,
+		NotificationRepo: repository.NewNotificationRepo(logger, cfg, db),
+	}
+}
+
+func (ns *NotificationService) GetPreferences(
+	ctx context.Context,
+	user *models.User,
+) (*models.NotificationPreferences, error) {
+	ctx, cancel := context.WithTimeout(ctx, ns.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	p, err := ns.NotificationRepo.GetPreferences(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting notification preferences %w", err)
+	}
+
+	return p, nil
+}
+
+func (ns *NotificationService) SavePreferences(
+	ctx context.Context,
+	user *models.User,
+	p *models.NotificationPreferences,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, ns.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	p.UserID = user.ID
+	if p.Locale == "" {
+		p.Locale = models.DefaultNotificationLocale
+	}
+	if p.Channels == nil {
+		p.Channels = []string{}
+	}
+	if p.Events == nil {
+		p.Events = []string{}
+	}
+	if err := ns.NotificationRepo.SavePreferences(ctx, p); err != nil {
+		return fmt.Errorf("error saving notification preferences %w", err)
+	}
+
+	return nil
+}
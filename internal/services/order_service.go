@@ -3,8 +3,10 @@ package services
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/Melikhov-p/go-loyalty-system/internal/config"
 	"github.com/Melikhov-p/go-loyalty-system/internal/models"
@@ -19,24 +21,39 @@ const (
 	processed OrderFinalStatus = "PROCESSED"
 )
 
+const batchInsertSize = 500
+
 type OrderService struct {
-	logger         *zap.Logger
-	cfg            *config.Config
-	OrderRepo      *repository.OrderRepo
-	BalanceService *BalanceService
+	logger              *zap.Logger
+	cfg                 *config.Config
+	OrderRepo           *repository.OrderRepo
+	BalanceService      *BalanceService
+	CampaignService     *CampaignService
+	ReferralService     *ReferralService
+	VerificationService *VerificationService
+	MerchantRepo        *repository.MerchantRepo
 }
 
 func NewOrderService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *OrderService {
 	return &OrderService{
-		logger:         logger,
-		cfg:            cfg,
-		OrderRepo:      repository.NewOrderRepo(logger, cfg, db),
-		BalanceService: NewBalanceService(logger, cfg, db),
+		logger:              logger,
+		cfg:                 cfg,
+		OrderRepo:           repository.NewOrderRepo(logger, cfg, db),
+		BalanceService:      NewBalanceService(logger, cfg, db),
+		CampaignService:     NewCampaignService(logger, cfg, db),
+		ReferralService:     NewReferralService(logger, cfg, db),
+		VerificationService: NewVerificationService(logger, cfg, db),
+		MerchantRepo:        repository.NewMerchantRepo(logger, cfg, db),
 	}
 }
 
 // ValidateOrderNumber Функция для проверки номера заказа с использованием алгоритма Луна.
 func (os *OrderService) ValidateOrderNumber(orderNumber string) bool {
+	// номер с кодом магазина от клиента не принимается
+	if models.IsMerchantOrderNumber(orderNumber) {
+		return false
+	}
+
 	// Удаляем все нецифровые символы из номера заказа
 	var cleanNumber string
 	for _, char := range orderNumber {
@@ -70,39 +87,256 @@ func (os *OrderService) ValidateOrderNumber(orderNumber string) bool {
 	return sum%10 == 0
 }
 
+// ValidateWithdrawNumber номер списания хранится как BIGINT.
+func (os *OrderService) ValidateWithdrawNumber(orderNumber string) bool {
+	if _, err := strconv.ParseUint(orderNumber, 10, 63); err != nil {
+		return false
+	}
+	return os.ValidateOrderNumber(orderNumber)
+}
+
 func (os *OrderService) CreateOrder(ctx context.Context, orderNumber string, user *models.User) error {
+	_, err := os.CreateOrderWithPurchase(ctx, orderNumber, nil, user)
+	return err
+}
+
+func (os *OrderService) CreateOrderWithPurchase(
+	ctx context.Context,
+	orderNumber string,
+	purchase *models.OrderPurchase,
+	user *models.User,
+) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, os.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	orderNumber, purchase, err := os.claimReceipt(ctx, user.ProgramID, orderNumber, purchase)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.OrderRepo.CreateOrder(ctx, orderNumber, user, purchase)
+	if err != nil {
+		return "", fmt.Errorf("error creating new order %w", err)
+	}
+
+	registered := purchase == nil || len(purchase.Items) == 0
+	err = os.OrderRepo.CreateWatchedOrder(ctx, orderNumber, user, registered)
+	if err != nil {
+		return "", fmt.Errorf("error creating new watched order %w", err)
+	}
+
+	return orderNumber, nil
+}
+
+// claimReceipt номер чека магазина с namespace дополняется кодом магазина.
+func (os *OrderService) claimReceipt(
+	ctx context.Context,
+	programID int,
+	orderNumber string,
+	purchase *models.OrderPurchase,
+) (string, *models.OrderPurchase, error) {
+	if purchase != nil {
+		merchant, err := os.MerchantRepo.GetMerchantByCode(ctx, programID, purchase.MerchantID)
+		if err != nil {
+			if errors.Is(err, repository.ErrMerchantNotFound) {
+				return orderNumber, purchase, nil
+			}
+			return "", nil, fmt.Errorf("error getting merchant %s: %w", purchase.MerchantID, err)
+		}
+		if !merchant.Namespaced {
+			return orderNumber, purchase, nil
+		}
+		claimed := *purchase
+		claimed.ReceiptNumber = orderNumber
+		return models.MerchantOrderNumber(merchant, orderNumber), &claimed, nil
+	}
+
+	receiptPurchase, err := os.MerchantRepo.GetReceiptPurchase(ctx, programID, orderNumber)
+	if err != nil {
+		if errors.Is(err, repository.ErrReceiptNotFound) {
+			return orderNumber, nil, nil
+		}
+		return "", nil, fmt.Errorf("error getting receipt for order %s: %w", orderNumber, err)
+	}
+
+	return orderNumber, receiptPurchase, nil
+}
+
+func (os *OrderService) CreateOrdersBatch(
+	ctx context.Context,
+	user *models.User,
+	lines []*models.BatchLine,
+) (*models.BatchResult, error) {
+	result := &models.BatchResult{
+		Results: make([]*models.BatchLineResult, 0, len(lines)),
+	}
+
+	for start := 0; start < len(lines); start += batchInsertSize {
+		end := min(start+batchInsertSize, len(lines))
+		if err := os.createOrdersChunk(ctx, user, lines[start:end], result); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (os *OrderService) createOrdersChunk(
+	ctx context.Context,
+	user *models.User,
+	lines []*models.BatchLine,
+	result *models.BatchResult,
+) error {
 	ctx, cancel := context.WithTimeout(ctx, os.cfg.DB.ContextTimeout)
 	defer cancel()
 
-	err := os.OrderRepo.CreateOrder(ctx, orderNumber, user)
+	numbers := make([]string, 0, len(lines))
+	seen := make(map[string]struct{}, len(lines))
+	for _, line := range lines {
+		if _, ok := seen[line.Number]; ok || !isDigits(line.Number) || !os.ValidateOrderNumber(line.Number) {
+			continue
+		}
+		seen[line.Number] = struct{}{}
+		numbers = append(numbers, line.Number)
+	}
+
+	// номер из пакета сопоставляется с чеками магазинов так же, как при одиночной загрузке
+	purchases, err := os.MerchantRepo.GetReceiptPurchases(ctx, user.ProgramID, numbers)
 	if err != nil {
-		return fmt.Errorf("error creating new order %w", err)
+		return fmt.Errorf("error getting receipts for orders batch %w", err)
 	}
 
-	err = os.OrderRepo.CreateWatchedOrder(ctx, orderNumber, user)
+	inserted, owners, err := os.OrderRepo.CreateOrdersBulk(ctx, numbers, purchases, user)
 	if err != nil {
-		return fmt.Errorf("error creating new watched order %w", err)
+		return fmt.Errorf("error creating orders in bulk %w", err)
+	}
+
+	// повтор номера внутри одного файла считается дубликатом уже принятой строки
+	reported := make(map[string]struct{}, len(lines))
+	for _, line := range lines {
+		_, isSeen := seen[line.Number]
+		_, isReported := reported[line.Number]
+		_, isInserted := inserted[line.Number]
+		owner, isExisting := owners[line.Number]
+
+		switch {
+		case !isSeen:
+			result.Add(line, models.BatchLineInvalid)
+		case isReported:
+			result.Add(line, models.BatchLineDuplicate)
+		case isInserted:
+			result.Add(line, models.BatchLineAccepted)
+		case isExisting && owner == user.ID:
+			result.Add(line, models.BatchLineDuplicate)
+		default:
+			result.Add(line, models.BatchLineConflict)
+		}
+		reported[line.Number] = struct{}{}
 	}
 
 	return nil
 }
 
-func (os *OrderService) GetOrdersByUser(ctx context.Context, user *models.User) ([]*models.Order, error) {
+// isDigits в пакетной загрузке номер не очищается от посторонних символов, в отличие от одиночной.
+func isDigits(number string) bool {
+	if number == "" {
+		return false
+	}
+	for _, char := range number {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (os *OrderService) StartOrdersBatchJob(
+	ctx context.Context,
+	jobs *Jobs,
+	user *models.User,
+	lines []*models.BatchLine,
+) (*models.OrderBatchJob, error) {
 	ctx, cancel := context.WithTimeout(ctx, os.cfg.DB.ContextTimeout)
 	defer cancel()
 
-	orders, err := os.OrderRepo.GetOrdersByUser(ctx, user.ID)
+	job, err := os.OrderRepo.CreateBatchJob(ctx, user, len(lines))
 	if err != nil {
-		return []*models.Order{}, fmt.Errorf("error getting orders by user with id %d: %w", user.ID, err)
+		return nil, fmt.Errorf("error creating order batch job %w", err)
 	}
-	return orders, nil
+
+	running := *job
+	jobs.Go(func(appCtx context.Context) {
+		jobCtx, jobCancel := context.WithTimeout(appCtx, os.cfg.Jobs.Timeout)
+		defer jobCancel()
+
+		var jobErr error
+		running.Status = models.BatchJobDone
+		running.Result, jobErr = os.CreateOrdersBatch(jobCtx, user, lines)
+		if jobErr != nil {
+			os.logger.Error("error processing order batch job", zap.Int("JOB_ID", running.ID), zap.Error(jobErr))
+			running.Status = models.BatchJobFailed
+			running.Error = jobErr.Error()
+		}
+
+		// статус записывается и тогда, когда задание прервано остановкой приложения
+		finishCtx, finishCancel := context.WithTimeout(context.WithoutCancel(appCtx), os.cfg.DB.ContextTimeout)
+		defer finishCancel()
+		if jobErr = os.OrderRepo.FinishBatchJob(finishCtx, &running); jobErr != nil {
+			os.logger.Error("error finishing order batch job", zap.Int("JOB_ID", running.ID), zap.Error(jobErr))
+		}
+	})
+
+	return job, nil
 }
 
-func (os *OrderService) GetOrderByNumber(ctx context.Context, number string) (*models.Order, error) {
+func (os *OrderService) FailStaleBatchJobs(ctx context.Context) error {
+	failed, err := os.OrderRepo.FailStaleBatchJobs(ctx, time.Now().Add(-os.cfg.Jobs.Timeout))
+	if err != nil {
+		return fmt.Errorf("error failing stale order batch jobs %w", err)
+	}
+	if failed > 0 {
+		os.logger.Warn("stale order batch jobs failed", zap.Int64("COUNT", failed))
+	}
+
+	return nil
+}
+
+func (os *OrderService) GetOrdersBatchJob(
+	ctx context.Context,
+	user *models.User,
+	id int,
+) (*models.OrderBatchJob, error) {
+	ctx, cancel := context.WithTimeout(ctx, os.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	job, err := os.OrderRepo.GetBatchJob(ctx, id, user)
+	if err != nil {
+		return nil, fmt.Errorf("error getting order batch job %d: %w", id, err)
+	}
+
+	return job, nil
+}
+
+func (os *OrderService) GetOrdersByUser(
+	ctx context.Context,
+	user *models.User,
+	filter *models.OrderListFilter,
+) ([]*models.Order, *models.Cursor, error) {
+	ctx, cancel := context.WithTimeout(ctx, os.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	orders, cursor, err := os.OrderRepo.GetOrdersByUser(ctx, user.ProgramID, user.ID, filter)
+	if err != nil {
+		return []*models.Order{}, nil, fmt.Errorf("error getting orders by user with id %d: %w", user.ID, err)
+	}
+	return orders, cursor, nil
+}
+
+func (os *OrderService) GetOrderByNumber(ctx context.Context, programID int, number string) (*models.Order, error) {
 	ctx, cancel := context.WithTimeout(ctx, os.cfg.DB.ContextTimeout)
 	defer cancel()
 
-	order, err := os.OrderRepo.GetOrderByNumber(ctx, number)
+	order, err := os.OrderRepo.GetOrderByNumber(ctx, programID, number)
 	if err != nil {
 		return nil, fmt.Errorf("error getting order by number %s: %w", number, err)
 	}
@@ -110,6 +344,75 @@ func (os *OrderService) GetOrderByNumber(ctx context.Context, number string) (*m
 	return order, nil
 }
 
+func (os *OrderService) GetOrderDetails(
+	ctx context.Context,
+	user *models.User,
+	number string,
+) (*models.OrderDetailsResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, os.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	order, err := os.OrderRepo.GetOrderByNumber(ctx, user.ProgramID, number)
+	if err != nil {
+		return nil, fmt.Errorf("error getting order by number %s: %w", number, err)
+	}
+	// чужой заказ для пользователя не существует
+	if order.UserID != user.ID {
+		return nil, fmt.Errorf("order %s belongs to another user: %w", number, repository.ErrOrderNumberNotFound)
+	}
+
+	details := &models.OrderDetailsResponse{Order: order}
+
+	if details.Attempts, details.Watching, err = os.OrderRepo.GetWatchState(ctx, user.ProgramID, number); err != nil {
+		return nil, fmt.Errorf("error getting watch state for order %s: %w", number, err)
+	}
+
+	if details.Timeline, err = os.OrderRepo.GetOrderStatusHistory(ctx, user.ProgramID, number); err != nil {
+		return nil, fmt.Errorf("error getting status history for order %s: %w", number, err)
+	}
+
+	if details.Actions, err = os.OrderRepo.GetOrderActions(ctx, user.ProgramID, number); err != nil {
+		return nil, fmt.Errorf("error getting actions for order %s: %w", number, err)
+	}
+
+	return details, nil
+}
+
+func (os *OrderService) CancelOrder(
+	ctx context.Context,
+	user *models.User,
+	number, reason string,
+) (*models.OrderAction, error) {
+	ctx, cancel := context.WithTimeout(ctx, os.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	action, err := os.OrderRepo.CancelOrder(ctx, user.ProgramID, user.ID, number, reason, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error cancelling order %s: %w", number, err)
+	}
+
+	return action, nil
+}
+
+// ReturnOrder actorID пуст, если возврат оформлен по ключу администратора.
+func (os *OrderService) ReturnOrder(
+	ctx context.Context,
+	programID int,
+	number, actorType string,
+	actorID *int,
+	reason string,
+) (*models.OrderAction, error) {
+	ctx, cancel := context.WithTimeout(ctx, os.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	result, err := os.OrderRepo.ReturnOrder(ctx, programID, number, actorType, actorID, reason, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error returning order %s: %w", number, err)
+	}
+
+	return result.Action, nil
+}
+
 func (os *OrderService) GetWatchedOrders(ctx context.Context) ([]*models.WatchedOrder, error) {
 	ctx, cancel := context.WithTimeout(ctx, os.cfg.DB.ContextTimeout)
 	defer cancel()
@@ -126,12 +429,12 @@ func (os *OrderService) UpdateOrderStatus(ctx context.Context, orders []*models.
 	ctx, cancel := context.WithTimeout(ctx, os.cfg.DB.ContextTimeout)
 	defer cancel()
 
-	err := os.OrderRepo.UpdateOrdersStatus(ctx, orders)
+	changed, err := os.OrderRepo.UpdateOrdersStatus(ctx, orders)
 	if err != nil {
 		return fmt.Errorf("error updating orders status %w", err)
 	}
 
-	for _, order := range orders {
+	for _, order := range changed {
 		if order.AccrualOrderStatus == string(invalid) || order.AccrualOrderStatus == string(processed) {
 			os.logger.Debug("order in final status",
 				zap.String("ORDER NUMBER", order.OrderNumber),
@@ -144,16 +447,49 @@ func (os *OrderService) UpdateOrderStatus(ctx context.Context, orders []*models.
 					zap.Error(err))
 			}
 
-			err = os.BalanceService.IncreaseBalance(ctx, order.UserID, order.AccrualPoints)
-			if err != nil {
+			if _, err = os.BalanceService.CreditOrderAccrual(ctx, order); err != nil {
 				os.logger.Error("error increasing user balance",
 					zap.Int("USERID", order.UserID),
 					zap.Float64("DIFF", order.AccrualPoints),
 					zap.Error(err),
 				)
+				continue
+			}
+
+			if order.AccrualOrderStatus == string(processed) {
+				os.applyCampaigns(ctx, order)
+
+				if err = os.ReferralService.QualifyOrder(ctx, order); err != nil {
+					os.logger.Error("error qualifying referral by order",
+						zap.String("NUMBER", order.OrderNumber),
+						zap.Error(err))
+				}
+				if err = os.VerificationService.ScheduleVerification(ctx, order); err != nil {
+					os.logger.Error("error scheduling order verification",
+						zap.String("NUMBER", order.OrderNumber),
+						zap.Error(err))
+				}
 			}
 		}
 	}
 
 	return nil
 }
+
+// applyCampaigns ошибка не отменяет уже зачисленное основное начисление.
+func (os *OrderService) applyCampaigns(ctx context.Context, order *models.WatchedOrder) {
+	grants, _, err := os.CampaignService.ApplyToOrder(ctx, order)
+	if err != nil {
+		os.logger.Error("error applying campaigns to order",
+			zap.String("NUMBER", order.OrderNumber),
+			zap.Error(err))
+		return
+	}
+
+	for _, g := range grants {
+		os.logger.Debug("campaign bonus granted",
+			zap.String("NUMBER", order.OrderNumber),
+			zap.Int("CAMPAIGN", g.CampaignID),
+			zap.Float64("POINTS", g.Points))
+	}
+}
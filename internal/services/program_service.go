@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/auth"
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"go.uber.org/zap"
+)
+
+type ProgramService struct {
+	logger      *zap.Logger
+	cfg         *config.Config
+	ProgramRepo *repository.ProgramRepo
+}
+
+func NewProgramService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *ProgramService {
+	return &ProgramService{
+		logger:      logger,
+		cfg:         cfg,
+		ProgramRepo: repository.NewProgramRepo(logger, cfg, db),
+	}
+}
+
+// ResolveProgram неизвестный явный код ошибка, неизвестный хост и чужой токен пропускаются.
+func (ps *ProgramService) ResolveProgram(ctx context.Context, code, host, token string) (*models.Program, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	if code = strings.TrimSpace(code); code != "" {
+		program, err := ps.ProgramRepo.GetProgramByCode(ctx, code)
+		if err != nil {
+			return nil, fmt.Errorf("error getting program %s: %w", code, err)
+		}
+		return program, nil
+	}
+
+	if host != "" {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		program, err := ps.ProgramRepo.GetProgramByHost(ctx, host)
+		if err == nil {
+			return program, nil
+		}
+		if !errors.Is(err, repository.ErrProgramNotFound) {
+			return nil, fmt.Errorf("error getting program by host %s: %w", host, err)
+		}
+	}
+
+	if token != "" {
+		if claims, err := auth.ParseToken(token, ps.cfg.DB.SecretKey); err == nil && claims.ProgramID != 0 {
+			program, err := ps.ProgramRepo.GetProgramByID(ctx, claims.ProgramID)
+			if err == nil {
+				return program, nil
+			}
+			if !errors.Is(err, repository.ErrProgramNotFound) {
+				return nil, fmt.Errorf("error getting program %d from token: %w", claims.ProgramID, err)
+			}
+		}
+	}
+
+	program, err := ps.ProgramRepo.GetProgramByCode(ctx, ps.cfg.DefaultProgram)
+	if err != nil {
+		return nil, fmt.Errorf("error getting default program %s: %w", ps.cfg.DefaultProgram, err)
+	}
+
+	return program, nil
+}
+
+func (ps *ProgramService) GetPrograms(ctx context.Context) ([]*models.Program, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	programs, err := ps.ProgramRepo.GetPrograms(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting programs %w", err)
+	}
+
+	return programs, nil
+}
+
+func (ps *ProgramService) CreateProgram(ctx context.Context, req *models.ProgramRequest) (*models.Program, error) {
+	ctx, cancel := context.WithTimeout(ctx, ps.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	program := &models.Program{
+		Code:        req.Code,
+		Name:        req.Name,
+		Host:        req.Host,
+		AccrualAddr: strings.TrimSuffix(req.AccrualAddr, "/"),
+		TokenIssuer: req.TokenIssuer,
+	}
+	if program.TokenIssuer == "" {
+		program.TokenIssuer = strings.ToLower(req.Code)
+	}
+
+	if err := ps.ProgramRepo.CreateProgram(ctx, program); err != nil {
+		return nil, fmt.Errorf("error creating program %s: %w", req.Code, err)
+	}
+
+	return program, nil
+}
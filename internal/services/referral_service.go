@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"go.uber.org/zap"
+)
+
+type ReferralService struct {
+	logger         *zap.Logger
+	cfg            *config.Config
+	ReferralRepo   *repository.ReferralRepo
+	BalanceService *BalanceService
+}
+
+func NewReferralService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *ReferralService {
+	return &ReferralService{
+		logger:         logger,
+		cfg:            cfg,
+		ReferralRepo:   repository.NewReferralRepo(logger, cfg, db),
+		BalanceService: NewBalanceService(logger, cfg, db),
+	}
+}
+
+func (rs *ReferralService) ResolveInviteCode(ctx context.Context, programID int, code string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, rs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	referrerID, err := rs.ReferralRepo.GetUserIDByInviteCode(ctx, programID, code)
+	if err != nil {
+		return 0, fmt.Errorf("error resolving invite code %w", err)
+	}
+
+	return referrerID, nil
+}
+
+func (rs *ReferralService) AddReferral(ctx context.Context, referrerID int, referee *models.User) error {
+	if referrerID == referee.ID {
+		return ErrSelfReferral
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, rs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	ref, err := rs.ReferralRepo.CreateReferral(ctx, referrerID, referee.ID, rs.cfg.Referral.MaxPerReferrer)
+	if err != nil {
+		return fmt.Errorf("error creating referral %w", err)
+	}
+	if ref.Status == models.ReferralRejected {
+		rs.logger.Info("referral rejected",
+			zap.Int("REFERRER", referrerID),
+			zap.Int("REFEREE", referee.ID),
+			zap.String("REASON", ref.Reason))
+	}
+
+	return nil
+}
+
+func (rs *ReferralService) QualifyOrder(ctx context.Context, order *models.WatchedOrder) error {
+	releaseAt := time.Now().Add(rs.cfg.Referral.HoldPeriod)
+
+	held, err := rs.ReferralRepo.HoldReferral(ctx, order.UserID, order.OrderNumber, releaseAt)
+	if err != nil {
+		return fmt.Errorf("error holding referral of user %d: %w", order.UserID, err)
+	}
+	if held {
+		rs.logger.Debug("referral held",
+			zap.Int("REFEREE", order.UserID),
+			zap.String("NUMBER", order.OrderNumber),
+			zap.Time("RELEASE_AT", releaseAt))
+	}
+
+	return nil
+}
+
+func (rs *ReferralService) ReleaseRewards(ctx context.Context) error {
+	rewards, err := rs.BalanceService.CreditReferralRewards(ctx)
+	if err != nil {
+		return fmt.Errorf("error releasing referral rewards %w", err)
+	}
+
+	for _, reward := range rewards {
+		rs.logger.Debug("referral rewarded",
+			zap.Int("REFERRER", reward.ReferrerID),
+			zap.Int("REFEREE", reward.RefereeID),
+			zap.String("NUMBER", reward.OrderNumber))
+	}
+
+	return nil
+}
+
+func (rs *ReferralService) GetReferralStats(ctx context.Context, user *models.User) (*models.ReferralStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, rs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	stats, err := rs.ReferralRepo.GetReferralStats(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting referral stats %w", err)
+	}
+	stats.Limit = rs.cfg.Referral.MaxPerReferrer
+
+	return stats, nil
+}
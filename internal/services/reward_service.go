@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"go.uber.org/zap"
+)
+
+type RewardService struct {
+	logger         *zap.Logger
+	cfg            *config.Config
+	RewardRepo     *repository.RewardRepo
+	BalanceService *BalanceService
+}
+
+func NewRewardService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *RewardService {
+	return &RewardService{
+		logger:         logger,
+		cfg:            cfg,
+		RewardRepo:     repository.NewRewardRepo(logger, cfg, db),
+		BalanceService: NewBalanceService(logger, cfg, db),
+	}
+}
+
+func rewardFromRequest(programID, id int, req *models.RewardRequest) *models.Reward {
+	r := &models.Reward{
+		ID:          id,
+		ProgramID:   programID,
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       req.Price,
+		Stock:       req.Stock,
+		UserLimit:   req.UserLimit,
+		Active:      true,
+	}
+	if req.Active != nil {
+		r.Active = *req.Active
+	}
+	return r
+}
+
+func (rs *RewardService) CreateReward(
+	ctx context.Context,
+	programID int,
+	req *models.RewardRequest,
+) (*models.Reward, error) {
+	ctx, cancel := context.WithTimeout(ctx, rs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	r := rewardFromRequest(programID, 0, req)
+	if err := rs.RewardRepo.CreateReward(ctx, r); err != nil {
+		return nil, fmt.Errorf("error creating reward %w", err)
+	}
+
+	return r, nil
+}
+
+func (rs *RewardService) UpdateReward(
+	ctx context.Context,
+	programID, id int,
+	req *models.RewardRequest,
+) (*models.Reward, error) {
+	ctx, cancel := context.WithTimeout(ctx, rs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	r := rewardFromRequest(programID, id, req)
+	if err := rs.RewardRepo.UpdateReward(ctx, r); err != nil {
+		return nil, fmt.Errorf("error updating reward %d: %w", id, err)
+	}
+
+	return r, nil
+}
+
+func (rs *RewardService) GetRewards(ctx context.Context, programID int, activeOnly bool) ([]*models.Reward, error) {
+	ctx, cancel := context.WithTimeout(ctx, rs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	rewards, err := rs.RewardRepo.GetRewards(ctx, programID, activeOnly)
+	if err != nil {
+		return nil, fmt.Errorf("error getting rewards %w", err)
+	}
+
+	return rewards, nil
+}
+
+func (rs *RewardService) Redeem(
+	ctx context.Context,
+	user *models.User,
+	rewardID int,
+) (*models.RewardRedemption, error) {
+	ctx, cancel := context.WithTimeout(ctx, rs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	reward, err := rs.RewardRepo.GetReward(ctx, user.ProgramID, rewardID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting reward %d: %w", rewardID, err)
+	}
+	if !reward.Active {
+		return nil, fmt.Errorf("reward %d is not active: %w", rewardID, repository.ErrRewardNotFound)
+	}
+	if user.BalanceInfo.Current < reward.Price {
+		return nil, ErrNotEnough
+	}
+	if err = rs.BalanceService.checkWithdrawalLimits(ctx, user, reward.Price); err != nil {
+		return nil, err
+	}
+
+	redemption, err := rs.RewardRepo.RedeemReward(ctx, user, rewardID, time.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrInsufficientBalance) {
+			return nil, fmt.Errorf("error redeeming reward %d: %w", rewardID, ErrNotEnough)
+		}
+		return nil, fmt.Errorf("error redeeming reward %d: %w", rewardID, err)
+	}
+
+	return redemption, nil
+}
+
+func (rs *RewardService) GetRedemptions(
+	ctx context.Context,
+	user *models.User,
+	page *models.ListPage,
+) ([]*models.RewardRedemption, *models.Cursor, error) {
+	ctx, cancel := context.WithTimeout(ctx, rs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	redemptions, cursor, err := rs.RewardRepo.GetRedemptions(ctx, user.ID, page)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting reward redemptions %w", err)
+	}
+
+	return redemptions, cursor, nil
+}
@@ -4,3 +4,13 @@ import "errors"
 
 var ErrNotEnough error = errors.New("current balance is not enough for withdraw")
 var ErrRetryAfter error = errors.New("got 429 Too Many Requests from Accrual service")
+var ErrWithdrawalLimit error = errors.New("withdrawal exceeds limit of the user tier")
+var ErrSelfReferral error = errors.New("user can not invite himself")
+var ErrSelfTransfer error = errors.New("can not transfer points to yourself")
+var ErrRecipientNotFound error = errors.New("transfer recipient not found")
+var ErrWithdrawalsBlocked error = errors.New("withdrawals are blocked until revoked accrual is reviewed")
+var ErrTokenProgram error = errors.New("token was issued for another loyalty program")
+var ErrCustomerNotFound error = errors.New("customer with provided login not found")
+var ErrTokenRevoked error = errors.New("token was revoked by password change or account deletion")
+var ErrWrongPassword error = errors.New("current password is incorrect")
+var ErrExportNotReady error = errors.New("data export archive is not ready")
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"go.uber.org/zap"
+)
+
+type TierService struct {
+	logger   *zap.Logger
+	cfg      *config.Config
+	TierRepo *repository.TierRepo
+}
+
+func NewTierService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *TierService {
+	return &TierService{
+		logger:   logger,
+		cfg:      cfg,
+		TierRepo: repository.NewTierRepo(logger, cfg, db),
+	}
+}
+
+func (ts *TierService) GetUserTier(ctx context.Context, user *models.User) (*models.UserTier, error) {
+	ctx, cancel := context.WithTimeout(ctx, ts.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	tier, err := ts.TierRepo.GetUserTier(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user tier %w", err)
+	}
+
+	return tier, nil
+}
+
+func (ts *TierService) GetUserTierHistory(ctx context.Context, user *models.User) ([]*models.TierChange, error) {
+	ctx, cancel := context.WithTimeout(ctx, ts.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	history, err := ts.TierRepo.GetUserTierHistory(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user tier history %w", err)
+	}
+
+	return history, nil
+}
+
+func (ts *TierService) RecalculateTiers(ctx context.Context) error {
+	tiers, err := ts.TierRepo.GetTiers(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting tiers %w", err)
+	}
+
+	stats, err := ts.TierRepo.GetTierStats(ctx, time.Now().Add(-ts.cfg.Scheduler.TierWindow))
+	if err != nil {
+		return fmt.Errorf("error getting tier stats %w", err)
+	}
+
+	var changed int
+	for _, s := range stats {
+		tier := models.QualifyingTier(tiers, s.Points, s.Spend)
+		if tier == nil || tier.ID == s.CurrentTierID {
+			continue
+		}
+
+		if err = ts.TierRepo.AssignTier(ctx, s, tier); err != nil {
+			return fmt.Errorf("error assigning tier %s to user %d: %w", tier.Code, s.UserID, err)
+		}
+		changed++
+	}
+
+	ts.logger.Debug("tiers recalculated", zap.Int("USERS", len(stats)), zap.Int("CHANGED", changed))
+	return nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"go.uber.org/zap"
+)
+
+type TransferService struct {
+	logger         *zap.Logger
+	cfg            *config.Config
+	TransferRepo   *repository.TransferRepo
+	UserRepo       *repository.UserRepo
+	BalanceService *BalanceService
+}
+
+func NewTransferService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *TransferService {
+	return &TransferService{
+		logger:         logger,
+		cfg:            cfg,
+		TransferRepo:   repository.NewTransferRepo(logger, cfg, db),
+		UserRepo:       repository.NewUserRepo(logger, cfg, db),
+		BalanceService: NewBalanceService(logger, cfg, db),
+	}
+}
+
+// Transfer суммы больше cfg.Transfer.ConfirmAbove ждут ConfirmTransfer.
+func (ts *TransferService) Transfer(
+	ctx context.Context,
+	sender *models.User,
+	req *models.TransferRequest,
+) (*models.Transfer, error) {
+	if req.To == sender.Login {
+		return nil, ErrSelfTransfer
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ts.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	if err := ts.BalanceService.checkWithdrawalsBlocked(ctx, sender.ID); err != nil {
+		return nil, err
+	}
+
+	// переводить можно только внутри своей программы
+	recipient, err := ts.UserRepo.GetUserWithLogin(ctx, sender.ProgramID, req.To)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecipientNotFound
+		}
+		return nil, fmt.Errorf("error getting transfer recipient %w", err)
+	}
+	if recipient.ID == sender.ID {
+		return nil, ErrSelfTransfer
+	}
+
+	t := &models.Transfer{
+		Counterparty: req.To,
+		Amount:       req.Amount,
+		Comment:      req.Comment,
+	}
+
+	if ts.cfg.Transfer.ConfirmAbove > 0 && req.Amount > ts.cfg.Transfer.ConfirmAbove {
+		if sender.BalanceInfo.Current < req.Amount {
+			return nil, ErrNotEnough
+		}
+
+		expiresAt := time.Now().Add(ts.cfg.Transfer.ConfirmTTL)
+		if err = ts.TransferRepo.CreatePendingTransfer(ctx, sender.ID, recipient.ID, t, expiresAt); err != nil {
+			return nil, fmt.Errorf("error creating pending transfer %w", err)
+		}
+		return t, nil
+	}
+
+	result, err := ts.TransferRepo.CreateTransfer(ctx, sender.ID, recipient.ID, t, ts.limits(time.Now()))
+	if err != nil {
+		return nil, transferError("error creating transfer", err)
+	}
+
+	return result.Transfer, nil
+}
+
+func (ts *TransferService) ConfirmTransfer(
+	ctx context.Context,
+	sender *models.User,
+	transferID int,
+) (*models.Transfer, error) {
+	ctx, cancel := context.WithTimeout(ctx, ts.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	if err := ts.BalanceService.checkWithdrawalsBlocked(ctx, sender.ID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result, err := ts.TransferRepo.ConfirmTransfer(ctx, sender.ID, transferID, now, ts.limits(now))
+	if err != nil {
+		return nil, transferError(fmt.Sprintf("error confirming transfer %d", transferID), err)
+	}
+
+	return result.Transfer, nil
+}
+
+func (ts *TransferService) GetUserTransfers(
+	ctx context.Context,
+	user *models.User,
+	filter *models.TransferListFilter,
+) ([]*models.Transfer, *models.Cursor, error) {
+	ctx, cancel := context.WithTimeout(ctx, ts.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	transfers, cursor, err := ts.TransferRepo.GetUserTransfers(ctx, user.ID, filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting user transfers %w", err)
+	}
+
+	return transfers, cursor, nil
+}
+
+func (ts *TransferService) limits(now time.Time) *models.TransferLimits {
+	return &models.TransferLimits{
+		DailyLimit: ts.cfg.Transfer.DailyLimit,
+		DayStart:   now.UTC().Truncate(24 * time.Hour),
+		LotExpiry:  now.AddDate(0, ts.cfg.Points.ExpiryMonths, 0),
+	}
+}
+
+// transferError приводит нехватку баллов на уровне хранилища к ErrNotEnough, как при списании.
+func transferError(msg string, err error) error {
+	if errors.Is(err, repository.ErrInsufficientBalance) {
+		return fmt.Errorf("%s: %w", msg, ErrNotEnough)
+	}
+	return fmt.Errorf("%s %w", msg, err)
+}
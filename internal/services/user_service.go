@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Melikhov-p/go-loyalty-system/internal/auth"
 	"github.com/Melikhov-p/go-loyalty-system/internal/config"
@@ -14,24 +15,36 @@ import (
 )
 
 type UserService struct {
-	logger         *zap.Logger
-	cfg            *config.Config
-	UserRepo       *repository.UserRepo
-	BalanceService *BalanceService
+	logger          *zap.Logger
+	cfg             *config.Config
+	UserRepo        *repository.UserRepo
+	BalanceService  *BalanceService
+	ReferralService *ReferralService
 }
 
 var ErrIncorrectPass error = errors.New("password diff")
 
-func (us *UserService) GetUserByToken(ctx context.Context, tokenString string) (*models.User, error) {
-	userID, err := auth.GetUserIDbyToken(tokenString, us.cfg.DB.SecretKey)
+func (us *UserService) GetUserByToken(
+	ctx context.Context,
+	tokenString string,
+	program *models.Program,
+) (*models.User, error) {
+	claims, err := auth.ParseToken(tokenString, us.cfg.DB.SecretKey)
 	if err != nil {
 		return nil, fmt.Errorf("error getting user id from token claims %w", err)
 	}
+	if (claims.ProgramID != 0 && claims.ProgramID != program.ID) ||
+		(claims.Issuer != "" && claims.Issuer != program.TokenIssuer) {
+		return nil, fmt.Errorf("token of program %d in program %s: %w", claims.ProgramID, program.Code, ErrTokenProgram)
+	}
 
-	user, err := us.UserRepo.GetUserWithID(ctx, userID)
+	user, err := us.UserRepo.GetUserWithID(ctx, program.ID, claims.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("error getting user: %w", err)
 	}
+	if claims.TokenVersion != user.TokenVersion {
+		return nil, fmt.Errorf("token version %d of user %d: %w", claims.TokenVersion, user.ID, ErrTokenRevoked)
+	}
 
 	user.AuthInfo.Token = tokenString
 	err = us.BalanceService.GetUserBalance(ctx, user)
@@ -44,17 +57,27 @@ func (us *UserService) GetUserByToken(ctx context.Context, tokenString string) (
 
 func (us *UserService) AddNewUser(
 	ctx context.Context,
+	program *models.Program,
 	login string, password string,
+	referralCode string,
 ) (*models.User, error) {
+	var referrerID int
+	if referralCode != "" {
+		var err error
+		if referrerID, err = us.ReferralService.ResolveInviteCode(ctx, program.ID, referralCode); err != nil {
+			return nil, fmt.Errorf("error checking referral code %w", err)
+		}
+	}
+
 	passHash := auth.HashFor(password)
 
-	user, err := us.UserRepo.AddUser(ctx, login, passHash)
+	user, err := us.UserRepo.AddUser(ctx, program.ID, login, passHash)
 	if err != nil {
 		return nil, fmt.Errorf("error adding new user %w", err)
 	}
 
 	user.Password = passHash
-	if user.AuthInfo.Token, err = auth.BuildJWTToken(user.ID, us.cfg.DB.SecretKey, us.cfg.TokenLifeTime); err != nil {
+	if user.AuthInfo.Token, err = us.buildToken(user, program); err != nil {
 		err = us.UserRepo.DeleteUserWithID(ctx, user.ID)
 		if err != nil {
 			us.logger.Error(
@@ -79,6 +102,16 @@ func (us *UserService) AddNewUser(
 		return nil, fmt.Errorf("error creating balance for user %w", err)
 	}
 
+	// пользователь уже создан, поэтому сбой приглашения не отменяет регистрацию
+	if referrerID != 0 {
+		if err = us.ReferralService.AddReferral(ctx, referrerID, user); err != nil {
+			us.logger.Error("error adding referral",
+				zap.Int("REFERRER", referrerID),
+				zap.Int("USER_ID", user.ID),
+				zap.Error(err))
+		}
+	}
+
 	_ = us.BalanceService.GetUserBalance(ctx, user)
 
 	return user, nil
@@ -86,12 +119,13 @@ func (us *UserService) AddNewUser(
 
 func (us *UserService) AuthUser(
 	ctx context.Context,
+	program *models.Program,
 	login string,
 	password string,
 ) (*models.User, error) {
 	passHash := auth.HashFor(password)
 
-	user, err := us.UserRepo.GetUserWithLogin(ctx, login)
+	user, err := us.UserRepo.GetUserWithLogin(ctx, program.ID, login)
 	if err != nil {
 		return us.UserRepo.NewEmptyUser(), fmt.Errorf("error getting user by login %w", err)
 	}
@@ -100,18 +134,98 @@ func (us *UserService) AuthUser(
 		return us.UserRepo.NewEmptyUser(), ErrIncorrectPass
 	}
 
-	if user.AuthInfo.Token, err = auth.BuildJWTToken(user.ID, us.cfg.DB.SecretKey, us.cfg.TokenLifeTime); err != nil {
+	if user.AuthInfo.Token, err = us.buildToken(user, program); err != nil {
 		return nil, fmt.Errorf("error build JWT token for user %w", err)
 	}
 
 	return user, nil
 }
 
+func (us *UserService) buildToken(user *models.User, program *models.Program) (string, error) {
+	token, err := auth.BuildJWTToken(user.ID, program.ID, user.TokenVersion, program.TokenIssuer,
+		us.cfg.DB.SecretKey, us.cfg.TokenLifeTime)
+	if err != nil {
+		return "", fmt.Errorf("error building token %w", err)
+	}
+	return token, nil
+}
+
+func (us *UserService) GetProfile(ctx context.Context, user *models.User) (*models.Profile, error) {
+	ctx, cancel := context.WithTimeout(ctx, us.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	profile, err := us.UserRepo.GetProfile(ctx, user.ProgramID, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting profile of user %d: %w", user.ID, err)
+	}
+
+	return profile, nil
+}
+
+func (us *UserService) UpdateProfile(
+	ctx context.Context,
+	user *models.User,
+	update *models.ProfileUpdate,
+) (*models.Profile, error) {
+	ctx, cancel := context.WithTimeout(ctx, us.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	profile, err := us.UserRepo.UpdateProfile(ctx, user.ProgramID, user.ID, update)
+	if err != nil {
+		return nil, fmt.Errorf("error updating profile of user %d: %w", user.ID, err)
+	}
+
+	return profile, nil
+}
+
+// ChangePassword все ранее выпущенные токены перестают действовать.
+func (us *UserService) ChangePassword(
+	ctx context.Context,
+	user *models.User,
+	program *models.Program,
+	req *models.PasswordChangeRequest,
+) (string, error) {
+	stored, err := us.UserRepo.GetUserWithLogin(ctx, program.ID, user.Login)
+	if err != nil {
+		return "", fmt.Errorf("error getting user by login %w", err)
+	}
+	if stored.Password != auth.HashFor(req.CurrentPassword) {
+		return "", ErrWrongPassword
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, us.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	passHash := auth.HashFor(req.NewPassword)
+	if user.TokenVersion, err = us.UserRepo.ChangePassword(ctx, user.ProgramID, user.ID, passHash); err != nil {
+		return "", fmt.Errorf("error changing password of user %d: %w", user.ID, err)
+	}
+
+	token, err := us.buildToken(user, program)
+	if err != nil {
+		return "", fmt.Errorf("error build JWT token for user %w", err)
+	}
+
+	return token, nil
+}
+
+func (us *UserService) DeleteUser(ctx context.Context, user *models.User) error {
+	ctx, cancel := context.WithTimeout(ctx, us.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	if err := us.UserRepo.AnonymizeUser(ctx, user.ProgramID, user.ID, time.Now()); err != nil {
+		return fmt.Errorf("error anonymizing user %d: %w", user.ID, err)
+	}
+
+	return nil
+}
+
 func NewUserService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *UserService {
 	return &UserService{
-		logger:         logger,
-		cfg:            cfg,
-		UserRepo:       repository.NewUserRepo(logger, cfg, db),
-		BalanceService: NewBalanceService(logger, cfg, db),
+		logger:          logger,
+		cfg:             cfg,
+		UserRepo:        repository.NewUserRepo(logger, cfg, db),
+		BalanceService:  NewBalanceService(logger, cfg, db),
+		ReferralService: NewReferralService(logger, cfg, db),
 	}
 }
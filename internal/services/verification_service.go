@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"go.uber.org/zap"
+)
+
+const verifyBatchSize = 100
+
+type VerificationService struct {
+	logger         *zap.Logger
+	cfg            *config.Config
+	AdjustmentRepo *repository.AdjustmentRepo
+	AccrualService *AccrualService
+}
+
+func NewVerificationService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *VerificationService {
+	return &VerificationService{
+		logger:         logger,
+		cfg:            cfg,
+		AdjustmentRepo: repository.NewAdjustmentRepo(logger, cfg, db),
+		AccrualService: NewAccrualService(logger, cfg),
+	}
+}
+
+func (vs *VerificationService) ScheduleVerification(ctx context.Context, order *models.WatchedOrder) error {
+	if vs.cfg.Verification.Window <= 0 {
+		return nil
+	}
+
+	until := time.Now().Add(vs.cfg.Verification.Window)
+	if err := vs.AdjustmentRepo.ScheduleVerification(ctx, order.ProgramID, order.OrderNumber, until); err != nil {
+		return fmt.Errorf("error scheduling order verification %w", err)
+	}
+
+	return nil
+}
+
+// VerifyOrders при 429 сверка откладывается до следующего запуска.
+func (vs *VerificationService) VerifyOrders(ctx context.Context) error {
+	if vs.cfg.Verification.Window <= 0 {
+		return nil
+	}
+
+	orders, err := vs.AdjustmentRepo.GetOrdersToVerify(ctx, time.Now(), verifyBatchSize)
+	if err != nil {
+		return fmt.Errorf("error getting orders to verify %w", err)
+	}
+
+	for _, order := range orders {
+		status, accrual := order.AccrualOrderStatus, order.AccrualPoints
+
+		if _, _, err = vs.AccrualService.CheckOrdersStatus(order); err != nil {
+			if errors.Is(err, ErrRetryAfter) {
+				vs.logger.Debug("accrual system asks to retry later, verification postponed")
+				return nil
+			}
+			vs.logger.Error("error verifying order", zap.String("NUMBER", order.OrderNumber), zap.Error(err))
+			continue
+		}
+
+		// пустой ответ или промежуточный статус не отменяют уже зачисленное
+		final := order.AccrualOrderStatus == string(invalid) || order.AccrualOrderStatus == string(processed)
+		if final && (order.AccrualOrderStatus != status || order.AccrualPoints != accrual) {
+			vs.applyChange(ctx, order)
+		}
+
+		if err = vs.AdjustmentRepo.MarkVerified(ctx, order.ProgramID, order.OrderNumber, time.Now()); err != nil {
+			vs.logger.Error("error marking order verified", zap.String("NUMBER", order.OrderNumber), zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+func (vs *VerificationService) applyChange(ctx context.Context, order *models.WatchedOrder) {
+	now := time.Now()
+	result, err := vs.AdjustmentRepo.ApplyAccrualChange(ctx, order, vs.cfg.Verification.NegativePolicy,
+		now.AddDate(0, vs.cfg.Points.ExpiryMonths, 0), now)
+	if err != nil {
+		vs.logger.Error("error applying accrual change", zap.String("NUMBER", order.OrderNumber), zap.Error(err))
+		return
+	}
+
+	if result.Adjustment != nil {
+		vs.logger.Info("accrual adjusted after verification",
+			zap.String("NUMBER", order.OrderNumber),
+			zap.Float64("AMOUNT", result.Adjustment.Amount),
+			zap.String("STATUS", result.Adjustment.Status))
+	}
+}
+
+func (vs *VerificationService) GetAdjustments(ctx context.Context, status string) ([]*models.AccrualAdjustment, error) {
+	ctx, cancel := context.WithTimeout(ctx, vs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	adjustments, err := vs.AdjustmentRepo.GetAdjustments(ctx, status)
+	if err != nil {
+		return nil, fmt.Errorf("error getting adjustments %w", err)
+	}
+
+	return adjustments, nil
+}
+
+func (vs *VerificationService) ResolveAdjustment(
+	ctx context.Context,
+	id int,
+	approve bool,
+) (*models.AccrualAdjustment, error) {
+	ctx, cancel := context.WithTimeout(ctx, vs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	now := time.Now()
+	expiresAt := now.AddDate(0, vs.cfg.Points.ExpiryMonths, 0)
+	result, err := vs.AdjustmentRepo.ResolveAdjustment(ctx, id, approve, expiresAt, now)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving adjustment %d: %w", id, err)
+	}
+
+	return result.Adjustment, nil
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/config"
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/Melikhov-p/go-loyalty-system/internal/repository"
+	"go.uber.org/zap"
+)
+
+type VoucherService struct {
+	logger      *zap.Logger
+	cfg         *config.Config
+	VoucherRepo *repository.VoucherRepo
+}
+
+func NewVoucherService(logger *zap.Logger, cfg *config.Config, db *sql.DB) *VoucherService {
+	return &VoucherService{
+		logger:      logger,
+		cfg:         cfg,
+		VoucherRepo: repository.NewVoucherRepo(logger, cfg, db),
+	}
+}
+
+func (vs *VoucherService) CreateBatch(
+	ctx context.Context,
+	programID int,
+	req *models.VoucherBatchRequest,
+) (*models.VoucherBatch, error) {
+	ctx, cancel := context.WithTimeout(ctx, vs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	b := &models.VoucherBatch{
+		ProgramID: programID,
+		Name:      req.Name,
+		Amount:    req.Amount,
+		Count:     req.Count,
+		MaxUses:   req.MaxUses,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if b.MaxUses == 0 {
+		b.MaxUses = 1
+	}
+	if err := vs.VoucherRepo.CreateBatch(ctx, b); err != nil {
+		return nil, fmt.Errorf("error creating voucher batch %w", err)
+	}
+
+	return b, nil
+}
+
+func (vs *VoucherService) GetBatches(ctx context.Context, programID int) ([]*models.VoucherBatch, error) {
+	ctx, cancel := context.WithTimeout(ctx, vs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	batches, err := vs.VoucherRepo.GetBatches(ctx, programID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting voucher batches %w", err)
+	}
+
+	return batches, nil
+}
+
+func (vs *VoucherService) VoidBatch(ctx context.Context, programID, id int) (*models.VoucherBatch, error) {
+	ctx, cancel := context.WithTimeout(ctx, vs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	b, err := vs.VoucherRepo.VoidBatch(ctx, programID, id, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("error voiding voucher batch %d: %w", id, err)
+	}
+
+	return b, nil
+}
+
+func (vs *VoucherService) GetBatchCodes(
+	ctx context.Context,
+	programID, id int,
+) (*models.VoucherBatch, []*models.Voucher, error) {
+	ctx, cancel := context.WithTimeout(ctx, vs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	b, err := vs.VoucherRepo.GetBatch(ctx, programID, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting voucher batch %d: %w", id, err)
+	}
+	vouchers, err := vs.VoucherRepo.GetVouchers(ctx, b.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting codes of voucher batch %d: %w", id, err)
+	}
+
+	return b, vouchers, nil
+}
+
+func (vs *VoucherService) Redeem(
+	ctx context.Context,
+	user *models.User,
+	code string,
+) (*models.VoucherRedemption, error) {
+	ctx, cancel := context.WithTimeout(ctx, vs.cfg.DB.ContextTimeout)
+	defer cancel()
+
+	now := time.Now()
+	redemption, err := vs.VoucherRepo.RedeemVoucher(ctx, user, code, now, now.AddDate(0, vs.cfg.Points.ExpiryMonths, 0))
+	if err != nil {
+		return nil, fmt.Errorf("error redeeming voucher %w", err)
+	}
+
+	return redemption, nil
+}
@@ -0,0 +1,171 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+)
+
+const (
+	pdfLinesPerPage = 50
+	pdfFontSize     = 10
+	pdfLeading      = 14
+	pdfTopY         = 800
+	pdfLeftX        = 50
+
+	// номера объектов, известные заранее: каталог, дерево страниц и шрифт
+	pdfCatalogObj = 1
+	pdfPagesObj   = 2
+	pdfFontObj    = 3
+)
+
+type countingWriter struct {
+	w      io.Writer
+	offset int
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.offset += n
+	if err != nil {
+		return n, fmt.Errorf("error writing pdf bytes %w", err)
+	}
+	return n, nil
+}
+
+// pdfWriter в памяти держится только текущая страница.
+type pdfWriter struct {
+	w       *countingWriter
+	offsets map[int]int
+	pages   []int
+	lines   []string
+	nextObj int
+	err     error
+}
+
+func newPDFWriter(w io.Writer) *pdfWriter {
+	return &pdfWriter{
+		w:       &countingWriter{w: w},
+		offsets: make(map[int]int),
+		nextObj: pdfFontObj + 1,
+	}
+}
+
+func (pw *pdfWriter) WriteHeader(statement *models.Statement) error {
+	pw.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+	pw.object(pdfFontObj, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>")
+
+	from := "beginning"
+	if statement.From != nil {
+		from = statement.From.Format(time.DateOnly)
+	}
+
+	pw.lines = append(pw.lines,
+		"Gophermart loyalty statement",
+		fmt.Sprintf("Period: %s - %s", from, statement.To.Format(time.DateOnly)),
+		"Opening balance: "+formatAmount(statement.OpeningBalance),
+		"Credits:         "+formatAmount(statement.Credits),
+		"Debits:          "+formatAmount(statement.Debits),
+		"Closing balance: "+formatAmount(statement.ClosingBalance),
+		"",
+		fmt.Sprintf("%-20s %-12s %-20s %12s", "Date", "Kind", "Reference", "Amount"),
+	)
+
+	return pw.err
+}
+
+func (pw *pdfWriter) WriteEntry(entry *models.StatementEntry) error {
+	pw.lines = append(pw.lines, fmt.Sprintf("%-20s %-12s %-20s %12s",
+		entry.OccurredAt.Format(time.DateTime),
+		entry.Kind,
+		entry.Reference,
+		formatAmount(entry.Amount)))
+
+	if len(pw.lines) >= pdfLinesPerPage {
+		pw.flushPage()
+	}
+
+	return pw.err
+}
+
+func (pw *pdfWriter) Close() error {
+	if len(pw.lines) > 0 || len(pw.pages) == 0 {
+		pw.flushPage()
+	}
+
+	kids := make([]string, 0, len(pw.pages))
+	for _, page := range pw.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+	pw.object(pdfPagesObj, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>",
+		strings.Join(kids, " "), len(pw.pages)))
+	pw.object(pdfCatalogObj, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfPagesObj))
+
+	xrefOffset := pw.w.offset
+	pw.printf("xref\n0 %d\n0000000000 65535 f \n", pw.nextObj)
+	for obj := 1; obj < pw.nextObj; obj++ {
+		pw.printf("%010d 00000 n \n", pw.offsets[obj])
+	}
+	pw.printf("trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		pw.nextObj, pdfCatalogObj, xrefOffset)
+
+	return pw.err
+}
+
+func (pw *pdfWriter) flushPage() {
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", pdfFontSize, pdfLeading, pdfLeftX, pdfTopY)
+	for _, line := range pw.lines {
+		fmt.Fprintf(&content, "(%s) '\n", escapePDFText(line))
+	}
+	content.WriteString("ET\n")
+	pw.lines = pw.lines[:0]
+
+	contentObj := pw.allocObj()
+	pw.object(contentObj, fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+
+	pageObj := pw.allocObj()
+	pw.object(pageObj, fmt.Sprintf(
+		"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+		pdfPagesObj, pdfFontObj, contentObj))
+	pw.pages = append(pw.pages, pageObj)
+}
+
+func (pw *pdfWriter) allocObj() int {
+	obj := pw.nextObj
+	pw.nextObj++
+	return obj
+}
+
+func (pw *pdfWriter) object(obj int, body string) {
+	pw.offsets[obj] = pw.w.offset
+	pw.printf("%d 0 obj\n%s\nendobj\n", obj, body)
+}
+
+func (pw *pdfWriter) printf(format string, args ...any) {
+	if pw.err != nil {
+		return
+	}
+	_, pw.err = fmt.Fprintf(pw.w, format, args...)
+}
+
+// escapePDFText шрифт Courier поддерживает только ASCII.
+func escapePDFText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
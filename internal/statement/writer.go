@@ -0,0 +1,134 @@
+package statement
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+)
+
+var ErrUnknownFormat error = errors.New("unknown statement format")
+
+type Writer interface {
+	WriteHeader(statement *models.Statement) error
+	WriteEntry(entry *models.StatementEntry) error
+	Close() error
+}
+
+func NewWriter(format string, w io.Writer) (Writer, string, error) {
+	switch format {
+	case models.StatementFormatJSON:
+		return &jsonWriter{w: w}, "application/json", nil
+	case models.StatementFormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, "text/csv", nil
+	case models.StatementFormatPDF:
+		return newPDFWriter(w), "application/pdf", nil
+	default:
+		return nil, "", fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+type jsonWriter struct {
+	w       io.Writer
+	entries int
+}
+
+func (jw *jsonWriter) WriteHeader(statement *models.Statement) error {
+	header, err := json.Marshal(statement)
+	if err != nil {
+		return fmt.Errorf("error marshal statement header %w", err)
+	}
+
+	// дописываем массив движений в тот же объект, не собирая его целиком
+	if _, err = fmt.Fprintf(jw.w, `%s,"entries":[`, header[:len(header)-1]); err != nil {
+		return fmt.Errorf("error writing json statement header %w", err)
+	}
+
+	return nil
+}
+
+func (jw *jsonWriter) WriteEntry(entry *models.StatementEntry) error {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error marshal statement entry %w", err)
+	}
+
+	if jw.entries > 0 {
+		if _, err = io.WriteString(jw.w, ","); err != nil {
+			return fmt.Errorf("error writing json statement separator %w", err)
+		}
+	}
+	if _, err = jw.w.Write(raw); err != nil {
+		return fmt.Errorf("error writing json statement entry %w", err)
+	}
+	jw.entries++
+
+	return nil
+}
+
+func (jw *jsonWriter) Close() error {
+	if _, err := io.WriteString(jw.w, "]}\n"); err != nil {
+		return fmt.Errorf("error closing json statement %w", err)
+	}
+
+	return nil
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (cw *csvWriter) WriteHeader(statement *models.Statement) error {
+	from := ""
+	if statement.From != nil {
+		from = statement.From.Format(time.RFC3339)
+	}
+
+	records := [][]string{
+		{"from", from},
+		{"to", statement.To.Format(time.RFC3339)},
+		{"opening_balance", formatAmount(statement.OpeningBalance)},
+		{"credits", formatAmount(statement.Credits)},
+		{"debits", formatAmount(statement.Debits)},
+		{"closing_balance", formatAmount(statement.ClosingBalance)},
+		{},
+		{"occurred_at", "kind", "reference", "amount"},
+	}
+	if err := cw.w.WriteAll(records); err != nil {
+		return fmt.Errorf("error writing csv statement header %w", err)
+	}
+
+	return nil
+}
+
+func (cw *csvWriter) WriteEntry(entry *models.StatementEntry) error {
+	err := cw.w.Write([]string{
+		entry.OccurredAt.Format(time.RFC3339),
+		entry.Kind,
+		entry.Reference,
+		formatAmount(entry.Amount),
+	})
+	if err != nil {
+		return fmt.Errorf("error writing csv statement entry %w", err)
+	}
+
+	return nil
+}
+
+func (cw *csvWriter) Close() error {
+	cw.w.Flush()
+	if err := cw.w.Error(); err != nil {
+		return fmt.Errorf("error flushing csv statement %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Melikhov-p/go-loyalty-system/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestStatement(t *testing.T, format string, entries int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer, _, err := NewWriter(format, &buf)
+	require.NoError(t, err)
+
+	to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, writer.WriteHeader(&models.Statement{
+		To:             to,
+		OpeningBalance: 10,
+		Credits:        500,
+		Debits:         100.5,
+		ClosingBalance: 409.5,
+	}))
+	for i := range entries {
+		require.NoError(t, writer.WriteEntry(&models.StatementEntry{
+			OccurredAt: to.Add(-time.Duration(i) * time.Hour),
+			Kind:       "accrual",
+			Reference:  "(" + strconv.Itoa(i) + ")",
+			Amount:     1.5,
+		}))
+	}
+	require.NoError(t, writer.Close())
+
+	return buf.Bytes()
+}
+
+func TestJSONWriter(t *testing.T) {
+	var got struct {
+		models.Statement
+		Entries []*models.StatementEntry `json:"entries"`
+	}
+
+	require.NoError(t, json.Unmarshal(writeTestStatement(t, models.StatementFormatJSON, 3), &got))
+	assert.InDelta(t, 409.5, got.ClosingBalance, 0.001)
+	assert.Len(t, got.Entries, 3)
+
+	require.NoError(t, json.Unmarshal(writeTestStatement(t, models.StatementFormatJSON, 0), &got))
+	assert.Empty(t, got.Entries)
+}
+
+func TestCSVWriter(t *testing.T) {
+	out := string(writeTestStatement(t, models.StatementFormatCSV, 2))
+
+	assert.Contains(t, out, "closing_balance,409.50\n")
+	assert.Contains(t, out, "occurred_at,kind,reference,amount\n")
+	assert.Equal(t, 10, strings.Count(out, "\n"))
+}
+
+func TestPDFWriter(t *testing.T) {
+	out := writeTestStatement(t, models.StatementFormatPDF, 120)
+
+	assert.True(t, bytes.HasPrefix(out, []byte("%PDF-1.4")))
+	assert.True(t, bytes.HasSuffix(out, []byte("%%EOF\n")))
+	assert.Contains(t, string(out), `\(0\)`)
+
+	// каждая запись xref должна указывать на начало своего объекта
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	require.NotNil(t, startxref)
+	xrefOffset, err := strconv.Atoi(string(startxref[1]))
+	require.NoError(t, err)
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xrefOffset:], -1)
+	require.NotEmpty(t, entries)
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(string(entry[1]))
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(out[offset:], []byte(strconv.Itoa(i+1)+" 0 obj")), "object %d", i+1)
+	}
+
+	assert.Contains(t, string(out), "/Count 3")
+}
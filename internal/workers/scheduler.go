@@ -0,0 +1,73 @@
+package workers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler пропускает запуск, если предыдущий ещё не завершился.
+type Scheduler struct {
+	log  *zap.Logger
+	jobs []*Job
+}
+
+func NewScheduler(log *zap.Logger) *Scheduler {
+	return &Scheduler{
+		log: log,
+	}
+}
+
+func (s *Scheduler) Add(name string, interval time.Duration, run func(ctx context.Context) error) {
+	s.jobs = append(s.jobs, &Job{
+		Name:     name,
+		Interval: interval,
+		Run:      run,
+	})
+}
+
+func (s *Scheduler) Run(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	for _, job := range s.jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, job)
+		}()
+	}
+	wg.Wait()
+
+	s.log.Debug("scheduler has been shutdown")
+}
+
+func (s *Scheduler) loop(ctx context.Context, job *Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.runJob(ctx, job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runJob(ctx context.Context, job *Job) {
+	started := time.Now()
+	if err := job.Run(ctx); err != nil {
+		s.log.Error("scheduled job failed", zap.String("JOB", job.Name), zap.Error(err))
+		return
+	}
+	s.log.Debug("scheduled job done", zap.String("JOB", job.Name), zap.Duration("TOOK", time.Since(started)))
+}
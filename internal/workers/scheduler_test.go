@@ -0,0 +1,75 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestScheduler(t *testing.T) {
+	testCases := []struct {
+		name     string
+		interval time.Duration
+		runFor   time.Duration
+		err      error
+		minRuns  int32
+		maxRuns  int32
+	}{
+		{
+			name:     "Runs Immediately",
+			interval: time.Hour,
+			runFor:   50 * time.Millisecond,
+			minRuns:  1,
+			maxRuns:  1,
+		},
+		{
+			name:     "Runs Periodically",
+			interval: 10 * time.Millisecond,
+			runFor:   55 * time.Millisecond,
+			minRuns:  3,
+			maxRuns:  7,
+		},
+		{
+			name:     "Keeps Running After Error",
+			interval: 10 * time.Millisecond,
+			runFor:   55 * time.Millisecond,
+			err:      errors.New("job failed"),
+			minRuns:  3,
+			maxRuns:  7,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			var runs atomic.Int32
+			s := NewScheduler(zap.NewNop())
+			s.Add("test", test.interval, func(_ context.Context) error {
+				runs.Add(1)
+				return test.err
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), test.runFor)
+			defer cancel()
+
+			done := make(chan struct{})
+			go func() {
+				s.Run(ctx)
+				close(done)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("scheduler did not stop after context cancel")
+			}
+
+			assert.GreaterOrEqual(t, runs.Load(), test.minRuns)
+			assert.LessOrEqual(t, runs.Load(), test.maxRuns)
+		})
+	}
+}
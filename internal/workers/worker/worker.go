@@ -65,6 +65,19 @@ func (w *Worker) Run() {
 			return
 		case task := <-w.taskCh:
 			w.log.Debug("worker found new task", zap.Int("WorkerID", w.id))
+			if !task.Registered {
+				retryAfter, err := w.accrualService.RegisterOrder(task)
+				if err != nil {
+					if errors.Is(err, services.ErrRetryAfter) {
+						w.dispatcher.RestWorkers(retryAfter)
+					} else {
+						w.log.Error("error registering order in accrual service",
+							zap.Error(err),
+							zap.Int("OrderID", task.ID))
+					}
+					continue
+				}
+			}
 			orderToUpdate, retryAfter, err := w.accrualService.CheckOrdersStatus(task)
 			if err != nil {
 				if errors.Is(err, services.ErrRetryAfter) {
@@ -0,0 +1,4 @@
+// Package client HTTP-клиент API лояльности, сгенерированный по internal/openapi/openapi.yaml.
+package client
+
+//go:generate go run github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen -config oapi-codegen.yaml ../../internal/openapi/openapi.yaml
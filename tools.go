@@ -0,0 +1,8 @@
+//go:build tools
+
+// Package tools фиксирует версии инструментов генерации кода в go.mod.
+package tools
+
+import (
+	_ "github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen"
+)